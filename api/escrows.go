@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sourcegraph/jsonrpc2"
+
+	"github.com/CovenantSQL/CovenantSQL/api/models"
+)
+
+func init() {
+	rpc.RegisterMethod("bp_getEscrowByID", bpGetEscrowByID, bpGetEscrowByIDParams{})
+	rpc.RegisterMethod("bp_getEscrowList", bpGetEscrowList, bpGetEscrowListParams{})
+	rpc.RegisterMethod("bp_getEscrowBalance", bpGetEscrowBalance, bpGetEscrowBalanceParams{})
+}
+
+type bpGetEscrowByIDParams struct {
+	ID string `json:"id"`
+}
+
+func bpGetEscrowByID(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (
+	result interface{}, err error,
+) {
+	params := ctx.Value("_params").(*bpGetEscrowByIDParams)
+	model := models.EscrowsModel{}
+	return model.GetEscrowByID(params.ID)
+}
+
+type bpGetEscrowListParams struct {
+	Address string `json:"address"`
+	Page    int    `json:"page"`
+	Size    int    `json:"size"`
+}
+
+func (params *bpGetEscrowListParams) Validate() error {
+	if params.Size > 1000 {
+		return errors.New("max size is 1000")
+	}
+	return nil
+}
+
+// BPGetEscrowListResponse is the response for method bp_getEscrowList.
+type BPGetEscrowListResponse struct {
+	Escrows    []*models.Escrow   `json:"escrows"`
+	Pagination *models.Pagination `json:"pagination"`
+}
+
+func bpGetEscrowList(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (
+	result interface{}, err error,
+) {
+	params := ctx.Value("_params").(*bpGetEscrowListParams)
+	model := models.EscrowsModel{}
+	escrows, pagination, err := model.GetEscrowList(params.Address, params.Page, params.Size)
+	if err != nil {
+		return nil, err
+	}
+	result = &BPGetEscrowListResponse{
+		Escrows:    escrows,
+		Pagination: pagination,
+	}
+	return result, nil
+}
+
+type bpGetEscrowBalanceParams struct {
+	Address string `json:"address"`
+}
+
+func (params *bpGetEscrowBalanceParams) Validate() error {
+	if params.Address == "" {
+		return errors.New("empty address")
+	}
+	return nil
+}
+
+// BPGetEscrowBalanceResponse is the response for method bp_getEscrowBalance.
+type BPGetEscrowBalanceResponse struct {
+	Address  string                  `json:"address"`
+	Balances []*models.EscrowBalance `json:"balances"`
+}
+
+func bpGetEscrowBalance(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (
+	result interface{}, err error,
+) {
+	params := ctx.Value("_params").(*bpGetEscrowBalanceParams)
+	model := models.EscrowsModel{}
+	balances, err := model.GetEscrowBalance(params.Address)
+	if err != nil {
+		return nil, err
+	}
+	result = &BPGetEscrowBalanceResponse{
+		Address:  params.Address,
+		Balances: balances,
+	}
+	return result, nil
+}
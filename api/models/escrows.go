@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+// EscrowsModel groups operations on Escrows.
+type EscrowsModel struct{}
+
+// Escrow is an escrow which is neither claimed nor refunded yet.
+type Escrow struct {
+	ID            string `db:"id" json:"id"` // pk
+	Sender        string `db:"sender" json:"sender"`
+	Receiver      string `db:"receiver" json:"receiver"`
+	TokenType     int32  `db:"token_type" json:"token_type"`
+	Token         string `db:"-" json:"token"`
+	Amount        uint64 `db:"amount" json:"amount"`
+	ReleaseHeight uint32 `db:"release_height" json:"release_height"`
+	ExpireHeight  uint32 `db:"expire_height" json:"expire_height"`
+}
+
+// PostGet is the hook after SELECT query.
+func (e *Escrow) PostGet(s gorp.SqlExecutor) error {
+	e.Token = types.TokenType(e.TokenType).String()
+	return nil
+}
+
+// EscrowBalance is the token amount locked in escrows of an account.
+type EscrowBalance struct {
+	TokenType int32  `db:"token_type" json:"token_type"`
+	Token     string `db:"-" json:"token"`
+	Locked    uint64 `db:"locked" json:"locked"`
+	Incoming  uint64 `db:"incoming" json:"incoming"`
+}
+
+// GetEscrowByID get an escrow by its id.
+func (m *EscrowsModel) GetEscrowByID(id string) (escrow *Escrow, err error) {
+	escrow = &Escrow{}
+	query := `SELECT id, sender, receiver, token_type, amount, release_height, expire_height
+	FROM escrow WHERE id = ?`
+	err = chaindb.SelectOne(escrow, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return escrow, err
+}
+
+// GetEscrowList get a list of escrows sent or to be received by the account address.
+func (m *EscrowsModel) GetEscrowList(address string, page, size int) (
+	escrows []*Escrow, pagination *Pagination, err error,
+) {
+	var (
+		querySQL = `
+		SELECT
+			id,
+			sender,
+			receiver,
+			token_type,
+			amount,
+			release_height,
+			expire_height
+		FROM
+			escrow
+		`
+		countSQL = buildCountSQL(querySQL)
+		conds    []string
+		args     []interface{}
+	)
+
+	pagination = NewPagination(page, size)
+	if address != "" {
+		conds = append(conds, "(sender = ? OR receiver = ?)")
+		args = append(args, address, address)
+	}
+
+	querySQL, countSQL = buildSQLWithConds(querySQL, countSQL, conds)
+	count, err := chaindb.SelectInt(countSQL, args...)
+	if err != nil {
+		return nil, pagination, err
+	}
+	pagination.SetTotal(int(count))
+	if pagination.Offset() > pagination.Total {
+		return escrows, pagination, nil
+	}
+
+	querySQL += " ORDER BY expire_height ASC, id ASC"
+	querySQL += " LIMIT ? OFFSET ?"
+	args = append(args, pagination.Limit(), pagination.Offset())
+
+	_, err = chaindb.Select(&escrows, querySQL, args...)
+	return escrows, pagination, err
+}
+
+// GetEscrowBalance get the escrow balances of the account address grouped by token type.
+func (m *EscrowsModel) GetEscrowBalance(address string) (balances []*EscrowBalance, err error) {
+	query := `
+	SELECT
+		token_type,
+		SUM(CASE WHEN sender = ? THEN amount ELSE 0 END) AS locked,
+		SUM(CASE WHEN receiver = ? THEN amount ELSE 0 END) AS incoming
+	FROM
+		escrow
+	WHERE
+		sender = ? OR receiver = ?
+	GROUP BY token_type
+	ORDER BY token_type ASC`
+	if _, err = chaindb.Select(&balances, query, address, address, address, address); err != nil {
+		return
+	}
+	for _, v := range balances {
+		v.Token = types.TokenType(v.TokenType).String()
+	}
+	return
+}
@@ -89,6 +89,56 @@ func (m *TransactionsModel) GetTransactionListOfBlock(ofBlockHeight int, page, s
 	return txs, pagination, err
 }
 
+// GetTransactionListOfType get a transaction list of the specified type, optionally filtered
+// by the account address.
+func (m *TransactionsModel) GetTransactionListOfType(txType int, address string, page, size int) (
+	txs []*Transaction, pagination *Pagination, err error,
+) {
+	var (
+		querySQL = `
+		SELECT
+			block_height,
+			tx_index,
+			hash,
+			block_hash,
+			timestamp,
+			tx_type,
+			address,
+			raw
+		FROM
+			indexed_transactions
+		`
+		countSQL = buildCountSQL(querySQL)
+		conds    []string
+		args     []interface{}
+	)
+
+	pagination = NewPagination(page, size)
+	conds = append(conds, "tx_type = ?")
+	args = append(args, txType)
+	if address != "" {
+		conds = append(conds, "address = ?")
+		args = append(args, address)
+	}
+
+	querySQL, countSQL = buildSQLWithConds(querySQL, countSQL, conds)
+	count, err := chaindb.SelectInt(countSQL, args...)
+	if err != nil {
+		return nil, pagination, err
+	}
+	pagination.SetTotal(int(count))
+	if pagination.Offset() > pagination.Total {
+		return txs, pagination, nil
+	}
+
+	querySQL += " ORDER BY block_height DESC, tx_index DESC"
+	querySQL += " LIMIT ? OFFSET ?"
+	args = append(args, pagination.Limit(), pagination.Offset())
+
+	_, err = chaindb.Select(&txs, querySQL, args...)
+	return txs, pagination, err
+}
+
 // GetTransactionList get a transaction list by hash marker.
 func (m *TransactionsModel) GetTransactionList(since string, page, size int) (
 	txs []*Transaction, pagination *Pagination, err error,
@@ -60,6 +60,18 @@ var (
 		`CREATE INDEX IF NOT EXISTS "idx__indexed_transactions__timestamp" ON "indexed_transactions" ("timestamp" DESC);`,
 		`CREATE INDEX IF NOT EXISTS "idx__indexed_transactions__tx_type__timestamp" ON "indexed_transactions" ("tx_type", "timestamp" DESC);`,
 		`CREATE INDEX IF NOT EXISTS "idx__indexed_transactions__address__timestamp" ON "indexed_transactions" ("address", "timestamp" DESC);`,
+
+		`CREATE TABLE IF NOT EXISTS "escrow" (
+	"id"				TEXT,
+	"sender"			TEXT,
+	"receiver"			TEXT,
+	"token_type"		INTEGER,
+	"amount"			INTEGER,
+	"release_height"	INTEGER,
+	"expire_height"		INTEGER,
+	"encoded"			BLOB,
+	UNIQUE ("id")
+);`,
 	}
 
 	blocksMockData = [][]interface{}{
@@ -90,6 +102,12 @@ var (
 		{10, 1, "5MX357EQDlMUxZVPjjXeFQ", "er05e7FvAZOP3gP5_w_RKw", 1546591421791893744, 4, addrB, `{}`},
 		{10, 2, "lXTWT_P7NRxMHukZCEUfng", "er05e7FvAZOP3gP5_w_RKw", 1546591421909181774, 2, addrB, `{}`},
 	}
+
+	escrowsMockData = [][]interface{}{
+		{"2bOqNhBRcEMqQTHMzERnMA", addrA, addrB, 0, 100, 10, 20, []byte{}},
+		{"6QPDnyyYiTpFyXMxy3W9Zw", addrA, addrB, 1, 50, 5, 30, []byte{}},
+		{"xwv8pHIAo8F8YEgwjmFUmA", addrB, addrA, 0, 30, 1, 15, []byte{}},
+	}
 )
 
 func mockData(t *testing.T) {
@@ -129,6 +147,13 @@ func mockData(t *testing.T) {
 	); err != nil {
 		t.Errorf("mock data for indexed_transactions failed: %v", err)
 	}
+
+	if err := insertRows(
+		"insert into escrow values (?,?,?,?,?,?,?,?)",
+		escrowsMockData,
+	); err != nil {
+		t.Errorf("mock data for escrow failed: %v", err)
+	}
 }
 
 func setupWebsocketClient(addr string) (client *jsonrpc2.Conn, err error) {
@@ -221,6 +246,24 @@ func (c *bpGetTransactionListOfBlockTestCase) String() string {
 	return fmt.Sprintf("fetch %d transactions at page %d of block %d", c.Size, c.Page, c.BlockHeight)
 }
 
+type bpGetTransactionListOfTypeTestCase struct {
+	TxType             int
+	Address            string
+	Page               int
+	Size               int
+	ExpectedResults    [][]interface{}
+	ExpectedPagination *models.Pagination
+}
+
+func (c *bpGetTransactionListOfTypeTestCase) Params() interface{} {
+	return []interface{}{c.TxType, c.Address, c.Page, c.Size}
+}
+
+func (c *bpGetTransactionListOfTypeTestCase) String() string {
+	return fmt.Sprintf("fetch %d transactions at page %d of type %d address %q",
+		c.Size, c.Page, c.TxType, c.Address)
+}
+
 type bpGetTransactionByHashTestCase struct {
 	Hash           string
 	ExpectedResult []interface{}
@@ -230,6 +273,22 @@ func (c *bpGetTransactionByHashTestCase) String() string {
 	return fmt.Sprintf("fetch transaction hashed %q", c.Hash)
 }
 
+type bpGetEscrowListTestCase struct {
+	Address            string
+	Page               int
+	Size               int
+	ExpectedResults    [][]interface{}
+	ExpectedPagination *models.Pagination
+}
+
+func (c *bpGetEscrowListTestCase) Params() interface{} {
+	return []interface{}{c.Address, c.Page, c.Size}
+}
+
+func (c *bpGetEscrowListTestCase) String() string {
+	return fmt.Sprintf("fetch %d escrows at page %d of address %q", c.Size, c.Page, c.Address)
+}
+
 func TestJSONRPCService(t *testing.T) {
 	t.Logf("testdb: %s", testdb)
 	mockData(t)
@@ -275,6 +334,21 @@ func TestJSONRPCService(t *testing.T) {
 			convey.So(item.Address, ShouldEqual, cp[6].(string))
 			convey.So(item.Raw, ShouldEqual, cp[7].(string))
 		}
+
+		conveyEscrow = func(convey C, item *models.Escrow, cp []interface{}) {
+			if cp == nil {
+				convey.So(item, ShouldBeNil)
+				return
+			}
+
+			convey.So(item.ID, ShouldEqual, cp[0].(string))
+			convey.So(item.Sender, ShouldEqual, cp[1].(string))
+			convey.So(item.Receiver, ShouldEqual, cp[2].(string))
+			convey.So(item.TokenType, ShouldEqual, cp[3].(int))
+			convey.So(item.Amount, ShouldEqual, cp[4].(int))
+			convey.So(item.ReleaseHeight, ShouldEqual, cp[5].(int))
+			convey.So(item.ExpireHeight, ShouldEqual, cp[6].(int))
+		}
 	)
 
 	Convey("API not found", t, func() {
@@ -529,6 +603,68 @@ func TestJSONRPCService(t *testing.T) {
 			}
 		})
 
+		Convey("bp_getTransactionListOfType should fail on invalid parameters", func(c C) {
+			var (
+				result    = new(api.BPGetTransactionListResponse)
+				testCases = map[string][]interface{}{
+					"invalid transaction type": {0, "", 1, 10},
+					"page size over 1000":      {1, "", 1, 1001},
+				}
+			)
+
+			for name, testCase := range testCases {
+				Convey(name, func() {
+					err := rpc.Call(
+						context.Background(),
+						"bp_getTransactionListOfType",
+						testCase,
+						&result,
+					)
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+
+		Convey("bp_getTransactionListOfType should success on fetching valid number of transactions", func(c C) {
+			var (
+				result    = new(api.BPGetTransactionListResponse)
+				testCases = []bpGetTransactionListOfTypeTestCase{
+					{
+						2, "", 1, 10,
+						[][]interface{}{transactionsMockData[3], transactionsMockData[4], transactionsMockData[8]},
+						&models.Pagination{Page: 1, Size: 10, Total: 3, Pages: 1},
+					},
+					{
+						2, addrB, 1, 10,
+						[][]interface{}{transactionsMockData[3], transactionsMockData[8]},
+						&models.Pagination{Page: 1, Size: 10, Total: 2, Pages: 1},
+					},
+					{
+						3, "", 1, 10, nil,
+						&models.Pagination{Page: 1, Size: 10, Total: 0, Pages: 0},
+					},
+				}
+			)
+
+			for i, testCase := range testCases {
+				Convey(fmt.Sprintf("case#%d: %s", i, testCase.String()), func() {
+					err := rpc.Call(
+						context.Background(),
+						"bp_getTransactionListOfType",
+						testCase.Params(),
+						&result,
+					)
+					So(err, ShouldBeNil)
+					So(len(result.Transactions), ShouldEqual, len(testCase.ExpectedResults))
+					So(result.Pagination, ShouldResemble, testCase.ExpectedPagination)
+					for i, item := range result.Transactions {
+						cp := testCase.ExpectedResults[len(result.Transactions)-i-1]
+						conveyTransaction(c, item, cp)
+					}
+				})
+			}
+		})
+
 		Convey("bp_getTransactionByHash should fetch transactions on existed hash and nothing for an non-existed hash", func(c C) {
 			var (
 				result = new(models.Transaction)
@@ -557,4 +693,83 @@ func TestJSONRPCService(t *testing.T) {
 			rpc.Close()
 		})
 	})
+
+	Convey("escrows API", t, func() {
+		rpc, err := setupWebsocketClient(addr)
+		if err != nil {
+			t.Errorf("failed to connect to wsapi server: %v", err)
+			return
+		}
+
+		Convey("bp_getEscrowByID should fetch escrows on existed id and nothing for an non-existed id", func(c C) {
+			var result = new(models.Escrow)
+			err := rpc.Call(context.Background(), "bp_getEscrowByID",
+				[]interface{}{escrowsMockData[1][0]}, &result)
+			So(err, ShouldBeNil)
+			So(result.Token, ShouldEqual, "Wave")
+			conveyEscrow(c, result, escrowsMockData[1])
+
+			result = new(models.Escrow)
+			err = rpc.Call(context.Background(), "bp_getEscrowByID",
+				[]interface{}{"o362ksNHl8gIL4cbXjkMEQ"}, &result)
+			So(err, ShouldBeNil)
+			conveyEscrow(c, result, nil)
+		})
+
+		Convey("bp_getEscrowList should success on fetching valid number of escrows", func(c C) {
+			var (
+				result    = new(api.BPGetEscrowListResponse)
+				testCases = []bpGetEscrowListTestCase{
+					{
+						"", 1, 10,
+						[][]interface{}{escrowsMockData[2], escrowsMockData[0], escrowsMockData[1]},
+						&models.Pagination{Page: 1, Size: 10, Total: 3, Pages: 1},
+					},
+					{
+						addrA, 2, 2,
+						[][]interface{}{escrowsMockData[1]},
+						&models.Pagination{Page: 2, Size: 2, Total: 3, Pages: 2},
+					},
+					{
+						bpA, 1, 10, nil,
+						&models.Pagination{Page: 1, Size: 10, Total: 0, Pages: 0},
+					},
+				}
+			)
+
+			for i, testCase := range testCases {
+				Convey(fmt.Sprintf("case#%d: %s", i, testCase.String()), func() {
+					err := rpc.Call(
+						context.Background(),
+						"bp_getEscrowList",
+						testCase.Params(),
+						&result,
+					)
+					So(err, ShouldBeNil)
+					So(len(result.Escrows), ShouldEqual, len(testCase.ExpectedResults))
+					So(result.Pagination, ShouldResemble, testCase.ExpectedPagination)
+					for i, item := range result.Escrows {
+						conveyEscrow(c, item, testCase.ExpectedResults[i])
+					}
+				})
+			}
+		})
+
+		Convey("bp_getEscrowBalance should sum up locked and incoming amounts", func() {
+			var result = new(api.BPGetEscrowBalanceResponse)
+			err := rpc.Call(context.Background(), "bp_getEscrowBalance", []interface{}{""}, &result)
+			So(err, ShouldNotBeNil)
+			err = rpc.Call(context.Background(), "bp_getEscrowBalance", []interface{}{addrA}, &result)
+			So(err, ShouldBeNil)
+			So(result.Address, ShouldEqual, addrA)
+			So(result.Balances, ShouldResemble, []*models.EscrowBalance{
+				{TokenType: 0, Token: "Particle", Locked: 100, Incoming: 30},
+				{TokenType: 1, Token: "Wave", Locked: 50, Incoming: 0},
+			})
+		})
+
+		Reset(func() {
+			rpc.Close()
+		})
+	})
 }
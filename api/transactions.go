@@ -8,12 +8,14 @@ import (
 	"github.com/sourcegraph/jsonrpc2"
 
 	"github.com/CovenantSQL/CovenantSQL/api/models"
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 )
 
 func init() {
 	rpc.RegisterMethod("bp_getTransactionList", bpGetTransactionList, bpGetTransactionListParams{})
 	rpc.RegisterMethod("bp_getTransactionByHash", bpGetTransactionByHash, bpGetTransactionByHashParams{})
 	rpc.RegisterMethod("bp_getTransactionListOfBlock", bpGetTransactionListOfBlock, bpGetTransactionListOfBlockParams{})
+	rpc.RegisterMethod("bp_getTransactionListOfType", bpGetTransactionListOfType, bpGetTransactionListOfTypeParams{})
 }
 
 type bpGetTransactionListParams struct {
@@ -83,6 +85,42 @@ func bpGetTransactionListOfBlock(ctx context.Context, conn *jsonrpc2.Conn, req *
 	return result, nil
 }
 
+type bpGetTransactionListOfTypeParams struct {
+	TxType  int    `json:"type"`
+	Address string `json:"address"`
+	Page    int    `json:"page"`
+	Size    int    `json:"size"`
+}
+
+func (params *bpGetTransactionListOfTypeParams) Validate() error {
+	if params.TxType <= int(pi.TransactionTypeDeprecated) || params.TxType >= int(pi.TransactionTypeNumber) {
+		return fmt.Errorf("invalid transaction type %d", params.TxType)
+	}
+	if params.Size > 1000 {
+		return errors.New("max size is 1000")
+	}
+	return nil
+}
+
+// bpGetTransactionListOfType lists transactions of a specific type, e.g. the database ownership
+// transfers, optionally filtered by the sender address.
+func bpGetTransactionListOfType(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (
+	result interface{}, err error,
+) {
+	params := ctx.Value("_params").(*bpGetTransactionListOfTypeParams)
+	model := models.TransactionsModel{}
+	transactions, pagination, err := model.GetTransactionListOfType(
+		params.TxType, params.Address, params.Page, params.Size)
+	if err != nil {
+		return nil, err
+	}
+	result = &BPGetTransactionListResponse{
+		Transactions: transactions,
+		Pagination:   pagination,
+	}
+	return result, nil
+}
+
 type bpGetTransactionByHashParams struct {
 	Hash string `json:"hash"`
 }
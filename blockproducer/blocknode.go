@@ -53,6 +53,14 @@ func newBlockNode(h uint32, b *types.BPBlock, p *blockNode) (node *blockNode) {
 	return
 }
 
+// newBaseBlockNode creates a parentless block node with the given count, which is used as the
+// chain base while the chain is initialized from a state snapshot instead of the genesis block.
+func newBaseBlockNode(h uint32, count uint32, b *types.BPBlock) (node *blockNode) {
+	node = newBlockNode(h, b, nil)
+	node.count = count
+	return
+}
+
 func (n *blockNode) load() *types.BPBlock {
 	return n.block.Load().(*types.BPBlock)
 }
@@ -62,16 +70,21 @@ func (n *blockNode) clear() {
 }
 
 // fetchNodeList returns the block node list within range [from, n.count] from node head n.
+// The list starts from the chain base instead if from is below the count of the base node.
 func (n *blockNode) fetchNodeList(from uint32) (bl []*blockNode) {
 	if n.count < from {
 		return
 	}
 	bl = make([]*blockNode, n.count-from+1)
-	var iter = n
-	for i := len(bl) - 1; i >= 0; i-- {
+	var (
+		iter = n
+		i    = len(bl) - 1
+	)
+	for ; i >= 0 && iter != nil; i-- {
 		bl[i] = iter
 		iter = iter.parent
 	}
+	bl = bl[i+1:]
 	return
 }
 
@@ -105,13 +118,13 @@ func (n *blockNode) ancestorByCount(c uint32) *blockNode {
 
 // lastIrreversible returns the last irreversible block node with the given confirmations
 // from head n. Especially, the block at count 0, also known as the genesis block,
-// is irreversible.
+// is irreversible, and so is the base block of a state snapshot.
 func (n *blockNode) lastIrreversible(confirm uint32) (irr *blockNode) {
 	var count uint32
 	if n.count > confirm {
 		count = n.count - confirm
 	}
-	for irr = n; irr.count > count; irr = irr.parent {
+	for irr = n; irr.count > count && irr.parent != nil; irr = irr.parent {
 	}
 	return
 }
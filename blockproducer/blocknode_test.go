@@ -159,5 +159,22 @@ func TestBlockNode(t *testing.T) {
 		So(ok, ShouldBeFalse)
 		f, ok = n4p.hasAncestorWithMinCount(n3.hash, n2.count)
 		So(ok, ShouldBeFalse)
+
+		Convey("The chain may also start from a snapshot base node", func() {
+			var (
+				nb2 = newBaseBlockNode(2, 2, b2)
+				nb3 = newBlockNode(3, b3, nb2)
+				nb4 = newBlockNode(5, b4, nb3)
+			)
+			So(nb2.count, ShouldEqual, n2.count)
+			So(nb4.count, ShouldEqual, n4.count)
+			So(nb4.fetchNodeList(0), ShouldResemble, []*blockNode{nb2, nb3, nb4})
+			So(nb4.fetchNodeList(3), ShouldResemble, []*blockNode{nb3, nb4})
+			So(nb4.ancestor(1), ShouldBeNil)
+			So(nb4.ancestorByCount(1), ShouldBeNil)
+			So(nb4.ancestorByCount(2), ShouldEqual, nb2)
+			So(nb4.lastIrreversible(1), ShouldEqual, nb3)
+			So(nb4.lastIrreversible(9), ShouldEqual, nb2)
+		})
 	})
 }
@@ -18,6 +18,7 @@ package blockproducer
 
 import (
 	"bytes"
+	"container/heap"
 	"fmt"
 	"sort"
 	"time"
@@ -37,6 +38,10 @@ type branch struct {
 	preview  *metaState
 	packed   map[hash.Hash]pi.Transaction
 	unpacked map[hash.Hash]pi.Transaction
+	// accounts indexes the unpacked transactions by account, and tails keeps the account queues
+	// in a min-heap ordered by the fees of their last transactions to find eviction candidates.
+	accounts map[proto.AccountAddress]*accountQueue
+	tails    tailQueues
 }
 
 func newBranch(
@@ -54,8 +59,8 @@ func newBranch(
 		}
 	)
 	// Copy pool
-	for k, v := range basePool {
-		inst.unpacked[k] = v
+	for _, v := range basePool {
+		inst.addUnpacked(v)
 	}
 	// Apply new blocks to view and pool
 	for _, bn := range list {
@@ -64,11 +69,13 @@ func newBranch(
 		}
 
 		var block = bn.load()
+		inst.preview.height = bn.height
+		inst.preview.producer = block.Producer()
 		for _, v := range block.Transactions {
 			var k = v.Hash()
 			// Check in tx pool
 			if _, ok := inst.unpacked[k]; ok {
-				delete(inst.unpacked, k)
+				inst.removeUnpacked(k)
 			} else if err = v.Verify(); err != nil {
 				return
 			}
@@ -82,6 +89,9 @@ func newBranch(
 				return
 			}
 		}
+		if err = checkStateRoot(bn, inst.preview); err != nil {
+			return
+		}
 	}
 	inst.preview.commit()
 	br = inst
@@ -101,6 +111,8 @@ func (b *branch) makeArena() *branch {
 	var (
 		p = make(map[hash.Hash]pi.Transaction)
 		u = make(map[hash.Hash]pi.Transaction)
+		a = make(map[proto.AccountAddress]*accountQueue, len(b.accounts))
+		t = make(tailQueues, len(b.tails))
 	)
 	for k, v := range b.packed {
 		p[k] = v
@@ -108,14 +120,26 @@ func (b *branch) makeArena() *branch {
 	for k, v := range b.unpacked {
 		u[k] = v
 	}
+	for k, v := range b.accounts {
+		var q = &accountQueue{
+			txs:   append([]pi.Transaction(nil), v.txs...),
+			index: v.index,
+		}
+		a[k] = q
+		t[q.index] = q
+	}
 	return &branch{
 		head: b.head,
 		preview: &metaState{
 			dirty:    newMetaIndex(),
 			readonly: b.preview.readonly,
+			height:   b.preview.height,
+			producer: b.preview.producer,
 		},
 		packed:   p,
 		unpacked: u,
+		accounts: a,
+		tails:    t,
 	}
 }
 
@@ -123,7 +147,7 @@ func (b *branch) addTx(tx pi.Transaction) {
 	var k = tx.Hash()
 	if _, ok := b.packed[k]; !ok {
 		if _, ok := b.unpacked[k]; !ok {
-			b.unpacked[k] = tx
+			b.addUnpacked(tx)
 		}
 	}
 }
@@ -135,6 +159,8 @@ func (b *branch) applyBlock(n *blockNode) (br *branch, err error) {
 		return
 	}
 	var cpy = b.makeArena()
+	cpy.preview.height = n.height
+	cpy.preview.producer = block.Producer()
 
 	if n.txCount > conf.MaxTransactionsPerBlock {
 		return nil, ErrTooManyTransactionsInBlock
@@ -144,7 +170,7 @@ func (b *branch) applyBlock(n *blockNode) (br *branch, err error) {
 		var k = v.Hash()
 		// Check in tx pool
 		if _, ok := cpy.unpacked[k]; ok {
-			delete(cpy.unpacked, k)
+			cpy.removeUnpacked(k)
 		} else if err = v.Verify(); err != nil {
 			return
 		}
@@ -158,25 +184,54 @@ func (b *branch) applyBlock(n *blockNode) (br *branch, err error) {
 			return
 		}
 	}
+	if err = checkStateRoot(n, cpy.preview); err != nil {
+		return
+	}
 	cpy.head = n
 	br = cpy
 	return
 }
 
+// checkStateRoot checks the state root committed by the block producer against the state view
+// after applying the block. A legacy block doesn't commit to its state and is not checked.
+func checkStateRoot(n *blockNode, view *metaState) (err error) {
+	var (
+		block = n.load()
+		root  hash.Hash
+	)
+	if block.SignedHeader.HashVersion == 0 {
+		return
+	}
+	if root, err = view.stateRoot(); err != nil {
+		return
+	}
+	if !root.IsEqual(&block.SignedHeader.StateRoot) {
+		err = errors.Wrapf(types.ErrStateRootNotMatch, "block %s state root %s, expected %s",
+			n.hash.Short(4), block.SignedHeader.StateRoot.Short(4), root.Short(4))
+	}
+	return
+}
+
+// sortUnpackedTxs sorts the unpacked transactions in packing order: the transactions of an
+// account are kept in nonce order, while the accounts are interleaved by the fees of their next
+// transactions, so that the higher-fee transactions are packed first.
 func (b *branch) sortUnpackedTxs() (txs []pi.Transaction) {
+	var queues = make(txQueues, 0, len(b.accounts))
+	for _, v := range b.accounts {
+		queues = append(queues, v.txs)
+	}
+	heap.Init(&queues)
 	txs = make([]pi.Transaction, 0, len(b.unpacked))
-	for _, v := range b.unpacked {
-		txs = append(txs, v)
-	}
-	sort.Slice(txs, func(i, j int) bool {
-		if cmp := bytes.Compare(
-			hash.Hash(txs[i].GetAccountAddress()).AsBytes(),
-			hash.Hash(txs[j].GetAccountAddress()).AsBytes(),
-		); cmp != 0 {
-			return cmp < 0
+	for queues.Len() > 0 {
+		var q = queues[0]
+		txs = append(txs, q[0])
+		if len(q) > 1 {
+			queues[0] = q[1:]
+			heap.Fix(&queues, 0)
+		} else {
+			heap.Pop(&queues)
 		}
-		return txs[i].GetAccountNonce() < txs[j].GetAccountNonce()
-	})
+	}
 	return
 }
 
@@ -192,6 +247,8 @@ func (b *branch) produceBlock(
 		packCount = conf.MaxTransactionsPerBlock
 	)
 
+	cpy.preview.height = h
+	cpy.preview.producer = addr
 	if len(txs) < packCount {
 		packCount = len(txs)
 	}
@@ -202,7 +259,7 @@ func (b *branch) produceBlock(
 		if ierr = cpy.preview.apply(v); ierr != nil {
 			continue
 		}
-		delete(cpy.unpacked, k)
+		cpy.removeUnpacked(k)
 		cpy.packed[k] = v
 		out = append(out, v)
 		if len(out) == packCount {
@@ -211,12 +268,17 @@ func (b *branch) produceBlock(
 	}
 
 	// Create new block and update head
+	var root hash.Hash
+	if root, err = cpy.preview.stateRoot(); err != nil {
+		return
+	}
 	var block = &types.BPBlock{
 		SignedHeader: types.BPSignedHeader{
 			BPHeader: types.BPHeader{
 				Version:    0x01000000,
 				Producer:   addr,
 				ParentHash: cpy.head.hash,
+				StateRoot:  root,
 				Timestamp:  ts,
 			},
 		},
@@ -240,8 +302,80 @@ func (b *branch) clearPackedTxs(txs []pi.Transaction) {
 
 func (b *branch) clearUnpackedTxs(txs []pi.Transaction) {
 	for _, v := range txs {
-		delete(b.unpacked, v.Hash())
+		b.removeUnpacked(v.Hash())
+	}
+}
+
+// pendingTxOfNonce returns the unpacked transaction sent by the account with the given nonce,
+// or nil if not found.
+func (b *branch) pendingTxOfNonce(addr proto.AccountAddress, nonce pi.AccountNonce) pi.Transaction {
+	var q, ok = b.accounts[addr]
+	if !ok {
+		return nil
+	}
+	var i = sort.Search(len(q.txs), func(i int) bool { return q.txs[i].GetAccountNonce() >= nonce })
+	if i < len(q.txs) && q.txs[i].GetAccountNonce() == nonce {
+		return q.txs[i]
+	}
+	return nil
+}
+
+// evictionCandidate returns the unpacked transaction with the lowest fee to be evicted from a
+// full pool. Only the transactions with the largest nonces of their accounts are considered, so
+// that the nonce sequences of the remaining transactions are kept continuous.
+func (b *branch) evictionCandidate() pi.Transaction {
+	if len(b.tails) == 0 {
+		return nil
+	}
+	return b.tails[0].tail()
+}
+
+// addUnpacked adds tx to the unpacked transactions and the account queue of its sender.
+func (b *branch) addUnpacked(tx pi.Transaction) {
+	var (
+		k    = tx.Hash()
+		addr = tx.GetAccountAddress()
+	)
+	b.unpacked[k] = tx
+	if b.accounts == nil {
+		b.accounts = make(map[proto.AccountAddress]*accountQueue)
+	}
+	if q, ok := b.accounts[addr]; ok {
+		var i = sort.Search(len(q.txs), func(i int) bool { return txLess(tx, q.txs[i]) })
+		q.txs = append(q.txs, nil)
+		copy(q.txs[i+1:], q.txs[i:])
+		q.txs[i] = tx
+		heap.Fix(&b.tails, q.index)
+		return
+	}
+	var q = &accountQueue{txs: []pi.Transaction{tx}}
+	b.accounts[addr] = q
+	heap.Push(&b.tails, q)
+}
+
+// removeUnpacked removes the unpacked transaction of hash k, if any.
+func (b *branch) removeUnpacked(k hash.Hash) {
+	var tx, ok = b.unpacked[k]
+	if !ok {
+		return
 	}
+	delete(b.unpacked, k)
+	var (
+		addr = tx.GetAccountAddress()
+		q    = b.accounts[addr]
+	)
+	for i, v := range q.txs {
+		if v.Hash() == k {
+			q.txs = append(q.txs[:i], q.txs[i+1:]...)
+			break
+		}
+	}
+	if len(q.txs) == 0 {
+		heap.Remove(&b.tails, q.index)
+		delete(b.accounts, addr)
+		return
+	}
+	heap.Fix(&b.tails, q.index)
 }
 
 func (b *branch) queryTxState(hash hash.Hash) (state pi.TransactionState, ok bool) {
@@ -272,3 +406,94 @@ func (b *branch) sprint(from uint32) (buff string) {
 	}
 	return
 }
+
+// txQueues is a max-heap of the per-account transaction queues, ordered by the fees of the queue
+// heads and then by the account addresses.
+type txQueues [][]pi.Transaction
+
+func (q txQueues) Len() int { return len(q) }
+
+func (q txQueues) Less(i, j int) bool {
+	if fi, fj := q[i][0].GetFee(), q[j][0].GetFee(); fi != fj {
+		return fi > fj
+	}
+	return bytes.Compare(
+		hash.Hash(q[i][0].GetAccountAddress()).AsBytes(),
+		hash.Hash(q[j][0].GetAccountAddress()).AsBytes(),
+	) < 0
+}
+
+func (q txQueues) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *txQueues) Push(x interface{}) { *q = append(*q, x.([]pi.Transaction)) }
+
+func (q *txQueues) Pop() interface{} {
+	var (
+		old = *q
+		n   = len(old)
+		x   = old[n-1]
+	)
+	*q = old[:n-1]
+	return x
+}
+
+// txLess reports whether tx i is ordered before tx j in the queue of their account, i.e. by nonce
+// and then by hash.
+func txLess(i, j pi.Transaction) bool {
+	if ni, nj := i.GetAccountNonce(), j.GetAccountNonce(); ni != nj {
+		return ni < nj
+	}
+	var hi, hj = i.Hash(), j.Hash()
+	return bytes.Compare(hi[:], hj[:]) < 0
+}
+
+// accountQueue is the unpacked transaction queue of an account in nonce order.
+type accountQueue struct {
+	txs   []pi.Transaction
+	index int // index in tailQueues
+}
+
+func (q *accountQueue) tail() pi.Transaction { return q.txs[len(q.txs)-1] }
+
+// tailQueues is a min-heap of the per-account transaction queues, ordered by the fees of the
+// queue tails, then the later transactions first, and then by the account addresses.
+type tailQueues []*accountQueue
+
+func (q tailQueues) Len() int { return len(q) }
+
+func (q tailQueues) Less(i, j int) bool {
+	var ti, tj = q[i].tail(), q[j].tail()
+	if fi, fj := ti.GetFee(), tj.GetFee(); fi != fj {
+		return fi < fj
+	}
+	if si, sj := ti.GetTimestamp(), tj.GetTimestamp(); !si.Equal(sj) {
+		return si.After(sj)
+	}
+	return bytes.Compare(
+		hash.Hash(ti.GetAccountAddress()).AsBytes(),
+		hash.Hash(tj.GetAccountAddress()).AsBytes(),
+	) < 0
+}
+
+func (q tailQueues) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *tailQueues) Push(x interface{}) {
+	var v = x.(*accountQueue)
+	v.index = len(*q)
+	*q = append(*q, v)
+}
+
+func (q *tailQueues) Pop() interface{} {
+	var (
+		old = *q
+		n   = len(old)
+		x   = old[n-1]
+	)
+	old[n-1] = nil
+	*q = old[:n-1]
+	return x
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+func TestBranchSortUnpackedTxs(t *testing.T) {
+	Convey("Given a branch with unpacked transactions of different fees", t, func() {
+		var (
+			addr1 = proto.AccountAddress{0x1}
+			addr2 = proto.AccountAddress{0x2}
+			priv  *asymmetric.PrivateKey
+			err   error
+			br    = &branch{preview: newMetaState(), unpacked: make(map[hash.Hash]pi.Transaction)}
+			tran  = func(sender proto.AccountAddress, nonce pi.AccountNonce, fee uint64) pi.Transaction {
+				var t = types.NewTransfer(&types.TransferHeader{
+					Sender: sender,
+					Amount: 1,
+					Nonce:  nonce,
+					Fee:    fee,
+				})
+				err = t.Sign(priv)
+				So(err, ShouldBeNil)
+				return t
+			}
+			add = func(txs ...pi.Transaction) {
+				for _, v := range txs {
+					br.addTx(v)
+				}
+			}
+		)
+		priv, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+
+		Convey("The account with higher fee at its queue head should be packed first", func() {
+			var (
+				t1 = tran(addr1, 1, 1)
+				t2 = tran(addr1, 2, 10)
+				t3 = tran(addr2, 1, 5)
+			)
+			add(t1, t2, t3)
+			So(br.sortUnpackedTxs(), ShouldResemble, []pi.Transaction{t3, t1, t2})
+		})
+		Convey("The transactions of the same account should be kept in nonce order", func() {
+			var (
+				t1 = tran(addr1, 1, 10)
+				t2 = tran(addr1, 2, 1)
+				t3 = tran(addr2, 1, 5)
+				t4 = tran(addr2, 2, 5)
+			)
+			add(t4, t3, t2, t1)
+			So(br.sortUnpackedTxs(), ShouldResemble, []pi.Transaction{t1, t3, t4, t2})
+		})
+		Convey("The accounts with equal fees should be ordered by address", func() {
+			var (
+				t1 = tran(addr1, 1, 1)
+				t2 = tran(addr2, 1, 1)
+			)
+			add(t2, t1)
+			So(br.sortUnpackedTxs(), ShouldResemble, []pi.Transaction{t1, t2})
+		})
+		Convey("The cheapest account tail should be the eviction candidate", func() {
+			var (
+				t1 = tran(addr1, 1, 1)
+				t2 = tran(addr1, 2, 10)
+				t3 = tran(addr2, 1, 5)
+				t4 = tran(addr2, 2, 3)
+			)
+			So(br.evictionCandidate(), ShouldBeNil)
+			add(t4, t3, t2, t1)
+			So(br.evictionCandidate(), ShouldEqual, t4)
+			So(br.pendingTxOfNonce(addr2, 1), ShouldEqual, t3)
+			So(br.pendingTxOfNonce(addr2, 3), ShouldBeNil)
+			br.clearUnpackedTxs([]pi.Transaction{t4})
+			So(br.evictionCandidate(), ShouldEqual, t3)
+			var arena = br.makeArena()
+			arena.clearUnpackedTxs([]pi.Transaction{t3})
+			So(arena.evictionCandidate(), ShouldEqual, t2)
+			So(br.evictionCandidate(), ShouldEqual, t3)
+			br.clearUnpackedTxs([]pi.Transaction{t1, t2, t3})
+			So(br.evictionCandidate(), ShouldBeNil)
+		})
+	})
+}
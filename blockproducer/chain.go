@@ -71,17 +71,24 @@ type Chain struct {
 	// NOTE(leventeliu): this LRU object is only used for block cache control,
 	// do NOT read it in any case.
 	blockCache *lru.Cache
+	// discardedTxs keeps the final states of the recently replaced or expired transactions,
+	// which are removed from the transaction pool.
+	discardedTxs *lru.Cache
 
 	// Channels for incoming blocks and transactions
 	pendingBlocks    chan *types.BPBlock
 	pendingAddTxReqs chan *types.AddTxReq
 
 	// The following fields are read-only in runtime
-	address     proto.AccountAddress
-	mode        RunMode
-	genesisTime time.Time
-	period      time.Duration
-	tick        time.Duration
+	address          proto.AccountAddress
+	mode             RunMode
+	genesisHash      hash.Hash
+	genesisTime      time.Time
+	period           time.Duration
+	tick             time.Duration
+	snapshotInterval uint32
+	minTxFee         uint64
+	txPoolSize       int
 
 	sync.RWMutex // protects following fields
 	bpInfos      []*blockProducerInfo
@@ -103,6 +110,18 @@ func NewChain(cfg *Config) (c *Chain, err error) {
 	return NewChainWithContext(context.Background(), cfg)
 }
 
+// ComputeGenesisStateRoot computes the state root after applying the genesis block transactions,
+// which should be set to the genesis block header before the block hash is computed.
+func ComputeGenesisStateRoot(genesis *types.BPBlock) (root hash.Hash, err error) {
+	var init = newMetaState()
+	for _, v := range genesis.Transactions {
+		if err = init.apply(v); err != nil {
+			return
+		}
+	}
+	return init.stateRoot()
+}
+
 // NewChainWithContext creates a new blockchain with context.
 func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error) {
 	var (
@@ -110,6 +129,7 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 
 		st        xi.Storage
 		cache     *lru.Cache
+		discarded *lru.Cache
 		lastIrre  *blockNode
 		heads     []*blockNode
 		immutable *metaState
@@ -164,6 +184,16 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 		return
 	}
 
+	// Create initial state from the latest state snapshot of the peers, fall back to genesis
+	// block on failure
+	if !existed && cfg.FastSync {
+		if ierr = fastSync(ctx, st, cfg); ierr == nil {
+			existed = true
+		} else {
+			log.WithError(ierr).Warn("failed to fast sync, initialize from genesis block instead")
+		}
+	}
+
 	// Create initial state from genesis block and store
 	if !existed {
 		var init = newMetaState()
@@ -173,6 +203,18 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 				return
 			}
 		}
+		// A zero state root means that the genesis block doesn't commit to its state
+		if root := cfg.Genesis.SignedHeader.StateRoot; !root.IsEqual(&hash.Hash{}) {
+			var expected hash.Hash
+			if expected, ierr = init.stateRoot(); ierr != nil {
+				err = errors.Wrap(ierr, "failed to compute genesis state root")
+				return
+			}
+			if !root.IsEqual(&expected) {
+				err = errors.Wrap(types.ErrStateRootNotMatch, "invalid genesis state root")
+				return
+			}
+		}
 		var sps = init.compileChanges(nil)
 		sps = append(sps, addBlock(0, cfg.Genesis))
 		sps = append(sps, updateIrreversible(cfg.Genesis.SignedHeader.DataHash))
@@ -188,10 +230,22 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 		return
 	}
 
-	// Check genesis block
-	var irreBlocks = lastIrre.fetchNodeList(0)
-	if persistedGenesis := irreBlocks[0]; persistedGenesis == nil ||
-		!persistedGenesis.hash.IsEqual(cfg.Genesis.BlockHash()) {
+	// Check genesis block, or the genesis hash of the base snapshot if the chain is initialized
+	// from a state snapshot
+	var (
+		irreBlocks    = lastIrre.fetchNodeList(0)
+		persistedBase = irreBlocks[0]
+		persistedHash = persistedBase.hash
+	)
+	if persistedBase.count > 0 {
+		var header *types.SignedStateSnapshotHeader
+		if header, ierr = loadSnapshotHeader(st, persistedBase.hash); ierr != nil {
+			err = errors.Wrap(ierr, "failed to load base snapshot")
+			return
+		}
+		persistedHash = header.GenesisHash
+	}
+	if !persistedHash.IsEqual(cfg.Genesis.BlockHash()) {
 		err = ErrGenesisHashNotMatch
 		return
 	}
@@ -246,6 +300,7 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 
 		threshold    float64
 		needConfirms uint32
+		txPoolSize   int
 	)
 	if localBPInfo, bpInfos, err = buildBlockProducerInfos(
 		cfg.NodeID, cfg.Peers, cfg.Mode == APINodeMode,
@@ -258,6 +313,12 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 	if needConfirms = uint32(math.Ceil(float64(l)*threshold + 1)); needConfirms > l {
 		needConfirms = l
 	}
+	if txPoolSize = cfg.TxPoolSize; txPoolSize <= 0 {
+		txPoolSize = conf.DefaultTxPoolSize
+	}
+	if discarded, err = lru.New(txPoolSize); err != nil {
+		return
+	}
 
 	// create chain
 	var cld, ccl = context.WithCancel(ctx)
@@ -269,17 +330,22 @@ func NewChainWithContext(ctx context.Context, cfg *Config) (c *Chain, err error)
 		server: cfg.Server,
 		caller: rpc.NewCaller(),
 
-		storage:    st,
-		blockCache: cache,
+		storage:      st,
+		blockCache:   cache,
+		discardedTxs: discarded,
 
 		pendingBlocks:    make(chan *types.BPBlock),
 		pendingAddTxReqs: make(chan *types.AddTxReq),
 
-		address:     addr,
-		mode:        cfg.Mode,
-		genesisTime: cfg.Genesis.SignedHeader.Timestamp,
-		period:      cfg.Period,
-		tick:        cfg.Tick,
+		address:          addr,
+		mode:             cfg.Mode,
+		genesisHash:      *cfg.Genesis.BlockHash(),
+		genesisTime:      cfg.Genesis.SignedHeader.Timestamp,
+		period:           cfg.Period,
+		tick:             cfg.Tick,
+		snapshotInterval: cfg.SnapshotInterval,
+		minTxFee:         cfg.MinTxFee,
+		txPoolSize:       txPoolSize,
 
 		bpInfos:     bpInfos,
 		localBPInfo: localBPInfo,
@@ -526,6 +592,10 @@ func (c *Chain) processAddTxReq(addTxReq *types.AddTxReq) {
 		le.WithError(err).Warn("failed to verify transaction")
 		return
 	}
+	if err = c.checkTxFee(tx); err != nil {
+		le.WithError(err).Warn("invalid transaction fee")
+		return
+	}
 	if base, err = c.immutableNextNonce(addr); err != nil {
 		le.WithError(err).Warn("failed to load base nonce of transaction account")
 		return
@@ -666,16 +736,67 @@ func (c *Chain) syncCurrentHead(ctx context.Context, requiredReachable uint32) (
 	return
 }
 
+// checkTxFee checks the transaction fee against the minimum fee, the fee-exempt transactions
+// are sent by the system roles and always accepted.
+func (c *Chain) checkTxFee(tx pi.Transaction) (err error) {
+	switch tx.GetTransactionType() {
+	case pi.TransactionTypeBaseAccount, pi.TransactionTypeUpdateBilling:
+		return
+	}
+	if fee := tx.GetFee(); fee < c.minTxFee {
+		err = errors.Wrapf(ErrInsufficientFee, "fee %d is less than %d", fee, c.minTxFee)
+	}
+	return
+}
+
 func (c *Chain) storeTx(tx pi.Transaction) (err error) {
-	var k = tx.Hash()
+	var (
+		k        = tx.Hash()
+		evicted  pi.Transaction
+		replaced pi.Transaction
+	)
 	c.Lock()
 	defer c.Unlock()
 	if _, ok := c.txPool[k]; ok {
 		err = ErrExistedTx
 		return
 	}
+	var sps = []storageProcedure{addTx(tx)}
+	if replaced, err = c.pendingReplacement(tx); err != nil {
+		return
+	}
+	if replaced != nil {
+		sps = append(sps, deleteTxs([]pi.Transaction{replaced}))
+	} else if len(c.txPool) >= c.txPoolSize {
+		if evicted = c.headBranch.evictionCandidate(); evicted == nil || evicted.GetFee() >= tx.GetFee() {
+			err = ErrTxPoolFull
+			return
+		}
+		sps = append(sps, deleteTxs([]pi.Transaction{evicted}))
+	}
 
-	return store(c.storage, []storageProcedure{addTx(tx)}, func() {
+	return store(c.storage, sps, func() {
+		if replaced != nil {
+			log.WithFields(log.Fields{
+				"hash":    replaced.Hash().Short(4),
+				"account": replaced.GetAccountAddress(),
+				"nonce":   replaced.GetAccountNonce(),
+				"by":      k.Short(4),
+			}).Debug("replaced pending transaction")
+			c.discardTx(replaced, pi.TransactionStateReplaced)
+		}
+		if evicted != nil {
+			log.WithFields(log.Fields{
+				"hash":    evicted.Hash().Short(4),
+				"account": evicted.GetAccountAddress(),
+				"nonce":   evicted.GetAccountNonce(),
+				"fee":     evicted.GetFee(),
+			}).Debug("evicted transaction from full pool")
+			delete(c.txPool, evicted.Hash())
+			for _, v := range c.branches {
+				v.clearUnpackedTxs([]pi.Transaction{evicted})
+			}
+		}
 		c.txPool[k] = tx
 		for _, v := range c.branches {
 			v.addTx(tx)
@@ -683,10 +804,53 @@ func (c *Chain) storeTx(tx pi.Transaction) (err error) {
 	})
 }
 
+// pendingReplacement returns the pending transaction in the head branch which will be replaced
+// by tx, i.e. the one with the same account and nonce. Caller should hold the chain lock.
+func (c *Chain) pendingReplacement(tx pi.Transaction) (replaced pi.Transaction, err error) {
+	if replaced = c.headBranch.pendingTxOfNonce(
+		tx.GetAccountAddress(), tx.GetAccountNonce(),
+	); replaced == nil || replaced.Hash() == tx.Hash() {
+		replaced = nil
+		return
+	}
+	// A cancellation takes the nonce from any other pending transaction regardless of the fee,
+	// otherwise a higher fee is required
+	if tx.GetTransactionType() == pi.TransactionTypeCancelTransaction &&
+		replaced.GetTransactionType() != pi.TransactionTypeCancelTransaction {
+		return
+	}
+	if tx.GetFee() <= replaced.GetFee() {
+		err = errors.Wrapf(ErrUnderpricedReplacement,
+			"fee %d is not higher than %d", tx.GetFee(), replaced.GetFee())
+		replaced = nil
+	}
+	return
+}
+
+// checkTxReplacement checks whether tx is allowed to replace the pending transaction, if any.
+func (c *Chain) checkTxReplacement(tx pi.Transaction) (err error) {
+	c.RLock()
+	defer c.RUnlock()
+	_, err = c.pendingReplacement(tx)
+	return
+}
+
+// discardTx removes the transaction from the transaction pool and the unpacked lists of all
+// branches, and records its final state for later queries. Caller should hold the chain lock.
+func (c *Chain) discardTx(tx pi.Transaction, state pi.TransactionState) {
+	var k = tx.Hash()
+	delete(c.txPool, k)
+	for _, v := range c.branches {
+		v.clearUnpackedTxs([]pi.Transaction{tx})
+	}
+	c.discardedTxs.Add(k, state)
+}
+
 func (c *Chain) replaceAndSwitchToBranch(
 	newBlock *types.BPBlock, originBrIdx int, newBranch *branch) (err error,
 ) {
 	var (
+		prevIrre = c.lastIrre
 		lastIrre *blockNode
 		newIrres []*blockNode
 		sps      []storageProcedure
@@ -711,8 +875,11 @@ func (c *Chain) replaceAndSwitchToBranch(
 		resultTxPool[k] = v
 	}
 	for _, b := range newIrres {
+		var block = b.load()
 		txCount += b.txCount
-		for _, tx := range b.load().Transactions {
+		c.immutable.height = b.height
+		c.immutable.producer = block.Producer()
+		for _, tx := range block.Transactions {
 			if err := c.immutable.apply(tx); err != nil {
 				log.WithError(err).Fatal("failed to apply block to immutable database")
 			}
@@ -795,6 +962,9 @@ func (c *Chain) replaceAndSwitchToBranch(
 		for _, br := range c.branches {
 			br.clearUnpackedTxs(expiredTxs)
 		}
+		for _, tx := range expiredTxs {
+			c.discardedTxs.Add(tx.Hash(), pi.TransactionStateExpired)
+		}
 		// Update txPool to result txPool (packed and expired transactions cleared!)
 		c.txPool = resultTxPool
 		// Register new irreversible blocks to LRU cache list
@@ -809,6 +979,15 @@ func (c *Chain) replaceAndSwitchToBranch(
 		return
 	}
 	expvar.Get(mwKeyTxConfirmed).(mw.Metric).Add(float64(txCount))
+	// Take state snapshot, the failure is not fatal and will be retried at the next interval
+	if c.needSnapshot(prevIrre, lastIrre) {
+		if ierr := c.takeSnapshot(lastIrre); ierr != nil {
+			log.WithFields(log.Fields{
+				"irre_hash":   lastIrre.hash.Short(4),
+				"irre_height": lastIrre.height,
+			}).WithError(ierr).Warn("failed to take state snapshot")
+		}
+	}
 	// TODO(leventeliu): trigger ChainBus.Publish.
 	// ...
 	return
@@ -17,8 +17,11 @@
 package blockproducer
 
 import (
+	"github.com/pkg/errors"
+
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/types"
 	"github.com/CovenantSQL/CovenantSQL/utils"
@@ -101,6 +104,79 @@ func (c *Chain) fetchBlockByCount(count uint32) (b *types.BPBlock, height uint32
 	return
 }
 
+func (c *Chain) fetchStateSnapshotChunk(height, index uint32) (
+	header *types.SignedStateSnapshotHeader, total uint32, chunk []byte, err error,
+) {
+	return loadSnapshotChunk(c.storage, height, index, snapshotChunkSize)
+}
+
+// proveStateObject proves the inclusion of obj in the state of the last irreversible block, and
+// returns the block header together with the proof.
+func (c *Chain) proveStateObject(obj types.StateObject) (
+	header *types.BPSignedHeader, height, count uint32, proof *merkle.Proof, err error,
+) {
+	var (
+		trie *merkle.Trie
+		root *hash.Hash
+		node = c.lastIrreversibleBlock()
+		b    *types.BPBlock
+	)
+	if b = node.load(); b == nil {
+		if b, err = c.loadBlock(node.hash); err != nil {
+			return
+		}
+	}
+	if b.SignedHeader.HashVersion == 0 {
+		err = errors.Wrapf(ErrNoStateRoot, "block %s", node.hash.Short(4))
+		return
+	}
+	if trie, err = c.immutable.readonly.stateTrie(); err != nil {
+		return
+	}
+	if root, proof, err = trie.Prove(obj.StateKey()); err != nil {
+		return
+	}
+	if !b.SignedHeader.StateRoot.IsEqual(root) {
+		err = errors.Wrapf(types.ErrStateRootNotMatch,
+			"state root of block %s mismatched", node.hash.Short(4))
+		return
+	}
+	header = &b.SignedHeader
+	height = node.height
+	count = node.count
+	return
+}
+
+func (c *Chain) queryAccountWithProof(addr proto.AccountAddress) (
+	header *types.BPSignedHeader, height, count uint32,
+	account *types.Account, proof *merkle.Proof, err error,
+) {
+	c.RLock()
+	defer c.RUnlock()
+	var ok bool
+	if account, ok = c.immutable.loadAccountObject(addr); !ok {
+		err = errors.Wrapf(ErrAccountNotFound, "account %s", addr)
+		return
+	}
+	header, height, count, proof, err = c.proveStateObject(account)
+	return
+}
+
+func (c *Chain) querySQLChainProfileWithProof(databaseID proto.DatabaseID) (
+	header *types.BPSignedHeader, height, count uint32,
+	profile *types.SQLChainProfile, proof *merkle.Proof, err error,
+) {
+	c.RLock()
+	defer c.RUnlock()
+	var ok bool
+	if profile, ok = c.immutable.loadSQLChainObject(databaseID); !ok {
+		err = errors.Wrapf(ErrDatabaseNotFound, "database %s", databaseID)
+		return
+	}
+	header, height, count, proof, err = c.proveStateObject(profile)
+	return
+}
+
 func (c *Chain) nextNonce(addr proto.AccountAddress) (n pi.AccountNonce, err error) {
 	c.RLock()
 	defer c.RUnlock()
@@ -132,6 +208,21 @@ func (c *Chain) loadSQLChainProfiles(addr proto.AccountAddress) []*types.SQLChai
 	return c.immutable.loadROSQLChains(addr)
 }
 
+func (c *Chain) loadEscrow(id hash.Hash) (profile *types.EscrowProfile, ok bool) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.immutable.loadEscrowObject(id)
+}
+
+func (c *Chain) loadAccountEscrowBalance(
+	addr proto.AccountAddress, tt types.TokenType) (locked, incoming uint64,
+) {
+	c.RLock()
+	defer c.RUnlock()
+	var l, i = c.immutable.loadEscrowBalance(addr)
+	return l[tt], i[tt]
+}
+
 func (c *Chain) queryTxState(hash hash.Hash) (state pi.TransactionState, err error) {
 	c.RLock()
 	defer c.RUnlock()
@@ -153,6 +244,10 @@ func (c *Chain) queryTxState(hash hash.Hash) (state pi.TransactionState, err err
 		return pi.TransactionStateConfirmed, nil
 	}
 
+	if v, ok := c.discardedTxs.Get(hash); ok {
+		return v.(pi.TransactionState), nil
+	}
+
 	return pi.TransactionStateNotFound, nil
 }
 
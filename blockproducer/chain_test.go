@@ -17,6 +17,7 @@
 package blockproducer
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -31,9 +32,12 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	mine "github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/rpc"
 	"github.com/CovenantSQL/CovenantSQL/types"
+	xi "github.com/CovenantSQL/CovenantSQL/xenomint/interfaces"
 )
 
 func newTransfer(
@@ -114,7 +118,8 @@ func TestChain(t *testing.T) {
 		genesis = &types.BPBlock{
 			SignedHeader: types.BPSignedHeader{
 				BPHeader: types.BPHeader{
-					Timestamp: time.Now().Add(-10 * time.Second),
+					Timestamp:   time.Now().Add(-10 * time.Second),
+					HashVersion: 1,
 				},
 			},
 			Transactions: []pi.Transaction{
@@ -124,6 +129,8 @@ func TestChain(t *testing.T) {
 				}),
 			},
 		}
+		genesis.SignedHeader.StateRoot, err = ComputeGenesisStateRoot(genesis)
+		So(err, ShouldBeNil)
 		err = genesis.SetHash()
 		So(err, ShouldBeNil)
 		begin = genesis.Timestamp()
@@ -209,6 +216,18 @@ func TestChain(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(queryBalanceResp.OK, ShouldBeFalse)
 
+			var queryEscrowBalanceResp = &types.QueryAccountEscrowBalanceResp{}
+			err = rpcService.QueryAccountEscrowBalance(&types.QueryAccountEscrowBalanceReq{
+				Addr: addr2, TokenType: types.Particle}, queryEscrowBalanceResp)
+			So(err, ShouldBeNil)
+			So(queryEscrowBalanceResp.Locked, ShouldEqual, 0)
+			So(queryEscrowBalanceResp.Incoming, ShouldEqual, 0)
+			err = rpcService.QueryAccountEscrowBalance(&types.QueryAccountEscrowBalanceReq{
+				Addr: addr2, TokenType: -1}, queryEscrowBalanceResp)
+			So(errors.Cause(err), ShouldEqual, ErrWrongTokenType)
+			err = rpcService.QueryEscrow(&types.QueryEscrowReq{}, &types.QueryEscrowResp{})
+			So(errors.Cause(err), ShouldEqual, ErrEscrowNotFound)
+
 			Convey("Chain APIs should return correct result of state objects", func() {
 				var loaded bool
 				_, loaded = chain.immutable.loadOrStoreProviderObject(addr1, &types.ProviderProfile{})
@@ -258,7 +277,7 @@ func TestChain(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(resp.State, ShouldEqual, pi.TransactionStatePending)
 
-				delete(chain.headBranch.unpacked, req.Hash)
+				chain.headBranch.clearUnpackedTxs([]pi.Transaction{tx})
 				chain.headBranch.packed[req.Hash] = tx
 				err = rpcService.QueryTxState(req, resp)
 				So(err, ShouldBeNil)
@@ -291,6 +310,96 @@ func TestChain(t *testing.T) {
 			})
 		})
 
+		Convey("When transaction fees are required", func() {
+			var t1, t2 pi.Transaction
+			t1, err = newTransfer(1, priv1, addr1, addr2, 1)
+			So(err, ShouldBeNil)
+			t2, err = newTransfer(2, priv1, addr1, addr2, 1)
+			So(err, ShouldBeNil)
+			err = chain.storeTx(t1)
+			So(err, ShouldBeNil)
+
+			Convey("The chain should reject transactions with insufficient fee", func() {
+				chain.minTxFee = 10
+				err = chain.checkTxFee(t2)
+				So(errors.Cause(err), ShouldEqual, ErrInsufficientFee)
+				var tx = types.NewTransfer(&types.TransferHeader{
+					Sender: addr1, Receiver: addr2, Nonce: 2, Amount: 1, Fee: 10,
+				})
+				err = tx.Sign(priv1)
+				So(err, ShouldBeNil)
+				err = chain.checkTxFee(tx)
+				So(err, ShouldBeNil)
+			})
+			Convey("The chain should evict the cheapest transaction from a full pool", func() {
+				chain.txPoolSize = 1
+				var tx = types.NewTransfer(&types.TransferHeader{
+					Sender: addr2, Receiver: addr1, Nonce: 1, Amount: 1, Fee: 0,
+				})
+				err = tx.Sign(priv2)
+				So(err, ShouldBeNil)
+				err = chain.storeTx(tx)
+				So(err, ShouldEqual, ErrTxPoolFull)
+				tx.Fee = 1
+				err = tx.Sign(priv2)
+				So(err, ShouldBeNil)
+				err = chain.storeTx(tx)
+				So(err, ShouldBeNil)
+				So(len(chain.txPool), ShouldEqual, 1)
+				So(chain.txPool, ShouldContainKey, tx.Hash())
+				So(chain.headBranch.unpacked, ShouldNotContainKey, t1.Hash())
+			})
+			Convey("The chain should replace pending transaction by nonce", func() {
+				var (
+					state pi.TransactionState
+					tx    = types.NewTransfer(&types.TransferHeader{
+						Sender: addr1, Receiver: addr2, Nonce: 1, Amount: 2, Fee: 0,
+					})
+				)
+				err = tx.Sign(priv1)
+				So(err, ShouldBeNil)
+				err = chain.checkTxReplacement(t1)
+				So(err, ShouldBeNil)
+				err = chain.checkTxReplacement(tx)
+				So(errors.Cause(err), ShouldEqual, ErrUnderpricedReplacement)
+				err = chain.storeTx(tx)
+				So(errors.Cause(err), ShouldEqual, ErrUnderpricedReplacement)
+
+				tx.Fee = 1
+				err = tx.Sign(priv1)
+				So(err, ShouldBeNil)
+				err = chain.storeTx(tx)
+				So(err, ShouldBeNil)
+				So(chain.txPool, ShouldNotContainKey, t1.Hash())
+				So(chain.headBranch.unpacked, ShouldNotContainKey, t1.Hash())
+				state, err = chain.queryTxState(t1.Hash())
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, pi.TransactionStateReplaced)
+				state, err = chain.queryTxState(tx.Hash())
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, pi.TransactionStatePending)
+
+				var ct = types.NewCancelTransaction(&types.CancelTransactionHeader{Nonce: 1})
+				err = ct.Sign(priv1)
+				So(err, ShouldBeNil)
+				err = chain.storeTx(ct)
+				So(err, ShouldBeNil)
+				state, err = chain.queryTxState(tx.Hash())
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, pi.TransactionStateReplaced)
+
+				err = chain.produceBlock(begin.Add(chain.period).UTC())
+				So(err, ShouldBeNil)
+				state, err = chain.queryTxState(ct.Hash())
+				So(err, ShouldBeNil)
+				So(state, ShouldEqual, pi.TransactionStatePacked)
+				var nonce pi.AccountNonce
+				nonce, err = chain.nextNonce(addr1)
+				So(err, ShouldBeNil)
+				So(nonce, ShouldEqual, 2)
+			})
+		})
+
 		Convey("When transfer transactions are added", func() {
 			var (
 				nonce          pi.AccountNonce
@@ -314,6 +423,8 @@ func TestChain(t *testing.T) {
 
 			// Fork from #0
 			f0 = chain.headBranch.makeArena()
+			// Detach from the read-only index shared with the head branch, which will keep growing
+			f0.preview = f0.preview.makeCopy()
 
 			err = chain.storeTx(t1)
 			So(err, ShouldBeNil)
@@ -333,6 +444,8 @@ func TestChain(t *testing.T) {
 
 			// Fork from #1
 			f1 = chain.headBranch.makeArena()
+			// Detach from the read-only index shared with the head branch, which will keep growing
+			f1.preview = f1.preview.makeCopy()
 
 			err = chain.storeTx(t2)
 			So(err, ShouldBeNil)
@@ -375,11 +488,193 @@ func TestChain(t *testing.T) {
 			}
 
 			Convey("The chain immutable should be updated to irreversible block", func() {
-				// Add more blocks to trigger immutable updating
+				// Add more blocks to trigger immutable updating, and take snapshot at each
+				// irreversible block
+				chain.snapshotInterval = 1
 				for i := uint32(7); i <= 12; i++ {
 					err = chain.produceBlock(begin.Add(time.Duration(i) * chain.period).UTC())
 					So(err, ShouldBeNil)
 				}
+				Convey("The chain should provide state snapshot for fast sync", func() {
+					var (
+						rpcService = &ChainRPCService{chain: chain}
+						chunkSize  = snapshotChunkSize
+						snapCount  int
+						call       = func(
+							ctx context.Context, node proto.NodeID, method string,
+							req interface{}, resp interface{},
+						) error {
+							switch method {
+							case route.MCCFetchStateSnapshot.String():
+								return rpcService.FetchStateSnapshot(
+									req.(*types.FetchStateSnapshotReq),
+									resp.(*types.FetchStateSnapshotResp))
+							case route.MCCFetchBlock.String():
+								return rpcService.FetchBlock(
+									req.(*types.FetchBlockReq), resp.(*types.FetchBlockResp))
+							}
+							return errors.New("unknown method")
+						}
+					)
+					snapshotChunkSize = 64
+					defer func() { snapshotChunkSize = chunkSize }()
+					kms.Unittest = true
+					err = kms.SetPublicKey(leader, mine.Uint256{}, priv1.PubKey())
+					So(err, ShouldBeNil)
+
+					err = chain.storage.Reader().QueryRow(
+						`SELECT COUNT(*) FROM "snapshots"`).Scan(&snapCount)
+					So(err, ShouldBeNil)
+					So(snapCount, ShouldEqual, maxSnapshotsKept)
+					err = rpcService.FetchStateSnapshot(
+						&types.FetchStateSnapshotReq{Height: 1000},
+						&types.FetchStateSnapshotResp{})
+					So(errors.Cause(err), ShouldEqual, ErrSnapshotNotFound)
+					err = rpcService.FetchStateSnapshot(
+						&types.FetchStateSnapshotReq{Index: 1 << 20},
+						&types.FetchStateSnapshotResp{})
+					So(errors.Cause(err), ShouldEqual, ErrInvalidSnapshotChunk)
+
+					// The peer without known public key should be skipped
+					var (
+						snap *types.StateSnapshot
+						base *types.BPBlock
+					)
+					_, _, err = fetchStateSnapshot(
+						context.Background(), call, []proto.NodeID{servers[1]}, *genesis.BlockHash())
+					So(err, ShouldNotBeNil)
+					_, _, err = fetchStateSnapshot(
+						context.Background(), call, []proto.NodeID{leader}, hash.Hash{})
+					So(errors.Cause(err), ShouldEqual, ErrGenesisHashNotMatch)
+					snap, base, err = fetchStateSnapshot(
+						context.Background(), call, []proto.NodeID{servers[1], leader},
+						*genesis.BlockHash())
+					So(err, ShouldBeNil)
+					So(snap.Header.BlockHash, ShouldEqual, chain.lastIrre.hash)
+					So(snap.Header.Height, ShouldEqual, chain.lastIrre.height)
+					So(snap.Header.Count, ShouldEqual, chain.lastIrre.count)
+					So(base.BlockHash(), ShouldResemble, &chain.lastIrre.hash)
+					So(base.SignedHeader.StateRoot, ShouldEqual, snap.Header.StateRoot)
+
+					// Initialize a new chain from the snapshot and replay the following blocks
+					var (
+						syncConfig = *config
+						syncChain  *Chain
+						st         xi.Storage
+						bl         *types.BPBlock
+						root       hash.Hash
+					)
+					syncConfig.DataFile = path.Join(testingDataDir, t.Name()+"_fast_sync")
+					defer os.Remove(syncConfig.DataFile)
+					st, err = openStorage(fmt.Sprintf("file:%s", syncConfig.DataFile))
+					So(err, ShouldBeNil)
+					err = initStorageFromSnapshot(st, snap, base)
+					So(err, ShouldBeNil)
+					err = st.Close()
+					So(err, ShouldBeNil)
+					syncChain, err = NewChain(&syncConfig)
+					So(err, ShouldBeNil)
+					So(syncChain.lastIrre.hash, ShouldEqual, snap.Header.BlockHash)
+					So(syncChain.lastIrre.count, ShouldEqual, snap.Header.Count)
+					root, err = syncChain.immutable.makeStateSnapshot().ComputeStateRoot()
+					So(err, ShouldBeNil)
+					So(root, ShouldEqual, snap.Header.StateRoot)
+					for i := snap.Header.Count + 1; i <= chain.head().count; i++ {
+						bl, _, err = chain.fetchBlockByCount(i)
+						So(err, ShouldBeNil)
+						err = syncChain.pushBlock(bl)
+						So(err, ShouldBeNil)
+					}
+					So(syncChain.head().hash, ShouldEqual, chain.head().hash)
+					So(syncChain.head().count, ShouldEqual, chain.head().count)
+					err = syncChain.Stop()
+					So(err, ShouldBeNil)
+
+					// Reload the fast synced chain
+					syncChain, err = NewChain(&syncConfig)
+					So(err, ShouldBeNil)
+					So(syncChain.head().hash, ShouldEqual, chain.head().hash)
+					So(syncChain.lastIrre.count, ShouldBeGreaterThanOrEqualTo, snap.Header.Count)
+					err = syncChain.Stop()
+					So(err, ShouldBeNil)
+
+					// The genesis hash of the base snapshot should be checked
+					syncConfig.Genesis = &types.BPBlock{
+						SignedHeader: types.BPSignedHeader{
+							BPHeader: types.BPHeader{Timestamp: begin.Add(time.Second)},
+						},
+					}
+					err = syncConfig.Genesis.SetHash()
+					So(err, ShouldBeNil)
+					syncChain, err = NewChain(&syncConfig)
+					So(err, ShouldEqual, ErrGenesisHashNotMatch)
+				})
+				Convey("The chain should provide state inclusion proofs", func() {
+					var (
+						rpcService = &ChainRPCService{chain: chain}
+						accResp    = &types.QueryAccountWithProofResp{}
+						dbResp     = &types.QuerySQLChainProfileWithProofResp{}
+						irre       *types.BPBlock
+					)
+					irre, _, _, err = chain.fetchLastIrreversibleBlock()
+					So(err, ShouldBeNil)
+					err = rpcService.QueryAccountWithProof(
+						&types.QueryAccountWithProofReq{Addr: addr1}, accResp)
+					So(err, ShouldBeNil)
+					So(accResp.Header, ShouldResemble, irre.SignedHeader)
+					So(accResp.Height, ShouldEqual, chain.lastIrre.height)
+					So(accResp.Count, ShouldEqual, chain.lastIrre.count)
+					So(accResp.Account.Address, ShouldEqual, addr1)
+					err = accResp.Header.Verify()
+					So(err, ShouldBeNil)
+					err = types.VerifyStateProof(
+						&accResp.Header.StateRoot, accResp.Account, accResp.Proof)
+					So(err, ShouldBeNil)
+					accResp.Account.TokenBalance[types.Particle]++
+					err = types.VerifyStateProof(
+						&accResp.Header.StateRoot, accResp.Account, accResp.Proof)
+					So(err, ShouldEqual, types.ErrStateProofVerification)
+
+					err = rpcService.QueryAccountWithProof(
+						&types.QueryAccountWithProofReq{Addr: proto.AccountAddress{}}, accResp)
+					So(errors.Cause(err), ShouldEqual, ErrAccountNotFound)
+					err = rpcService.QuerySQLChainProfileWithProof(
+						&types.QuerySQLChainProfileWithProofReq{DBID: "not_exist"}, dbResp)
+					So(errors.Cause(err), ShouldEqual, ErrDatabaseNotFound)
+
+					// A block committing to a wrong state root should be rejected
+					_, bl, err = chain.headBranch.produceBlock(
+						13, begin.Add(13*chain.period).UTC(), addr1, priv1)
+					So(err, ShouldBeNil)
+					bl.SignedHeader.StateRoot = hash.Hash{0x1}
+					err = bl.PackAndSignBlock(priv1)
+					So(err, ShouldBeNil)
+					err = chain.pushBlock(bl)
+					So(errors.Cause(err), ShouldEqual, types.ErrStateRootNotMatch)
+
+					// A legacy block doesn't commit to its state and is not checked
+					bl.SignedHeader.HashVersion = 0
+					bl.SignedHeader.StateRoot = hash.Hash{}
+					err = bl.SignedHeader.DefaultHashSignVerifierImpl.Sign(
+						&bl.SignedHeader.BPHeader, priv1)
+					So(err, ShouldBeNil)
+					err = chain.pushBlock(bl)
+					So(err, ShouldBeNil)
+
+					// A genesis block committing to a wrong state root should be rejected
+					var (
+						badConfig  = *config
+						badGenesis = *genesis
+					)
+					badConfig.DataFile = path.Join(testingDataDir, t.Name()+"_bad_root")
+					defer os.Remove(badConfig.DataFile)
+					badGenesis.SignedHeader.StateRoot = hash.Hash{0x1}
+					err = badGenesis.SetHash()
+					So(err, ShouldBeNil)
+					badConfig.Genesis = &badGenesis
+					_, err = NewChain(&badConfig)
+					So(errors.Cause(err), ShouldEqual, types.ErrStateRootNotMatch)
+				})
 				Convey("The chain should have same state after reloading", func() {
 					err = chain.Stop()
 					So(err, ShouldBeNil)
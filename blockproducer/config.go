@@ -53,4 +53,16 @@ type Config struct {
 	Tick   time.Duration
 
 	BlockCacheSize int
+
+	// SnapshotInterval is the block height interval of state snapshots, 0 disables snapshot.
+	SnapshotInterval uint32
+	// FastSync makes a new node initialize its state from the latest snapshot of the peers
+	// instead of replaying from the genesis block.
+	FastSync bool
+
+	// MinTxFee is the minimum fee of a transaction to be accepted into the transaction pool.
+	MinTxFee uint64
+	// TxPoolSize is the capacity of the transaction pool, the transactions with the lowest fees
+	// are evicted when the pool is full.
+	TxPoolSize int
 }
@@ -58,6 +58,16 @@ var (
 	ErrInvalidRange = errors.New("invalid billing range")
 	// ErrNoSuchMiner indicates that this miner does not exist or register.
 	ErrNoSuchMiner = errors.New("no such miner")
+	// ErrMinerSlashed indicates that the miner deposit has been slashed for misbehavior.
+	ErrMinerSlashed = errors.New("miner slashed")
+	// ErrSelfSlashReport indicates that the offender reports its own misbehavior, which would pay
+	// the reporter reward out of its own slashed deposit back to it.
+	ErrSelfSlashReport = errors.New("offender can not report itself")
+	// ErrMinerStillActive indicates that the miner is still reporting billing and can only be
+	// replaced by the database owner.
+	ErrMinerStillActive = errors.New("miner still active")
+	// ErrMinerAlreadyServing indicates that the miner is already serving the sqlchain.
+	ErrMinerAlreadyServing = errors.New("miner already serving")
 	// ErrNoEnoughMiner indicates that there is not enough miners
 	ErrNoEnoughMiner = errors.New("can not get enough miners")
 	// ErrAccountPermissionDeny indicates that the sender does not own admin permission to the sqlchain.
@@ -66,6 +76,8 @@ var (
 	ErrNoSuperUserLeft = errors.New("no super user left")
 	// ErrInvalidPermission indicates that the permission is invalid.
 	ErrInvalidPermission = errors.New("invalid permission")
+	// ErrInvalidRowPolicy indicates that the row-level security policy is invalid.
+	ErrInvalidRowPolicy = errors.New("invalid row policy")
 	// ErrMinerUserNotMatch indicates that the miner and user do not match.
 	ErrMinerUserNotMatch = errors.New("miner and user do not match")
 	// ErrInsufficientAdvancePayment indicates that the advance payment is insufficient.
@@ -87,4 +99,38 @@ var (
 	ErrNoAvailableBranch = errors.New("no available branch from state storage")
 	// ErrWrongTokenType indicates that token type in transfer is wrong.
 	ErrWrongTokenType = errors.New("wrong token type")
+	// ErrInvalidNewOwner indicates that the new owner of a database ownership transfer is invalid.
+	ErrInvalidNewOwner = errors.New("invalid new database owner")
+	// ErrMultiSigAccountExists indicates that the multi-signature account already exists.
+	ErrMultiSigAccountExists = errors.New("multi-signature account already exists")
+	// ErrMultiSigAccountNotFound indicates that the multi-signature account is not found.
+	ErrMultiSigAccountNotFound = errors.New("multi-signature account not found")
+	// ErrInsufficientSignatures indicates that a multi-signature transaction is signed by less
+	// members than the account threshold.
+	ErrInsufficientSignatures = errors.New("insufficient signatures")
+	// ErrEscrowExists indicates that the escrow already exists.
+	ErrEscrowExists = errors.New("escrow already exists")
+	// ErrEscrowNotFound indicates that the escrow is not found.
+	ErrEscrowNotFound = errors.New("escrow not found")
+	// ErrEscrowNotClaimable indicates that the escrow is not released yet or already expired.
+	ErrEscrowNotClaimable = errors.New("escrow is not claimable at current height")
+	// ErrEscrowNotRefundable indicates that the escrow is not expired yet.
+	ErrEscrowNotRefundable = errors.New("escrow is not refundable at current height")
+	// ErrSnapshotNotFound indicates that the state snapshot is not found.
+	ErrSnapshotNotFound = errors.New("state snapshot not found")
+	// ErrInvalidSnapshotChunk indicates that the state snapshot chunk index is out of range or
+	// the chunk doesn't belong to the requested snapshot.
+	ErrInvalidSnapshotChunk = errors.New("invalid state snapshot chunk")
+	// ErrInvalidSnapshot indicates that the state snapshot or its base block is invalid.
+	ErrInvalidSnapshot = errors.New("invalid state snapshot")
+	// ErrNoStateRoot indicates that the block is a legacy block which doesn't commit to its state.
+	ErrNoStateRoot = errors.New("block doesn't commit to its state")
+	// ErrInsufficientFee indicates that the transaction fee is less than the minimum fee.
+	ErrInsufficientFee = errors.New("insufficient transaction fee")
+	// ErrTxPoolFull indicates that the transaction pool is full and the transaction doesn't pay
+	// more than the pending ones.
+	ErrTxPoolFull = errors.New("transaction pool is full")
+	// ErrUnderpricedReplacement indicates that the transaction takes the nonce of a pending
+	// transaction without paying a higher fee.
+	ErrUnderpricedReplacement = errors.New("replacement transaction underpriced")
 )
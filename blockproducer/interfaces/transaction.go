@@ -72,6 +72,28 @@ const (
 	TransactionTypeIssueKeys
 	// TransactionTypeUpdateBilling defines SQLChain update billing information.
 	TransactionTypeUpdateBilling
+	// TransactionTypeTransferDatabaseOwnership defines SQLChain owner transfers database ownership.
+	TransactionTypeTransferDatabaseOwnership
+	// TransactionTypeCreateMultiSigAccount defines M-of-N multi-signature account creation.
+	TransactionTypeCreateMultiSigAccount
+	// TransactionTypeMultiSig defines transaction wrapper signed by multi-signature account members.
+	TransactionTypeMultiSig
+	// TransactionTypeCreateEscrow defines time-locked token escrow creation.
+	TransactionTypeCreateEscrow
+	// TransactionTypeClaimEscrow defines escrow claiming by the receiver.
+	TransactionTypeClaimEscrow
+	// TransactionTypeRefundEscrow defines expired escrow refunding to the sender.
+	TransactionTypeRefundEscrow
+	// TransactionTypeCancelTransaction defines pending transaction cancellation by nonce.
+	TransactionTypeCancelTransaction
+	// TransactionTypeSlash defines miner deposit slashing with misbehavior evidence.
+	TransactionTypeSlash
+	// TransactionTypeReplaceMiner defines miner replacement of a SQLChain.
+	TransactionTypeReplaceMiner
+	// TransactionTypeUpdateDatabaseResources defines database resources updating.
+	TransactionTypeUpdateDatabaseResources
+	// TransactionTypeUpdateRowPolicies defines row-level security policies updating of SQLChain.
+	TransactionTypeUpdateRowPolicies
 	// TransactionTypeNumber defines transaction types number.
 	TransactionTypeNumber
 )
@@ -102,6 +124,28 @@ func (t TransactionType) String() string {
 		return "IssueKeys"
 	case TransactionTypeUpdateBilling:
 		return "UpdateBilling"
+	case TransactionTypeTransferDatabaseOwnership:
+		return "TransferDatabaseOwnership"
+	case TransactionTypeCreateMultiSigAccount:
+		return "CreateMultiSigAccount"
+	case TransactionTypeMultiSig:
+		return "MultiSig"
+	case TransactionTypeCreateEscrow:
+		return "CreateEscrow"
+	case TransactionTypeClaimEscrow:
+		return "ClaimEscrow"
+	case TransactionTypeRefundEscrow:
+		return "RefundEscrow"
+	case TransactionTypeCancelTransaction:
+		return "CancelTransaction"
+	case TransactionTypeSlash:
+		return "Slash"
+	case TransactionTypeReplaceMiner:
+		return "ReplaceMiner"
+	case TransactionTypeUpdateDatabaseResources:
+		return "UpdateDatabaseResources"
+	case TransactionTypeUpdateRowPolicies:
+		return "UpdateRowPolicies"
 	default:
 		return "Unknown"
 	}
@@ -116,6 +160,8 @@ type TransactionState uint32
 //        |                     x                              +------[ Prune ]--> Not Found
 //        x                     |
 //        |                     +------------------------------------[ Expire ]--> Expired
+//        |                     |
+//        |                     +-----------------------------------[ Replace ]--> Replaced
 //        |
 //        +----------------------------------------------------------------------> Not Found.
 const (
@@ -124,6 +170,7 @@ const (
 	TransactionStateConfirmed
 	TransactionStateExpired
 	TransactionStateNotFound
+	TransactionStateReplaced
 )
 
 func (s TransactionState) String() string {
@@ -138,6 +185,8 @@ func (s TransactionState) String() string {
 		return "Expired"
 	case TransactionStateNotFound:
 		return "Not Found"
+	case TransactionStateReplaced:
+		return "Replaced"
 	default:
 		return "Unknown"
 	}
@@ -149,6 +198,7 @@ type Transaction interface {
 	GetTransactionType() TransactionType
 	GetAccountAddress() proto.AccountAddress
 	GetAccountNonce() AccountNonce
+	GetFee() uint64
 	GetTimestamp() time.Time
 	Hash() hash.Hash
 	Sign(signer *asymmetric.PrivateKey) error
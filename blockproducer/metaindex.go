@@ -17,8 +17,12 @@
 package blockproducer
 
 import (
+	"sync"
+
 	"github.com/mohae/deepcopy"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/types"
 )
@@ -45,6 +49,13 @@ type metaIndex struct {
 	accounts  map[proto.AccountAddress]*types.Account
 	databases map[proto.DatabaseID]*types.SQLChainProfile
 	provider  map[proto.AccountAddress]*types.ProviderProfile
+	multisigs map[proto.AccountAddress]*types.MultiSigProfile
+	escrows   map[hash.Hash]*types.EscrowProfile
+
+	// trie is the state trie over the objects of the index, which is built on demand and then
+	// kept up to date by the metaState commit.
+	trieLock sync.Mutex
+	trie     *merkle.Trie
 }
 
 func newMetaIndex() *metaIndex {
@@ -52,6 +63,8 @@ func newMetaIndex() *metaIndex {
 		accounts:  make(map[proto.AccountAddress]*types.Account),
 		databases: make(map[proto.DatabaseID]*types.SQLChainProfile),
 		provider:  make(map[proto.AccountAddress]*types.ProviderProfile),
+		multisigs: make(map[proto.AccountAddress]*types.MultiSigProfile),
+		escrows:   make(map[hash.Hash]*types.EscrowProfile),
 	}
 }
 
@@ -66,5 +79,96 @@ func (i *metaIndex) deepCopy() (cpy *metaIndex) {
 	for k, v := range i.provider {
 		cpy.provider[k] = deepcopy.Copy(v).(*types.ProviderProfile)
 	}
+	for k, v := range i.multisigs {
+		cpy.multisigs[k] = deepcopy.Copy(v).(*types.MultiSigProfile)
+	}
+	for k, v := range i.escrows {
+		cpy.escrows[k] = deepcopy.Copy(v).(*types.EscrowProfile)
+	}
+	return
+}
+
+// putStateObject puts the encoded state object into the state trie updates.
+func putStateObject(updates map[string][]byte, obj types.StateObject) (err error) {
+	var enc []byte
+	if enc, err = obj.MarshalHash(); err != nil {
+		return
+	}
+	updates[string(obj.StateKey())] = enc
 	return
 }
+
+// stateUpdates returns the state trie updates of the objects in the index, a nil object, which
+// marks a deletion, is mapped to a nil value.
+func (i *metaIndex) stateUpdates() (updates map[string][]byte, err error) {
+	updates = make(map[string][]byte)
+	for k, v := range i.accounts {
+		if v == nil {
+			updates[string((&types.Account{Address: k}).StateKey())] = nil
+		} else if err = putStateObject(updates, v); err != nil {
+			return
+		}
+	}
+	for k, v := range i.databases {
+		if v == nil {
+			updates[string((&types.SQLChainProfile{ID: k}).StateKey())] = nil
+		} else if err = putStateObject(updates, v); err != nil {
+			return
+		}
+	}
+	for k, v := range i.provider {
+		if v == nil {
+			updates[string((&types.ProviderProfile{Provider: k}).StateKey())] = nil
+		} else if err = putStateObject(updates, v); err != nil {
+			return
+		}
+	}
+	for k, v := range i.multisigs {
+		if v == nil {
+			updates[string((&types.MultiSigProfile{Address: k}).StateKey())] = nil
+		} else if err = putStateObject(updates, v); err != nil {
+			return
+		}
+	}
+	for k, v := range i.escrows {
+		if v == nil {
+			updates[string((&types.EscrowProfile{ID: k}).StateKey())] = nil
+		} else if err = putStateObject(updates, v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// stateTrie returns the state trie over the objects of the index, it's built on the first call.
+func (i *metaIndex) stateTrie() (trie *merkle.Trie, err error) {
+	i.trieLock.Lock()
+	defer i.trieLock.Unlock()
+	if i.trie == nil {
+		var updates map[string][]byte
+		if updates, err = i.stateUpdates(); err != nil {
+			return
+		}
+		i.trie = merkle.NewPatricia()
+		i.trie.Update(updates)
+	}
+	trie = i.trie
+	return
+}
+
+// updateStateTrie applies the changes of the dirty index to the state trie if it's built, so that
+// only the changed objects are rehashed.
+func (i *metaIndex) updateStateTrie(dirty *metaIndex) {
+	i.trieLock.Lock()
+	defer i.trieLock.Unlock()
+	if i.trie == nil {
+		return
+	}
+	var updates, err = dirty.stateUpdates()
+	if err != nil {
+		// Drop the trie, it will be rebuilt on demand
+		i.trie = nil
+		return
+	}
+	i.trie.Update(updates)
+}
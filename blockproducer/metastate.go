@@ -19,6 +19,7 @@ package blockproducer
 import (
 	"bytes"
 	"sort"
+	"strings"
 
 	"github.com/mohae/deepcopy"
 	"github.com/pkg/errors"
@@ -26,6 +27,9 @@ import (
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/conf"
 	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/types"
 	"github.com/CovenantSQL/CovenantSQL/utils"
@@ -40,6 +44,12 @@ var (
 
 type metaState struct {
 	dirty, readonly *metaIndex
+	// height is the height of the block which the applying transactions belong to, it's used to
+	// check the time locks of escrows.
+	height uint32
+	// producer is the producer of the block which the applying transactions belong to, it's
+	// credited with the transaction fees.
+	producer proto.AccountAddress
 }
 
 // MinerInfos is MinerInfo array.
@@ -201,6 +211,32 @@ func (s *metaState) loadOrStoreProviderObject(k proto.AccountAddress, v *types.P
 	return
 }
 
+func (s *metaState) loadMultiSigObject(k proto.AccountAddress) (o *types.MultiSigProfile, loaded bool) {
+	if o, loaded = s.dirty.multisigs[k]; loaded {
+		if o == nil {
+			loaded = false
+		}
+		return
+	}
+	if o, loaded = s.readonly.multisigs[k]; loaded {
+		return
+	}
+	return
+}
+
+func (s *metaState) loadEscrowObject(k hash.Hash) (o *types.EscrowProfile, loaded bool) {
+	if o, loaded = s.dirty.escrows[k]; loaded {
+		if o == nil {
+			loaded = false
+		}
+		return
+	}
+	if o, loaded = s.readonly.escrows[k]; loaded {
+		return
+	}
+	return
+}
+
 func (s *metaState) deleteAccountObject(k proto.AccountAddress) {
 	// Use a nil pointer to mark a deletion, which will be later used by commit procedure.
 	s.dirty.accounts[k] = nil
@@ -216,7 +252,13 @@ func (s *metaState) deleteProviderObject(k proto.AccountAddress) {
 	s.dirty.provider[k] = nil
 }
 
+func (s *metaState) deleteEscrowObject(k hash.Hash) {
+	// Use a nil pointer to mark a deletion, which will be later used by commit procedure.
+	s.dirty.escrows[k] = nil
+}
+
 func (s *metaState) commit() {
+	s.readonly.updateStateTrie(s.dirty)
 	for k, v := range s.dirty.accounts {
 		if v != nil {
 			// New/update object
@@ -244,6 +286,24 @@ func (s *metaState) commit() {
 			delete(s.readonly.provider, k)
 		}
 	}
+	for k, v := range s.dirty.multisigs {
+		if v != nil {
+			// New/update object
+			s.readonly.multisigs[k] = v
+		} else {
+			// Delete object
+			delete(s.readonly.multisigs, k)
+		}
+	}
+	for k, v := range s.dirty.escrows {
+		if v != nil {
+			// New/update object
+			s.readonly.escrows[k] = v
+		} else {
+			// Delete object
+			delete(s.readonly.escrows, k)
+		}
+	}
 	// Clean dirty map
 	s.dirty = newMetaIndex()
 	return
@@ -303,6 +363,12 @@ func (s *metaState) transferAccountToken(transfer *types.Transfer) (err error) {
 		err = errors.Wrap(err, "applyTx failed")
 		return err
 	}
+	return s.transferAccountTokenBy(realSender, transfer)
+}
+
+func (s *metaState) transferAccountTokenBy(
+	realSender proto.AccountAddress, transfer *types.Transfer) (err error,
+) {
 	if realSender != transfer.Sender {
 		err = errors.Wrapf(ErrInvalidSender,
 			"applyTx failed: real sender %s, sender %s", realSender, transfer.Sender)
@@ -516,6 +582,11 @@ func (s *metaState) updateProviderList(tx *types.ProvideService) (err error) {
 		return
 	}
 
+	if s.isSlashedMiner(sender) {
+		err = errors.Wrapf(ErrMinerSlashed, "provider %s is not allowed to serve", sender)
+		return
+	}
+
 	// deposit
 	var (
 		minDeposit = conf.GConf.MinProviderDeposit
@@ -544,6 +615,12 @@ func (s *metaState) matchProvidersWithUser(tx *types.CreateDatabase) (err error)
 		err = errors.Wrap(err, "matchProviders failed")
 		return
 	}
+	return s.matchProvidersWithUserBy(sender, tx)
+}
+
+func (s *metaState) matchProvidersWithUserBy(
+	sender proto.AccountAddress, tx *types.CreateDatabase) (err error,
+) {
 	if sender != tx.Owner {
 		err = errors.Wrapf(ErrInvalidSender, "match failed with real sender: %s, sender: %s",
 			sender, tx.Owner)
@@ -673,6 +750,7 @@ func (s *metaState) matchProvidersWithUser(tx *types.CreateDatabase) (err error)
 	s.dirty.accounts[dbAddr] = &types.Account{Address: dbAddr}
 	s.dirty.databases[dbID] = sp
 	for _, miner := range miners {
+		miner.LastActiveHeight = s.height
 		s.deleteProviderObject(miner.Address)
 	}
 	log.Infof("success create sqlchain with database ID: %s", dbID)
@@ -803,6 +881,12 @@ func (s *metaState) updatePermission(tx *types.UpdatePermission) (err error) {
 		}).WithError(err).Error("unexpected err")
 		return
 	}
+	return s.updatePermissionBy(sender, tx)
+}
+
+func (s *metaState) updatePermissionBy(
+	sender proto.AccountAddress, tx *types.UpdatePermission) (err error,
+) {
 	so, loaded := s.loadSQLChainObject(tx.TargetSQLChain.DatabaseID())
 	if !loaded {
 		log.WithFields(log.Fields{
@@ -815,14 +899,14 @@ func (s *metaState) updatePermission(tx *types.UpdatePermission) (err error) {
 	numOfSuperUsers := 0
 	targetUserIndex := -1
 	for i, u := range so.Users {
-		if sender == u.Address && !u.Permission.HasSuperPermission() {
+		if sender == u.Address && !u.Permission.Effective(s.height).HasSuperPermission() {
 			log.WithFields(log.Fields{
 				"sender": sender,
 				"dbID":   tx.TargetSQLChain,
 			}).WithError(ErrAccountPermissionDeny).Error("unexpected error in updatePermission")
 			return ErrAccountPermissionDeny
 		}
-		if u.Permission.HasSuperPermission() {
+		if u.Permission.Effective(s.height).HasSuperPermission() {
 			numOfSuperUsers++
 		}
 		if tx.TargetUser == u.Address {
@@ -830,8 +914,10 @@ func (s *metaState) updatePermission(tx *types.UpdatePermission) (err error) {
 		}
 	}
 
-	// return error if number of Admin <= 1 and Admin want to revoke permission of itself
-	if numOfSuperUsers <= 1 && tx.TargetUser == sender && !tx.Permission.HasSuperPermission() {
+	// return error if number of Admin <= 1 and Admin want to revoke permission of itself, an
+	// expiring grant would also revoke it sooner or later
+	if numOfSuperUsers <= 1 && tx.TargetUser == sender &&
+		(!tx.Permission.HasSuperPermission() || tx.Permission.ExpireHeight != 0) {
 		err = ErrNoSuperUserLeft
 		log.WithFields(log.Fields{
 			"sender":     sender,
@@ -856,8 +942,66 @@ func (s *metaState) updatePermission(tx *types.UpdatePermission) (err error) {
 	return
 }
 
+func (s *metaState) updateRowPolicies(tx *types.UpdateRowPolicies) (err error) {
+	var sender proto.AccountAddress
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "updateRowPolicies failed")
+		return
+	}
+	return s.updateRowPoliciesBy(sender, tx)
+}
+
+// updateRowPoliciesBy replaces the row-level security policies of the target SQLChain, which is
+// only permitted to the super users.
+func (s *metaState) updateRowPoliciesBy(
+	sender proto.AccountAddress, tx *types.UpdateRowPolicies) (err error,
+) {
+	var dbID = tx.TargetSQLChain.DatabaseID()
+	so, loaded := s.loadSQLChainObject(dbID)
+	if !loaded {
+		err = errors.Wrap(ErrDatabaseNotFound, "updateRowPolicies failed")
+		return
+	}
+	var isSuper bool
+	for _, u := range so.Users {
+		if u.Address == sender {
+			isSuper = u.Permission.Effective(s.height).HasSuperPermission()
+			break
+		}
+	}
+	if !isSuper {
+		err = errors.Wrapf(ErrAccountPermissionDeny,
+			"sender %s is not a super user of sqlchain %s", sender, dbID)
+		return
+	}
+	var tables = make(map[string]bool, len(tx.Policies))
+	for _, v := range tx.Policies {
+		if v == nil || v.Table == "" || v.Column == "" {
+			err = errors.Wrap(ErrInvalidRowPolicy, "empty table or column")
+			return
+		}
+		var table = strings.ToLower(v.Table)
+		if tables[table] {
+			err = errors.Wrapf(ErrInvalidRowPolicy, "duplicate policies of table %s", v.Table)
+			return
+		}
+		tables[table] = true
+	}
+	so.RowPolicies = tx.Policies
+	s.dirty.databases[dbID] = so
+	log.WithFields(log.Fields{
+		"tx_hash":  tx.Hash(),
+		"db_id":    dbID,
+		"policies": len(tx.Policies),
+	}).Info("updated sqlchain row policies")
+	return
+}
+
 func (s *metaState) updateKeys(tx *types.IssueKeys) (err error) {
-	sender := tx.GetAccountAddress()
+	return s.updateKeysBy(tx.GetAccountAddress(), tx)
+}
+
+func (s *metaState) updateKeysBy(sender proto.AccountAddress, tx *types.IssueKeys) (err error) {
 	so, loaded := s.loadSQLChainObject(tx.TargetSQLChain.DatabaseID())
 	if !loaded {
 		log.WithFields(log.Fields{
@@ -869,7 +1013,7 @@ func (s *metaState) updateKeys(tx *types.IssueKeys) (err error) {
 	// check sender's permission
 	for _, user := range so.Users {
 		if sender == user.Address {
-			if !user.Permission.HasSuperPermission() {
+			if !user.Permission.Effective(s.height).HasSuperPermission() {
 				log.WithFields(log.Fields{
 					"sender": sender,
 					"dbID":   tx.TargetSQLChain,
@@ -901,30 +1045,40 @@ func (s *metaState) updateBilling(tx *types.UpdateBilling) (err error) {
 		return
 	}
 
+	var minerAddr = tx.GetAccountAddress()
+	if tx.Version > 0 && tx.Range.From < tx.Range.To && newProfile.LastUpdatedHeight == tx.Range.To {
+		// The same range has been billed by another miner, take it as a heartbeat of the sender
+		if !markMinerActive(newProfile, minerAddr, s.height) {
+			err = errors.Wrapf(ErrInvalidSender, "sender %s is not a miner of sqlchain", minerAddr)
+			return
+		}
+		s.dirty.databases[tx.Receiver.DatabaseID()] = newProfile
+		return
+	}
 	if tx.Version > 0 && (tx.Range.From >= tx.Range.To || newProfile.LastUpdatedHeight != tx.Range.From) {
 		err = errors.Wrapf(ErrInvalidRange,
 			"update billing within range %d:(%d, %d]",
 			newProfile.LastUpdatedHeight, tx.Range.From, tx.Range.To)
 		return
 	}
-	log.Debugf("update billing addr: %s, user: %d, tx: %v", tx.GetAccountAddress(), len(tx.Users), tx)
+	log.Debugf("update billing addr: %s, user: %d, tx: %v", minerAddr, len(tx.Users), tx)
 
 	if newProfile.GasPrice == 0 {
+		if markMinerActive(newProfile, minerAddr, s.height) {
+			s.dirty.databases[tx.Receiver.DatabaseID()] = newProfile
+		}
 		return
 	}
 
 	var (
-		costMap   = make(map[proto.AccountAddress]uint64)
-		userMap   = make(map[proto.AccountAddress]map[proto.AccountAddress]uint64)
-		minerAddr = tx.GetAccountAddress()
-		isMiner   = false
+		costMap = make(map[proto.AccountAddress]uint64)
+		userMap = make(map[proto.AccountAddress]map[proto.AccountAddress]uint64)
 	)
 	for _, miner := range newProfile.Miners {
-		isMiner = isMiner || (miner.Address == minerAddr)
 		miner.ReceivedIncome += miner.PendingIncome
 		miner.PendingIncome = 0
 	}
-	if !isMiner {
+	if !markMinerActive(newProfile, minerAddr, s.height) {
 		err = ErrInvalidSender
 		log.WithFields(log.Fields{
 			"miner_addr": minerAddr,
@@ -985,6 +1139,737 @@ func (s *metaState) updateBilling(tx *types.UpdateBilling) (err error) {
 	return
 }
 
+// markMinerActive records the BP height as the last active height of the miner, it returns false
+// if the address is not a miner of the sqlchain.
+func markMinerActive(so *types.SQLChainProfile, addr proto.AccountAddress, height uint32) bool {
+	for _, miner := range so.Miners {
+		if miner.Address == addr {
+			miner.LastActiveHeight = height
+			return true
+		}
+	}
+	return false
+}
+
+// replaceMiner replaces a miner of the target SQLChain with the specified provider, or with a
+// newly matched one if not specified. The database owner can replace any miner at any time, while
+// the other miners of the database can only replace the one which has not reported any billing
+// in the last conf.MinerInactiveBlocks blocks.
+func (s *metaState) replaceMiner(tx *types.ReplaceMiner) (err error) {
+	var (
+		sender    proto.AccountAddress
+		emptyAddr proto.AccountAddress
+		dbID      = tx.TargetSQLChain.DatabaseID()
+		oldIndex  = -1
+		isMiner   = false
+	)
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "replaceMiner failed")
+		return
+	}
+	so, loaded := s.loadSQLChainObject(dbID)
+	if !loaded {
+		err = errors.Wrap(ErrDatabaseNotFound, "replaceMiner failed")
+		return
+	}
+	for i, v := range so.Miners {
+		if v.Address == tx.OldMiner {
+			oldIndex = i
+		}
+		isMiner = isMiner || (v.Address == sender)
+	}
+	if oldIndex < 0 {
+		err = errors.Wrapf(ErrNoSuchMiner, "miner %s not found in sqlchain %s", tx.OldMiner, dbID)
+		return
+	}
+	var oldMiner = so.Miners[oldIndex]
+	if sender != so.Owner {
+		if !isMiner || sender == tx.OldMiner {
+			err = errors.Wrapf(ErrInvalidSender,
+				"sender %s is neither the owner nor another miner of sqlchain %s", sender, dbID)
+			return
+		}
+		if s.height < oldMiner.LastActiveHeight+conf.MinerInactiveBlocks {
+			err = errors.Wrapf(ErrMinerStillActive, "miner %s last active at height %d",
+				oldMiner.Address, oldMiner.LastActiveHeight)
+			return
+		}
+	}
+
+	for _, v := range so.Miners {
+		if v.Address == tx.NewMiner {
+			err = errors.Wrapf(ErrMinerAlreadyServing,
+				"miner %s already serves sqlchain %s", tx.NewMiner, dbID)
+			return
+		}
+	}
+	// Match the new miner with the original request of the database
+	var (
+		req       = newMatchingRequest(so, so.Meta)
+		newMiners MinerInfos
+	)
+	if tx.NewMiner != emptyAddr {
+		po, loaded := s.loadProviderObject(tx.NewMiner)
+		if !loaded {
+			err = errors.Wrapf(ErrNoSuchMiner, "provider %s not found", tx.NewMiner)
+			return
+		}
+		if newMiners, err = filterAndAppendMiner(newMiners, po, req, so.Owner); err != nil {
+			err = errors.Wrapf(err, "provider %s mismatch", tx.NewMiner)
+			return
+		}
+	} else if newMiners, err = s.filterNMiners(req, so.Owner, 1); err != nil {
+		return
+	}
+
+	// Return the deposit of the old miner, which is zero if it has been slashed
+	if oldMiner.Deposit > 0 {
+		if err = s.increaseAccountStableBalance(oldMiner.Address, oldMiner.Deposit); err != nil {
+			return
+		}
+	}
+	// The new miner is appended to the tail, so that a remaining miner will take over the leader
+	// if the old one was
+	var (
+		newMiner = newMiners[0]
+		miners   = make([]*types.MinerInfo, 0, len(so.Miners))
+	)
+	newMiner.LastActiveHeight = s.height
+	miners = append(miners, so.Miners[:oldIndex]...)
+	miners = append(miners, so.Miners[oldIndex+1:]...)
+	so.Miners = append(miners, newMiner)
+	s.deleteProviderObject(newMiner.Address)
+	s.dirty.databases[dbID] = so
+	log.WithFields(log.Fields{
+		"tx_hash":   tx.Hash(),
+		"db_id":     dbID,
+		"old_miner": oldMiner.Address,
+		"new_miner": newMiner.Address,
+	}).Info("replaced sqlchain miner")
+	return
+}
+
+// newMatchingRequest builds a miner matching request with the resource meta for an existing
+// SQLChain, all the current miners of the SQLChain are excluded from matching.
+func newMatchingRequest(so *types.SQLChainProfile, meta types.ResourceMeta) *types.CreateDatabase {
+	var req = &types.CreateDatabase{}
+	req.ResourceMeta = meta
+	req.ResourceMeta.TargetMiners = make([]proto.AccountAddress, 0, len(so.Miners))
+	for _, v := range so.Miners {
+		req.ResourceMeta.TargetMiners = append(req.ResourceMeta.TargetMiners, v.Address)
+	}
+	req.GasPrice = so.GasPrice
+	req.TokenType = so.TokenType
+	return req
+}
+
+// updateDatabaseResources scales the target SQLChain to the new miner count and resource
+// requirement. New miners are matched with the new requirement and appended to the miner list,
+// and redundant miners are removed by removalOrder so that the leader is kept. The deposit of the
+// owner is adjusted to the new miner count, the difference is charged from or refunded to its
+// account balance.
+func (s *metaState) updateDatabaseResources(tx *types.UpdateDatabaseResources) (err error) {
+	var (
+		sender proto.AccountAddress
+		owner  *types.SQLChainUser
+		dbID   = tx.TargetSQLChain.DatabaseID()
+	)
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "updateDatabaseResources failed")
+		return
+	}
+	so, loaded := s.loadSQLChainObject(dbID)
+	if !loaded {
+		err = errors.Wrap(ErrDatabaseNotFound, "updateDatabaseResources failed")
+		return
+	}
+	if sender != so.Owner {
+		err = errors.Wrapf(ErrAccountPermissionDeny,
+			"sender %s is not the owner of sqlchain %s", sender, dbID)
+		return
+	}
+	if tx.Node <= 0 {
+		err = ErrInvalidMinerCount
+		return
+	}
+	for _, v := range so.Users {
+		if v.Address == sender {
+			owner = v
+			break
+		}
+	}
+	if owner == nil {
+		err = errors.Wrapf(ErrAccountPermissionDeny, "owner %s not found in users", sender)
+		return
+	}
+
+	var (
+		minerCount = uint64(tx.Node)
+		deposit    = minDeposit(so.GasPrice, minerCount)
+		meta       = so.Meta
+	)
+	if deposit > owner.Deposit {
+		var balance, _ = s.loadAccountTokenBalance(sender, so.TokenType)
+		if balance < deposit-owner.Deposit {
+			err = errors.Wrapf(ErrInsufficientBalance,
+				"balance %d is less than deposit increment %d", balance, deposit-owner.Deposit)
+			return
+		}
+	}
+	meta.Node = tx.Node
+	meta.Space = tx.Space
+	meta.Memory = tx.Memory
+
+	var (
+		miners    = so.Miners
+		newMiners MinerInfos
+	)
+	if minerCount > uint64(len(miners)) {
+		if newMiners, err = s.filterNMiners(
+			newMatchingRequest(so, meta), so.Owner, int(minerCount)-len(miners),
+		); err != nil {
+			return
+		}
+	}
+	if deposit > owner.Deposit {
+		err = s.decreaseAccountToken(sender, deposit-owner.Deposit, so.TokenType)
+	} else if deposit < owner.Deposit {
+		err = s.increaseAccountToken(sender, owner.Deposit-deposit, so.TokenType)
+	}
+	if err != nil {
+		return
+	}
+	if len(newMiners) > 0 {
+		for _, v := range newMiners {
+			v.LastActiveHeight = s.height
+			s.deleteProviderObject(v.Address)
+		}
+		miners = append(miners, newMiners...)
+	} else if minerCount < uint64(len(miners)) {
+		var removed MinerInfos
+		miners, removed = removeMiners(miners, len(miners)-int(minerCount))
+		// Return the deposits of the removed miners, which are zero if they have been slashed
+		for _, v := range removed {
+			if v.Deposit == 0 {
+				continue
+			}
+			if err = s.increaseAccountStableBalance(v.Address, v.Deposit); err != nil {
+				return
+			}
+			log.WithFields(log.Fields{
+				"db_id":   dbID,
+				"miner":   v.Address,
+				"deposit": v.Deposit,
+			}).Info("removed sqlchain miner")
+		}
+	}
+	owner.Deposit = deposit
+	so.Miners = miners
+	so.Meta = meta
+	s.dirty.databases[dbID] = so
+	log.WithFields(log.Fields{
+		"tx_hash": tx.Hash(),
+		"db_id":   dbID,
+		"miners":  len(miners),
+		"space":   meta.Space,
+		"memory":  meta.Memory,
+	}).Info("updated sqlchain resources")
+	return
+}
+
+// removeMiners removes n miners from the miner list of a SQLChain. The first miner, which is the
+// leader of the SQLChain, is always kept. The other miners are removed in the order of: the
+// slashed ones, the ones with lower last active heights, and the later added ones.
+func removeMiners(miners MinerInfos, n int) (kept, removed MinerInfos) {
+	var candidates = make([]int, 0, len(miners))
+	for i := 1; i < len(miners); i++ {
+		candidates = append(candidates, i)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		var mi, mj = miners[candidates[i]], miners[candidates[j]]
+		if (mi.Deposit == 0) != (mj.Deposit == 0) {
+			return mi.Deposit == 0
+		}
+		if mi.LastActiveHeight != mj.LastActiveHeight {
+			return mi.LastActiveHeight < mj.LastActiveHeight
+		}
+		return candidates[i] > candidates[j]
+	})
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	var drop = make(map[int]bool, n)
+	for _, v := range candidates[:n] {
+		drop[v] = true
+	}
+	kept = make(MinerInfos, 0, len(miners)-n)
+	for i, v := range miners {
+		if drop[i] {
+			removed = append(removed, v)
+		} else {
+			kept = append(kept, v)
+		}
+	}
+	return
+}
+
+func (s *metaState) transferDatabaseOwnership(tx *types.TransferDatabaseOwnership) (err error) {
+	var (
+		sender   proto.AccountAddress
+		newOwner = tx.NewOwner
+		dbID     = tx.TargetSQLChain.DatabaseID()
+		le       = log.WithFields(log.Fields{
+			"tx_hash":   tx.Hash(),
+			"db_id":     dbID,
+			"new_owner": newOwner,
+		})
+	)
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "transferDatabaseOwnership failed")
+		return
+	}
+	so, loaded := s.loadSQLChainObject(dbID)
+	if !loaded {
+		err = errors.Wrap(ErrDatabaseNotFound, "transferDatabaseOwnership failed")
+		return
+	}
+	if so.Owner != sender {
+		err = errors.Wrapf(ErrAccountPermissionDeny,
+			"sender %s is not the owner %s of database", sender, so.Owner)
+		le.WithError(err).Warning("in transferDatabaseOwnership")
+		return
+	}
+	if newOwner == sender || newOwner == (proto.AccountAddress{}) || newOwner == so.Address {
+		err = errors.Wrapf(ErrInvalidNewOwner, "cannot transfer ownership to %s", newOwner)
+		return
+	}
+	for _, m := range so.Miners {
+		if m.Address == newOwner {
+			err = errors.Wrapf(ErrInvalidNewOwner, "new owner %s is a miner of database", newOwner)
+			return
+		}
+	}
+
+	// Merge the billing and permission rows of the old owner into the new owner
+	var (
+		oldIdx, newIdx = -1, -1
+		users          = make([]*types.SQLChainUser, 0, len(so.Users))
+	)
+	for i, u := range so.Users {
+		switch u.Address {
+		case sender:
+			oldIdx = i
+		case newOwner:
+			newIdx = i
+		}
+	}
+	if oldIdx == -1 {
+		err = errors.Wrapf(ErrDatabaseNotFound, "owner %s not found in database users", sender)
+		return
+	}
+	var ou, nu = so.Users[oldIdx], &types.SQLChainUser{Address: newOwner, Status: types.Normal}
+	if newIdx != -1 {
+		nu = so.Users[newIdx]
+	}
+	if err = safeAdd(&nu.AdvancePayment, &ou.AdvancePayment); err != nil {
+		return
+	}
+	if err = safeAdd(&nu.Arrears, &ou.Arrears); err != nil {
+		return
+	}
+	if err = safeAdd(&nu.Deposit, &ou.Deposit); err != nil {
+		return
+	}
+	if nu.Arrears > 0 {
+		nu.Status = types.Arrears
+	} else if !nu.Status.EnableQuery() {
+		nu.Status = ou.Status
+	}
+	nu.Permission = types.UserPermissionFromRole(types.Admin)
+	for i, u := range so.Users {
+		if i == oldIdx {
+			// The new owner takes over the row position of the old owner
+			users = append(users, nu)
+		} else if i != newIdx {
+			users = append(users, u)
+		}
+	}
+	so.Users = users
+
+	// Move the arrears records of the old owner in miner incomes
+	for _, m := range so.Miners {
+		var (
+			merged *types.UserArrears
+			uas    = make([]*types.UserArrears, 0, len(m.UserArrears))
+		)
+		for _, ua := range m.UserArrears {
+			if ua.User != sender && ua.User != newOwner {
+				uas = append(uas, ua)
+				continue
+			}
+			if merged == nil {
+				merged = &types.UserArrears{User: newOwner}
+				uas = append(uas, merged)
+			}
+			if err = safeAdd(&merged.Arrears, &ua.Arrears); err != nil {
+				return
+			}
+		}
+		m.UserArrears = uas
+	}
+
+	so.Owner = newOwner
+	s.dirty.databases[dbID] = so
+	le.WithFields(log.Fields{
+		"old_owner": sender,
+		"cosigned":  tx.IsCoSigned(),
+	}).Info("database ownership transferred")
+	return
+}
+
+func (s *metaState) createMultiSigAccount(tx *types.CreateMultiSigAccount) (err error) {
+	var (
+		addr    proto.AccountAddress
+		members []*asymmetric.PublicKey
+	)
+	if addr, err = tx.MultiSigAccount(); err != nil {
+		return
+	}
+	if !(&types.MultiSigProfile{Members: tx.Members}).IsMember(tx.Signee) {
+		err = errors.Wrap(ErrInvalidSender, "multi-signature account must be created by a member")
+		return
+	}
+	if _, loaded := s.loadMultiSigObject(addr); loaded {
+		err = errors.Wrapf(ErrMultiSigAccountExists, "multisig account %s", addr)
+		return
+	}
+	if members, err = types.SortMultiSigMembers(tx.Members); err != nil {
+		return
+	}
+	// Keep the balance if there were any transfers to the address before its creation
+	s.loadOrStoreAccountObject(addr, &types.Account{Address: addr})
+	s.dirty.multisigs[addr] = &types.MultiSigProfile{
+		Address:   addr,
+		Threshold: tx.Threshold,
+		Members:   members,
+	}
+	log.WithFields(log.Fields{
+		"tx_hash":   tx.Hash(),
+		"account":   addr,
+		"threshold": tx.Threshold,
+		"members":   len(members),
+	}).Info("multisig account created")
+	return
+}
+
+// verifyMultiSigThreshold checks the signers of tx against the threshold of its multi-signature
+// account, signature validity is checked by tx.Verify before it's accepted by the tx pool.
+func (s *metaState) verifyMultiSigThreshold(tx *types.MultiSigTransaction) (err error) {
+	ms, loaded := s.loadMultiSigObject(tx.Account)
+	if !loaded {
+		err = errors.Wrapf(ErrMultiSigAccountNotFound, "multisig account %s", tx.Account)
+		return
+	}
+	var signed = make(map[proto.AccountAddress]bool)
+	for _, signer := range tx.Signers() {
+		if !ms.IsMember(signer) {
+			err = errors.Wrapf(ErrInvalidSender, "signer is not a member of multisig account %s",
+				tx.Account)
+			return
+		}
+		var addr proto.AccountAddress
+		if addr, err = crypto.PubKeyHash(signer); err != nil {
+			return
+		}
+		signed[addr] = true
+	}
+	if uint32(len(signed)) < ms.Threshold {
+		err = errors.Wrapf(ErrInsufficientSignatures, "signed by %d of %d required members",
+			len(signed), ms.Threshold)
+	}
+	return
+}
+
+func (s *metaState) applyMultiSigTransaction(tx *types.MultiSigTransaction) (err error) {
+	if err = s.verifyMultiSigThreshold(tx); err != nil {
+		log.WithFields(log.Fields{
+			"tx_hash": tx.Hash(),
+			"account": tx.Account,
+		}).WithError(err).Warning("in applyMultiSigTransaction")
+		return
+	}
+	var sender = tx.Account
+	switch t := tx.Unwrap().(type) {
+	case *types.Transfer:
+		err = s.transferSQLChainTokenBalanceBy(sender, t)
+		if err == ErrDatabaseNotFound {
+			err = s.transferAccountTokenBy(sender, t)
+		}
+	case *types.CreateDatabase:
+		err = s.matchProvidersWithUserBy(sender, t)
+	case *types.UpdatePermission:
+		err = s.updatePermissionBy(sender, t)
+	case *types.UpdateRowPolicies:
+		err = s.updateRowPoliciesBy(sender, t)
+	case *types.IssueKeys:
+		err = s.updateKeysBy(sender, t)
+	default:
+		err = errors.Wrapf(ErrUnknownTransactionType, "can not apply %T in multisig transaction", t)
+	}
+	return
+}
+
+func (s *metaState) createEscrow(tx *types.CreateEscrow) (err error) {
+	var (
+		sender  proto.AccountAddress
+		profile *types.EscrowProfile
+	)
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "createEscrow failed")
+		return
+	}
+	if !tx.TokenType.Listed() {
+		err = errors.Wrapf(ErrWrongTokenType, "unknown token type %d", tx.TokenType)
+		return
+	}
+	if tx.Amount == 0 || tx.ExpireHeight <= tx.ReleaseHeight {
+		err = errors.Wrapf(types.ErrInvalidEscrow,
+			"amount %d, release height %d, expire height %d",
+			tx.Amount, tx.ReleaseHeight, tx.ExpireHeight)
+		return
+	}
+	if tx.ExpireHeight <= s.height {
+		err = errors.Wrapf(types.ErrInvalidEscrow,
+			"expire height %d is not after current height %d", tx.ExpireHeight, s.height)
+		return
+	}
+	if tx.Receiver == sender {
+		err = errors.Wrap(types.ErrInvalidEscrow, "escrow receiver is the sender")
+		return
+	}
+	profile = tx.EscrowProfile()
+	if _, loaded := s.loadEscrowObject(profile.ID); loaded {
+		err = errors.Wrapf(ErrEscrowExists, "escrow %s", profile.ID.String())
+		return
+	}
+	if err = s.decreaseAccountToken(sender, tx.Amount, tx.TokenType); err != nil {
+		err = errors.Wrap(err, "createEscrow failed")
+		return
+	}
+	s.dirty.escrows[profile.ID] = profile
+	log.WithFields(log.Fields{
+		"escrow_id":      profile.ID.String(),
+		"sender":         sender,
+		"receiver":       profile.Receiver,
+		"amount":         profile.Amount,
+		"token_type":     profile.TokenType,
+		"release_height": profile.ReleaseHeight,
+		"expire_height":  profile.ExpireHeight,
+	}).Info("escrow created")
+	return
+}
+
+func (s *metaState) claimEscrow(tx *types.ClaimEscrow) (err error) {
+	var sender proto.AccountAddress
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "claimEscrow failed")
+		return
+	}
+	profile, loaded := s.loadEscrowObject(tx.EscrowID)
+	if !loaded {
+		err = errors.Wrapf(ErrEscrowNotFound, "escrow %s", tx.EscrowID.String())
+		return
+	}
+	if profile.Receiver != sender {
+		err = errors.Wrapf(ErrInvalidSender,
+			"sender %s is not the escrow receiver %s", sender, profile.Receiver)
+		return
+	}
+	if !profile.IsClaimable(s.height) {
+		err = errors.Wrapf(ErrEscrowNotClaimable,
+			"current height %d not in [%d, %d)", s.height, profile.ReleaseHeight, profile.ExpireHeight)
+		return
+	}
+	return s.releaseEscrow(profile, profile.Receiver)
+}
+
+func (s *metaState) refundEscrow(tx *types.RefundEscrow) (err error) {
+	var sender proto.AccountAddress
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "refundEscrow failed")
+		return
+	}
+	profile, loaded := s.loadEscrowObject(tx.EscrowID)
+	if !loaded {
+		err = errors.Wrapf(ErrEscrowNotFound, "escrow %s", tx.EscrowID.String())
+		return
+	}
+	if profile.Sender != sender {
+		err = errors.Wrapf(ErrInvalidSender,
+			"sender %s is not the escrow sender %s", sender, profile.Sender)
+		return
+	}
+	if !profile.IsRefundable(s.height) {
+		err = errors.Wrapf(ErrEscrowNotRefundable,
+			"current height %d before expire height %d", s.height, profile.ExpireHeight)
+		return
+	}
+	return s.releaseEscrow(profile, profile.Sender)
+}
+
+// releaseEscrow pays the locked tokens of the escrow to the target account and deletes it.
+func (s *metaState) releaseEscrow(profile *types.EscrowProfile, target proto.AccountAddress) (err error) {
+	// Create empty target account if not found
+	s.loadOrStoreAccountObject(target, &types.Account{Address: target})
+	if err = s.increaseAccountToken(target, profile.Amount, profile.TokenType); err != nil {
+		return
+	}
+	s.deleteEscrowObject(profile.ID)
+	log.WithFields(log.Fields{
+		"escrow_id":  profile.ID.String(),
+		"target":     target,
+		"amount":     profile.Amount,
+		"token_type": profile.TokenType,
+		"height":     s.height,
+	}).Info("escrow released")
+	return
+}
+
+// loadEscrowBalance returns the token amounts locked in escrows sent and to be received by the
+// account.
+func (s *metaState) loadEscrowBalance(addr proto.AccountAddress) (
+	locked, incoming [types.SupportTokenNumber]uint64,
+) {
+	var count = func(v *types.EscrowProfile) {
+		if !v.TokenType.Listed() {
+			return
+		}
+		if v.Sender == addr {
+			locked[v.TokenType] += v.Amount
+		}
+		if v.Receiver == addr {
+			incoming[v.TokenType] += v.Amount
+		}
+	}
+	for _, v := range s.dirty.escrows {
+		if v != nil {
+			count(v)
+		}
+	}
+	for k, v := range s.readonly.escrows {
+		// Skip objects updated or deleted in dirty index
+		if _, ok := s.dirty.escrows[k]; !ok {
+			count(v)
+		}
+	}
+	return
+}
+
+// slashMiner verifies the misbehavior evidence of a miner serving the target SQLChain, then
+// slashes its deposits and marks it as slashed. A part of the slashed deposits is rewarded to the
+// sender of the transaction, and the rest is burned.
+func (s *metaState) slashMiner(tx *types.Slash) (err error) {
+	var (
+		sender, offender proto.AccountAddress
+		miner            *types.MinerInfo
+		genesis          = &types.Block{}
+		evidence         = &tx.Evidence
+		dbID             = tx.TargetSQLChain.DatabaseID()
+	)
+	if sender, err = crypto.PubKeyHash(tx.Signee); err != nil {
+		err = errors.Wrap(err, "slashMiner failed")
+		return
+	}
+	if offender, err = evidence.Offender(); err != nil {
+		err = errors.Wrap(err, "slashMiner failed")
+		return
+	}
+	if sender == offender {
+		err = errors.Wrapf(ErrSelfSlashReport, "slashMiner failed for %s", offender)
+		return
+	}
+	so, loaded := s.loadSQLChainObject(dbID)
+	if !loaded {
+		err = errors.Wrap(ErrDatabaseNotFound, "slashMiner failed")
+		return
+	}
+	if err = utils.DecodeMsgPack(so.EncodedGenesis, genesis); err != nil {
+		err = errors.Wrap(err, "failed to decode genesis block")
+		return
+	}
+	if evidence.BlockA.GenesisHash != *genesis.BlockHash() {
+		err = errors.Wrap(types.ErrInvalidEvidence, "blocks not belong to the target sqlchain")
+		return
+	}
+	for _, v := range so.Miners {
+		if v.Address == offender {
+			miner = v
+			break
+		}
+	}
+	if miner == nil {
+		err = errors.Wrapf(ErrNoSuchMiner, "miner %s not found in sqlchain %s", offender, dbID)
+		return
+	}
+	if miner.NodeID != evidence.BlockA.Producer {
+		err = errors.Wrapf(types.ErrInvalidEvidence,
+			"producer %s doesn't match the miner node %s", evidence.BlockA.Producer, miner.NodeID)
+		return
+	}
+	if miner.Status == types.Slashed {
+		err = errors.Wrapf(ErrMinerSlashed, "miner %s already slashed", offender)
+		return
+	}
+
+	// The provider profile is also removed, so that the miner will never be matched again
+	var slashed = miner.Deposit
+	if po, loaded := s.loadProviderObject(offender); loaded {
+		if err = safeAdd(&slashed, &po.Deposit); err != nil {
+			return
+		}
+		s.deleteProviderObject(offender)
+	}
+	if reward := slashed * conf.SlashReporterRewardPercent / 100; reward > 0 {
+		if err = s.increaseAccountToken(sender, reward, types.Particle); err != nil {
+			return
+		}
+	}
+	miner.Deposit = 0
+	miner.Status = types.Slashed
+	s.dirty.databases[dbID] = so
+	log.WithFields(log.Fields{
+		"tx_hash":  tx.Hash(),
+		"db_id":    dbID,
+		"miner":    offender,
+		"reporter": sender,
+		"slashed":  slashed,
+	}).Info("slashed miner deposit")
+	return
+}
+
+// isSlashedMiner returns whether the account has been slashed as a miner of any SQLChain.
+func (s *metaState) isSlashedMiner(addr proto.AccountAddress) bool {
+	var isSlashedIn = func(db *types.SQLChainProfile) bool {
+		for _, v := range db.Miners {
+			if v.Address == addr && v.Status == types.Slashed {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range s.dirty.databases {
+		if v != nil && isSlashedIn(v) {
+			return true
+		}
+	}
+	for k, v := range s.readonly.databases {
+		if _, ok := s.dirty.databases[k]; !ok && isSlashedIn(v) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *metaState) loadROSQLChains(addr proto.AccountAddress) (dbs []*types.SQLChainProfile) {
 	for _, db := range s.readonly.databases {
 		for _, miner := range db.Miners {
@@ -1009,7 +1894,12 @@ func (s *metaState) transferSQLChainTokenBalance(transfer *types.Transfer) (err
 		err = errors.Wrap(err, "applyTx failed")
 		return
 	}
+	return s.transferSQLChainTokenBalanceBy(realSender, transfer)
+}
 
+func (s *metaState) transferSQLChainTokenBalanceBy(
+	realSender proto.AccountAddress, transfer *types.Transfer) (err error,
+) {
 	if realSender != transfer.Sender {
 		err = errors.Wrapf(ErrInvalidSender,
 			"applyTx failed: real sender %s, sender %s", realSender, transfer.Sender)
@@ -1122,17 +2012,41 @@ func (s *metaState) applyTransaction(tx pi.Transaction) (err error) {
 		}
 		return
 	case *types.BaseAccount:
-		err = s.storeBaseAccount(t.Address, &t.Account)
+		// Store a copy, so that the following nonce increasing won't modify the transaction
+		var acc = t.Account
+		err = s.storeBaseAccount(t.Address, &acc)
 	case *types.ProvideService:
 		err = s.updateProviderList(t)
 	case *types.CreateDatabase:
 		err = s.matchProvidersWithUser(t)
 	case *types.UpdatePermission:
 		err = s.updatePermission(t)
+	case *types.UpdateRowPolicies:
+		err = s.updateRowPolicies(t)
 	case *types.IssueKeys:
 		err = s.updateKeys(t)
 	case *types.UpdateBilling:
 		err = s.updateBilling(t)
+	case *types.TransferDatabaseOwnership:
+		err = s.transferDatabaseOwnership(t)
+	case *types.CreateMultiSigAccount:
+		err = s.createMultiSigAccount(t)
+	case *types.MultiSigTransaction:
+		err = s.applyMultiSigTransaction(t)
+	case *types.CreateEscrow:
+		err = s.createEscrow(t)
+	case *types.ClaimEscrow:
+		err = s.claimEscrow(t)
+	case *types.RefundEscrow:
+		err = s.refundEscrow(t)
+	case *types.Slash:
+		err = s.slashMiner(t)
+	case *types.ReplaceMiner:
+		err = s.replaceMiner(t)
+	case *types.UpdateDatabaseResources:
+		err = s.updateDatabaseResources(t)
+	case *types.CancelTransaction:
+		// Nothing to apply, the nonce is consumed and the fee is charged by the caller
 	case *pi.TransactionWrapper:
 		// call again using unwrapped transaction
 		err = s.applyTransaction(t.Unwrap())
@@ -1183,21 +2097,64 @@ func (s *metaState) apply(t pi.Transaction) (err error) {
 		}).WithError(err).Debug("nonce not match during transaction apply")
 		return
 	}
+	// Charge transaction fee first, so that the transaction cannot spend the fee
+	var fee = t.GetFee()
+	if err = s.chargeFee(addr, fee); err != nil {
+		log.WithError(err).Debug("charge transaction fee failed")
+		return
+	}
 	// Try to apply transaction to metaState
 	if err = s.applyTransaction(t); err != nil {
 		log.WithError(err).Debug("apply transaction failed")
+		s.refundFee(addr, fee)
 		return
 	}
 	if err = s.increaseNonce(addr); err != nil {
 		return
 	}
+	if err = s.creditFee(fee); err != nil {
+		return
+	}
 	return
 }
 
+// chargeFee charges the transaction fee from the sender account.
+func (s *metaState) chargeFee(addr proto.AccountAddress, fee uint64) (err error) {
+	if fee == 0 {
+		return
+	}
+	if err = s.decreaseAccountToken(addr, fee, types.Particle); err != nil {
+		err = errors.Wrapf(err, "failed to charge fee %d from %s", fee, addr)
+	}
+	return
+}
+
+// refundFee returns the charged fee to the sender account of a failed transaction.
+func (s *metaState) refundFee(addr proto.AccountAddress, fee uint64) {
+	if fee == 0 {
+		return
+	}
+	if err := s.increaseAccountToken(addr, fee, types.Particle); err != nil {
+		log.WithError(err).Warning("failed to refund transaction fee")
+	}
+}
+
+// creditFee credits the transaction fee to the block producer.
+func (s *metaState) creditFee(fee uint64) (err error) {
+	if fee == 0 {
+		return
+	}
+	// Create empty producer account if not found
+	s.loadOrStoreAccountObject(s.producer, &types.Account{Address: s.producer})
+	return s.increaseAccountToken(s.producer, fee, types.Particle)
+}
+
 func (s *metaState) makeCopy() *metaState {
 	return &metaState{
 		dirty:    newMetaIndex(),
 		readonly: s.readonly.deepCopy(),
+		height:   s.height,
+		producer: s.producer,
 	}
 }
 
@@ -1227,6 +2184,37 @@ func (s *metaState) compileChanges(
 			results = append(results, deleteProvider(k))
 		}
 	}
+	for k, v := range s.dirty.multisigs {
+		if v != nil {
+			results = append(results, updateMultiSig(v))
+		} else {
+			results = append(results, deleteMultiSig(k))
+		}
+	}
+	for k, v := range s.dirty.escrows {
+		if v != nil {
+			results = append(results, updateEscrow(v))
+		} else {
+			results = append(results, deleteEscrow(k))
+		}
+	}
+	return
+}
+
+// stateRoot returns the state trie root of the current view of the state objects, the dirty
+// objects take the place of their read-only versions.
+func (s *metaState) stateRoot() (root hash.Hash, err error) {
+	var (
+		trie    *merkle.Trie
+		updates map[string][]byte
+	)
+	if trie, err = s.readonly.stateTrie(); err != nil {
+		return
+	}
+	if updates, err = s.dirty.stateUpdates(); err != nil {
+		return
+	}
+	root = *trie.RootWith(updates)
 	return
 }
 
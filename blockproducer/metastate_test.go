@@ -19,6 +19,7 @@ package blockproducer
 import (
 	"math"
 	"os"
+	"path"
 	"sync"
 	"testing"
 
@@ -34,6 +35,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
 )
 
@@ -473,6 +475,21 @@ func TestMetaState(t *testing.T) {
 						_, loaded = ms.loadSQLChainObject(dbID1)
 						So(loaded, ShouldBeFalse)
 					})
+					Convey("The state root should be kept up to date with the state trie", func() {
+						var root, expected hash.Hash
+						_, err = ms.readonly.stateTrie()
+						So(err, ShouldBeNil)
+						root, err = ms.stateRoot()
+						So(err, ShouldBeNil)
+						ms.commit()
+						expected, err = ms.makeStateSnapshot().ComputeStateRoot()
+						So(err, ShouldBeNil)
+						So(root, ShouldEqual, expected)
+						root, err = ms.stateRoot()
+						So(err, ShouldBeNil)
+						So(root, ShouldEqual, expected)
+						So(*ms.readonly.trie.Root(), ShouldEqual, expected)
+					})
 				})
 			})
 			Convey("When transactions are added", func() {
@@ -611,6 +628,361 @@ func TestMetaState(t *testing.T) {
 				So(bl, ShouldEqual, 117)
 			})
 		})
+		Convey("When multisig account txs are added", func() {
+			var (
+				txs = []pi.Transaction{
+					types.NewBaseAccount(
+						&types.Account{
+							Address:      addr1,
+							TokenBalance: [types.SupportTokenNumber]uint64{100, 100},
+						},
+					),
+					types.NewBaseAccount(
+						&types.Account{
+							Address:      addr2,
+							TokenBalance: [types.SupportTokenNumber]uint64{100, 100},
+						},
+					),
+				}
+				members = []*asymmetric.PublicKey{
+					privKey1.PubKey(), privKey2.PubKey(), privKey3.PubKey(),
+				}
+			)
+			err = txs[0].Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = txs[1].Sign(privKey2)
+			So(err, ShouldBeNil)
+			for _, tx := range txs {
+				err = ms.apply(tx)
+				So(err, ShouldBeNil)
+			}
+			ms.commit()
+
+			msAddr, err := types.MultiSigAddress(2, members)
+			So(err, ShouldBeNil)
+			cm := types.NewCreateMultiSigAccount(&types.CreateMultiSigAccountHeader{
+				Threshold: 2,
+				Members:   members,
+				Nonce:     1,
+			})
+			err = cm.Sign(privKey2)
+			So(err, ShouldBeNil)
+			err = ms.apply(cm)
+			So(err, ShouldBeNil)
+			ms.commit()
+			err = cm.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = ms.apply(cm)
+			So(errors.Cause(err), ShouldEqual, ErrMultiSigAccountExists)
+			mo, loaded := ms.loadMultiSigObject(msAddr)
+			So(loaded, ShouldBeTrue)
+			So(mo.Threshold, ShouldEqual, 2)
+			So(len(mo.Members), ShouldEqual, 3)
+
+			tran := types.NewTransfer(&types.TransferHeader{
+				Sender:    addr1,
+				Receiver:  msAddr,
+				Amount:    50,
+				TokenType: types.Particle,
+				Nonce:     1,
+			})
+			err = tran.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = ms.apply(tran)
+			So(err, ShouldBeNil)
+			ms.commit()
+
+			Convey("The multisig transaction should be checked against the threshold", func() {
+				inner := types.NewTransfer(&types.TransferHeader{
+					Sender:    msAddr,
+					Receiver:  addr2,
+					Amount:    10,
+					TokenType: types.Particle,
+					Nonce:     0,
+				})
+				err = inner.Sign(privKey1)
+				So(err, ShouldBeNil)
+				// the inner transaction can not be sent alone
+				err = ms.apply(inner)
+				So(errors.Cause(err), ShouldEqual, ErrInvalidSender)
+				mt := types.NewMultiSigTransaction(msAddr, inner)
+				err = ms.apply(mt)
+				So(errors.Cause(err), ShouldEqual, ErrInsufficientSignatures)
+				err = mt.Sign(privKey1)
+				So(err, ShouldBeNil)
+				err = ms.apply(mt)
+				So(errors.Cause(err), ShouldEqual, ErrInsufficientSignatures)
+				err = mt.Sign(privKey4)
+				So(err, ShouldBeNil)
+				err = ms.apply(mt)
+				So(errors.Cause(err), ShouldEqual, ErrInvalidSender)
+				mt = types.NewMultiSigTransaction(msAddr, inner)
+				err = mt.Sign(privKey3)
+				So(err, ShouldBeNil)
+				err = mt.Verify()
+				So(err, ShouldBeNil)
+				err = ms.apply(mt)
+				So(err, ShouldBeNil)
+				ms.commit()
+				bl, loaded = ms.loadAccountTokenBalance(msAddr, types.Particle)
+				So(loaded, ShouldBeTrue)
+				So(bl, ShouldEqual, 40)
+				bl, loaded = ms.loadAccountTokenBalance(addr2, types.Particle)
+				So(loaded, ShouldBeTrue)
+				So(bl, ShouldEqual, 110)
+				nonce, err := ms.nextNonce(msAddr)
+				So(err, ShouldBeNil)
+				So(nonce, ShouldEqual, 1)
+			})
+			Convey("The multisig transaction should not be sent from unknown account", func() {
+				account, err := types.MultiSigAddress(1, members)
+				So(err, ShouldBeNil)
+				ms.loadOrStoreAccountObject(account, &types.Account{Address: account})
+				inner := types.NewTransfer(&types.TransferHeader{
+					Sender:    account,
+					Receiver:  addr2,
+					Amount:    10,
+					TokenType: types.Particle,
+				})
+				err = inner.Sign(privKey1)
+				So(err, ShouldBeNil)
+				mt := types.NewMultiSigTransaction(account, inner)
+				err = ms.apply(mt)
+				So(errors.Cause(err), ShouldEqual, ErrMultiSigAccountNotFound)
+			})
+		})
+		Convey("When escrow txs are added", func() {
+			var txs = []pi.Transaction{
+				types.NewBaseAccount(
+					&types.Account{
+						Address:      addr1,
+						TokenBalance: [types.SupportTokenNumber]uint64{100, 100},
+					},
+				),
+				types.NewBaseAccount(
+					&types.Account{
+						Address:      addr2,
+						TokenBalance: [types.SupportTokenNumber]uint64{100, 100},
+					},
+				),
+			}
+			err = txs[0].Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = txs[1].Sign(privKey2)
+			So(err, ShouldBeNil)
+			for _, tx := range txs {
+				err = ms.apply(tx)
+				So(err, ShouldBeNil)
+			}
+			ms.commit()
+
+			ms.height = 5
+			ce := types.NewCreateEscrow(&types.CreateEscrowHeader{
+				Receiver:      addr2,
+				Amount:        40,
+				TokenType:     types.Particle,
+				ReleaseHeight: 10,
+				ExpireHeight:  20,
+				Nonce:         1,
+			})
+			err = ce.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = ms.apply(ce)
+			So(err, ShouldBeNil)
+			ms.commit()
+
+			var id = ce.Hash()
+			eo, loaded := ms.loadEscrowObject(id)
+			So(loaded, ShouldBeTrue)
+			So(eo.Sender, ShouldEqual, addr1)
+			So(eo.Receiver, ShouldEqual, addr2)
+			b, loaded := ms.loadAccountTokenBalance(addr1, types.Particle)
+			So(loaded, ShouldBeTrue)
+			So(b, ShouldEqual, 60)
+			locked, incoming := ms.loadEscrowBalance(addr1)
+			So(locked[types.Particle], ShouldEqual, 40)
+			So(incoming[types.Particle], ShouldEqual, 0)
+			locked, incoming = ms.loadEscrowBalance(addr2)
+			So(locked[types.Particle], ShouldEqual, 0)
+			So(incoming[types.Particle], ShouldEqual, 40)
+
+			Convey("The escrow creation should be checked", func() {
+				var invalid = []struct {
+					header *types.CreateEscrowHeader
+					cause  error
+				}{
+					{&types.CreateEscrowHeader{
+						Receiver: addr2, Amount: 200, ReleaseHeight: 10, ExpireHeight: 20, Nonce: 2,
+					}, ErrInsufficientBalance},
+					{&types.CreateEscrowHeader{
+						Receiver: addr2, Amount: 10, ReleaseHeight: 1, ExpireHeight: 5, Nonce: 2,
+					}, types.ErrInvalidEscrow},
+					{&types.CreateEscrowHeader{
+						Receiver: addr1, Amount: 10, ReleaseHeight: 10, ExpireHeight: 20, Nonce: 2,
+					}, types.ErrInvalidEscrow},
+					{&types.CreateEscrowHeader{
+						Receiver: addr2, Amount: 10, TokenType: -1, ReleaseHeight: 10, ExpireHeight: 20, Nonce: 2,
+					}, ErrWrongTokenType},
+				}
+				for _, v := range invalid {
+					tx := types.NewCreateEscrow(v.header)
+					err = tx.Sign(privKey1)
+					So(err, ShouldBeNil)
+					err = ms.apply(tx)
+					So(errors.Cause(err), ShouldEqual, v.cause)
+				}
+			})
+			Convey("The escrow should be claimed by the receiver after release height", func() {
+				claim := types.NewClaimEscrow(&types.ClaimEscrowHeader{EscrowID: id, Nonce: 1})
+				err = claim.Sign(privKey2)
+				So(err, ShouldBeNil)
+				err = ms.apply(claim)
+				So(errors.Cause(err), ShouldEqual, ErrEscrowNotClaimable)
+
+				ms.height = 10
+				refund := types.NewRefundEscrow(&types.RefundEscrowHeader{EscrowID: id, Nonce: 2})
+				err = refund.Sign(privKey1)
+				So(err, ShouldBeNil)
+				err = ms.apply(refund)
+				So(errors.Cause(err), ShouldEqual, ErrEscrowNotRefundable)
+				other := types.NewClaimEscrow(&types.ClaimEscrowHeader{EscrowID: id, Nonce: 2})
+				err = other.Sign(privKey1)
+				So(err, ShouldBeNil)
+				err = ms.apply(other)
+				So(errors.Cause(err), ShouldEqual, ErrInvalidSender)
+
+				err = ms.apply(claim)
+				So(err, ShouldBeNil)
+				ms.commit()
+				_, loaded = ms.loadEscrowObject(id)
+				So(loaded, ShouldBeFalse)
+				b, loaded = ms.loadAccountTokenBalance(addr2, types.Particle)
+				So(loaded, ShouldBeTrue)
+				So(b, ShouldEqual, 140)
+
+				claim.Nonce = 2
+				err = claim.Sign(privKey2)
+				So(err, ShouldBeNil)
+				err = ms.apply(claim)
+				So(errors.Cause(err), ShouldEqual, ErrEscrowNotFound)
+			})
+			Convey("The escrow should be refunded to the sender after expiration", func() {
+				ms.height = 20
+				claim := types.NewClaimEscrow(&types.ClaimEscrowHeader{EscrowID: id, Nonce: 1})
+				err = claim.Sign(privKey2)
+				So(err, ShouldBeNil)
+				err = ms.apply(claim)
+				So(errors.Cause(err), ShouldEqual, ErrEscrowNotClaimable)
+
+				refund := types.NewRefundEscrow(&types.RefundEscrowHeader{EscrowID: id, Nonce: 2})
+				err = refund.Sign(privKey1)
+				So(err, ShouldBeNil)
+				err = ms.apply(refund)
+				So(err, ShouldBeNil)
+				ms.commit()
+				_, loaded = ms.loadEscrowObject(id)
+				So(loaded, ShouldBeFalse)
+				b, loaded = ms.loadAccountTokenBalance(addr1, types.Particle)
+				So(loaded, ShouldBeTrue)
+				So(b, ShouldEqual, 100)
+				locked, _ = ms.loadEscrowBalance(addr1)
+				So(locked[types.Particle], ShouldEqual, 0)
+			})
+			Convey("The escrow changes should be compiled to storage procedures", func() {
+				ms.height = 10
+				claim := types.NewClaimEscrow(&types.ClaimEscrowHeader{EscrowID: id, Nonce: 1})
+				err = claim.Sign(privKey2)
+				So(err, ShouldBeNil)
+				err = ms.apply(claim)
+				So(err, ShouldBeNil)
+				st, err := openStorage(path.Join(testingDataDir, "escrow_test.db"))
+				So(err, ShouldBeNil)
+				defer st.Close()
+				// Persist the escrow before claiming
+				err = store(st, (&metaState{dirty: ms.readonly}).compileChanges(nil), nil)
+				So(err, ShouldBeNil)
+				immutable, err := loadImmutableState(st)
+				So(err, ShouldBeNil)
+				eo, loaded = immutable.loadEscrowObject(id)
+				So(loaded, ShouldBeTrue)
+				So(eo, ShouldResemble, ce.EscrowProfile())
+
+				err = store(st, ms.compileChanges(nil), nil)
+				So(err, ShouldBeNil)
+				immutable, err = loadImmutableState(st)
+				So(err, ShouldBeNil)
+				_, loaded = immutable.loadEscrowObject(id)
+				So(loaded, ShouldBeFalse)
+			})
+		})
+		Convey("When transactions with fees are added", func() {
+			var txs = []pi.Transaction{
+				types.NewBaseAccount(
+					&types.Account{
+						Address:      addr1,
+						TokenBalance: [types.SupportTokenNumber]uint64{100, 100},
+					},
+				),
+				types.NewBaseAccount(
+					&types.Account{
+						Address:      addr2,
+						TokenBalance: [types.SupportTokenNumber]uint64{100, 100},
+					},
+				),
+			}
+			for _, tx := range txs {
+				err = ms.apply(tx)
+				So(err, ShouldBeNil)
+			}
+			ms.commit()
+
+			// Fees are credited to the block producer, whose account is created on demand
+			ms.producer = addr3
+			var tran = func(amount, fee uint64, nonce pi.AccountNonce) *types.Transfer {
+				var t = types.NewTransfer(&types.TransferHeader{
+					Sender:    addr1,
+					Receiver:  addr2,
+					Amount:    amount,
+					TokenType: types.Particle,
+					Nonce:     nonce,
+					Fee:       fee,
+				})
+				err = t.Sign(privKey1)
+				So(err, ShouldBeNil)
+				return t
+			}
+			err = ms.apply(tran(10, 5, 1))
+			So(err, ShouldBeNil)
+			ms.commit()
+			bl, loaded = ms.loadAccountTokenBalance(addr1, types.Particle)
+			So(loaded, ShouldBeTrue)
+			So(bl, ShouldEqual, 85)
+			bl, loaded = ms.loadAccountTokenBalance(addr2, types.Particle)
+			So(loaded, ShouldBeTrue)
+			So(bl, ShouldEqual, 110)
+			bl, loaded = ms.loadAccountTokenBalance(addr3, types.Particle)
+			So(loaded, ShouldBeTrue)
+			So(bl, ShouldEqual, 5)
+
+			Convey("The transaction should fail if the fee is not affordable", func() {
+				err = ms.apply(tran(10, 100, 2))
+				So(errors.Cause(err), ShouldEqual, ErrInsufficientBalance)
+			})
+			Convey("The fee should be refunded if the transaction fails", func() {
+				err = ms.apply(tran(90, 5, 2))
+				So(errors.Cause(err), ShouldEqual, ErrInsufficientBalance)
+				bl, loaded = ms.loadAccountTokenBalance(addr1, types.Particle)
+				So(loaded, ShouldBeTrue)
+				So(bl, ShouldEqual, 85)
+				bl, loaded = ms.loadAccountTokenBalance(addr3, types.Particle)
+				So(loaded, ShouldBeTrue)
+				So(bl, ShouldEqual, 5)
+				var nonce pi.AccountNonce
+				nonce, err = ms.nextNonce(addr1)
+				So(err, ShouldBeNil)
+				So(nonce, ShouldEqual, 2)
+			})
+		})
 		Convey("When SQLChain are created", func() {
 			conf.GConf, err = conf.LoadConfig("../test/node_standalone/config.yaml")
 			So(err, ShouldBeNil)
@@ -1507,7 +1879,429 @@ func TestMetaState(t *testing.T) {
 					So(sqlchain.Miners[0].PendingIncome, ShouldEqual, 115)
 					So(sqlchain.Miners[0].ReceivedIncome, ShouldEqual, 115)
 				})
+				Convey("transfer database ownership", func() {
+					nonce, err := ms.nextNonce(addr3)
+					So(err, ShouldBeNil)
+					tdo := types.NewTransferDatabaseOwnership(&types.TransferDatabaseOwnershipHeader{
+						TargetSQLChain: dbAccount,
+						NewOwner:       addr4,
+						Nonce:          nonce,
+					})
+					// addr3(admin) is not the owner
+					err = tdo.Sign(privKey3)
+					So(err, ShouldBeNil)
+					err = ms.apply(tdo)
+					So(errors.Cause(err), ShouldEqual, ErrAccountPermissionDeny)
+					nonce, err = ms.nextNonce(addr1)
+					So(err, ShouldBeNil)
+					tdo.Nonce = nonce
+					// miner can not be the new owner
+					tdo.NewOwner = addr2
+					err = tdo.Sign(privKey1)
+					So(err, ShouldBeNil)
+					err = ms.apply(tdo)
+					So(errors.Cause(err), ShouldEqual, ErrInvalidNewOwner)
+					tdo.NewOwner = addr1
+					err = tdo.Sign(privKey1)
+					So(err, ShouldBeNil)
+					err = ms.apply(tdo)
+					So(errors.Cause(err), ShouldEqual, ErrInvalidNewOwner)
+					tdo.NewOwner = addr4
+					err = tdo.Sign(privKey1)
+					So(err, ShouldBeNil)
+					err = tdo.CoSign(privKey4)
+					So(err, ShouldBeNil)
+
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					var oldPayment, oldDeposit uint64
+					for _, user := range co.Users {
+						if user.Address == addr1 || user.Address == addr4 {
+							oldPayment += user.AdvancePayment
+							oldDeposit += user.Deposit
+						}
+					}
+					userCount := len(co.Users)
+					err = ms.apply(tdo)
+					So(err, ShouldBeNil)
+					ms.commit()
+
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					So(co.Owner, ShouldEqual, addr4)
+					So(len(co.Users), ShouldEqual, userCount-1)
+					for _, user := range co.Users {
+						So(user.Address, ShouldNotEqual, addr1)
+						if user.Address == addr4 {
+							So(user.Permission, ShouldNotBeNil)
+							So(user.Permission.Role, ShouldEqual, types.Admin)
+							So(user.AdvancePayment, ShouldEqual, oldPayment)
+							So(user.Deposit, ShouldEqual, oldDeposit)
+						}
+					}
+
+					// old owner has no right to transfer again
+					nonce, err = ms.nextNonce(addr1)
+					So(err, ShouldBeNil)
+					tdo2 := types.NewTransferDatabaseOwnership(&types.TransferDatabaseOwnershipHeader{
+						TargetSQLChain: dbAccount,
+						NewOwner:       addr1,
+						Nonce:          nonce,
+					})
+					err = tdo2.Sign(privKey1)
+					So(err, ShouldBeNil)
+					err = ms.apply(tdo2)
+					So(errors.Cause(err), ShouldEqual, ErrAccountPermissionDeny)
+				})
+				Convey("slash miner", func() {
+					var (
+						genesis  = &types.Block{}
+						newBlock = func(merkleRoot hash.Hash) (h types.SignedHeader) {
+							h = types.SignedHeader{Header: types.Header{
+								GenesisHash: *genesis.BlockHash(),
+								ParentHash:  *genesis.BlockHash(),
+								MerkleRoot:  merkleRoot,
+							}}
+							err = h.Sign(privKey2)
+							So(err, ShouldBeNil)
+							return
+						}
+					)
+					err = utils.DecodeMsgPack(co.EncodedGenesis, genesis)
+					So(err, ShouldBeNil)
+					nonce, err := ms.nextNonce(addr4)
+					So(err, ShouldBeNil)
+					sl := types.NewSlash(&types.SlashHeader{
+						TargetSQLChain: dbAccount,
+						Evidence: types.ConflictingBlocks{
+							BlockA: newBlock(hash.Hash{0x1}),
+							BlockB: newBlock(hash.Hash{0x2}),
+						},
+						Nonce: nonce,
+					})
+					// evidence of unknown sqlchain
+					sl.TargetSQLChain = addr1
+					err = sl.Sign(privKey4)
+					So(err, ShouldBeNil)
+					err = ms.apply(sl)
+					So(errors.Cause(err), ShouldEqual, ErrDatabaseNotFound)
+					// evidence of blocks from another sqlchain
+					sl.TargetSQLChain = dbAccount
+					sl.Evidence.BlockA.GenesisHash = hash.Hash{0x1}
+					err = sl.Evidence.BlockA.Sign(privKey2)
+					So(err, ShouldBeNil)
+					err = sl.Sign(privKey4)
+					So(err, ShouldBeNil)
+					err = ms.apply(sl)
+					So(errors.Cause(err), ShouldEqual, types.ErrInvalidEvidence)
+					sl.Evidence.BlockA = newBlock(hash.Hash{0x1})
+					// the offender can not report itself to get back the reporter reward
+					sl.Nonce, err = ms.nextNonce(addr2)
+					So(err, ShouldBeNil)
+					err = sl.Sign(privKey2)
+					So(err, ShouldBeNil)
+					err = ms.apply(sl)
+					So(errors.Cause(err), ShouldEqual, ErrSelfSlashReport)
+					sl.Nonce = nonce
+
+					var deposit uint64
+					for _, v := range co.Miners {
+						if v.Address == addr2 {
+							deposit = v.Deposit
+						}
+					}
+					So(deposit, ShouldEqual, conf.GConf.MinProviderDeposit)
+					b1, loaded := ms.loadAccountTokenBalance(addr4, types.Particle)
+					So(loaded, ShouldBeTrue)
+					err = sl.Sign(privKey4)
+					So(err, ShouldBeNil)
+					err = ms.apply(sl)
+					So(err, ShouldBeNil)
+					ms.commit()
+					b2, loaded := ms.loadAccountTokenBalance(addr4, types.Particle)
+					So(loaded, ShouldBeTrue)
+					So(b2-b1, ShouldEqual, deposit*conf.SlashReporterRewardPercent/100)
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					for _, v := range co.Miners {
+						if v.Address == addr2 {
+							So(v.Deposit, ShouldEqual, 0)
+							So(v.Status, ShouldEqual, types.Slashed)
+						}
+					}
+					So(ms.isSlashedMiner(addr2), ShouldBeTrue)
+
+					// the miner can not be slashed again
+					sl.Nonce++
+					err = sl.Sign(privKey4)
+					So(err, ShouldBeNil)
+					err = ms.apply(sl)
+					So(errors.Cause(err), ShouldEqual, ErrMinerSlashed)
+					// the slashed miner can not provide service any more
+					nonce, err = ms.nextNonce(addr2)
+					So(err, ShouldBeNil)
+					ps.Nonce = nonce
+					err = ps.Sign(privKey2)
+					So(err, ShouldBeNil)
+					err = ms.apply(&ps)
+					So(errors.Cause(err), ShouldEqual, ErrMinerSlashed)
+				})
+				Convey("replace miner", func() {
+					var newReplaceMiner = func(
+						sender proto.AccountAddress, privKey *asymmetric.PrivateKey,
+						oldMiner, newMiner proto.AccountAddress,
+					) (tx *types.ReplaceMiner) {
+						nonce, err := ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						tx = types.NewReplaceMiner(&types.ReplaceMinerHeader{
+							TargetSQLChain: dbAccount,
+							OldMiner:       oldMiner,
+							NewMiner:       newMiner,
+							Nonce:          nonce,
+						})
+						err = tx.Sign(privKey)
+						So(err, ShouldBeNil)
+						return
+					}
+					var provide = func(addr proto.AccountAddress, privKey *asymmetric.PrivateKey) {
+						nonce, err := ms.nextNonce(addr)
+						So(err, ShouldBeNil)
+						ps.Nonce = nonce
+						ps.TargetUser = nil
+						err = ps.Sign(privKey)
+						So(err, ShouldBeNil)
+						err = ms.apply(&ps)
+						So(err, ShouldBeNil)
+						ms.commit()
+					}
+
+					// only the owner and the other miners are allowed
+					err = ms.apply(newReplaceMiner(addr3, privKey3, addr2, proto.AccountAddress{}))
+					So(errors.Cause(err), ShouldEqual, ErrInvalidSender)
+					err = ms.apply(newReplaceMiner(addr2, privKey2, addr2, proto.AccountAddress{}))
+					So(errors.Cause(err), ShouldEqual, ErrInvalidSender)
+					// no provider to match
+					err = ms.apply(newReplaceMiner(addr1, privKey1, addr2, proto.AccountAddress{}))
+					So(errors.Cause(err), ShouldEqual, ErrNoEnoughMiner)
+
+					provide(addr4, privKey4)
+					err = ms.apply(newReplaceMiner(addr1, privKey1, addr4, proto.AccountAddress{}))
+					So(errors.Cause(err), ShouldEqual, ErrNoSuchMiner)
+					err = ms.apply(newReplaceMiner(addr1, privKey1, addr2, addr2))
+					So(errors.Cause(err), ShouldEqual, ErrMinerAlreadyServing)
+
+					b1, loaded := ms.loadAccountTokenBalance(addr2, types.Particle)
+					So(loaded, ShouldBeTrue)
+					ms.height = 10
+					err = ms.apply(newReplaceMiner(addr1, privKey1, addr2, proto.AccountAddress{}))
+					So(err, ShouldBeNil)
+					ms.commit()
+					b2, loaded := ms.loadAccountTokenBalance(addr2, types.Particle)
+					So(loaded, ShouldBeTrue)
+					So(b2-b1, ShouldEqual, conf.GConf.MinProviderDeposit)
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					So(len(co.Miners), ShouldEqual, 1)
+					So(co.Miners[0].Address, ShouldEqual, addr4)
+					So(co.Miners[0].LastActiveHeight, ShouldEqual, 10)
+					_, loaded = ms.loadProviderObject(addr4)
+					So(loaded, ShouldBeFalse)
+
+					// an inactive miner can be replaced by the other miners
+					co.Miners = append(co.Miners, &types.MinerInfo{Address: addr3, LastActiveHeight: 10})
+					ms.dirty.databases[dbID] = co
+					ms.commit()
+					provide(addr2, privKey2)
+					err = ms.apply(newReplaceMiner(addr4, privKey4, addr3, addr2))
+					So(errors.Cause(err), ShouldEqual, ErrMinerStillActive)
+					ms.height = 10 + conf.MinerInactiveBlocks
+					err = ms.apply(newReplaceMiner(addr4, privKey4, addr3, addr2))
+					So(err, ShouldBeNil)
+					ms.commit()
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					So(len(co.Miners), ShouldEqual, 2)
+					So(co.Miners[0].Address, ShouldEqual, addr4)
+					So(co.Miners[1].Address, ShouldEqual, addr2)
+				})
+				Convey("update database resources", func() {
+					var newUpdateResources = func(
+						sender proto.AccountAddress, privKey *asymmetric.PrivateKey, node uint16,
+					) (tx *types.UpdateDatabaseResources) {
+						nonce, err := ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						tx = types.NewUpdateDatabaseResources(&types.UpdateDatabaseResourcesHeader{
+							TargetSQLChain: dbAccount,
+							Node:           node,
+							Nonce:          nonce,
+						})
+						err = tx.Sign(privKey)
+						So(err, ShouldBeNil)
+						return
+					}
+
+					err = ms.apply(newUpdateResources(addr3, privKey3, 2))
+					So(errors.Cause(err), ShouldEqual, ErrAccountPermissionDeny)
+					err = ms.apply(newUpdateResources(addr1, privKey1, 0))
+					So(errors.Cause(err), ShouldEqual, ErrInvalidMinerCount)
+					// the balance of owner should cover the deposit increment
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					b0, loaded := ms.loadAccountTokenBalance(addr1, types.Particle)
+					So(loaded, ShouldBeTrue)
+					err = ms.decreaseAccountToken(addr1, b0, types.Particle)
+					So(err, ShouldBeNil)
+					ms.commit()
+					err = ms.apply(newUpdateResources(addr1, privKey1, 3))
+					So(errors.Cause(err), ShouldEqual, ErrInsufficientBalance)
+					err = ms.increaseAccountToken(addr1, b0, types.Particle)
+					So(err, ShouldBeNil)
+					ms.commit()
+					err = ms.apply(newUpdateResources(addr1, privKey1, 2))
+					So(errors.Cause(err), ShouldEqual, ErrNoEnoughMiner)
+
+					// scale up
+					nonce, err := ms.nextNonce(addr4)
+					So(err, ShouldBeNil)
+					ps.Nonce = nonce
+					ps.TargetUser = nil
+					err = ps.Sign(privKey4)
+					So(err, ShouldBeNil)
+					err = ms.apply(&ps)
+					So(err, ShouldBeNil)
+					ms.commit()
+					b1, loaded := ms.loadAccountTokenBalance(addr1, types.Particle)
+					So(loaded, ShouldBeTrue)
+					err = ms.apply(newUpdateResources(addr1, privKey1, 2))
+					So(err, ShouldBeNil)
+					ms.commit()
+					b2, loaded := ms.loadAccountTokenBalance(addr1, types.Particle)
+					So(loaded, ShouldBeTrue)
+					So(b1-b2, ShouldEqual, minDeposit(co.GasPrice, 1))
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					So(co.Meta.Node, ShouldEqual, 2)
+					So(len(co.Miners), ShouldEqual, 2)
+					So(co.Miners[0].Address, ShouldEqual, addr2)
+					So(co.Miners[1].Address, ShouldEqual, addr4)
+					_, loaded = ms.loadProviderObject(addr4)
+					So(loaded, ShouldBeFalse)
+
+					// scale down
+					b1, loaded = ms.loadAccountTokenBalance(addr4, types.Particle)
+					So(loaded, ShouldBeTrue)
+					err = ms.apply(newUpdateResources(addr1, privKey1, 1))
+					So(err, ShouldBeNil)
+					ms.commit()
+					b2, loaded = ms.loadAccountTokenBalance(addr4, types.Particle)
+					So(loaded, ShouldBeTrue)
+					So(b2-b1, ShouldEqual, conf.GConf.MinProviderDeposit)
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					So(co.Meta.Node, ShouldEqual, 1)
+					So(len(co.Miners), ShouldEqual, 1)
+					So(co.Miners[0].Address, ShouldEqual, addr2)
+					for _, v := range co.Users {
+						if v.Address == addr1 {
+							So(v.Deposit, ShouldEqual, minDeposit(co.GasPrice, 1))
+						}
+					}
+				})
+				Convey("update row policies", func() {
+					var newUpdateRowPolicies = func(
+						sender proto.AccountAddress, privKey *asymmetric.PrivateKey,
+						policies []*types.RowPolicy,
+					) (tx *types.UpdateRowPolicies) {
+						nonce, err := ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						tx = types.NewUpdateRowPolicies(&types.UpdateRowPoliciesHeader{
+							TargetSQLChain: dbAccount,
+							Policies:       policies,
+							Nonce:          nonce,
+						})
+						err = tx.Sign(privKey)
+						So(err, ShouldBeNil)
+						return
+					}
+
+					var policies = []*types.RowPolicy{{Table: "notes", Column: "owner"}}
+					err = ms.apply(newUpdateRowPolicies(addr1, privKey1, policies))
+					So(errors.Cause(err), ShouldEqual, ErrAccountPermissionDeny)
+					err = ms.apply(newUpdateRowPolicies(addr3, privKey3, []*types.RowPolicy{
+						{Table: "notes"},
+					}))
+					So(errors.Cause(err), ShouldEqual, ErrInvalidRowPolicy)
+					err = ms.apply(newUpdateRowPolicies(addr3, privKey3, []*types.RowPolicy{
+						{Table: "notes", Column: "owner"}, {Table: "Notes", Column: "user"},
+					}))
+					So(errors.Cause(err), ShouldEqual, ErrInvalidRowPolicy)
+					err = ms.apply(newUpdateRowPolicies(addr3, privKey3, policies))
+					So(err, ShouldBeNil)
+					ms.commit()
+					co, loaded = ms.loadSQLChainObject(dbID)
+					So(loaded, ShouldBeTrue)
+					So(co.RowPolicies, ShouldResemble, policies)
+				})
+				Convey("expire permission", func() {
+					var newUpdatePermission = func(
+						sender proto.AccountAddress, privKey *asymmetric.PrivateKey,
+						target proto.AccountAddress, perm *types.UserPermission,
+					) (tx *types.UpdatePermission) {
+						nonce, err := ms.nextNonce(sender)
+						So(err, ShouldBeNil)
+						tx = types.NewUpdatePermission(&types.UpdatePermissionHeader{
+							TargetSQLChain: dbAccount,
+							TargetUser:     target,
+							Permission:     perm,
+							Nonce:          nonce,
+						})
+						err = tx.Sign(privKey)
+						So(err, ShouldBeNil)
+						return
+					}
+
+					var perm = types.UserPermissionFromRole(types.Admin)
+					perm.ExpireHeight = ms.height + 10
+					// the only admin can not grant itself an expiring permission
+					err = ms.apply(newUpdatePermission(addr3, privKey3, addr3, perm))
+					So(errors.Cause(err), ShouldEqual, ErrNoSuperUserLeft)
+					err = ms.apply(newUpdatePermission(addr3, privKey3, addr4, perm))
+					So(err, ShouldBeNil)
+					ms.commit()
+					err = ms.apply(newUpdatePermission(
+						addr4, privKey4, addr1, types.UserPermissionFromRole(types.Write)))
+					So(err, ShouldBeNil)
+					ms.commit()
+
+					ms.height = perm.ExpireHeight
+					err = ms.apply(newUpdatePermission(
+						addr4, privKey4, addr1, types.UserPermissionFromRole(types.Admin)))
+					So(errors.Cause(err), ShouldEqual, ErrAccountPermissionDeny)
+					err = ms.apply(newUpdatePermission(
+						addr3, privKey3, addr3, types.UserPermissionFromRole(types.Read)))
+					So(errors.Cause(err), ShouldEqual, ErrNoSuperUserLeft)
+				})
 			})
 		})
 	})
 }
+
+func TestRemoveMiners(t *testing.T) {
+	Convey("Given a miner list of sqlchain", t, func() {
+		var miners = MinerInfos{
+			{Address: proto.AccountAddress{0x1}, Deposit: 1, LastActiveHeight: 1},
+			{Address: proto.AccountAddress{0x2}, Deposit: 1, LastActiveHeight: 5},
+			{Address: proto.AccountAddress{0x3}, Deposit: 0, LastActiveHeight: 9},
+			{Address: proto.AccountAddress{0x4}, Deposit: 1, LastActiveHeight: 5},
+			{Address: proto.AccountAddress{0x5}, Deposit: 1, LastActiveHeight: 3},
+		}
+		Convey("The leader should be kept and the slashed or inactive miners removed first", func() {
+			kept, removed := removeMiners(miners, 3)
+			So(kept, ShouldResemble, MinerInfos{miners[0], miners[1]})
+			So(removed, ShouldResemble, MinerInfos{miners[2], miners[3], miners[4]})
+			kept, removed = removeMiners(miners, 10)
+			So(kept, ShouldResemble, MinerInfos{miners[0]})
+			So(len(removed), ShouldEqual, 4)
+		})
+	})
+}
@@ -90,6 +90,15 @@ func (s *ChainRPCService) NextAccountNonce(
 
 // AddTx is the RPC method to add a transaction.
 func (s *ChainRPCService) AddTx(req *types.AddTxReq, _ *types.AddTxResp) (err error) {
+	// Reject the transaction with insufficient fee early, so that the sender gets notified
+	if req != nil && req.Tx != nil {
+		if err = s.chain.checkTxFee(req.Tx); err != nil {
+			return
+		}
+		if err = s.chain.checkTxReplacement(req.Tx); err != nil {
+			return
+		}
+	}
 	s.chain.addTx(req)
 	return
 }
@@ -127,3 +136,75 @@ func (s *ChainRPCService) QueryTxState(
 	resp.State = state
 	return
 }
+
+// QueryEscrow is the RPC method to query an escrow by its ID.
+func (s *ChainRPCService) QueryEscrow(
+	req *types.QueryEscrowReq, resp *types.QueryEscrowResp) (err error,
+) {
+	p, ok := s.chain.loadEscrow(req.ID)
+	if ok {
+		resp.Escrow = *p
+		return
+	}
+	err = errors.Wrap(ErrEscrowNotFound, "rpc query escrow failed")
+	return
+}
+
+// QueryAccountEscrowBalance is the RPC method to query the escrow balances of an account.
+func (s *ChainRPCService) QueryAccountEscrowBalance(
+	req *types.QueryAccountEscrowBalanceReq, resp *types.QueryAccountEscrowBalanceResp) (err error,
+) {
+	if !req.TokenType.Listed() {
+		err = errors.Wrapf(ErrWrongTokenType, "unknown token type %d", req.TokenType)
+		return
+	}
+	resp.Addr = req.Addr
+	resp.Locked, resp.Incoming = s.chain.loadAccountEscrowBalance(req.Addr, req.TokenType)
+	return
+}
+
+// FetchStateSnapshot is the RPC method to fetch a chunk of the state snapshot at the given
+// height, or the latest one if height is 0.
+func (s *ChainRPCService) FetchStateSnapshot(
+	req *types.FetchStateSnapshotReq, resp *types.FetchStateSnapshotResp) (err error,
+) {
+	var header *types.SignedStateSnapshotHeader
+	if header, resp.Total, resp.Chunk, err = s.chain.fetchStateSnapshotChunk(
+		req.Height, req.Index,
+	); err != nil {
+		return
+	}
+	resp.Header = *header
+	resp.Index = req.Index
+	return
+}
+
+// QueryAccountWithProof is the RPC method to query an account with the inclusion proof against
+// the state root of the last irreversible block.
+func (s *ChainRPCService) QueryAccountWithProof(
+	req *types.QueryAccountWithProofReq, resp *types.QueryAccountWithProofResp) (err error,
+) {
+	var header *types.BPSignedHeader
+	if header, resp.Height, resp.Count, resp.Account, resp.Proof, err = s.chain.queryAccountWithProof(
+		req.Addr,
+	); err != nil {
+		return
+	}
+	resp.Header = *header
+	return
+}
+
+// QuerySQLChainProfileWithProof is the RPC method to query SQLChainProfile with the inclusion
+// proof against the state root of the last irreversible block.
+func (s *ChainRPCService) QuerySQLChainProfileWithProof(
+	req *types.QuerySQLChainProfileWithProofReq, resp *types.QuerySQLChainProfileWithProofResp,
+) (err error) {
+	var header *types.BPSignedHeader
+	if header, resp.Height, resp.Count, resp.Profile, resp.Proof, err = s.chain.querySQLChainProfileWithProof(
+		req.DBID,
+	); err != nil {
+		return
+	}
+	resp.Header = *header
+	return
+}
@@ -0,0 +1,287 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package blockproducer
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	"github.com/CovenantSQL/CovenantSQL/rpc"
+	"github.com/CovenantSQL/CovenantSQL/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	xi "github.com/CovenantSQL/CovenantSQL/xenomint/interfaces"
+)
+
+// This file provides the state snapshot producing and the fast sync from snapshots.
+
+const (
+	// maxSnapshotsKept is the number of the latest state snapshots kept in storage.
+	maxSnapshotsKept = 3
+)
+
+var (
+	// snapshotChunkSize is the max size of an encoded state snapshot chunk in an RPC response.
+	snapshotChunkSize uint32 = 1 << 20
+)
+
+// rpcCallFunc defines the signature of rpc.Caller.CallNodeWithContext.
+type rpcCallFunc func(
+	ctx context.Context, node proto.NodeID, method string, args interface{}, reply interface{},
+) error
+
+// makeStateSnapshot collects the committed state objects into an unsigned snapshot, the objects
+// are sorted by their keys to produce a stable encoding.
+func (s *metaState) makeStateSnapshot() (snap *types.StateSnapshot) {
+	snap = &types.StateSnapshot{
+		Accounts:  make([]*types.Account, 0, len(s.readonly.accounts)),
+		Databases: make([]*types.SQLChainProfile, 0, len(s.readonly.databases)),
+		Providers: make([]*types.ProviderProfile, 0, len(s.readonly.provider)),
+		MultiSigs: make([]*types.MultiSigProfile, 0, len(s.readonly.multisigs)),
+		Escrows:   make([]*types.EscrowProfile, 0, len(s.readonly.escrows)),
+	}
+	for _, v := range s.readonly.accounts {
+		snap.Accounts = append(snap.Accounts, v)
+	}
+	for _, v := range s.readonly.databases {
+		snap.Databases = append(snap.Databases, v)
+	}
+	for _, v := range s.readonly.provider {
+		snap.Providers = append(snap.Providers, v)
+	}
+	for _, v := range s.readonly.multisigs {
+		snap.MultiSigs = append(snap.MultiSigs, v)
+	}
+	for _, v := range s.readonly.escrows {
+		snap.Escrows = append(snap.Escrows, v)
+	}
+	sort.Slice(snap.Accounts, func(i, j int) bool {
+		return bytes.Compare(snap.Accounts[i].Address[:], snap.Accounts[j].Address[:]) < 0
+	})
+	sort.Slice(snap.Databases, func(i, j int) bool {
+		return snap.Databases[i].ID < snap.Databases[j].ID
+	})
+	sort.Slice(snap.Providers, func(i, j int) bool {
+		return bytes.Compare(snap.Providers[i].Provider[:], snap.Providers[j].Provider[:]) < 0
+	})
+	sort.Slice(snap.MultiSigs, func(i, j int) bool {
+		return bytes.Compare(snap.MultiSigs[i].Address[:], snap.MultiSigs[j].Address[:]) < 0
+	})
+	sort.Slice(snap.Escrows, func(i, j int) bool {
+		return bytes.Compare(snap.Escrows[i].ID[:], snap.Escrows[j].ID[:]) < 0
+	})
+	return
+}
+
+// needSnapshot returns whether a snapshot interval boundary is crossed while the last
+// irreversible block moves from prev to irre.
+func (c *Chain) needSnapshot(prev, irre *blockNode) bool {
+	if c.mode != BPMode || c.snapshotInterval == 0 {
+		return false
+	}
+	return irre.height/c.snapshotInterval > prev.height/c.snapshotInterval
+}
+
+// takeSnapshot takes a signed state snapshot at the irreversible block irre and stores it. The
+// caller should hold the chain lock and make sure that the immutable state is committed to irre.
+func (c *Chain) takeSnapshot(irre *blockNode) (err error) {
+	var (
+		b    *types.BPBlock
+		priv *asymmetric.PrivateKey
+		snap = c.immutable.makeStateSnapshot()
+	)
+	if b = irre.load(); b == nil {
+		if b, err = c.loadBlock(irre.hash); err != nil {
+			return
+		}
+	}
+	// A snapshot of a legacy block can't be checked against its state root by the peers
+	if b.SignedHeader.HashVersion == 0 {
+		return
+	}
+	if priv, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	snap.Header.StateSnapshotHeader = types.StateSnapshotHeader{
+		GenesisHash: c.genesisHash,
+		BlockHash:   irre.hash,
+		Height:      irre.height,
+		Count:       irre.count,
+		Timestamp:   b.Timestamp(),
+	}
+	if err = snap.Sign(priv); err != nil {
+		return
+	}
+	return store(c.storage, []storageProcedure{addSnapshot(snap)}, nil)
+}
+
+// fetchStateSnapshotFromPeer fetches the latest state snapshot and its base block from the
+// remote peer, both are verified against the peer public key and the genesis block hash.
+func fetchStateSnapshotFromPeer(
+	ctx context.Context, call rpcCallFunc, peer proto.NodeID, genesisHash hash.Hash,
+) (
+	snap *types.StateSnapshot, base *types.BPBlock, err error,
+) {
+	var (
+		pub    *asymmetric.PublicKey
+		header types.SignedStateSnapshotHeader
+		buffer bytes.Buffer
+		dec    = &types.StateSnapshot{}
+		resp   = &types.FetchStateSnapshotResp{}
+	)
+	if pub, err = kms.GetPublicKey(peer); err != nil {
+		return
+	}
+	// Fetch the first chunk of the latest snapshot, and then the rest chunks at the same height
+	if err = call(ctx, peer, route.MCCFetchStateSnapshot.String(),
+		&types.FetchStateSnapshotReq{}, resp,
+	); err != nil {
+		return
+	}
+	header = resp.Header
+	if err = header.Verify(); err != nil {
+		return
+	}
+	if header.Signee == nil || !header.Signee.IsEqual(pub) {
+		err = errors.Wrap(ErrInvalidSnapshot, "snapshot is not signed by the peer")
+		return
+	}
+	if !header.GenesisHash.IsEqual(&genesisHash) {
+		err = errors.Wrap(ErrGenesisHashNotMatch, "snapshot genesis hash not match")
+		return
+	}
+	buffer.Write(resp.Chunk)
+	for i := uint32(1); i < resp.Total; i++ {
+		var next = &types.FetchStateSnapshotResp{}
+		if err = call(ctx, peer, route.MCCFetchStateSnapshot.String(),
+			&types.FetchStateSnapshotReq{Height: header.Height, Index: i}, next,
+		); err != nil {
+			return
+		}
+		if next.Index != i || next.Header.Hash() != header.Hash() {
+			err = errors.Wrapf(ErrInvalidSnapshotChunk, "unexpected chunk #%d", i)
+			return
+		}
+		buffer.Write(next.Chunk)
+	}
+	if err = utils.DecodeMsgPack(buffer.Bytes(), dec); err != nil {
+		return
+	}
+	if dec.Header.Hash() != header.Hash() {
+		err = errors.Wrap(ErrInvalidSnapshot, "snapshot header not match")
+		return
+	}
+	if err = dec.Verify(); err != nil {
+		return
+	}
+	// Fetch the base block of the snapshot
+	var blockResp = &types.FetchBlockResp{}
+	if err = call(ctx, peer, route.MCCFetchBlock.String(),
+		&types.FetchBlockReq{Height: header.Height}, blockResp,
+	); err != nil {
+		return
+	}
+	if blockResp.Block == nil || blockResp.Count != header.Count ||
+		!blockResp.Block.BlockHash().IsEqual(&header.BlockHash) ||
+		!blockResp.Block.SignedHeader.StateRoot.IsEqual(&header.StateRoot) {
+		err = errors.Wrapf(ErrInvalidSnapshot, "base block %s not match", header.BlockHash.Short(4))
+		return
+	}
+	if err = blockResp.Block.Verify(); err != nil {
+		return
+	}
+	snap = dec
+	base = blockResp.Block
+	return
+}
+
+// fetchStateSnapshot tries to fetch a verified state snapshot from the peers in order.
+func fetchStateSnapshot(
+	ctx context.Context, call rpcCallFunc, peers []proto.NodeID, genesisHash hash.Hash,
+) (
+	snap *types.StateSnapshot, base *types.BPBlock, err error,
+) {
+	err = errors.Wrap(ErrSnapshotNotFound, "no available peer")
+	for _, v := range peers {
+		if snap, base, err = fetchStateSnapshotFromPeer(ctx, call, v, genesisHash); err == nil {
+			return
+		}
+		log.WithField("peer", v).WithError(err).Warn("failed to fetch state snapshot")
+	}
+	return
+}
+
+// initStorageFromSnapshot initializes an empty chain storage with the snapshot, its base block
+// is stored as the first block and also the last irreversible block.
+func initStorageFromSnapshot(st xi.Storage, snap *types.StateSnapshot, base *types.BPBlock) error {
+	var sps []storageProcedure
+	for _, v := range snap.Accounts {
+		sps = append(sps, updateAccount(v))
+	}
+	for _, v := range snap.Databases {
+		sps = append(sps, updateShardChain(v))
+	}
+	for _, v := range snap.Providers {
+		sps = append(sps, updateProvider(v))
+	}
+	for _, v := range snap.MultiSigs {
+		sps = append(sps, updateMultiSig(v))
+	}
+	for _, v := range snap.Escrows {
+		sps = append(sps, updateEscrow(v))
+	}
+	sps = append(sps, addBlock(snap.Header.Height, base))
+	sps = append(sps, buildBlockIndex(snap.Header.Height, base))
+	sps = append(sps, addSnapshot(snap))
+	sps = append(sps, updateIrreversible(snap.Header.BlockHash))
+	return store(st, sps, nil)
+}
+
+// fastSync initializes the chain storage from the latest state snapshot of the remote peers.
+func fastSync(ctx context.Context, st xi.Storage, cfg *Config) (err error) {
+	var (
+		caller = rpc.NewCaller()
+		peers  []proto.NodeID
+		snap   *types.StateSnapshot
+		base   *types.BPBlock
+	)
+	for _, v := range cfg.Peers.Servers {
+		if !v.IsEqual(&cfg.NodeID) {
+			peers = append(peers, v)
+		}
+	}
+	if snap, base, err = fetchStateSnapshot(
+		ctx, caller.CallNodeWithContext, peers, *cfg.Genesis.BlockHash(),
+	); err != nil {
+		return
+	}
+	log.WithFields(log.Fields{
+		"height": snap.Header.Height,
+		"count":  snap.Header.Count,
+		"block":  snap.Header.BlockHash.Short(4),
+		"root":   snap.Header.StateRoot.Short(4),
+	}).Info("initializing chain from state snapshot")
+	return initStorageFromSnapshot(st, snap, base)
+}
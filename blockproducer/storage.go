@@ -57,6 +57,14 @@ var (
 	UNIQUE ("id")
 );`,
 
+		`CREATE TABLE IF NOT EXISTS "snapshots" (
+	"height"	INT,
+	"hash"		TEXT,
+	"header"	BLOB,
+	"encoded"	BLOB,
+	UNIQUE ("height")
+);`,
+
 		// Meta state tables
 		`CREATE TABLE IF NOT EXISTS "accounts" (
 	"address"	TEXT,
@@ -77,6 +85,27 @@ var (
 	UNIQUE ("address")
 );`,
 
+		`CREATE TABLE IF NOT EXISTS "multisig" (
+	"address"	TEXT,
+	"encoded"	BLOB,
+	UNIQUE ("address")
+);`,
+
+		`CREATE TABLE IF NOT EXISTS "escrow" (
+	"id"				TEXT,
+	"sender"			TEXT,
+	"receiver"			TEXT,
+	"token_type"		INTEGER,
+	"amount"			INTEGER,
+	"release_height"	INTEGER,
+	"expire_height"		INTEGER,
+	"encoded"			BLOB,
+	UNIQUE ("id")
+);`,
+
+		`CREATE INDEX IF NOT EXISTS "idx__escrow__sender" ON "escrow" ("sender");`,
+		`CREATE INDEX IF NOT EXISTS "idx__escrow__receiver" ON "escrow" ("receiver");`,
+
 		`CREATE TABLE IF NOT EXISTS "indexed_blocks" (
 	"height"		INTEGER PRIMARY KEY,
 	"hash"			TEXT,
@@ -249,6 +278,43 @@ func updateIrreversible(h hash.Hash) storageProcedure {
 	}
 }
 
+func addSnapshot(snap *types.StateSnapshot) storageProcedure {
+	var (
+		header, enc *bytes.Buffer
+		err         error
+	)
+	if header, err = utils.EncodeMsgPack(&snap.Header); err != nil {
+		return errPass(err)
+	}
+	if enc, err = utils.EncodeMsgPack(snap); err != nil {
+		return errPass(err)
+	}
+	return func(tx *sql.Tx) (err error) {
+		log.WithFields(log.Fields{
+			"snapshot_height": snap.Header.Height,
+			"snapshot_block":  snap.Header.BlockHash.Short(4),
+			"snapshot_root":   snap.Header.StateRoot.Short(4),
+			"snapshot_size":   enc.Len(),
+		}).Debug("adding snapshot")
+		if _, err = tx.Exec(`INSERT OR REPLACE INTO "snapshots" ("height", "hash", "header", "encoded")
+	VALUES (?, ?, ?, ?)`,
+			snap.Header.Height,
+			snap.Header.BlockHash.String(),
+			header.Bytes(),
+			enc.Bytes(),
+		); err != nil {
+			return
+		}
+		// Prune old snapshots, but always keep the one which the chain is initialized from
+		_, err = tx.Exec(`DELETE FROM "snapshots" WHERE "height" NOT IN (
+	SELECT "height" FROM "snapshots" ORDER BY "height" DESC LIMIT ?
+) AND "hash" NOT IN (
+	SELECT "hash" FROM "blocks" ORDER BY "rowid" LIMIT 1
+)`, maxSnapshotsKept)
+		return
+	}
+}
+
 func deleteTxs(txs []pi.Transaction) storageProcedure {
 	var hs = make([]hash.Hash, len(txs))
 	for i, v := range txs {
@@ -367,6 +433,76 @@ func deleteProvider(address proto.AccountAddress) storageProcedure {
 	}
 }
 
+func updateMultiSig(profile *types.MultiSigProfile) storageProcedure {
+	var (
+		enc *bytes.Buffer
+		err error
+	)
+	if enc, err = utils.EncodeMsgPack(profile); err != nil {
+		return errPass(err)
+	}
+	return func(tx *sql.Tx) (err error) {
+		log.WithFields(log.Fields{
+			"multisig_address":   profile.Address.String(),
+			"multisig_threshold": profile.Threshold,
+			"multisig_members":   len(profile.Members),
+		}).Debug("updating multisig")
+		_, err = tx.Exec(`INSERT OR REPLACE INTO "multisig" ("address", "encoded") VALUES (?, ?)`,
+			profile.Address.String(),
+			enc.Bytes())
+		return
+	}
+}
+
+func deleteMultiSig(address proto.AccountAddress) storageProcedure {
+	return func(tx *sql.Tx) (err error) {
+		log.WithFields(log.Fields{
+			"multisig_address": address.String(),
+		}).Debug("deleting multisig")
+		_, err = tx.Exec(`DELETE FROM "multisig" WHERE "address"=?`, address.String())
+		return
+	}
+}
+
+func updateEscrow(profile *types.EscrowProfile) storageProcedure {
+	var (
+		enc *bytes.Buffer
+		err error
+	)
+	if enc, err = utils.EncodeMsgPack(profile); err != nil {
+		return errPass(err)
+	}
+	return func(tx *sql.Tx) (err error) {
+		log.WithFields(log.Fields{
+			"escrow_id":       profile.ID.String(),
+			"escrow_sender":   profile.Sender.String(),
+			"escrow_receiver": profile.Receiver.String(),
+			"escrow_amount":   profile.Amount,
+		}).Debug("updating escrow")
+		_, err = tx.Exec(`INSERT OR REPLACE INTO "escrow" ("id", "sender", "receiver", "token_type",
+	"amount", "release_height", "expire_height", "encoded") VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			profile.ID.String(),
+			profile.Sender.String(),
+			profile.Receiver.String(),
+			int32(profile.TokenType),
+			profile.Amount,
+			profile.ReleaseHeight,
+			profile.ExpireHeight,
+			enc.Bytes())
+		return
+	}
+}
+
+func deleteEscrow(id hash.Hash) storageProcedure {
+	return func(tx *sql.Tx) (err error) {
+		log.WithFields(log.Fields{
+			"escrow_id": id.String(),
+		}).Debug("deleting escrow")
+		_, err = tx.Exec(`DELETE FROM "escrow" WHERE "id"=?`, id.String())
+		return
+	}
+}
+
 func loadIrreHash(st xi.Storage) (irre hash.Hash, err error) {
 	var hex string
 	// Load last irreversible block hash
@@ -381,6 +517,63 @@ func loadIrreHash(st xi.Storage) (irre hash.Hash, err error) {
 	return
 }
 
+func loadSnapshotHeader(
+	st xi.Storage, blockHash hash.Hash) (header *types.SignedStateSnapshotHeader, err error,
+) {
+	var enc []byte
+	if err = st.Reader().QueryRow(
+		`SELECT "header" FROM "snapshots" WHERE "hash"=?`, blockHash.String(),
+	).Scan(&enc); err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.Wrapf(ErrSnapshotNotFound, "snapshot of block %s", blockHash.Short(4))
+		}
+		return
+	}
+	var dec = &types.SignedStateSnapshotHeader{}
+	if err = utils.DecodeMsgPack(enc, dec); err != nil {
+		return
+	}
+	header = dec
+	return
+}
+
+// loadSnapshotChunk loads the index-th chunk of the encoded snapshot at the given height, or the
+// latest snapshot if height is 0.
+func loadSnapshotChunk(st xi.Storage, height, index, chunkSize uint32) (
+	header *types.SignedStateSnapshotHeader, total uint32, chunk []byte, err error,
+) {
+	var (
+		size uint32
+		enc  []byte
+		cond = `"height"=?`
+		args = []interface{}{uint64(index)*uint64(chunkSize) + 1, chunkSize}
+	)
+	if height == 0 {
+		cond = `"height"=(SELECT MAX("height") FROM "snapshots")`
+	} else {
+		args = append(args, height)
+	}
+	if err = st.Reader().QueryRow(
+		`SELECT "header", length("encoded"), substr("encoded", ?, ?) FROM "snapshots" WHERE `+cond,
+		args...,
+	).Scan(&enc, &size, &chunk); err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.Wrapf(ErrSnapshotNotFound, "snapshot at height %d", height)
+		}
+		return
+	}
+	if total = (size + chunkSize - 1) / chunkSize; index >= total {
+		err = errors.Wrapf(ErrInvalidSnapshotChunk, "chunk index %d out of range %d", index, total)
+		return
+	}
+	var dec = &types.SignedStateSnapshotHeader{}
+	if err = utils.DecodeMsgPack(enc, dec); err != nil {
+		return
+	}
+	header = dec
+	return
+}
+
 func loadTxPool(st xi.Storage) (txPool map[hash.Hash]pi.Transaction, err error) {
 	var (
 		th   hash.Hash
@@ -482,6 +675,23 @@ func loadBlocks(
 			}).Debug("set genesis block")
 			continue
 		}
+		// Add base block if the chain is initialized from a state snapshot
+		if len(index) == 0 {
+			var header *types.SignedStateSnapshotHeader
+			if header, err = loadSnapshotHeader(st, bh); err != nil {
+				return
+			}
+			bn = newBaseBlockNode(height, header.Count, dec)
+			index[bh] = bn
+			headsIndex[bh] = bn
+			log.WithFields(log.Fields{
+				"rowid":  id,
+				"height": height,
+				"count":  header.Count,
+				"hash":   bh.Short(4),
+			}).Debug("set snapshot base block")
+			continue
+		}
 		// Add normal block
 		if pn, ok = index[ph]; !ok {
 			err = errors.Wrapf(ErrParentNotFound, "parent %s not found", ph.Short(4))
@@ -593,6 +803,61 @@ func loadAndCacheProviders(st xi.Storage, view *metaState) (err error) {
 	return
 }
 
+func loadAndCacheMultiSigs(st xi.Storage, view *metaState) (err error) {
+	var (
+		rows *sql.Rows
+		hex  string
+		addr hash.Hash
+		enc  []byte
+	)
+
+	if rows, err = st.Reader().Query(`SELECT "address", "encoded" FROM "multisig"`); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&hex, &enc); err != nil {
+			return
+		}
+		if err = hash.Decode(&addr, hex); err != nil {
+			return
+		}
+		var dec = &types.MultiSigProfile{}
+		if err = utils.DecodeMsgPack(enc, dec); err != nil {
+			return
+		}
+		view.readonly.multisigs[proto.AccountAddress(addr)] = dec
+	}
+
+	return
+}
+
+func loadAndCacheEscrows(st xi.Storage, view *metaState) (err error) {
+	var (
+		rows *sql.Rows
+		enc  []byte
+	)
+
+	if rows, err = st.Reader().Query(`SELECT "encoded" FROM "escrow"`); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&enc); err != nil {
+			return
+		}
+		var dec = &types.EscrowProfile{}
+		if err = utils.DecodeMsgPack(enc, dec); err != nil {
+			return
+		}
+		view.readonly.escrows[dec.ID] = dec
+	}
+
+	return
+}
+
 func loadImmutableState(st xi.Storage) (immutable *metaState, err error) {
 	immutable = newMetaState()
 	if err = loadAndCacheAccounts(st, immutable); err != nil {
@@ -604,6 +869,12 @@ func loadImmutableState(st xi.Storage) (immutable *metaState, err error) {
 	if err = loadAndCacheProviders(st, immutable); err != nil {
 		return
 	}
+	if err = loadAndCacheMultiSigs(st, immutable); err != nil {
+		return
+	}
+	if err = loadAndCacheEscrows(st, immutable); err != nil {
+		return
+	}
 	return
 }
 
@@ -627,6 +898,7 @@ func loadDatabase(st xi.Storage) (
 	if immutable, err = loadImmutableState(st); err != nil {
 		return
 	}
+	immutable.height = irre.height
 	// Load tx pool
 	if txPool, err = loadTxPool(st); err != nil {
 		return
@@ -17,15 +17,26 @@
 package client
 
 import (
+	"encoding/base64"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
 )
 
 const (
 	paramUseLeader   = "use_leader"
 	paramUseFollower = "use_follower"
 	paramMirror      = "mirror"
+	paramGasLimit    = "gas_limit"
+
+	paramReadYourWrites = "read_your_writes"
+	paramMaxStaleness   = "max_staleness"
+
+	paramAccessToken = "access_token"
 )
 
 // Config is a configuration parsed from a DSN string.
@@ -44,6 +55,20 @@ type Config struct {
 
 	// Mirror option forces client to query from mirror server
 	Mirror string
+
+	// GasLimit sets the max gas units a single request may use, 0 for the server default
+	GasLimit uint64
+
+	// ReadYourWrites option makes reads see the state applied by all the requests already
+	// responded to this client, a follower which falls behind redirects the reads to leader
+	ReadYourWrites bool
+
+	// MaxStaleness option bounds the staleness of follower reads, 0 for unbounded
+	MaxStaleness time.Duration
+
+	// AccessToken is the delegated access token issued to the client by a super user of the
+	// database, it overrides the permission granted on chain before it expires
+	AccessToken *types.AccessToken
 }
 
 // NewConfig creates a new config with default value.
@@ -71,6 +96,20 @@ func (cfg *Config) FormatDSN() string {
 	if cfg.Mirror != "" {
 		newQuery.Add(paramMirror, cfg.Mirror)
 	}
+	if cfg.GasLimit > 0 {
+		newQuery.Add(paramGasLimit, strconv.FormatUint(cfg.GasLimit, 10))
+	}
+	if cfg.ReadYourWrites {
+		newQuery.Add(paramReadYourWrites, strconv.FormatBool(cfg.ReadYourWrites))
+	}
+	if cfg.MaxStaleness > 0 {
+		newQuery.Add(paramMaxStaleness, cfg.MaxStaleness.String())
+	}
+	if cfg.AccessToken != nil {
+		if buf, err := utils.EncodeMsgPack(cfg.AccessToken); err == nil {
+			newQuery.Add(paramAccessToken, base64.RawURLEncoding.EncodeToString(buf.Bytes()))
+		}
+	}
 	u.RawQuery = newQuery.Encode()
 
 	return u.String()
@@ -98,6 +137,29 @@ func ParseDSN(dsn string) (cfg *Config, err error) {
 		cfg.UseLeader = true
 	}
 	cfg.Mirror = q.Get(paramMirror)
+	if gl := q.Get(paramGasLimit); gl != "" {
+		if cfg.GasLimit, err = strconv.ParseUint(gl, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	// option: read_your_writes, max_staleness
+	cfg.ReadYourWrites, _ = strconv.ParseBool(q.Get(paramReadYourWrites))
+	if ms := q.Get(paramMaxStaleness); ms != "" {
+		if cfg.MaxStaleness, err = time.ParseDuration(ms); err != nil {
+			return nil, err
+		}
+	}
+	// option: access_token
+	if at := q.Get(paramAccessToken); at != "" {
+		var buf []byte
+		if buf, err = base64.RawURLEncoding.DecodeString(at); err != nil {
+			return nil, err
+		}
+		cfg.AccessToken = &types.AccessToken{}
+		if err = utils.DecodeMsgPack(buf, cfg.AccessToken); err != nil {
+			return nil, err
+		}
+	}
 
 	return cfg, nil
 }
@@ -18,6 +18,7 @@ package client
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -100,4 +101,32 @@ func TestConfig(t *testing.T) {
 		cfg.Mirror = ""
 		So(cfg.FormatDSN(), ShouldEqual, "covenantsql://db")
 	})
+
+	Convey("test format and parse dsn with gas limit option", t, func() {
+		cfg, err := ParseDSN("covenantsql://db?gas_limit=10000")
+		So(err, ShouldBeNil)
+		So(cfg.GasLimit, ShouldEqual, 10000)
+		So(cfg.FormatDSN(), ShouldEqual, "covenantsql://db?gas_limit=10000")
+		_, err = ParseDSN("covenantsql://db?gas_limit=-1")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("test format and parse dsn with consistency options", t, func() {
+		cfg, err := ParseDSN("covenantsql://db?use_follower=true&read_your_writes=true&max_staleness=3s")
+		So(err, ShouldBeNil)
+		So(cfg.ReadYourWrites, ShouldBeTrue)
+		So(cfg.MaxStaleness, ShouldEqual, 3*time.Second)
+		recoveredCfg, err := ParseDSN(cfg.FormatDSN())
+		So(err, ShouldBeNil)
+		So(recoveredCfg, ShouldResemble, cfg)
+		_, err = ParseDSN("covenantsql://db?max_staleness=3")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("test format and parse dsn with invalid access token", t, func() {
+		_, err := ParseDSN("covenantsql://db?access_token=invalid")
+		So(err, ShouldNotBeNil)
+		_, err = ParseDSN("covenantsql://db?access_token=!")
+		So(err, ShouldNotBeNil)
+	})
 }
@@ -38,15 +38,26 @@ import (
 
 // conn implements an interface sql.Conn.
 type conn struct {
-	dbID proto.DatabaseID
+	dbID        proto.DatabaseID
+	gasLimit    uint64
+	accessToken *types.AccessToken
+
+	// consistency options of reads
+	readYourWrites bool
+	maxStaleness   time.Duration
 
-	queries     []types.Query
 	localNodeID proto.NodeID
 	privKey     *asymmetric.PrivateKey
 
+	// interactive transaction states
 	inTransaction bool
+	txID          uint64
+	txTime        time.Time     // begin time of the transaction, carried by its requests
+	txConn        *pconn        // peer connection holding the transaction
+	queries       []types.Query // executed write queries to commit
 	closed        int32
 
+	peers    *proto.Peers // nil in mirror mode
 	leader   *pconn
 	follower *pconn
 }
@@ -72,10 +83,14 @@ func newConn(cfg *Config) (c *conn, err error) {
 	}
 
 	c = &conn{
-		dbID:        proto.DatabaseID(cfg.DatabaseID),
-		localNodeID: localNodeID,
-		privKey:     privKey,
-		queries:     make([]types.Query, 0),
+		dbID:           proto.DatabaseID(cfg.DatabaseID),
+		gasLimit:       cfg.GasLimit,
+		accessToken:    cfg.AccessToken,
+		readYourWrites: cfg.ReadYourWrites,
+		maxStaleness:   cfg.MaxStaleness,
+		localNodeID:    localNodeID,
+		privKey:        privKey,
+		queries:        make([]types.Query, 0),
 	}
 
 	// get peers from BP
@@ -92,6 +107,7 @@ func newConn(cfg *Config) (c *conn, err error) {
 
 		// no ack workers required, mirror mode does not support ack worker
 	} else {
+		c.peers = peers
 		if cfg.UseLeader {
 			c.leader = &pconn{
 				parent:  c,
@@ -220,7 +236,9 @@ func (c *conn) Begin() (driver.Tx, error) {
 	return c.BeginTx(context.Background(), driver.TxOptions{})
 }
 
-// BeginTx implements the driver.ConnBeginTx.BeginTx method.
+// BeginTx implements the driver.ConnBeginTx.BeginTx method. The transaction is held by the
+// database leader until it's committed or rolled back, and read queries in the transaction see
+// the uncommitted writes of it.
 func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
 	if atomic.LoadInt32(&c.closed) != 0 {
 		return nil, driver.ErrBadConn
@@ -233,8 +251,45 @@ func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 		return nil, sql.ErrTxDone
 	}
 
-	// TODO(xq262144): make use of the ctx argument
+	// use follower pconn only when the transaction is readonly
+	uc := c.leader
+	if (opts.ReadOnly && c.follower != nil) || uc == nil {
+		uc = c.follower
+	}
+
+	req := &types.BeginTxReq{
+		Header: types.SignedTxHeader{
+			TxHeader: types.TxHeader{
+				NodeID:      c.localNodeID,
+				DatabaseID:  c.dbID,
+				Isolation:   int32(opts.Isolation),
+				ReadOnly:    opts.ReadOnly,
+				Timestamp:   getLocalTime(),
+				AccessToken: c.accessToken,
+			},
+		},
+	}
+	if err := req.Header.Sign(c.privKey); err != nil {
+		return nil, err
+	}
+
+	var (
+		resp     types.BeginTxResp
+		rollback = func(done <-chan error) {
+			// the abandoned call may still open the transaction on the peer
+			if err := <-done; err == nil {
+				_, _ = c.endTx(uc, resp.TxID, nil)
+			}
+		}
+	)
+	if err := uc.callContext(ctx, route.DBSBeginTx.String(), req, &resp, rollback); err != nil {
+		return nil, err
+	}
+
 	c.inTransaction = true
+	c.txID = resp.TxID
+	c.txTime = req.Header.Timestamp
+	c.txConn = uc
 	c.queries = c.queries[:0]
 
 	return c, nil
@@ -261,7 +316,6 @@ func (c *conn) ExecContext(ctx context.Context, query string, args []driver.Name
 		return
 	}
 
-	// TODO(xq262144): make use of the ctx argument
 	sq := convertQuery(query, args)
 
 	var affectedRows, lastInsertID int64
@@ -286,7 +340,6 @@ func (c *conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 		return
 	}
 
-	// TODO(xq262144): make use of the ctx argument
 	sq := convertQuery(query, args)
 	_, _, rows, err = c.addQuery(ctx, types.ReadQuery, sq)
 
@@ -303,23 +356,35 @@ func (c *conn) Commit() (err error) {
 		return sql.ErrTxDone
 	}
 
-	defer func() {
-		c.queries = c.queries[:0]
-		c.inTransaction = false
-	}()
+	defer c.resetTx()
 
+	var commit *types.Request
 	if len(c.queries) > 0 {
-		// send query
-		if _, _, _, err = c.sendQuery(context.Background(), types.WriteQuery, c.queries); err != nil {
+		// allocate sequence
+		connID, seqNo := allocateConnAndSeq()
+		defer putBackConn(connID)
+
+		if commit, err = c.buildRequest(
+			context.Background(), types.WriteQuery, connID, seqNo, c.queries,
+		); err != nil {
 			return
 		}
 	}
 
+	var response *types.Response
+	if response, err = c.endTx(c.txConn, c.txID, commit); err != nil {
+		return
+	}
+	if response != nil {
+		observeOffset(c.dbID, response.Header.AppliedOffset)
+		c.txConn.ack(context.Background(), response)
+	}
+
 	return
 }
 
 // Rollback implements the driver.Tx.Rollback method.
-func (c *conn) Rollback() error {
+func (c *conn) Rollback() (err error) {
 	if atomic.LoadInt32(&c.closed) != 0 {
 		return driver.ErrBadConn
 	}
@@ -328,44 +393,118 @@ func (c *conn) Rollback() error {
 		return sql.ErrTxDone
 	}
 
-	defer func() {
-		c.queries = c.queries[:0]
-		c.inTransaction = false
-	}()
-
-	if len(c.queries) == 0 {
-		return sql.ErrTxDone
-	}
+	defer c.resetTx()
 
-	return nil
+	_, err = c.endTx(c.txConn, c.txID, nil)
+	return
 }
 
-func (c *conn) addQuery(ctx context.Context, queryType types.QueryType, query *types.Query) (affectedRows int64, lastInsertID int64, rows driver.Rows, err error) {
-	if c.inTransaction {
-		// check query type, enqueue query
-		if queryType == types.ReadQuery {
-			// read query is not supported in transaction
-			err = ErrQueryInTransaction
-			return
-		}
-
-		// append queries
-		c.queries = append(c.queries, *query)
-
-		log.WithFields(log.Fields{
-			"pattern": query.Pattern,
-			"args":    query.Args,
-		}).Debug("add query to tx")
+func (c *conn) endTx(
+	uc *pconn, txID uint64, commit *types.Request) (response *types.Response, err error,
+) {
+	req := &types.EndTxReq{
+		Header: types.SignedTxHeader{
+			TxHeader: types.TxHeader{
+				NodeID:      c.localNodeID,
+				DatabaseID:  c.dbID,
+				TxID:        txID,
+				Timestamp:   getLocalTime(),
+				AccessToken: c.accessToken,
+			},
+		},
+		Commit: commit,
+	}
+	if err = req.Header.Sign(c.privKey); err != nil {
+		return
+	}
 
+	var resp types.EndTxResp
+	if err = uc.pCaller.Call(route.DBSEndTx.String(), req, &resp); err != nil {
 		return
 	}
+	response = resp.Response
+
+	log.WithFields(log.Fields{
+		"tx":     txID,
+		"commit": commit != nil,
+		"target": uc.pCaller.Target(),
+	}).Debug("end transaction")
+	return
+}
 
+func (c *conn) resetTx() {
+	c.inTransaction = false
+	c.txID = 0
+	c.txTime = time.Time{}
+	c.txConn = nil
+	c.queries = c.queries[:0]
+}
+
+func (c *conn) addQuery(ctx context.Context, queryType types.QueryType, query *types.Query) (affectedRows int64, lastInsertID int64, rows driver.Rows, err error) {
 	log.WithFields(log.Fields{
 		"pattern": query.Pattern,
 		"args":    query.Args,
+		"tx":      c.txID,
 	}).Debug("execute query")
 
-	return c.sendQuery(ctx, queryType, []types.Query{*query})
+	if affectedRows, lastInsertID, rows, err = c.sendQuery(
+		ctx, queryType, []types.Query{*query},
+	); err != nil {
+		return
+	}
+
+	if c.inTransaction && queryType == types.WriteQuery {
+		// record executed write queries, they are applied by the commit request
+		c.queries = append(c.queries, *query)
+	}
+
+	return
+}
+
+func (c *conn) buildRequest(
+	ctx context.Context, queryType types.QueryType, connID, seqNo uint64, queries []types.Query,
+) (
+	req *types.Request, err error,
+) {
+	var (
+		deadline     time.Time
+		minOffset    uint64
+		maxStaleness time.Duration
+	)
+	if d, ok := ctx.Deadline(); ok {
+		deadline = d.UTC()
+	}
+	if queryType == types.ReadQuery {
+		if c.readYourWrites {
+			minOffset = lastOffset(c.dbID)
+		}
+		maxStaleness = c.maxStaleness
+	}
+	req = &types.Request{
+		Header: types.SignedRequestHeader{
+			RequestHeader: types.RequestHeader{
+				QueryType:    queryType,
+				NodeID:       c.localNodeID,
+				DatabaseID:   c.dbID,
+				ConnectionID: connID,
+				SeqNo:        seqNo,
+				Timestamp:    getLocalTime(),
+				TxID:         c.txID,
+				TxTimestamp:  c.txTime,
+				GasLimit:     c.gasLimit,
+				Deadline:     deadline,
+				MinOffset:    minOffset,
+				MaxStaleness: maxStaleness,
+				AccessToken:  c.accessToken,
+			},
+		},
+		Payload: types.RequestPayload{
+			Queries: queries,
+		},
+	}
+
+	err = req.Sign(c.privKey)
+	return
 }
 
 func (c *conn) sendQuery(ctx context.Context, queryType types.QueryType, queries []types.Query) (affectedRows int64, lastInsertID int64, rows driver.Rows, err error) {
@@ -379,6 +518,10 @@ func (c *conn) sendQuery(ctx context.Context, queryType types.QueryType, queries
 	if uc == nil {
 		uc = c.follower
 	}
+	// queries in transaction must be sent to the peer holding the transaction
+	if c.inTransaction {
+		uc = c.txConn
+	}
 
 	// allocate sequence
 	connID, seqNo := allocateConnAndSeq()
@@ -396,28 +539,36 @@ func (c *conn) sendQuery(ctx context.Context, queryType types.QueryType, queries
 	}()
 
 	// build request
-	req := &types.Request{
-		Header: types.SignedRequestHeader{
-			RequestHeader: types.RequestHeader{
-				QueryType:    queryType,
-				NodeID:       c.localNodeID,
-				DatabaseID:   c.dbID,
-				ConnectionID: connID,
-				SeqNo:        seqNo,
-				Timestamp:    getLocalTime(),
-			},
-		},
-		Payload: types.RequestPayload{
-			Queries: queries,
-		},
-	}
-
-	if err = req.Sign(c.privKey); err != nil {
+	var req *types.Request
+	if req, err = c.buildRequest(ctx, queryType, connID, seqNo, queries); err != nil {
 		return
 	}
 
-	var response types.Response
-	if err = uc.pCaller.Call(route.DBSQuery.String(), req, &response); err != nil {
+	var (
+		response types.Response
+		cancel   = func(<-chan error) { uc.cancelQuery(req) }
+	)
+	if err = uc.callContext(ctx, route.DBSQuery.String(), req, &response, cancel); err != nil {
+		switch {
+		case ctx.Err() != nil || c.inTransaction:
+			return
+		case uc == c.follower && c.leader != nil && errors.Cause(err) == ErrStaleState:
+			// the follower falls behind the consistency requirement, redirect to leader
+			uc = c.leader
+		case uc == c.leader && c.switchLeader():
+			// retry once on the new leader
+			uc = c.leader
+		default:
+			return
+		}
+		if err = uc.callContext(ctx, route.DBSQuery.String(), req, &response, cancel); err != nil {
+			return
+		}
+	}
+	observeOffset(c.dbID, response.Header.AppliedOffset)
+	if response.Cursor != 0 {
+		// the streamed result set is acknowledged with its last chunk
+		rows, err = newStreamRows(&response, uc)
 		return
 	}
 	rows = newRows(&response)
@@ -427,23 +578,147 @@ func (c *conn) sendQuery(ctx context.Context, queryType types.QueryType, queries
 		lastInsertID = response.Header.LastInsertID
 	}
 
-	// build ack
-	func() {
-		defer trace.StartRegion(ctx, "ackEnqueue").End()
-		if uc.ackCh != nil {
-			uc.ackCh <- &types.Ack{
-				Header: types.SignedAckHeader{
-					AckHeader: types.AckHeader{
-						Response:     response.Header.ResponseHeader,
-						ResponseHash: response.Header.Hash(),
-						NodeID:       c.localNodeID,
-						Timestamp:    getLocalTime(),
-					},
+	// responses in transaction are not acknowledged, the commit response is
+	if !c.inTransaction {
+		uc.ack(ctx, &response)
+	}
+
+	return
+}
+
+// callContext calls the RPC method of the peer, and returns as soon as ctx is done. The
+// onCancel function is called to interrupt or undo the remote execution if it's given, done
+// receives the result of the abandoned call.
+func (c *pconn) callContext(
+	ctx context.Context, method string, args, reply interface{}, onCancel func(done <-chan error),
+) (err error) {
+	if ctx.Done() == nil {
+		return types.FromRemoteError(c.pCaller.Call(method, args, reply))
+	}
+	var done = make(chan error, 1)
+	go func() {
+		done <- c.pCaller.Call(method, args, reply)
+	}()
+	select {
+	case err = <-done:
+		return types.FromRemoteError(err)
+	case <-ctx.Done():
+		if onCancel != nil {
+			go onCancel(done)
+		}
+		return ctx.Err()
+	}
+}
+
+// cancelQuery asks the peer to interrupt the in-flight request, the result is ignored.
+func (c *pconn) cancelQuery(req *types.Request) {
+	var (
+		cancel = &types.CancelQueryReq{
+			DatabaseID: req.Header.DatabaseID,
+			Query:      req.Header.GetQueryKey(),
+		}
+		err = c.pCaller.Call(route.DBSCancelQuery.String(), cancel, &types.CancelQueryResp{})
+	)
+	log.WithFields(log.Fields{
+		"connID": req.Header.ConnectionID,
+		"seqNo":  req.Header.SeqNo,
+		"target": c.pCaller.Target(),
+	}).WithError(err).Debug("cancel query")
+}
+
+// ack enqueues an acknowledgement of response.
+func (c *pconn) ack(ctx context.Context, response *types.Response) {
+	defer trace.StartRegion(ctx, "ackEnqueue").End()
+	if c.ackCh != nil {
+		c.ackCh <- &types.Ack{
+			Header: types.SignedAckHeader{
+				AckHeader: types.AckHeader{
+					Response:     response.Header.ResponseHeader,
+					ResponseHash: response.Header.Hash(),
+					NodeID:       c.parent.localNodeID,
+					Timestamp:    getLocalTime(),
 				},
-			}
+			},
 		}
-	}()
+	}
+}
+
+// switchLeader queries the database peers for a newly elected leader, and replaces the leader
+// connection if found.
+func (c *conn) switchLeader() (switched bool) {
+	if c.peers == nil {
+		return
+	}
+
+	var (
+		req = &types.QueryPeersReq{
+			DatabaseID: c.dbID,
+		}
+		latest = c.peers
+	)
+	for _, node := range c.peers.Servers {
+		if node == c.peers.Leader {
+			continue
+		}
+		resp := &types.QueryPeersResp{}
+		if err := rpc.NewCaller().CallNode(node, route.DBSQueryPeers.String(), req, resp); err != nil {
+			log.WithField("node", node).WithError(err).Debug("query peers failed")
+			continue
+		}
+		if resp.Peers == nil || resp.Peers.Term <= latest.Term {
+			continue
+		}
+		if err := verifyLeaderPeers(resp.Peers, c.peers); err != nil {
+			log.WithField("node", node).WithError(err).Debug("verify peers failed")
+			continue
+		}
+		latest = resp.Peers
+	}
+	if latest == c.peers || latest.Leader == c.peers.Leader {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"db":     c.dbID,
+		"term":   latest.Term,
+		"leader": latest.Leader,
+	}).Info("switch to new database leader")
+
+	peerList.Store(c.dbID, latest)
+	c.peers = latest
+
+	c.leader.close()
+	c.leader = &pconn{
+		parent:  c,
+		pCaller: rpc.NewPersistentCaller(latest.Leader),
+	}
+	if err := c.leader.startAckWorkers(2); err != nil {
+		log.WithError(err).Debug("leader startAckWorkers failed")
+	}
+	return true
+}
 
+// verifyLeaderPeers verifies that the peers are signed by its leader and served by the same
+// servers as the current ones, which are only changed by block producers.
+func verifyLeaderPeers(peers, current *proto.Peers) (err error) {
+	if err = peers.Verify(); err != nil {
+		return
+	}
+	if len(peers.Servers) != len(current.Servers) {
+		return errors.Wrap(ErrUntrustedPeers, "servers not match")
+	}
+	for i := range peers.Servers {
+		if peers.Servers[i] != current.Servers[i] {
+			return errors.Wrap(ErrUntrustedPeers, "servers not match")
+		}
+	}
+	node, err := rpc.GetNodeInfo(peers.Leader.ToRawNodeID())
+	if err != nil {
+		return
+	}
+	if peers.Signee == nil || !peers.Signee.IsEqual(node.PublicKey) {
+		return errors.Wrapf(ErrUntrustedPeers, "peers not signed by leader %s", peers.Leader)
+	}
 	return
 }
 
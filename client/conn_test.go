@@ -17,12 +17,23 @@
 package client
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/route"
+	"github.com/CovenantSQL/CovenantSQL/rpc"
+	"github.com/CovenantSQL/CovenantSQL/types"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
 )
 
@@ -58,6 +69,42 @@ func TestConn(t *testing.T) {
 		So(rows.Next(), ShouldBeFalse)
 		rows.Close()
 
+		// query peers for the current leader
+		var (
+			peersReq  = &types.QueryPeersReq{DatabaseID: proto.DatabaseID("db")}
+			peersResp = &types.QueryPeersResp{}
+		)
+		err = testRequest(route.DBSQueryPeers, peersReq, peersResp)
+		So(err, ShouldBeNil)
+		So(peersResp.Peers, ShouldNotBeNil)
+		err = peersResp.Peers.Verify()
+		So(err, ShouldBeNil)
+		var nodeID proto.NodeID
+		nodeID, err = kms.GetLocalNodeID()
+		So(err, ShouldBeNil)
+		So(peersResp.Peers.Leader, ShouldEqual, nodeID)
+		// peers should be signed by leader and keep the servers
+		err = verifyLeaderPeers(peersResp.Peers, peersResp.Peers)
+		So(err, ShouldBeNil)
+		forged := peersResp.Peers.Clone()
+		forged.Servers = append(forged.Servers, nodeID)
+		localKey, err := kms.GetLocalPrivateKey()
+		So(err, ShouldBeNil)
+		err = forged.Sign(localKey)
+		So(err, ShouldBeNil)
+		err = verifyLeaderPeers(&forged, peersResp.Peers)
+		So(errors.Cause(err), ShouldEqual, ErrUntrustedPeers)
+		otherKey, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		forged = peersResp.Peers.Clone()
+		err = forged.Sign(otherKey)
+		So(err, ShouldBeNil)
+		err = verifyLeaderPeers(&forged, peersResp.Peers)
+		So(errors.Cause(err), ShouldEqual, ErrUntrustedPeers)
+		peersReq.DatabaseID = proto.DatabaseID("not_exists")
+		err = testRequest(route.DBSQueryPeers, peersReq, peersResp)
+		So(err, ShouldNotBeNil)
+
 		testRowCount := func(expected int) {
 			var row *sql.Row
 			var err error
@@ -139,6 +186,51 @@ func TestConn(t *testing.T) {
 		rows.Close()
 		So(rows.Next(), ShouldBeFalse)
 
+		// large result set streamed in chunks
+		const streamCount, batchCount = 2500, 500
+		for i := 0; i < streamCount; i += batchCount {
+			var args = make([]interface{}, batchCount)
+			for j := range args {
+				args[j] = 10 + i + j
+			}
+			_, err = db.Exec("insert into test values "+
+				strings.TrimSuffix(strings.Repeat("(?),", batchCount), ","), args...)
+			So(err, ShouldBeNil)
+		}
+		rows, err = db.Query("select * from test where test >= 10")
+		So(err, ShouldBeNil)
+		for i := 0; i < streamCount; i++ {
+			So(rows.Next(), ShouldBeTrue)
+			err = rows.Scan(&result)
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, 10+i)
+		}
+		So(rows.Next(), ShouldBeFalse)
+		So(rows.Err(), ShouldBeNil)
+		rows.Close()
+
+		// close the streamed rows during read
+		rows, err = db.Query("select * from test where test >= 10")
+		So(err, ShouldBeNil)
+		So(rows.Next(), ShouldBeTrue)
+		err = rows.Close()
+		So(err, ShouldBeNil)
+
+		// query interrupted at the context deadline
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		var start = time.Now()
+		_, err = db.QueryContext(ctx, "select count(*) from test a, test b, test c")
+		So(err, ShouldNotBeNil)
+		So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+		rows, err = db.Query("select count(*) from test where test >= 10")
+		So(err, ShouldBeNil)
+		So(rows.Next(), ShouldBeTrue)
+		err = rows.Scan(&result)
+		So(err, ShouldBeNil)
+		So(result, ShouldEqual, streamCount)
+		rows.Close()
+
 		// use of closed connection
 		db.Close()
 
@@ -179,31 +271,32 @@ func TestTransaction(t *testing.T) {
 		So(tx, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		// test fill invalid read query in transaction
-		_, err = tx.Query("select * from test")
-		So(err, ShouldNotBeNil)
-
-		// test query
-		_, err = tx.Exec("insert into test values(2)")
-		So(err, ShouldBeNil)
-
-		// test rollback
-		err = tx.Rollback()
-		So(err, ShouldBeNil)
-
-		testRowCount := func(expected int) {
+		testRowCount := func(q interface {
+			QueryRow(string, ...interface{}) *sql.Row
+		}, expected int) {
 			var row *sql.Row
 			var err error
 			var result int
-			row = db.QueryRow("select count(1) as cnt from test")
+			row = q.QueryRow("select count(1) as cnt from test")
 			So(row, ShouldNotBeNil)
 			err = row.Scan(&result)
 			So(err, ShouldBeNil)
 			So(result, ShouldEqual, expected)
 		}
 
+		// test query
+		_, err = tx.Exec("insert into test values(2)")
+		So(err, ShouldBeNil)
+
+		// test read query in transaction, uncommitted writes should be visible
+		testRowCount(tx, 2)
+
+		// test rollback
+		err = tx.Rollback()
+		So(err, ShouldBeNil)
+
 		// test row count on rollback
-		testRowCount(1)
+		testRowCount(db, 1)
 
 		// test commit this time
 		err = tx.Commit()
@@ -216,16 +309,36 @@ func TestTransaction(t *testing.T) {
 
 		_, err = tx.Exec("insert into test values(2)")
 		So(err, ShouldBeNil)
-		_, err = tx.Exec("insert into test values(3)")
+		execResult, err = tx.Exec("insert into test values(3)")
+		So(err, ShouldBeNil)
+		lastInsertID, err = execResult.LastInsertId()
 		So(err, ShouldBeNil)
+		So(lastInsertID, ShouldEqual, 3)
+		testRowCount(tx, 3)
 
 		err = tx.Commit()
 		So(err, ShouldBeNil)
-		testRowCount(3)
+		testRowCount(db, 3)
 		err = tx.Rollback()
 		So(err, ShouldNotBeNil)
 
-		// test failures during transaction batch
+		// test commit of random values, the values seen in transaction should be committed
+		var seen, committed int64
+		_, err = db.Exec("create table rnd (r int)")
+		So(err, ShouldBeNil)
+		tx, err = db.Begin()
+		So(err, ShouldBeNil)
+		_, err = tx.Exec("insert into rnd values (random())")
+		So(err, ShouldBeNil)
+		err = tx.QueryRow("select r from rnd").Scan(&seen)
+		So(err, ShouldBeNil)
+		err = tx.Commit()
+		So(err, ShouldBeNil)
+		err = db.QueryRow("select r from rnd").Scan(&committed)
+		So(err, ShouldBeNil)
+		So(committed, ShouldEqual, seen)
+
+		// test failures during transaction
 		tx, err = db.Begin()
 		So(tx, ShouldNotBeNil)
 		So(err, ShouldBeNil)
@@ -233,17 +346,32 @@ func TestTransaction(t *testing.T) {
 		_, err = tx.Exec("insert into test values(4)")
 		So(err, ShouldBeNil)
 		_, err = tx.Exec("THIS IS NOT A SQL!!!!")
-		So(err, ShouldBeNil) // should be nil since query is not committed
+		So(err, ShouldNotBeNil) // should fail immediately
 		err = tx.Commit()
-		So(err, ShouldNotBeNil) // should failed
-		testRowCount(3)         // should still be 3 rows
+		So(err, ShouldBeNil) // failed query is not committed
+		testRowCount(db, 4)
+
+		// test read-only transaction
+		tx, err = db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+		So(tx, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+		testRowCount(tx, 4)
+		_, err = tx.Exec("insert into test values(5)")
+		So(err, ShouldNotBeNil)
+		err = tx.Commit()
+		So(err, ShouldBeNil)
+
+		// test unsupported isolation level
+		tx, err = db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelLinearizable})
+		So(tx, ShouldBeNil)
+		So(err, ShouldNotBeNil)
 
 		// test rollback empty transaction
 		tx, err = db.Begin()
 		So(tx, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 		err = tx.Rollback()
-		So(err, ShouldNotBeNil)
+		So(err, ShouldBeNil)
 
 		// test commit empty transaction, should silently success
 		tx, err = db.Begin()
@@ -252,6 +380,27 @@ func TestTransaction(t *testing.T) {
 		err = tx.Commit()
 		So(err, ShouldBeNil)
 
+		// test writes held off by an ongoing transaction
+		tx, err = db.Begin()
+		So(tx, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+		_, err = tx.Exec("delete from test")
+		So(err, ShouldBeNil)
+		var written = make(chan error)
+		go func() {
+			_, err := db.Exec("insert into test values(5)")
+			written <- err
+		}()
+		select {
+		case <-written:
+			t.Fatal("write should be held off by transaction")
+		case <-time.After(500 * time.Millisecond):
+		}
+		err = tx.Commit()
+		So(err, ShouldBeNil)
+		So(<-written, ShouldBeNil)
+		testRowCount(db, 1)
+
 		db.Close()
 
 		// test starting transaction after connection closed
@@ -261,6 +410,56 @@ func TestTransaction(t *testing.T) {
 	})
 }
 
+// blockingCaller holds the BeginTx call until released, and records the ended transactions.
+type blockingCaller struct {
+	released chan struct{}
+	ended    chan uint64
+}
+
+func (c *blockingCaller) Call(method string, request interface{}, reply interface{}) error {
+	switch method {
+	case route.DBSBeginTx.String():
+		<-c.released
+		reply.(*types.BeginTxResp).TxID = 42
+	case route.DBSEndTx.String():
+		c.ended <- request.(*types.EndTxReq).Header.TxID
+	}
+	return nil
+}
+
+func (c *blockingCaller) Close()           {}
+func (c *blockingCaller) Target() string   { return "blocking" }
+func (c *blockingCaller) New() rpc.PCaller { return c }
+
+func TestAbandonedTransaction(t *testing.T) {
+	Convey("transaction abandoned by the canceled context should be rolled back", t, func() {
+		var (
+			caller = &blockingCaller{
+				released: make(chan struct{}),
+				ended:    make(chan uint64, 1),
+			}
+			c = &conn{}
+		)
+		privKey, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		c.privKey = privKey
+		c.leader = &pconn{parent: c, pCaller: caller}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		_, err = c.BeginTx(ctx, driver.TxOptions{})
+		So(err, ShouldEqual, context.Canceled)
+		So(c.inTransaction, ShouldBeFalse)
+		close(caller.released)
+		select {
+		case txID := <-caller.ended:
+			So(txID, ShouldEqual, 42)
+		case <-time.After(5 * time.Second):
+			t.Fatal("abandoned transaction is not rolled back")
+		}
+	})
+}
+
 func TestConnAndSeqAllocation(t *testing.T) {
 	Convey("conn id and seq no allocation test", t, func() {
 		var wg sync.WaitGroup
@@ -35,6 +35,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/rpc"
@@ -61,6 +62,7 @@ var (
 	driverInitialized   uint32
 	peersUpdaterRunning uint32
 	peerList            sync.Map // map[proto.DatabaseID]*proto.Peers
+	appliedOffsets      sync.Map // map[proto.DatabaseID]*uint64
 	connIDLock          sync.Mutex
 	connIDAvail         []uint64
 	globalSeqNo         uint64
@@ -68,6 +70,10 @@ var (
 
 	// DefaultConfigFile is the default path of config file
 	DefaultConfigFile = "~/.cql/config.yaml"
+
+	// TxFee defines the fee in Particle paid to block producers for each transaction sent by the
+	// client.
+	TxFee uint64
 )
 
 func init() {
@@ -192,6 +198,7 @@ func Create(meta ResourceMeta) (txHash hash.Hash, dsn string, err error) {
 		AdvancePayment: meta.AdvancePayment,
 		TokenType:      types.Particle,
 		Nonce:          nonceResp.Nonce,
+		Fee:            TxFee,
 	})
 
 	if err = req.Tx.Sign(privateKey); err != nil {
@@ -322,6 +329,67 @@ func GetTokenBalance(tt types.TokenType) (balance uint64, err error) {
 	return
 }
 
+// GetVerifiedTokenBalance gets the token balance of current account, which is verified against
+// the state root of the last irreversible block signed by a known block producer.
+func GetVerifiedTokenBalance(tt types.TokenType) (balance uint64, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+	if !tt.Listed() {
+		err = ErrNoSuchTokenBalance
+		return
+	}
+
+	req := new(types.QueryAccountWithProofReq)
+	resp := new(types.QueryAccountWithProofResp)
+
+	var pubKey *asymmetric.PublicKey
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	if req.Addr, err = crypto.PubKeyHash(pubKey); err != nil {
+		return
+	}
+	if err = requestBP(route.MCCQueryAccountWithProof, req, resp); err != nil {
+		return
+	}
+	if resp.Account == nil || resp.Account.Address != req.Addr {
+		err = errors.Wrap(ErrInvalidStateProof, "unexpected account in response")
+		return
+	}
+	if err = verifyStateProof(&resp.Header, resp.Account, resp.Proof); err != nil {
+		return
+	}
+	balance = resp.Account.TokenBalance[tt]
+	return
+}
+
+// GetVerifiedSQLChainProfile gets the SQLChain profile of the database, which is verified
+// against the state root of the last irreversible block signed by a known block producer.
+func GetVerifiedSQLChainProfile(dbID proto.DatabaseID) (profile *types.SQLChainProfile, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	req := &types.QuerySQLChainProfileWithProofReq{DBID: dbID}
+	resp := new(types.QuerySQLChainProfileWithProofResp)
+
+	if err = requestBP(route.MCCQuerySQLChainProfileWithProof, req, resp); err != nil {
+		return
+	}
+	if resp.Profile == nil || resp.Profile.ID != dbID {
+		err = errors.Wrap(ErrInvalidStateProof, "unexpected sqlchain profile in response")
+		return
+	}
+	if err = verifyStateProof(&resp.Header, resp.Profile, resp.Proof); err != nil {
+		return
+	}
+	profile = resp.Profile
+	return
+}
+
 // UpdatePermission sends UpdatePermission transaction to chain.
 func UpdatePermission(targetUser proto.AccountAddress,
 	targetChain proto.AccountAddress, perm *types.UserPermission) (txHash hash.Hash, err error) {
@@ -356,6 +424,7 @@ func UpdatePermission(targetUser proto.AccountAddress,
 		TargetUser:     targetUser,
 		Permission:     perm,
 		Nonce:          nonce,
+		Fee:            TxFee,
 	})
 	err = up.Sign(privKey)
 	if err != nil {
@@ -375,6 +444,240 @@ func UpdatePermission(targetUser proto.AccountAddress,
 	return
 }
 
+// UpdateRowPolicies sends UpdateRowPolicies transaction to chain, which replaces the row-level
+// security policies of the database applied to its non-super users.
+func UpdateRowPolicies(targetChain proto.AccountAddress, policies []*types.RowPolicy) (
+	txHash hash.Hash, err error,
+) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	var (
+		pubKey  *asymmetric.PublicKey
+		privKey *asymmetric.PrivateKey
+		addr    proto.AccountAddress
+		nonce   interfaces.AccountNonce
+	)
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if addr, err = crypto.PubKeyHash(pubKey); err != nil {
+		return
+	}
+	if nonce, err = getNonce(addr); err != nil {
+		return
+	}
+
+	ur := types.NewUpdateRowPolicies(&types.UpdateRowPoliciesHeader{
+		TargetSQLChain: targetChain,
+		Policies:       policies,
+		Nonce:          nonce,
+		Fee:            TxFee,
+	})
+	if err = ur.Sign(privKey); err != nil {
+		log.WithError(err).Warning("sign failed")
+		return
+	}
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = ur
+	if err = requestBP(route.MCCAddTx, addTxReq, addTxResp); err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = ur.Hash()
+	return
+}
+
+// IssueAccessToken signs an off-chain access token with the local key, which grants the role of
+// the database to the user until ttl passes. The token is accepted by miners only if the local
+// account is a super user of the database, and the user should set it in the DSN.
+func IssueAccessToken(dbID proto.DatabaseID, user proto.AccountAddress,
+	role types.UserPermissionRole, ttl time.Duration) (token *types.AccessToken, err error,
+) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+	if ttl <= 0 || ttl > types.MaxAccessTokenTTL {
+		err = errors.Wrapf(types.ErrInvalidAccessToken, "invalid ttl %s", ttl)
+		return
+	}
+
+	var privKey *asymmetric.PrivateKey
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	var now = time.Now().UTC()
+	token = types.NewAccessToken(&types.AccessTokenHeader{
+		DatabaseID: dbID,
+		User:       user,
+		Role:       role,
+		Issued:     now,
+		Expire:     now.Add(ttl),
+	})
+	if err = token.Sign(privKey); err != nil {
+		token = nil
+	}
+	return
+}
+
+// TransferDatabaseOwnership sends TransferDatabaseOwnership transaction to chain, the optional
+// coSigner is the private key of the new owner.
+func TransferDatabaseOwnership(targetChain proto.AccountAddress, newOwner proto.AccountAddress,
+	coSigner *asymmetric.PrivateKey) (txHash hash.Hash, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	var (
+		pubKey  *asymmetric.PublicKey
+		privKey *asymmetric.PrivateKey
+		addr    proto.AccountAddress
+		nonce   interfaces.AccountNonce
+	)
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if addr, err = crypto.PubKeyHash(pubKey); err != nil {
+		return
+	}
+
+	nonce, err = getNonce(addr)
+	if err != nil {
+		return
+	}
+
+	tdo := types.NewTransferDatabaseOwnership(&types.TransferDatabaseOwnershipHeader{
+		TargetSQLChain: targetChain,
+		NewOwner:       newOwner,
+		Nonce:          nonce,
+		Fee:            TxFee,
+	})
+	if err = tdo.Sign(privKey); err != nil {
+		log.WithError(err).Warning("sign failed")
+		return
+	}
+	if coSigner != nil {
+		if err = tdo.CoSign(coSigner); err != nil {
+			log.WithError(err).Warning("co-sign failed")
+			return
+		}
+	}
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = tdo
+	err = requestBP(route.MCCAddTx, addTxReq, addTxResp)
+	if err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = tdo.Hash()
+	return
+}
+
+// CreateMultiSigAccount sends CreateMultiSigAccount transaction to chain, the local account should
+// be one of the members. It returns the address of the new multi-signature account.
+func CreateMultiSigAccount(threshold uint32, members []*asymmetric.PublicKey) (
+	txHash hash.Hash, account proto.AccountAddress, err error,
+) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	var (
+		pubKey  *asymmetric.PublicKey
+		privKey *asymmetric.PrivateKey
+		addr    proto.AccountAddress
+		nonce   interfaces.AccountNonce
+	)
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if addr, err = crypto.PubKeyHash(pubKey); err != nil {
+		return
+	}
+
+	nonce, err = getNonce(addr)
+	if err != nil {
+		return
+	}
+
+	cm := types.NewCreateMultiSigAccount(&types.CreateMultiSigAccountHeader{
+		Threshold: threshold,
+		Members:   members,
+		Nonce:     nonce,
+		Fee:       TxFee,
+	})
+	if account, err = cm.MultiSigAccount(); err != nil {
+		return
+	}
+	if err = cm.Sign(privKey); err != nil {
+		log.WithError(err).Warning("sign failed")
+		return
+	}
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = cm
+	err = requestBP(route.MCCAddTx, addTxReq, addTxResp)
+	if err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = cm.Hash()
+	return
+}
+
+// GetAccountNonce returns the next nonce of the account, the transactions wrapped in a
+// multi-signature transaction should use the nonce of the multi-signature account.
+func GetAccountNonce(addr proto.AccountAddress) (nonce interfaces.AccountNonce, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+	return getNonce(addr)
+}
+
+// SendMultiSigTransaction sends the multi-signature transaction to chain, the transaction should
+// be signed by enough members of the multi-signature account.
+func SendMultiSigTransaction(tx *types.MultiSigTransaction) (txHash hash.Hash, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+	if err = tx.Verify(); err != nil {
+		return
+	}
+
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = tx
+	err = requestBP(route.MCCAddTx, addTxReq, addTxResp)
+	if err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = tx.Hash()
+	return
+}
+
 // TransferToken send Transfer transaction to chain.
 func TransferToken(targetUser proto.AccountAddress, amount uint64, tokenType types.TokenType) (
 	txHash hash.Hash, err error,
@@ -411,6 +714,7 @@ func TransferToken(targetUser proto.AccountAddress, amount uint64, tokenType typ
 		Amount:    amount,
 		TokenType: tokenType,
 		Nonce:     nonce,
+		Fee:       TxFee,
 	})
 	err = tran.Sign(privKey)
 	if err != nil {
@@ -430,6 +734,143 @@ func TransferToken(targetUser proto.AccountAddress, amount uint64, tokenType typ
 	return
 }
 
+// CancelTransaction sends a transaction cancellation to chain, which replaces the pending
+// transaction of the given nonce sent by the local account.
+func CancelTransaction(nonce interfaces.AccountNonce) (txHash hash.Hash, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	var privKey *asymmetric.PrivateKey
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+
+	ct := types.NewCancelTransaction(&types.CancelTransactionHeader{
+		Nonce: nonce,
+		Fee:   TxFee,
+	})
+	if err = ct.Sign(privKey); err != nil {
+		log.WithError(err).Warning("sign failed")
+		return
+	}
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = ct
+	if err = requestBP(route.MCCAddTx, addTxReq, addTxResp); err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = ct.Hash()
+	return
+}
+
+// ReplaceMiner sends a miner replacement transaction to chain, which replaces the old miner of
+// the database with the new miner, or with a provider matched by block producers if the new
+// miner is empty.
+func ReplaceMiner(targetChain, oldMiner, newMiner proto.AccountAddress) (txHash hash.Hash, err error) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	var (
+		pubKey  *asymmetric.PublicKey
+		privKey *asymmetric.PrivateKey
+		addr    proto.AccountAddress
+		nonce   interfaces.AccountNonce
+	)
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if addr, err = crypto.PubKeyHash(pubKey); err != nil {
+		return
+	}
+	if nonce, err = getNonce(addr); err != nil {
+		return
+	}
+
+	rm := types.NewReplaceMiner(&types.ReplaceMinerHeader{
+		TargetSQLChain: targetChain,
+		OldMiner:       oldMiner,
+		NewMiner:       newMiner,
+		Nonce:          nonce,
+		Fee:            TxFee,
+	})
+	if err = rm.Sign(privKey); err != nil {
+		log.WithError(err).Warning("sign failed")
+		return
+	}
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = rm
+	if err = requestBP(route.MCCAddTx, addTxReq, addTxResp); err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = rm.Hash()
+	return
+}
+
+// UpdateDatabaseResources sends a transaction to chain, which scales the database to the new miner
+// node count, reserved space and memory.
+func UpdateDatabaseResources(targetChain proto.AccountAddress, node uint16, space, memory uint64) (
+	txHash hash.Hash, err error,
+) {
+	if atomic.LoadUint32(&driverInitialized) == 0 {
+		err = ErrNotInitialized
+		return
+	}
+
+	var (
+		pubKey  *asymmetric.PublicKey
+		privKey *asymmetric.PrivateKey
+		addr    proto.AccountAddress
+		nonce   interfaces.AccountNonce
+	)
+	if pubKey, err = kms.GetLocalPublicKey(); err != nil {
+		return
+	}
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	if addr, err = crypto.PubKeyHash(pubKey); err != nil {
+		return
+	}
+	if nonce, err = getNonce(addr); err != nil {
+		return
+	}
+
+	ur := types.NewUpdateDatabaseResources(&types.UpdateDatabaseResourcesHeader{
+		TargetSQLChain: targetChain,
+		Node:           node,
+		Space:          space,
+		Memory:         memory,
+		Nonce:          nonce,
+		Fee:            TxFee,
+	})
+	if err = ur.Sign(privKey); err != nil {
+		log.WithError(err).Warning("sign failed")
+		return
+	}
+	addTxReq := new(types.AddTxReq)
+	addTxResp := new(types.AddTxResp)
+	addTxReq.Tx = ur
+	if err = requestBP(route.MCCAddTx, addTxReq, addTxResp); err != nil {
+		log.WithError(err).Warning("send tx failed")
+		return
+	}
+
+	txHash = ur.Hash()
+	return
+}
+
 // WaitTxConfirmation waits for the transaction with target hash txHash to be confirmed. It also
 // returns if any error occurs or a final state is returned from BP.
 func WaitTxConfirmation(
@@ -459,7 +900,8 @@ func WaitTxConfirmation(
 		case interfaces.TransactionStatePacked:
 		case interfaces.TransactionStateConfirmed,
 			interfaces.TransactionStateExpired,
-			interfaces.TransactionStateNotFound:
+			interfaces.TransactionStateNotFound,
+			interfaces.TransactionStateReplaced:
 			return
 		default:
 			err = errors.Errorf("unknown transaction state %d", state)
@@ -488,6 +930,37 @@ func getNonce(addr proto.AccountAddress) (nonce interfaces.AccountNonce, err err
 	return
 }
 
+// verifyStateProof verifies that the header is signed by a known block producer and that the
+// object is included in the state trie committed by the header.
+func verifyStateProof(
+	header *types.BPSignedHeader, obj types.StateObject, proof *merkle.Proof,
+) (err error) {
+	if proof == nil {
+		return errors.Wrap(ErrInvalidStateProof, "missing proof")
+	}
+	if err = header.Verify(); err != nil {
+		return errors.Wrap(ErrInvalidStateProof, err.Error())
+	}
+	var known bool
+	for _, id := range route.GetBPs() {
+		var pub *asymmetric.PublicKey
+		if pub, err = kms.GetPublicKey(id); err != nil {
+			continue
+		}
+		if pub.IsEqual(header.Signee) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return errors.Wrap(ErrInvalidStateProof, "header is not signed by a known block producer")
+	}
+	if err = types.VerifyStateProof(&header.StateRoot, obj, proof); err != nil {
+		return errors.Wrap(ErrInvalidStateProof, err.Error())
+	}
+	return
+}
+
 func requestBP(method route.RemoteFunc, request interface{}, response interface{}) (err error) {
 	var bpNodeID proto.NodeID
 	if bpNodeID, err = rpc.GetCurrentBP(); err != nil {
@@ -626,6 +1099,15 @@ func getPeers(dbID proto.DatabaseID, privKey *asymmetric.PrivateKey) (peers *pro
 			Servers: nodeIDs[:],
 		},
 	}
+	// keep the leader elected by the database peers if it is still serving
+	if rawPeers, ok := peerList.Load(dbID); ok {
+		if cached, ok := rawPeers.(*proto.Peers); ok && cached.Term > 0 {
+			if _, found := peers.Find(cached.Leader); found {
+				peers.Term = cached.Term
+				peers.Leader = cached.Leader
+			}
+		}
+	}
 	err = peers.Sign(privKey)
 	if err != nil {
 		err = errors.Wrap(err, "sign peers failed in getPeers")
@@ -638,6 +1120,26 @@ func getPeers(dbID proto.DatabaseID, privKey *asymmetric.PrivateKey) (peers *pro
 	return
 }
 
+// observeOffset records the applied offset of database seen in a response, the later reads with
+// the read-your-writes option require the state to be applied to at least this offset.
+func observeOffset(dbID proto.DatabaseID, offset uint64) {
+	v, _ := appliedOffsets.LoadOrStore(dbID, new(uint64))
+	p := v.(*uint64)
+	for {
+		current := atomic.LoadUint64(p)
+		if offset <= current || atomic.CompareAndSwapUint64(p, current, offset) {
+			return
+		}
+	}
+}
+
+func lastOffset(dbID proto.DatabaseID) uint64 {
+	if v, ok := appliedOffsets.Load(dbID); ok {
+		return atomic.LoadUint64(v.(*uint64))
+	}
+	return 0
+}
+
 func allocateConnAndSeq() (connID uint64, seqNo uint64) {
 	connIDLock.Lock()
 	defer connIDLock.Unlock()
@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto"
@@ -244,6 +245,52 @@ func TestGetTokenBalance(t *testing.T) {
 	})
 }
 
+func TestGetVerifiedTokenBalance(t *testing.T) {
+	Convey("test get verified token balance", t, func() {
+		var stopTestService func()
+		var err error
+		// reset driver not initialized
+		atomic.StoreUint32(&driverInitialized, 0)
+
+		// driver not initialized
+		_, err = GetVerifiedTokenBalance(types.Particle)
+		So(err, ShouldEqual, ErrNotInitialized)
+
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		var balance uint64
+		balance, err = GetVerifiedTokenBalance(types.Particle)
+		So(err, ShouldBeNil)
+		So(balance, ShouldEqual, stubTokenBalance)
+
+		_, err = GetVerifiedTokenBalance(-1)
+		So(err, ShouldEqual, ErrNoSuchTokenBalance)
+
+		// header signed by an unknown block producer should be rejected
+		var (
+			req      = &types.QueryAccountWithProofReq{}
+			resp     = &types.QueryAccountWithProofResp{}
+			fakeKey  *asymmetric.PrivateKey
+			fakeHead *types.BPBlock
+		)
+		err = requestBP(route.MCCQueryAccountWithProof, req, resp)
+		So(err, ShouldBeNil)
+		err = verifyStateProof(&resp.Header, resp.Account, resp.Proof)
+		So(err, ShouldBeNil)
+		fakeKey, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		fakeHead = &types.BPBlock{SignedHeader: resp.Header}
+		err = fakeHead.PackAndSignBlock(fakeKey)
+		So(err, ShouldBeNil)
+		err = verifyStateProof(&fakeHead.SignedHeader, resp.Account, resp.Proof)
+		So(errors.Cause(err), ShouldEqual, ErrInvalidStateProof)
+		err = verifyStateProof(&resp.Header, resp.Account, nil)
+		So(errors.Cause(err), ShouldEqual, ErrInvalidStateProof)
+	})
+}
+
 func TestWaitDBCreation(t *testing.T) {
 	Convey("test WaitDBCreation", t, func() {
 		var stopTestService func()
@@ -306,6 +353,77 @@ func TestTransferToken(t *testing.T) {
 	})
 }
 
+func TestCancelTransaction(t *testing.T) {
+	Convey("test CancelTransaction of a nonce", t, func() {
+		var stopTestService func()
+		var err error
+
+		// driver not initialized
+		_, err = CancelTransaction(1)
+		So(err, ShouldEqual, ErrNotInitialized)
+
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		// with mock bp, any params will be success
+		txHash, err := CancelTransaction(1)
+		So(err, ShouldBeNil)
+
+		ctx := context.Background()
+		_, err = WaitTxConfirmation(ctx, txHash)
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestReplaceMiner(t *testing.T) {
+	Convey("test ReplaceMiner of a database", t, func() {
+		var stopTestService func()
+		var err error
+		var chain, oldMiner proto.AccountAddress
+
+		// driver not initialized
+		_, err = ReplaceMiner(chain, oldMiner, proto.AccountAddress{})
+		So(err, ShouldEqual, ErrNotInitialized)
+
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		// with mock bp, any params will be success
+		txHash, err := ReplaceMiner(chain, oldMiner, proto.AccountAddress{})
+		So(err, ShouldBeNil)
+
+		ctx := context.Background()
+		_, err = WaitTxConfirmation(ctx, txHash)
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestUpdateDatabaseResources(t *testing.T) {
+	Convey("test UpdateDatabaseResources of a database", t, func() {
+		var stopTestService func()
+		var err error
+		var chain proto.AccountAddress
+
+		// driver not initialized
+		_, err = UpdateDatabaseResources(chain, 2, 0, 0)
+		So(err, ShouldEqual, ErrNotInitialized)
+
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		// with mock bp, any params will be success
+		txHash, err := UpdateDatabaseResources(chain, 2, 0, 0)
+		So(err, ShouldBeNil)
+
+		ctx := context.Background()
+		_, err = WaitTxConfirmation(ctx, txHash)
+		So(err, ShouldBeNil)
+	})
+}
+
 func TestUpdatePermission(t *testing.T) {
 	Convey("test UpdatePermission to a address", t, func() {
 		var stopTestService func()
@@ -334,6 +452,63 @@ func TestUpdatePermission(t *testing.T) {
 	})
 }
 
+func TestUpdateRowPolicies(t *testing.T) {
+	Convey("test UpdateRowPolicies of a database", t, func() {
+		var stopTestService func()
+		var err error
+		var chain proto.AccountAddress
+		policies := []*types.RowPolicy{{Table: "orders", Column: "owner"}}
+
+		// driver not initialized
+		_, err = UpdateRowPolicies(chain, policies)
+		So(err, ShouldEqual, ErrNotInitialized)
+
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		// with mock bp, any params will be success
+		_, err = UpdateRowPolicies(chain, policies)
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestIssueAccessToken(t *testing.T) {
+	Convey("test IssueAccessToken of a database", t, func() {
+		var stopTestService func()
+		var err error
+		var token *types.AccessToken
+		dbID := proto.DatabaseID("db")
+		user := proto.AccountAddress{0x01}
+
+		// driver not initialized
+		_, err = IssueAccessToken(dbID, user, types.Read, time.Hour)
+		So(err, ShouldEqual, ErrNotInitialized)
+
+		stopTestService, _, err = startTestService()
+		So(err, ShouldBeNil)
+		defer stopTestService()
+
+		_, err = IssueAccessToken(dbID, user, types.Read, 0)
+		So(errors.Cause(err), ShouldEqual, types.ErrInvalidAccessToken)
+		_, err = IssueAccessToken(dbID, user, types.Read, types.MaxAccessTokenTTL+time.Second)
+		So(errors.Cause(err), ShouldEqual, types.ErrInvalidAccessToken)
+		token, err = IssueAccessToken(dbID, user, types.Read, time.Hour)
+		So(err, ShouldBeNil)
+		err = token.Check(dbID, user, time.Now())
+		So(err, ShouldBeNil)
+
+		// token should be passed by dsn
+		cfg := NewConfig()
+		cfg.DatabaseID = string(dbID)
+		cfg.AccessToken = token
+		cfg, err = ParseDSN(cfg.FormatDSN())
+		So(err, ShouldBeNil)
+		err = cfg.AccessToken.Check(dbID, user, time.Now())
+		So(err, ShouldBeNil)
+	})
+}
+
 func TestRunPeerListUpdater(t *testing.T) {
 	Convey("test peersUpdaterRunning", t, func() {
 		var stopTestService func()
@@ -16,11 +16,17 @@
 
 package client
 
-import "github.com/pkg/errors"
+import (
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
 
 // Various errors the driver might returns.
 var (
 	// ErrQueryInTransaction represents a read query is presented during user transaction.
+	//
+	// Deprecated: read queries are supported in interactive transactions.
 	ErrQueryInTransaction = errors.New("only write is supported during transaction")
 	// ErrNotInitialized represents the driver is not initialized yet.
 	ErrNotInitialized = errors.New("driver not initialized")
@@ -32,4 +38,18 @@ var (
 	ErrInvalidProfile = errors.New("invalid sqlchain profile")
 	// ErrNoSuchTokenBalance indicates no such token balance in chain.
 	ErrNoSuchTokenBalance = errors.New("no such token balance")
+	// ErrInvalidStateProof indicates the state proof returned by block producer is invalid.
+	ErrInvalidStateProof = errors.New("invalid state proof")
+	// ErrInvalidResultSet indicates the streamed result set does not match its response header.
+	ErrInvalidResultSet = errors.New("invalid streamed result set")
+	// ErrQueryTimeout indicates the query execution is interrupted by the database at its
+	// deadline, which is the earlier one of the context deadline and the max execution time of
+	// the database.
+	ErrQueryTimeout = types.ErrQueryTimeout
+	// ErrStaleState indicates the follower state is staler than required by the read-your-writes
+	// or max staleness option, the reads are redirected to leader if it's available.
+	ErrStaleState = types.ErrStaleState
+	// ErrUntrustedPeers indicates the database peers are not signed by its leader or not served by
+	// the current servers.
+	ErrUntrustedPeers = errors.New("untrusted database peers")
 )
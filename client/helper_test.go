@@ -32,6 +32,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/rpc"
@@ -49,6 +50,8 @@ const (
 var (
 	rootHash                      = hash.Hash{}
 	stubNextNonce pi.AccountNonce = 1
+
+	stubTokenBalance uint64 = 100
 )
 
 // fake BPDB service.
@@ -60,6 +63,37 @@ func (s *stubBPService) QueryAccountTokenBalance(req *types.QueryAccountTokenBal
 	return
 }
 
+func (s *stubBPService) QueryAccountWithProof(req *types.QueryAccountWithProofReq,
+	resp *types.QueryAccountWithProofResp) (err error) {
+	var (
+		privKey *asymmetric.PrivateKey
+		trie    = merkle.NewPatricia()
+		root    *hash.Hash
+		block   *types.BPBlock
+	)
+	resp.Account = &types.Account{Address: req.Addr}
+	resp.Account.TokenBalance[types.Particle] = stubTokenBalance
+	if err = types.InsertStateObject(trie, resp.Account); err != nil {
+		return
+	}
+	if root, resp.Proof, err = trie.Prove(resp.Account.StateKey()); err != nil {
+		return
+	}
+	if privKey, err = kms.GetLocalPrivateKey(); err != nil {
+		return
+	}
+	block = &types.BPBlock{
+		SignedHeader: types.BPSignedHeader{
+			BPHeader: types.BPHeader{StateRoot: *root},
+		},
+	}
+	if err = block.PackAndSignBlock(privKey); err != nil {
+		return
+	}
+	resp.Header = block.SignedHeader
+	return
+}
+
 func (s *stubBPService) QuerySQLChainProfile(req *types.QuerySQLChainProfileReq,
 	resp *types.QuerySQLChainProfileResp) (err error) {
 	var nodeID proto.NodeID
@@ -17,10 +17,15 @@
 package client
 
 import (
+	"context"
 	"database/sql/driver"
 	"io"
 	"strings"
 
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/route"
 	"github.com/CovenantSQL/CovenantSQL/types"
 )
 
@@ -28,6 +33,15 @@ type rows struct {
 	columns []string
 	types   []string
 	data    []types.ResponseRow
+	cursor  *cursor // nil if the whole result set is returned in the response
+}
+
+// cursor fetches the remaining chunks of a streamed result set from the peer.
+type cursor struct {
+	uc      *pconn
+	id      uint64
+	reqHash hash.Hash
+	hasher  *types.PayloadHasher
 }
 
 func newRows(res *types.Response) *rows {
@@ -38,21 +52,107 @@ func newRows(res *types.Response) *rows {
 	}
 }
 
+// newStreamRows returns the rows of a streamed result set, the first chunk is returned in res
+// and the remaining chunks are fetched from uc on demand.
+func newStreamRows(res *types.Response, uc *pconn) (r *rows, err error) {
+	var cur = &cursor{
+		uc:      uc,
+		id:      res.Cursor,
+		reqHash: res.Header.RequestHash,
+	}
+	if cur.hasher, err = types.NewPayloadHasher(
+		res.Payload.Columns, res.Payload.DeclTypes,
+	); err != nil {
+		return
+	}
+	if err = cur.hasher.Write(res.Payload.Rows); err != nil {
+		return
+	}
+	r = newRows(res)
+	r.cursor = cur
+	return
+}
+
+// fetch fetches the next chunk of rows. The running payload hash is verified against the final
+// response header with the last chunk, and the header is acknowledged then.
+func (c *cursor) fetch() (data []types.ResponseRow, err error) {
+	var (
+		parent = c.uc.parent
+		req    = &types.FetchRowsReq{
+			NodeID:     parent.localNodeID,
+			DatabaseID: parent.dbID,
+			Cursor:     c.id,
+		}
+		resp = &types.FetchRowsResp{}
+	)
+	if err = c.uc.pCaller.Call(route.DBSFetchRows.String(), req, resp); err != nil {
+		return
+	}
+	if err = c.hasher.Write(resp.Rows); err != nil {
+		return
+	}
+	if resp.Header == nil {
+		return resp.Rows, nil
+	}
+	// the last chunk
+	c.id = 0
+	var header = resp.Header
+	if err = header.VerifyHash(); err != nil {
+		return
+	}
+	if header.RequestHash != c.reqHash ||
+		header.RowCount != c.hasher.Count() ||
+		header.PayloadHash != c.hasher.Sum() {
+		err = errors.Wrapf(ErrInvalidResultSet, "row count %d, received %d",
+			header.RowCount, c.hasher.Count())
+		return
+	}
+	c.uc.ack(context.Background(), &types.Response{Header: *header})
+	return resp.Rows, nil
+}
+
+// close releases the cursor on the peer if the result set is not fully fetched.
+func (c *cursor) close() (err error) {
+	if c.id == 0 {
+		return
+	}
+	var (
+		parent = c.uc.parent
+		req    = &types.FetchRowsReq{
+			NodeID:     parent.localNodeID,
+			DatabaseID: parent.dbID,
+			Cursor:     c.id,
+			Close:      true,
+		}
+	)
+	c.id = 0
+	return c.uc.pCaller.Call(route.DBSFetchRows.String(), req, &types.FetchRowsResp{})
+}
+
 // Columns implements driver.Rows.Columns method.
 func (r *rows) Columns() []string {
 	return r.columns[:]
 }
 
 // Close implements driver.Rows.Close method.
-func (r *rows) Close() error {
+func (r *rows) Close() (err error) {
 	r.data = nil
-	return nil
+	if r.cursor != nil {
+		err = r.cursor.close()
+		r.cursor = nil
+	}
+	return
 }
 
 // Next implements driver.Rows.Next method.
-func (r *rows) Next(dest []driver.Value) error {
-	if len(r.data) == 0 {
-		return io.EOF
+func (r *rows) Next(dest []driver.Value) (err error) {
+	for len(r.data) == 0 {
+		if r.cursor == nil || r.cursor.id == 0 {
+			return io.EOF
+		}
+		if r.data, err = r.cursor.fetch(); err != nil {
+			return
+		}
 	}
 
 	for i, d := range r.data[0].Values {
@@ -62,7 +162,7 @@ func (r *rows) Next(dest []driver.Value) error {
 	// unshift data
 	r.data = r.data[1:]
 
-	return nil
+	return
 }
 
 // ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName.ColumnTypeDatabaseTypeName method.
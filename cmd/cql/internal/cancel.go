@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"strconv"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/client"
+)
+
+// CmdCancel is cql cancel command entity.
+var CmdCancel = &Command{
+	UsageLine: "cql cancel [-config file] [-wait-tx-confirm] [-fee amount] nonce",
+	Short:     "cancel a pending transaction by its nonce",
+	Long: `
+Cancel command cancels your pending transaction which is not packed into block yet, by sending a
+cancellation transaction with the same nonce. The nonce is consumed once the cancellation is
+confirmed.
+e.g.
+    cql cancel 5
+
+A pending cancellation can only be replaced by another one with a higher fee.
+e.g.
+    cql cancel -fee 10 5
+`,
+}
+
+func init() {
+	CmdCancel.Run = runCancel
+
+	addCommonFlags(CmdCancel)
+	addWaitFlag(CmdCancel)
+	addFeeFlag(CmdCancel)
+}
+
+func runCancel(cmd *Command, args []string) {
+	configInit()
+
+	if len(args) != 1 {
+		ConsoleLog.Error("Cancel command need the nonce of pending transaction as param")
+		SetExitStatus(1)
+		return
+	}
+
+	nonce, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		ConsoleLog.WithError(err).Error("cancel transaction failed: invalid nonce")
+		SetExitStatus(1)
+		return
+	}
+
+	txHash, err := client.CancelTransaction(pi.AccountNonce(nonce))
+	if err != nil {
+		ConsoleLog.WithError(err).Error("cancel transaction failed")
+		SetExitStatus(1)
+		return
+	}
+
+	if waitTxConfirmation {
+		err = wait(txHash)
+		if err != nil {
+			SetExitStatus(1)
+			return
+		}
+	}
+
+	ConsoleLog.Info("succeed in sending transaction to CovenantSQL")
+}
@@ -84,6 +84,10 @@ func addWaitFlag(cmd *Command) {
 	cmd.Flag.BoolVar(&waitTxConfirmation, "wait-tx-confirm", false, "Wait for transaction confirmation")
 }
 
+func addFeeFlag(cmd *Command) {
+	cmd.Flag.Uint64Var(&client.TxFee, "fee", 0, "Transaction fee in Particle paid to block producers")
+}
+
 func wait(txHash hash.Hash) (err error) {
 	var ctx, cancel = context.WithTimeout(context.Background(), waitTxConfirmationMaxDuration)
 	defer cancel()
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"encoding/json"
+
+	"github.com/CovenantSQL/CovenantSQL/client"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+var (
+	coSignKeyFile string // private key file of the new owner to co-sign the transfer
+)
+
+// CmdChown is cql chown command entity.
+var CmdChown = &Command{
+	UsageLine: "cql chown [-config file] [-wait-tx-confirm] [-fee amount] [-cosign-key file] ownership_meta_json",
+	Short:     "transfer the ownership of a database to another account",
+	Long: `
+Chown command transfers the ownership of your database to another account, the billing and
+Admin permission of the current owner are moved to the new owner.
+e.g.
+    cql chown '{"chain":"your_chain_addr","owner":"new_owner_addr"}'
+
+The new owner may co-sign the transaction with its private key file.
+e.g.
+    cql chown -cosign-key new_owner.key '{"chain":"your_chain_addr","owner":"new_owner_addr"}'
+
+Since CovenantSQL is blockchain database, you may want get confirm of ownership transfer.
+e.g.
+    cql chown -wait-tx-confirm '{"chain":"your_chain_addr","owner":"new_owner_addr"}'
+`,
+}
+
+func init() {
+	CmdChown.Run = runChown
+
+	addCommonFlags(CmdChown)
+	addWaitFlag(CmdChown)
+	addFeeFlag(CmdChown)
+	CmdChown.Flag.StringVar(&coSignKeyFile, "cosign-key", "",
+		"Private key file of the new owner to co-sign the transaction, using the same master key")
+}
+
+type dbOwnership struct {
+	TargetChain proto.AccountAddress `json:"chain"`
+	NewOwner    proto.AccountAddress `json:"owner"`
+}
+
+func runChown(cmd *Command, args []string) {
+	configInit()
+
+	if len(args) != 1 {
+		ConsoleLog.Error("Chown command need CovenantSQL ownership meta json string as param")
+		SetExitStatus(1)
+		return
+	}
+
+	var ownership dbOwnership
+	if err := json.Unmarshal([]byte(args[0]), &ownership); err != nil {
+		ConsoleLog.WithError(err).Error("transfer ownership failed: invalid ownership description")
+		SetExitStatus(1)
+		return
+	}
+
+	var coSigner *asymmetric.PrivateKey
+	if coSignKeyFile != "" {
+		var err error
+		if coSigner, err = kms.LoadPrivateKey(
+			utils.HomeDirExpand(coSignKeyFile), []byte(password),
+		); err != nil {
+			ConsoleLog.WithError(err).Error("load co-sign private key file failed")
+			SetExitStatus(1)
+			return
+		}
+	}
+
+	txHash, err := client.TransferDatabaseOwnership(ownership.TargetChain, ownership.NewOwner, coSigner)
+	if err != nil {
+		ConsoleLog.WithError(err).Error("transfer ownership failed")
+		SetExitStatus(1)
+		return
+	}
+
+	if waitTxConfirmation {
+		err = wait(txHash)
+		if err != nil {
+			SetExitStatus(1)
+			return
+		}
+	}
+
+	ConsoleLog.Info("succeed in sending transaction to CovenantSQL")
+}
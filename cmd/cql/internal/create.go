@@ -26,7 +26,7 @@ import (
 
 // CmdCreate is cql create command entity.
 var CmdCreate = &Command{
-	UsageLine: "cql create [-config file] [-wait-tx-confirm] db_meta_json",
+	UsageLine: "cql create [-config file] [-wait-tx-confirm] [-fee amount] db_meta_json",
 	Short:     "create a database",
 	Long: `
 Create CovenantSQL database by database meta info JSON string, meta info must include node count.
@@ -44,6 +44,7 @@ func init() {
 
 	addCommonFlags(CmdCreate)
 	addWaitFlag(CmdCreate)
+	addFeeFlag(CmdCreate)
 }
 
 func runCreate(cmd *Command, args []string) {
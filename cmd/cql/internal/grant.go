@@ -26,13 +26,31 @@ import (
 
 // CmdGrant is cql grant command entity.
 var CmdGrant = &Command{
-	UsageLine: "cql grant [-config file] [-wait-tx-confirm] permission_meta_json",
+	UsageLine: "cql grant [-config file] [-wait-tx-confirm] [-fee amount] permission_meta_json",
 	Short:     "grant a user's permissions on specific sqlchain",
 	Long: `
 Grant command can give a user some specific permissions on your database
 e.g.
     cql grant '{"chain":"your_chain_addr","user":"user_addr","perm":"perm_struct"}'
 
+The perm_struct could be a role string like "Read,Write", or an object with table and column
+privileges, the user is limited to the listed tables and columns (all columns if omitted)
+e.g.
+    cql grant '{"chain":"your_chain_addr","user":"user_addr","perm":{"role":"Read,Write",
+        "tables":[{"table":"orders","actions":"Select,Insert"},
+            {"table":"users","actions":"Select","columns":["id","name"]}]}}'
+
+The user queries could also be limited by patterns, a plain pattern is matched exactly, a "tpl:"
+prefixed template matches the queries of the same normalized form with any literal values, and a
+"re:" prefixed regular expression matches the whole normalized query
+e.g.
+    cql grant '{"chain":"your_chain_addr","user":"user_addr","perm":{"role":"Read",
+        "patterns":["tpl:SELECT * FROM orders WHERE id = ?","re:select name from users limit \\?"]}}'
+
+The permission could also expire at a main chain block height, after which it drops to Void
+e.g.
+    cql grant '{"chain":"your_chain_addr","user":"user_addr","perm":{"role":"Read","expire_height":100000}}'
+
 Since CovenantSQL is blockchain database, you may want get confirm of permission update.
 e.g.
     cql grant -wait-tx-confirm '{"chain":"your_chain_addr","user":"user_addr","perm":"perm_struct"}'
@@ -44,6 +62,7 @@ func init() {
 
 	addCommonFlags(CmdGrant)
 	addWaitFlag(CmdGrant)
+	addFeeFlag(CmdGrant)
 }
 
 type userPermission struct {
@@ -56,8 +75,13 @@ type userPermPayload struct {
 	// User role to access database.
 	Role types.UserPermissionRole `json:"role"`
 	// SQL pattern regulations for user queries
-	// only a fully matched (case-sensitive) sql query is permitted to execute.
+	// only a fully matched (case-sensitive) sql query, or a query matched by the "tpl:" templates
+	// or the "re:" regular expressions, is permitted to execute.
 	Patterns []string `json:"patterns"`
+	// Table and column privileges, the user is limited to the listed tables if it's not empty.
+	Tables []*types.TablePrivilege `json:"tables"`
+	// Main chain height at which the permission expires, zero if it never expires.
+	ExpireHeight uint32 `json:"expire_height"`
 }
 
 func runGrant(cmd *Command, args []string) {
@@ -90,8 +114,10 @@ func runGrant(cmd *Command, args []string) {
 	}
 
 	p := &types.UserPermission{
-		Role:     permPayload.Role,
-		Patterns: permPayload.Patterns,
+		Role:         permPayload.Role,
+		Patterns:     permPayload.Patterns,
+		Tables:       permPayload.Tables,
+		ExpireHeight: permPayload.ExpireHeight,
 	}
 
 	if !p.IsValid() {
@@ -182,7 +182,8 @@ func runRPC(cmd *Command, args []string) {
 			case pi.TransactionStateConfirmed:
 				fmt.Print("✔\n")
 				return
-			case pi.TransactionStateExpired, pi.TransactionStateNotFound:
+			case pi.TransactionStateExpired, pi.TransactionStateNotFound,
+				pi.TransactionStateReplaced:
 				fmt.Print("✘\n")
 				ConsoleLog.Errorf("bad transaction state: %s", resp.State)
 				SetExitStatus(1)
@@ -39,6 +39,8 @@ func init() {
 		internal.CmdWallet,
 		internal.CmdTransfer,
 		internal.CmdGrant,
+		internal.CmdChown,
+		internal.CmdCancel,
 		internal.CmdMirror,
 		internal.CmdExplorer,
 		internal.CmdAdapter,
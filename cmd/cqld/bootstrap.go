@@ -154,6 +154,11 @@ func runNode(nodeID proto.NodeID, listenAddr string) (err error) {
 		Period:         conf.GConf.BPPeriod,
 		Tick:           conf.GConf.BPTick,
 		BlockCacheSize: 1000,
+
+		SnapshotInterval: conf.GConf.BP.SnapshotInterval,
+		FastSync:         conf.GConf.BP.FastSync,
+		MinTxFee:         conf.GConf.BP.MinTxFee,
+		TxPoolSize:       conf.GConf.BP.TxPoolSize,
 	}
 	chain, err := bp.NewChain(chainConfig)
 	if err != nil {
@@ -202,8 +207,9 @@ func loadGenesis() (genesis *types.BPBlock, err error) {
 	genesis = &types.BPBlock{
 		SignedHeader: types.BPSignedHeader{
 			BPHeader: types.BPHeader{
-				Version:   genesisInfo.Version,
-				Timestamp: genesisInfo.Timestamp,
+				Version:     genesisInfo.Version,
+				Timestamp:   genesisInfo.Timestamp,
+				HashVersion: genesisInfo.HashVersion,
 			},
 		},
 	}
@@ -221,7 +227,13 @@ func loadGenesis() (genesis *types.BPBlock, err error) {
 			}))
 	}
 
-	// Rewrite genesis merkle and block hash
+	// Rewrite genesis state root, merkle and block hash, a legacy genesis block keeps its hash
+	// without the state root
+	if genesis.SignedHeader.HashVersion != 0 {
+		if genesis.SignedHeader.StateRoot, err = bp.ComputeGenesisStateRoot(genesis); err != nil {
+			return
+		}
+	}
 	if err = genesis.SetHash(); err != nil {
 		return
 	}
@@ -55,6 +55,9 @@ type BaseAccountInfo struct {
 type BPGenesisInfo struct {
 	// Version defines the block version
 	Version int32 `yaml:"Version"`
+	// HashVersion defines the hash version of the genesis header, the genesis block commits to
+	// its state root only at a non-zero version
+	HashVersion int32 `yaml:"HashVersion"`
 	// Timestamp defines the initial time of chain
 	Timestamp time.Time `yaml:"Timestamp"`
 	// BaseAccounts defines the base accounts for testnet
@@ -75,6 +78,14 @@ type BPInfo struct {
 	ChainFileName string `yaml:"ChainFileName"`
 	// BPGenesis is the genesis block filed
 	BPGenesis BPGenesisInfo `yaml:"BPGenesisInfo,omitempty"`
+	// SnapshotInterval is the block height interval of state snapshots, 0 disables snapshot
+	SnapshotInterval uint32 `yaml:"SnapshotInterval,omitempty"`
+	// FastSync makes a new node initialize from the latest state snapshot of other block producers
+	FastSync bool `yaml:"FastSync,omitempty"`
+	// MinTxFee is the minimum fee of the transactions accepted by the block producer
+	MinTxFee uint64 `yaml:"MinTxFee,omitempty"`
+	// TxPoolSize is the capacity of the pending transaction pool, 0 means the default size
+	TxPoolSize int `yaml:"TxPoolSize,omitempty"`
 }
 
 // MinerDatabaseFixture config.
@@ -19,9 +19,16 @@ package conf
 // This parameters should be kept consistent in all BPs.
 const (
 	DefaultConfirmThreshold = float64(2) / 3.0
+	// SlashReporterRewardPercent is the percentage of the slashed miner deposit rewarded to the
+	// reporter, the rest is burned.
+	SlashReporterRewardPercent = 50
+	// MinerInactiveBlocks is the number of BP blocks without any billing reported by a miner,
+	// after which the other miners of the same SQLChain are allowed to replace it.
+	MinerInactiveBlocks = 1440
 )
 
 // These parameters will not cause inconsistency within certain range.
 const (
 	BPStartupRequiredReachableCount = 2 // NOTE: this includes myself
+	DefaultTxPoolSize               = 100000
 )
@@ -16,16 +16,23 @@
 
 // Package etls implements "Enhanced Transport Layer Security", but more efficient
 // than TLS used in https.
+// The Version2 protocol exchanges ephemeral ECDH keys authenticated by the static shared
+// secret at first, then seals data in AES-GCM records with sequence number nonces.
 // example can be found in test case.
 package etls
 
 import (
+	"encoding/binary"
 	"io"
 	"net"
+	"os"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
 )
@@ -33,13 +40,52 @@ import (
 var (
 	// ETLSMagicBytes is the ETLS connection magic header
 	ETLSMagicBytes = []byte{0xC0, 0x4E}
+	// ETLSMagicBytesV2 is the ETLS connection magic header of Version2
+	ETLSMagicBytesV2 = []byte{0xC0, 0x4F}
 )
 
+const (
+	// maxRecordDataLen is the max plaintext size sealed in a Version2 record.
+	maxRecordDataLen = 16 * 1024
+	// recordHeaderLen is the Version2 record header size with the uint16 sealed data length.
+	recordHeaderLen = 2
+)
+
+// MagicBytes returns the connection magic header of the protocol version.
+func MagicBytes(version Version) []byte {
+	if version == Version2 {
+		return ETLSMagicBytesV2
+	}
+	return ETLSMagicBytes
+}
+
+// ParseMagicBytes returns the protocol version of the connection magic header.
+func ParseMagicBytes(header []byte) (version Version, err error) {
+	switch {
+	case len(header) < 2:
+		err = errors.New("bad ETLS header")
+	case header[0] == ETLSMagicBytes[0] && header[1] == ETLSMagicBytes[1]:
+		version = Version1
+	case header[0] == ETLSMagicBytesV2[0] && header[1] == ETLSMagicBytesV2[1]:
+		version = Version2
+	default:
+		err = errors.New("bad ETLS header")
+	}
+	return
+}
+
 // CryptoConn implements net.Conn and Cipher interface.
 type CryptoConn struct {
 	net.Conn
 	*Cipher
 	NodeID *proto.RawNodeID
+
+	// session states of Version2
+	header        []byte // plaintext connection header authenticated by the hellos
+	handshakeLock sync.Mutex
+	handshaked    bool
+	handshakeErr  error
+	readBuf       []byte // opened record data not read yet
 }
 
 // NewConn returns a new CryptoConn.
@@ -51,6 +97,13 @@ func NewConn(c net.Conn, cipher *Cipher, nodeID *proto.RawNodeID) *CryptoConn {
 	}
 }
 
+// SetHeader sets the plaintext header sent before the connection is encrypted, e.g. the magic
+// bytes, node id and nonce of the dialer. Both sides of a Version2 connection must set the same
+// header before the handshake, so it can not be tampered on the wire.
+func (c *CryptoConn) SetHeader(header []byte) {
+	c.header = header
+}
+
 // Dial connects to a address with a Cipher
 // address should be in the form of host:port.
 func Dial(network, address string, cipher *Cipher) (c *CryptoConn, err error) {
@@ -64,8 +117,118 @@ func Dial(network, address string, cipher *Cipher) (c *CryptoConn, err error) {
 	return
 }
 
+// Handshake exchanges the ephemeral keys with peer if the Version2 session is not established yet,
+// it does nothing for Version1. Read and Write do the handshake automatically, call it explicitly
+// to find out whether the peer supports Version2.
+func (c *CryptoConn) Handshake() (err error) {
+	if c.Version() != Version2 {
+		return
+	}
+	c.handshakeLock.Lock()
+	defer c.handshakeLock.Unlock()
+	if c.handshaked {
+		return c.handshakeErr
+	}
+	c.handshaked = true
+
+	// both sides send hello while reading the other one, there is no round trip to wait
+	var (
+		priv     *asymmetric.PrivateKey
+		local    []byte
+		remote   = make([]byte, helloLen)
+		writeErr = make(chan error, 1)
+	)
+	defer func() { c.handshakeErr = err }()
+	if priv, local, err = c.newHello(c.header); err != nil {
+		return
+	}
+	go func() {
+		_, err := c.Conn.Write(local)
+		writeErr <- err
+	}()
+	var n int
+	if n, err = io.ReadFull(c.Conn, remote); err != nil {
+		if n == 0 && isClosedByPeer(err) {
+			return errors.Wrapf(ErrHandshakeRefused, "read hello failed: %v", err)
+		}
+		return errors.Wrapf(ErrHandshakeFailed, "read hello failed: %v", err)
+	}
+	if err = <-writeErr; err != nil {
+		return errors.Wrapf(ErrHandshakeFailed, "write hello failed: %v", err)
+	}
+	return c.initSession(priv, c.header, local, remote)
+}
+
+// isClosedByPeer reports whether err indicates the connection is closed or reset by peer.
+func isClosedByPeer(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	var opErr, ok = err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	var sysErr, isSys = opErr.Err.(*os.SyscallError)
+	return isSys && sysErr.Err == syscall.ECONNRESET
+}
+
+// readRecord reads Version2 records until some data is opened.
+func (c *CryptoConn) readRecord(b []byte) (n int, err error) {
+	if err = c.Handshake(); err != nil {
+		return
+	}
+	var header = make([]byte, recordHeaderLen)
+	for len(c.readBuf) == 0 {
+		if _, err = io.ReadFull(c.Conn, header); err != nil {
+			return
+		}
+		var sealed = make([]byte, binary.BigEndian.Uint16(header))
+		if _, err = io.ReadFull(c.Conn, sealed); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return
+		}
+		if c.readBuf, err = c.open(sealed[:0], header, sealed); err != nil {
+			return
+		}
+	}
+	n = copy(b, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return
+}
+
+// writeRecord seals the data into Version2 records, each record is written in a single call.
+func (c *CryptoConn) writeRecord(b []byte) (n int, err error) {
+	if err = c.Handshake(); err != nil {
+		return
+	}
+	var overhead = recordHeaderLen + c.encAEAD.Overhead()
+	for len(b) > 0 {
+		var (
+			data   = b
+			record []byte
+		)
+		if len(data) > maxRecordDataLen {
+			data = data[:maxRecordDataLen]
+		}
+		record = make([]byte, recordHeaderLen, len(data)+overhead)
+		binary.BigEndian.PutUint16(record, uint16(len(data)+c.encAEAD.Overhead()))
+		record = c.seal(record, record[:recordHeaderLen], data)
+		if _, err = c.Conn.Write(record); err != nil {
+			return
+		}
+		n += len(data)
+		b = b[len(data):]
+	}
+	return
+}
+
 // Read iv and Encrypted data.
 func (c *CryptoConn) Read(b []byte) (n int, err error) {
+	if c.Version() == Version2 {
+		return c.readRecord(b)
+	}
 	if c.decStream == nil {
 		buf := make([]byte, c.info.ivLen+len(ETLSMagicBytes))
 		if _, err = io.ReadFull(c.Conn, buf); err != nil {
@@ -98,6 +261,9 @@ func (c *CryptoConn) Read(b []byte) (n int, err error) {
 
 // Write iv and Encrypted data.
 func (c *CryptoConn) Write(b []byte) (n int, err error) {
+	if c.Version() == Version2 {
+		return c.writeRecord(b)
+	}
 	var iv []byte
 	if c.encStream == nil {
 		iv, err = c.initEncrypt()
@@ -17,6 +17,7 @@
 package etls
 
 import (
+	"io"
 	"net"
 	"net/rpc"
 	"strings"
@@ -306,3 +307,126 @@ func TestCryptoConn_RW(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestCryptoConnV2(t *testing.T) {
+	var v2CipherHandler CipherHandler = func(conn net.Conn) (cryptoConn *CryptoConn, err error) {
+		cryptoConn = NewConn(conn, NewCipherWithVersion([]byte(pass), Version2), nil)
+		return
+	}
+
+	Convey("server client OK", t, func(c C) {
+		msg := strings.Repeat("x", 3*maxRecordDataLen+1)
+		l, err := NewCryptoListener("tcp", "127.0.0.1:0", v2CipherHandler)
+		So(err, ShouldBeNil)
+		defer l.Close()
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := l.Accept()
+			c.So(err, ShouldBeNil)
+			conn, err = l.CHandler(conn.(*CryptoConn).Conn)
+			c.So(err, ShouldBeNil)
+			defer conn.Close()
+			rBuf := make([]byte, len(msg))
+			_, err = io.ReadFull(conn, rBuf)
+			c.So(err, ShouldBeNil)
+			c.So(string(rBuf), ShouldEqual, msg)
+			n, err := conn.Write(rBuf[:10])
+			c.So(n, ShouldEqual, 10)
+			c.So(err, ShouldBeNil)
+		}()
+		conn, err := Dial("tcp", l.Addr().String(), NewCipherWithVersion([]byte(pass), Version2))
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		err = conn.Handshake()
+		So(err, ShouldBeNil)
+		n, err := conn.Write([]byte(msg))
+		So(n, ShouldEqual, len(msg))
+		So(err, ShouldBeNil)
+		rBuf := make([]byte, 20)
+		n, err = conn.Read(rBuf)
+		So(n, ShouldEqual, 10)
+		So(err, ShouldBeNil)
+		So(string(rBuf[:n]), ShouldEqual, msg[:10])
+		wg.Wait()
+	})
+
+	Convey("pass not match", t, func(c C) {
+		l, err := NewCryptoListener("tcp", "127.0.0.1:0", v2CipherHandler)
+		So(err, ShouldBeNil)
+		defer l.Close()
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := l.Accept()
+			c.So(err, ShouldBeNil)
+			conn, err = l.CHandler(conn.(*CryptoConn).Conn)
+			c.So(err, ShouldBeNil)
+			defer conn.Close()
+			_, err = conn.Read(make([]byte, 1))
+			c.So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+		}()
+		conn, err := Dial("tcp", l.Addr().String(), NewCipherWithVersion([]byte("1234"), Version2))
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		err = conn.Handshake()
+		So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+		_, err = conn.Write([]byte("xxx"))
+		So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+		wg.Wait()
+	})
+
+	Convey("handshake over synchronous pipe", t, func(c C) {
+		server, client := net.Pipe()
+		serverConn := NewConn(server, NewCipherWithVersion([]byte(pass), Version2), nil)
+		clientConn := NewConn(client, NewCipherWithVersion([]byte(pass), Version2), nil)
+		defer serverConn.Close()
+		defer clientConn.Close()
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := clientConn.Write([]byte("hello"))
+			c.So(err, ShouldBeNil)
+		}()
+		rBuf := make([]byte, 5)
+		_, err := io.ReadFull(serverConn, rBuf)
+		So(err, ShouldBeNil)
+		So(string(rBuf), ShouldEqual, "hello")
+		wg.Wait()
+	})
+
+	Convey("peer closes before or in the middle of hello", t, func() {
+		server, client := net.Pipe()
+		clientConn := NewConn(client, NewCipherWithVersion([]byte(pass), Version2), nil)
+		defer clientConn.Close()
+		_ = server.Close()
+		err := clientConn.Handshake()
+		So(errors.Cause(err), ShouldEqual, ErrHandshakeRefused)
+
+		server, client = net.Pipe()
+		clientConn = NewConn(client, NewCipherWithVersion([]byte(pass), Version2), nil)
+		defer clientConn.Close()
+		go func() {
+			_, _ = server.Write(make([]byte, helloLen-1))
+			_ = server.Close()
+		}()
+		err = clientConn.Handshake()
+		So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+	})
+
+	Convey("version of magic bytes", t, func() {
+		v, err := ParseMagicBytes(MagicBytes(Version1))
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, Version1)
+		v, err = ParseMagicBytes(MagicBytes(Version2))
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, Version2)
+		_, err = ParseMagicBytes([]byte{0xC0, 0x00})
+		So(err, ShouldNotBeNil)
+		_, err = ParseMagicBytes(nil)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -19,12 +19,53 @@ package etls
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"io"
 
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 )
 
+// Version is the ETLS protocol version.
+type Version uint8
+
+const (
+	// Version1 encrypts the stream with AES-CFB keyed by the static shared secret, it provides
+	// neither integrity nor forward secrecy.
+	Version1 Version = 1
+	// Version2 seals framed records with AES-GCM keyed by an ephemeral ECDH exchange of each
+	// session, the exchange is authenticated by the static shared secret.
+	Version2 Version = 2
+)
+
+const (
+	// helloLen is the Version2 hello size with ephemeral public key + HMAC of it.
+	helloLen = asymmetric.PublicKeyBytesLen + sha256.Size
+	// aeadKeyLen is the AES-256-GCM key size.
+	aeadKeyLen = 32
+)
+
+var (
+	// ErrHandshakeFailed indicates the Version2 handshake with peer failed, the peer may not
+	// share the same static secret or the connection header may be tampered.
+	ErrHandshakeFailed = errors.New("ETLS handshake failed")
+	// ErrHandshakeRefused indicates the peer closed the connection without sending any byte of
+	// the Version2 hello, it may not support Version2.
+	ErrHandshakeRefused = errors.New("ETLS handshake refused")
+	// ErrBadRecord indicates the Version2 record is malformed or fails authentication.
+	ErrBadRecord = errors.New("bad ETLS record")
+
+	kdfHashSuite = &hash.HashSuite{
+		HashLen:  hash.HashBSize,
+		HashFunc: hash.DoubleHashB,
+	}
+)
+
 // KeyDerivation .according to ANSI X9.63 we should do a key derivation before using
 // it as a symmetric key, there is not really a common standard KDF(Key Derivation Func).
 // But as SSL/TLS/DTLS did it described in "RFC 4492 TLS ECC", we prefer a Double
@@ -73,6 +114,14 @@ func newAESCFBDecStream(key, iv []byte) (cipher.Stream, error) {
 	return newDecStream(block, iv)
 }
 
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 type cipherInfo struct {
 	keyLen       int
 	ivLen        int
@@ -86,26 +135,42 @@ type Cipher struct {
 	decStream cipher.Stream
 	key       []byte
 	info      *cipherInfo
+	version   Version
+
+	// session states of Version2, the sequence numbers are used as nonces
+	encAEAD cipher.AEAD
+	decAEAD cipher.AEAD
+	encSeq  uint64
+	decSeq  uint64
 }
 
-// NewCipher creates a cipher that can be used in Dial(), Listen() etc.
+// NewCipher creates a Version1 cipher that can be used in Dial(), Listen() etc.
 func NewCipher(rawKey []byte) (c *Cipher) {
+	return NewCipherWithVersion(rawKey, Version1)
+}
+
+// NewCipherWithVersion creates a cipher of the protocol version.
+func NewCipherWithVersion(rawKey []byte, version Version) (c *Cipher) {
 	mi := &cipherInfo{
 		32,
 		16,
 		newAESCFBDecStream,
 		newAESCFBEncStream,
 	}
-	hSuite := &hash.HashSuite{
-		HashLen:  hash.HashBSize,
-		HashFunc: hash.DoubleHashB,
-	}
-	key := KeyDerivation(rawKey, mi.keyLen, hSuite)
-	c = &Cipher{key: key, info: mi}
+	key := KeyDerivation(rawKey, mi.keyLen, kdfHashSuite)
+	c = &Cipher{key: key, info: mi, version: version}
 
 	return c
 }
 
+// Version returns the protocol version of the cipher, which is Version1 for a nil cipher.
+func (c *Cipher) Version() Version {
+	if c == nil {
+		return Version1
+	}
+	return c.version
+}
+
 // initEncrypt Initializes the block cipher with CFB mode, returns IV.
 func (c *Cipher) initEncrypt() (iv []byte, err error) {
 	iv = make([]byte, c.info.ivLen)
@@ -128,3 +193,93 @@ func (c *Cipher) encrypt(dst, src []byte) {
 func (c *Cipher) decrypt(dst, src []byte) {
 	c.decStream.XORKeyStream(dst, src)
 }
+
+// helloMAC authenticates the plaintext connection header and the ephemeral public key with the
+// static key.
+func (c *Cipher) helloMAC(header, pub []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(MagicBytes(Version2))
+	mac.Write(header)
+	mac.Write(pub)
+	return mac.Sum(nil)
+}
+
+// newHello generates the ephemeral key pair of a Version2 session, returns the private key and
+// the hello message sent to peer.
+func (c *Cipher) newHello(header []byte) (priv *asymmetric.PrivateKey, hello []byte, err error) {
+	var pub *asymmetric.PublicKey
+	if priv, pub, err = asymmetric.GenSecp256k1KeyPair(); err != nil {
+		return
+	}
+	hello = make([]byte, 0, helloLen)
+	hello = append(hello, pub.Serialize()...)
+	hello = append(hello, c.helloMAC(header, hello)...)
+	return
+}
+
+// initSession verifies the hello message of peer and derives the session keys from the ephemeral
+// shared secret mixed with the static key. Each direction has its own key bound to the ephemeral
+// public key of the sender, so the nonces never repeat under the same key. The hello must be
+// authenticated with the same connection header as the local one.
+func (c *Cipher) initSession(priv *asymmetric.PrivateKey, header, local, remote []byte) (err error) {
+	if len(remote) != helloLen {
+		return errors.Wrap(ErrHandshakeFailed, "invalid hello size")
+	}
+	var (
+		localPub  = local[:asymmetric.PublicKeyBytesLen]
+		remotePub = remote[:asymmetric.PublicKeyBytesLen]
+		pub       *asymmetric.PublicKey
+	)
+	if !hmac.Equal(c.helloMAC(header, remotePub), remote[asymmetric.PublicKeyBytesLen:]) {
+		return errors.Wrap(ErrHandshakeFailed, "hello authentication failed")
+	}
+	if hmac.Equal(localPub, remotePub) {
+		return errors.Wrap(ErrHandshakeFailed, "reflected hello")
+	}
+	if pub, err = asymmetric.ParsePubKey(remotePub); err != nil {
+		return errors.Wrapf(ErrHandshakeFailed, "invalid ephemeral public key: %v", err)
+	}
+	var (
+		secret = asymmetric.GenECDHSharedSecret(priv, pub)
+		derive = func(senderPub []byte) []byte {
+			var raw = make([]byte, 0, len(secret)+len(c.key)+len(senderPub))
+			raw = append(raw, secret...)
+			raw = append(raw, c.key...)
+			raw = append(raw, senderPub...)
+			return KeyDerivation(raw, aeadKeyLen, kdfHashSuite)
+		}
+	)
+	if c.encAEAD, err = newAESGCM(derive(localPub)); err != nil {
+		return
+	}
+	if c.decAEAD, err = newAESGCM(derive(remotePub)); err != nil {
+		return
+	}
+	c.encSeq, c.decSeq = 0, 0
+	return
+}
+
+func (c *Cipher) seqNonce(seq uint64) []byte {
+	var nonce = make([]byte, c.encAEAD.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+// seal encrypts and authenticates the record data with the record header, appends the result to
+// dst.
+func (c *Cipher) seal(dst, header, data []byte) []byte {
+	var nonce = c.seqNonce(c.encSeq)
+	c.encSeq++
+	return c.encAEAD.Seal(dst, nonce, data, header)
+}
+
+// open decrypts and authenticates the sealed record data, appends the result to dst.
+func (c *Cipher) open(dst, header, sealed []byte) (data []byte, err error) {
+	var nonce = c.seqNonce(c.decSeq)
+	if data, err = c.decAEAD.Open(dst, nonce, sealed, header); err != nil {
+		err = errors.Wrapf(ErrBadRecord, "open record %d failed: %v", c.decSeq, err)
+		return
+	}
+	c.decSeq++
+	return
+}
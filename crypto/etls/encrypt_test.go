@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
@@ -37,3 +38,78 @@ func TestKeyDerivation(t *testing.T) {
 		So(dKey, ShouldHaveLength, 100)
 	})
 }
+
+func TestCipherV2(t *testing.T) {
+	Convey("Given two ciphers of an established session", t, func() {
+		var (
+			c1     = NewCipherWithVersion([]byte("pass"), Version2)
+			c2     = NewCipherWithVersion([]byte("pass"), Version2)
+			header = []byte("node id and nonce")
+		)
+		So(c1.Version(), ShouldEqual, Version2)
+		priv1, hello1, err := c1.newHello(header)
+		So(err, ShouldBeNil)
+		So(hello1, ShouldHaveLength, helloLen)
+		priv2, hello2, err := c2.newHello(header)
+		So(err, ShouldBeNil)
+		err = c1.initSession(priv1, header, hello1, hello2)
+		So(err, ShouldBeNil)
+		err = c2.initSession(priv2, header, hello2, hello1)
+		So(err, ShouldBeNil)
+
+		Convey("The sealed records should be opened in order", func() {
+			var (
+				header = []byte{0, 1}
+				r1     = c1.seal(nil, header, []byte("record1"))
+				r2     = c1.seal(nil, header, []byte("record2"))
+			)
+			So(bytes.Equal(r1, r2), ShouldBeFalse)
+			_, err = c2.open(nil, header, r2)
+			So(errors.Cause(err), ShouldEqual, ErrBadRecord)
+			data, err := c2.open(nil, header, r1)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "record1")
+			// replayed record
+			_, err = c2.open(nil, header, r1)
+			So(errors.Cause(err), ShouldEqual, ErrBadRecord)
+			// tampered record
+			r2[0] ^= 0xff
+			_, err = c2.open(nil, header, r2)
+			So(errors.Cause(err), ShouldEqual, ErrBadRecord)
+			r2[0] ^= 0xff
+			_, err = c2.open(nil, []byte{0, 2}, r2)
+			So(errors.Cause(err), ShouldEqual, ErrBadRecord)
+			data, err = c2.open(nil, header, r2)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "record2")
+
+			// records sent by peer are sealed by a different key
+			r3 := c2.seal(nil, header, []byte("record3"))
+			data, err = c1.open(nil, header, r3)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "record3")
+		})
+		Convey("The forged or reflected hello should be rejected", func() {
+			var c3 = NewCipherWithVersion([]byte("other"), Version2)
+			priv3, hello3, err := c3.newHello(header)
+			So(err, ShouldBeNil)
+			err = c1.initSession(priv1, header, hello1, hello3)
+			So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+			err = c3.initSession(priv3, header, hello3, hello1)
+			So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+			err = c1.initSession(priv1, header, hello1, hello1)
+			So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+			err = c1.initSession(priv1, header, hello1, hello2[:helloLen-1])
+			So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+		})
+		Convey("The hello of a different connection header should be rejected", func() {
+			var c3 = NewCipherWithVersion([]byte("pass"), Version2)
+			priv3, hello3, err := c3.newHello([]byte("node id and nonc3"))
+			So(err, ShouldBeNil)
+			err = c1.initSession(priv1, header, hello1, hello3)
+			So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+			err = c3.initSession(priv3, []byte("node id and nonc3"), hello3, hello1)
+			So(errors.Cause(err), ShouldEqual, ErrHandshakeFailed)
+		})
+	})
+}
@@ -0,0 +1,295 @@
+/*
+ * Copyright 2019 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kayak
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	"github.com/CovenantSQL/CovenantSQL/utils/timer"
+	"github.com/CovenantSQL/CovenantSQL/utils/trace"
+)
+
+// applyReq defines a leader apply request awaiting to be folded into a batch.
+type applyReq struct {
+	ctx    context.Context
+	data   interface{}
+	enc    []byte
+	result *commitFuture
+}
+
+func (r *Runtime) doLeaderBatchApply(ctx context.Context, tm *timer.Timer, req interface{}) (
+	result interface{}, logIndex uint64, err error) {
+	defer trace.StartRegion(ctx, "doLeaderBatchApply").End()
+
+	// check and encode in caller routine, so the batch cycle only does the log and rpc works
+	if err = r.doCheck(ctx, req); err != nil {
+		err = errors.Wrap(err, "leader verify log")
+		return
+	}
+
+	tm.Add("leader_check")
+
+	ar := &applyReq{
+		ctx:    ctx,
+		data:   req,
+		result: newCommitFuture(),
+	}
+	if ar.enc, err = r.doEncodePayload(ctx, req); err != nil {
+		err = errors.Wrap(err, "encode kayak payload failed")
+		return
+	}
+
+	tm.Add("leader_encode_payload")
+
+	select {
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "enqueue batch timeout")
+		return
+	case r.applyCh <- ar:
+	}
+
+	tm.Add("queue")
+
+	var cr *commitResult
+	if cr, err = ar.result.Get(ctx); err != nil {
+		return
+	}
+
+	result = cr.result
+	logIndex = cr.index
+	err = cr.err
+
+	tm.Add("batch_commit")
+
+	if cr.rpc != nil {
+		cr.rpc.get(ctx)
+	}
+
+	tm.Add("wait_follower_commit")
+
+	return
+}
+
+// batchCycle folds the queued leader requests into batches. A batch is handed over to the commit
+// cycle once prepared, and the prepare of next batch goes on while the previous one is committing.
+func (r *Runtime) batchCycle() {
+	for {
+		var batch []*applyReq
+
+		select {
+		case <-r.stopCh:
+			return
+		case ar := <-r.applyCh:
+			batch = append(batch, ar)
+		}
+
+	collect:
+		for len(batch) < r.maxBatchSize {
+			select {
+			case ar := <-r.applyCh:
+				batch = append(batch, ar)
+			default:
+				break collect
+			}
+		}
+
+		r.doLeaderBatch(batch)
+	}
+}
+
+func (r *Runtime) doLeaderBatch(batch []*applyReq) {
+	var (
+		ctx        = context.Background()
+		tm         = timer.NewTimer()
+		payloads   = make([][]byte, 0, len(batch))
+		pending    = batch[:0]
+		prepareLog *kt.Log
+		err        error
+	)
+
+	defer func() {
+		var index uint64
+		if prepareLog != nil {
+			index = prepareLog.Index
+		}
+		log.WithFields(log.Fields{
+			"r":    index,
+			"size": len(pending),
+		}).WithFields(tm.ToLogFields()).WithError(err).Debug("kayak leader batch")
+	}()
+
+	// drop the requests which are already canceled by caller
+	for _, ar := range batch {
+		if ar.ctx.Err() != nil {
+			ar.result.Set(&commitResult{err: errors.Wrap(ar.ctx.Err(), "batch request canceled")})
+			continue
+		}
+		pending = append(pending, ar)
+		payloads = append(payloads, ar.enc)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	failBatch := func(err error) {
+		for _, ar := range pending {
+			ar.result.Set(&commitResult{err: err})
+		}
+	}
+
+	// a single request is prepared as normal
+	var (
+		logType = kt.LogPrepare
+		enc     = payloads[0]
+	)
+	if len(payloads) > 1 {
+		logType = kt.LogBatchPrepare
+		if enc, err = encodeBatchPayloads(payloads); err != nil {
+			failBatch(err)
+			return
+		}
+	}
+
+	tm.Add("encode_batch")
+
+	if prepareLog, err = r.newLog(ctx, logType, enc); err != nil {
+		failBatch(err)
+		return
+	}
+
+	r.markPendingPrepare(ctx, prepareLog.Index)
+
+	tm.Add("leader_prepare")
+
+	if err = r.waitFollowerPrepare(ctx, prepareLog); err != nil {
+		r.doLeaderRollback(ctx, tm, prepareLog)
+		r.markPrepareFinished(ctx, prepareLog.Index)
+		failBatch(err)
+		return
+	}
+
+	tm.Add("follower_prepare")
+
+	// the commit cycle resolves the results of all requests in batch
+	req := &commitReq{
+		ctx:    ctx,
+		index:  prepareLog.Index,
+		result: newCommitFuture(),
+		tm:     tm,
+		batch:  pending,
+	}
+
+	select {
+	case <-r.stopCh:
+		r.markPrepareFinished(ctx, prepareLog.Index)
+		err = kt.ErrStopped
+		failBatch(err)
+	case r.commitCh <- req:
+	}
+}
+
+func (r *Runtime) waitFollowerPrepare(ctx context.Context, prepareLog *kt.Log) (err error) {
+	// send prepare to all nodes
+	prepareTracker := r.applyRPC(prepareLog, r.minPreparedFollowers)
+	prepareCtx, prepareCtxCancelFunc := context.WithTimeout(ctx, r.prepareTimeout)
+	defer prepareCtxCancelFunc()
+	prepareErrors, prepareDone, _ := prepareTracker.get(prepareCtx)
+	if !prepareDone {
+		// timeout, rollback
+		err = kt.ErrPrepareTimeout
+		return
+	}
+
+	// collect errors
+	return r.errorSummary(prepareErrors)
+}
+
+func (r *Runtime) leaderDoBatchCommit(req *commitReq, l *kt.Log) {
+	results := make([]*commitResult, len(req.batch))
+	for i, ar := range req.batch {
+		results[i] = &commitResult{index: l.Index}
+		// not wrapping underlying handler commit error
+		results[i].result, results[i].err = r.doCommit(ar.ctx, ar.data, true)
+	}
+
+	req.tm.Add("db_write")
+
+	// mark last commit
+	r.doCommitted(l.Index)
+	atomic.StoreUint64(&r.lastCommit, l.Index)
+	r.markPrepareFinished(req.ctx, req.index)
+
+	// send commit
+	rpc := r.applyRPC(l, r.minCommitFollowers)
+	for i, ar := range req.batch {
+		results[i].rpc = rpc
+		ar.result.Set(results[i])
+	}
+
+	req.tm.Add("send_follower_commit")
+}
+
+func encodeBatchPayloads(payloads [][]byte) (enc []byte, err error) {
+	buf, err := utils.EncodeMsgPack(payloads)
+	if err != nil {
+		err = errors.Wrap(err, "encode batch payloads failed")
+		return
+	}
+	enc = buf.Bytes()
+	return
+}
+
+// decodePrepareLog decodes the requests in the prepare log, which may contain batched requests.
+func (r *Runtime) decodePrepareLog(ctx context.Context, l *kt.Log) (reqs []interface{}, err error) {
+	if l.Type != kt.LogBatchPrepare {
+		var req interface{}
+		if req, err = r.doDecodePayload(ctx, l.Data); err != nil {
+			return
+		}
+		reqs = []interface{}{req}
+		return
+	}
+
+	var payloads [][]byte
+	if err = utils.DecodeMsgPack(l.Data, &payloads); err != nil {
+		err = errors.Wrap(err, "decode batch payloads failed")
+		return
+	}
+	reqs = make([]interface{}, len(payloads))
+	for i, p := range payloads {
+		if reqs[i], err = r.doDecodePayload(ctx, p); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r *Runtime) followerDoBatchCommit(req *commitReq) (err error) {
+	for _, ar := range req.batch {
+		// keep committing the rest of batch, as leader does
+		if _, cerr := r.doCommit(req.ctx, ar.data, false); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return
+}
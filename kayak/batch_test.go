@@ -0,0 +1,312 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kayak_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/kayak"
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
+	kl "github.com/CovenantSQL/CovenantSQL/kayak/wal"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/storage"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+var batchTestNodes = []proto.NodeID{
+	proto.NodeID("000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade"),
+	proto.NodeID("000005f4f22c06f76c43c4f48d5a7ec1309cc94030cbf9ebae814172884ac8b5"),
+}
+
+type closableWal interface {
+	kt.Wal
+	Close()
+}
+
+// newBatchTestRuntimes creates a started leader and follower pair with the given handlers and wals.
+func newBatchTestRuntimes(
+	handlers []kt.Handler, wals []closableWal, maxBatchSize int) (rts []*kayak.Runtime, err error,
+) {
+	peers := &proto.Peers{
+		PeersHeader: proto.PeersHeader{
+			Leader:  batchTestNodes[0],
+			Servers: batchTestNodes,
+		},
+	}
+	privKey, _, err := asymmetric.GenSecp256k1KeyPair()
+	if err != nil {
+		return
+	}
+	if err = peers.Sign(privKey); err != nil {
+		return
+	}
+
+	m := newFakeMux()
+	rts = make([]*kayak.Runtime, len(batchTestNodes))
+	for i, node := range batchTestNodes {
+		if rts[i], err = kayak.NewRuntime(&kt.RuntimeConfig{
+			Handler:          handlers[i],
+			PrepareThreshold: 1.0,
+			CommitThreshold:  1.0,
+			PrepareTimeout:   time.Second,
+			CommitTimeout:    10 * time.Second,
+			LogWaitTimeout:   10 * time.Second,
+			Peers:            peers,
+			Wal:              wals[i],
+			NodeID:           node,
+			ServiceName:      "Test",
+			ApplyMethodName:  "Apply",
+			FetchMethodName:  "Fetch",
+			MaxBatchSize:     maxBatchSize,
+		}); err != nil {
+			return
+		}
+		m.register(node, newFakeService(rts[i]))
+	}
+	rts[0].SetCaller(batchTestNodes[1], newFakeCaller(m, batchTestNodes[0], batchTestNodes[1]))
+	rts[1].SetCaller(batchTestNodes[0], newFakeCaller(m, batchTestNodes[1], batchTestNodes[0]))
+	for _, rt := range rts {
+		if err = rt.Start(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func TestBatchApply(t *testing.T) {
+	Convey("test batched apply", t, func() {
+		var (
+			dsns     = []string{"testBatch1.db", "testBatch2.db"}
+			dbs      = make([]*sqliteStorage, len(dsns))
+			handlers = make([]kt.Handler, len(dsns))
+			wals     = make([]closableWal, len(dsns))
+			err      error
+		)
+		for i, dsn := range dsns {
+			dbs[i], err = newSQLiteStorage(dsn)
+			So(err, ShouldBeNil)
+			handlers[i] = dbs[i]
+			wals[i], err = kl.NewLevelDBWal(dsn + ".ldb")
+			So(err, ShouldBeNil)
+		}
+		defer func() {
+			for i, dsn := range dsns {
+				dbs[i].Close()
+				wals[i].Close()
+				os.Remove(dsn)
+				os.RemoveAll(dsn + ".ldb")
+			}
+		}()
+
+		rts, err := newBatchTestRuntimes(handlers, wals, 16)
+		So(err, ShouldBeNil)
+
+		_, _, err = rts[0].Apply(context.Background(), &queryStructure{
+			Queries: []storage.Query{
+				{Pattern: "CREATE TABLE IF NOT EXISTS test (t1 text)"},
+			},
+		})
+		So(err, ShouldBeNil)
+
+		// concurrent requests are folded into batches
+		var (
+			wg       sync.WaitGroup
+			failures int32
+			indexes  sync.Map
+		)
+		for i := 0; i != 200; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				q := &queryStructure{
+					Queries: []storage.Query{
+						{Pattern: "INSERT INTO test VALUES(?)", Args: []sql.NamedArg{sql.Named("", fmt.Sprint(i))}},
+					},
+				}
+				if i%50 == 0 {
+					// failure of a request should not affect other requests in batch
+					q.Queries[0].Pattern = "INVALID QUERY"
+				}
+				_, index, err := rts[0].Apply(context.Background(), q)
+				if err != nil {
+					atomic.AddInt32(&failures, 1)
+					return
+				}
+				indexes.Store(index, true)
+			}(i)
+		}
+		wg.Wait()
+		So(atomic.LoadInt32(&failures), ShouldEqual, 4)
+
+		var logs int
+		indexes.Range(func(_, _ interface{}) bool {
+			logs++
+			return true
+		})
+		So(logs, ShouldBeLessThanOrEqualTo, 196)
+
+		for _, db := range dbs {
+			var count string
+			for i := 0; i != 50; i++ {
+				_, _, d, err := db.Query(context.Background(), []storage.Query{
+					{Pattern: "SELECT COUNT(1) FROM test"},
+				})
+				if err == nil && len(d) == 1 {
+					if count = fmt.Sprint(d[0][0]); count == "196" {
+						break
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			So(count, ShouldEqual, "196")
+		}
+
+		// batched logs are reloaded after restart
+		for i, rt := range rts {
+			So(rt.Shutdown(), ShouldBeNil)
+			wals[i].Close()
+			wals[i], err = kl.NewLevelDBWal(dsns[i] + ".ldb")
+			So(err, ShouldBeNil)
+		}
+		rts, err = newBatchTestRuntimes(handlers, wals, 16)
+		So(err, ShouldBeNil)
+		defer func() {
+			for _, rt := range rts {
+				rt.Shutdown()
+			}
+		}()
+		_, _, err = rts[0].Apply(context.Background(), &queryStructure{
+			Queries: []storage.Query{
+				{Pattern: "INSERT INTO test VALUES(?)", Args: []sql.NamedArg{sql.Named("", "last")}},
+			},
+		})
+		So(err, ShouldBeNil)
+	})
+}
+
+// nopHandler commits nothing, the benchmarks measure the overhead of kayak only.
+type nopHandler struct{}
+
+func (h *nopHandler) EncodePayload(request interface{}) ([]byte, error) {
+	return request.([]byte), nil
+}
+
+func (h *nopHandler) DecodePayload(data []byte) (interface{}, error) {
+	return data, nil
+}
+
+func (h *nopHandler) Check(request interface{}) error {
+	return nil
+}
+
+func (h *nopHandler) Commit(request interface{}, isLeader bool) (interface{}, error) {
+	return nil, nil
+}
+
+func benchmarkApply(b *testing.B, newWal func(name string) (closableWal, error), maxBatchSize int) {
+	var (
+		handlers = []kt.Handler{&nopHandler{}, &nopHandler{}}
+		wals     = make([]closableWal, len(handlers))
+		dir      string
+		err      error
+	)
+	// sub-benchmark names contain slashes, keep the logs out of the source tree
+	if dir, err = ioutil.TempDir("", "kayak_bench"); err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i := range wals {
+		if wals[i], err = newWal(filepath.Join(dir, fmt.Sprintf("%d.ldb", i))); err != nil {
+			b.Fatal(err)
+		}
+	}
+	defer func() {
+		for i := range wals {
+			wals[i].Close()
+		}
+	}()
+
+	rts, err := newBatchTestRuntimes(handlers, wals, maxBatchSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		for _, rt := range rts {
+			rt.Shutdown()
+		}
+	}()
+
+	payload := []byte(RandStringRunes(256))
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := rts[0].Apply(context.Background(), payload); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+	b.StopTimer()
+}
+
+func BenchmarkBatchApply(b *testing.B) {
+	lvl := log.GetLevel()
+	log.SetLevel(log.FatalLevel)
+	defer log.SetLevel(lvl)
+
+	var (
+		walTypes = []struct {
+			name   string
+			newWal func(name string) (closableWal, error)
+		}{
+			{
+				name: "MemWal",
+				newWal: func(string) (closableWal, error) {
+					return kl.NewMemWal(), nil
+				},
+			},
+			{
+				name: "LevelDBWal",
+				newWal: func(name string) (closableWal, error) {
+					return kl.NewLevelDBWal(name)
+				},
+			},
+		}
+		batchSizes = []int{0, 16, 64}
+	)
+
+	for _, w := range walTypes {
+		for _, size := range batchSizes {
+			b.Run(fmt.Sprintf("%s/Batch%d", w.name, size), func(b *testing.B) {
+				benchmarkApply(b, w.newWal, size)
+			})
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
 	"github.com/CovenantSQL/CovenantSQL/utils/trace"
 )
 
@@ -53,3 +54,9 @@ func (r *Runtime) doCommit(ctx context.Context, req interface{}, isLeader bool)
 	defer trace.StartRegion(ctx, "commitCallback").End()
 	return r.sh.Commit(req, isLeader)
 }
+
+func (r *Runtime) doCommitted(index uint64) {
+	if h, ok := r.sh.(kt.CommitIndexHandler); ok {
+		h.Committed(index)
+	}
+}
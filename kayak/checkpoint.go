@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kayak
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+// Checkpoint truncates the logs before index which are not referenced by any following logs, a
+// checkpoint log is saved in place of the truncated logs, so the restart of runtime replays logs
+// since the checkpoint. The index should be the last log index whose data is already persisted by
+// the handler, the logs after the last commit and all pending prepares are always kept.
+func (r *Runtime) Checkpoint(index uint64) (err error) {
+	if atomic.LoadUint32(&r.started) != 1 {
+		err = kt.ErrStopped
+		return
+	}
+
+	r.checkpointLock.Lock()
+	defer r.checkpointLock.Unlock()
+
+	// first index to keep
+	lastCommit := atomic.LoadUint64(&r.lastCommit)
+	base := lastCommit
+	if index < base {
+		base = index
+	}
+	r.pendingPreparesLock.RLock()
+	for i := range r.pendingPrepares {
+		if i < base {
+			base = i
+		}
+	}
+	r.pendingPreparesLock.RUnlock()
+
+	if base <= atomic.LoadUint64(&r.logBase) || base == 0 {
+		// nothing to truncate
+		return
+	}
+
+	l := &kt.Log{
+		LogHeader: kt.LogHeader{
+			Index:    base - 1,
+			Type:     kt.LogCheckpoint,
+			Producer: r.nodeID,
+		},
+		Data: r.uint64ToBytes(lastCommit),
+	}
+	if err = r.wal.Truncate(l); err != nil {
+		err = errors.Wrap(err, "truncate wal failed")
+		return
+	}
+
+	atomic.StoreUint64(&r.logBase, base)
+
+	log.WithFields(log.Fields{
+		"instance":   r.instanceID,
+		"checkpoint": l.Index,
+		"lastCommit": lastCommit,
+	}).Debug("kayak checkpoint")
+
+	return
+}
@@ -83,9 +83,9 @@ func (r *Runtime) followerCommitResult(ctx context.Context, tm *timer.Timer, com
 	}
 
 	// decode prepare log
-	var logReq interface{}
+	var logReqs []interface{}
 	var err error
-	if logReq, err = r.doDecodePayload(ctx, prepareLog.Data); err != nil {
+	if logReqs, err = r.decodePrepareLog(ctx, prepareLog); err != nil {
 		res.Set(&commitResult{err: errors.Wrap(err, "decode log payload failed")})
 		return
 	}
@@ -94,13 +94,20 @@ func (r *Runtime) followerCommitResult(ctx context.Context, tm *timer.Timer, com
 
 	req := &commitReq{
 		ctx:        ctx,
-		data:       logReq,
 		index:      prepareLog.Index,
 		lastCommit: lastCommit,
 		result:     res,
 		log:        commitLog,
 		tm:         tm,
 	}
+	if prepareLog.Type == kt.LogBatchPrepare {
+		req.batch = make([]*applyReq, len(logReqs))
+		for i := range logReqs {
+			req.batch[i] = &applyReq{ctx: ctx, data: logReqs[i]}
+		}
+	} else {
+		req.data = logReqs[0]
+	}
 
 	select {
 	case <-ctx.Done():
@@ -153,12 +160,18 @@ func (r *Runtime) leaderDoCommit(req *commitReq) {
 
 	req.tm.Add("write_wal")
 
+	if req.batch != nil {
+		r.leaderDoBatchCommit(req, l)
+		return
+	}
+
 	// not wrapping underlying handler commit error
 	cr.result, err = r.doCommit(req.ctx, req.data, true)
 
 	req.tm.Add("db_write")
 
 	// mark last commit
+	r.doCommitted(l.Index)
 	atomic.StoreUint64(&r.lastCommit, l.Index)
 
 	// send commit
@@ -187,6 +200,14 @@ func (r *Runtime) followerDoCommit(req *commitReq) {
 
 	// check for last commit availability
 	myLastCommit := atomic.LoadUint64(&r.lastCommit)
+	if req.lastCommit < myLastCommit {
+		// already covered by an installed snapshot
+		waitCommitTask.End()
+		req.result.Set(&commitResult{
+			err: errors.Wrap(kt.ErrInvalidLog, "commit before last commit"),
+		})
+		return
+	}
 	if req.lastCommit != myLastCommit {
 		// TODO(): need counter for retries, infinite commit re-order would cause troubles
 		go func(req *commitReq) {
@@ -212,12 +233,18 @@ func (r *Runtime) followerDoCommit(req *commitReq) {
 	req.tm.Add("write_wal")
 
 	// do commit, not wrapping underlying handler commit error
-	_, err = r.doCommit(req.ctx, req.data, false)
+	if req.batch != nil {
+		err = r.followerDoBatchCommit(req)
+	} else {
+		_, err = r.doCommit(req.ctx, req.data, false)
+	}
 
 	req.tm.Add("db_write")
 
 	// mark last commit
+	r.doCommitted(req.log.Index)
 	atomic.StoreUint64(&r.lastCommit, req.log.Index)
+	r.checkSynced()
 
 	req.result.Set(&commitResult{
 		err: err,
@@ -246,7 +273,16 @@ func (r *Runtime) getPrepareLog(ctx context.Context, l *kt.Log) (lastCommitIndex
 	if len(l.Data) >= 16 {
 		lastCommitIndex, _ = r.bytesToUint64(l.Data[8:])
 
+		if lastCommitIndex <= atomic.LoadUint64(&r.lastCommit) {
+			// already committed, the log may be truncated by checkpoint
+			return
+		}
 		if _, err = r.waitForLog(ctx, lastCommitIndex); err != nil {
+			if lastCommitIndex <= atomic.LoadUint64(&r.lastCommit) {
+				// committed by an installed snapshot during waiting
+				err = nil
+				return
+			}
 			err = errors.Wrap(err, "wait for last commit log failed")
 			return
 		}
@@ -258,6 +294,8 @@ func (r *Runtime) getPrepareLog(ctx context.Context, l *kt.Log) (lastCommitIndex
 func (r *Runtime) doCommitCycle(req *commitReq) {
 	r.peersLock.RLock()
 	defer r.peersLock.RUnlock()
+	r.commitLock.Lock()
+	defer r.commitLock.Unlock()
 
 	if r.role == proto.Leader {
 		defer trace.StartRegion(req.ctx, "commitCycle").End()
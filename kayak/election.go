@@ -0,0 +1,412 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kayak
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/rpc"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+// Leader election works in the raft style. The leader sends heartbeats carrying the signed peers
+// of its term to followers periodically. A follower which has not heard from the leader for an
+// election timeout starts an election of the next term, and becomes the leader with the votes of
+// the majority of peers. A peer votes at most once per term, and only for a candidate whose
+// committed log and last log are not behind its own while the lease of the current leader has
+// expired. On a term change, the uncommitted prepares of the deposed leader are dropped, since
+// the new leader never commits them and reuses their indexes.
+//
+// The peers of a term are signed by its leader. A follower only accepts the peers sent by the
+// leader itself, which keep the current servers unchanged, since the membership is only changed
+// by block producers.
+
+// senderOf returns the node id of the rpc caller carried by the request envelope.
+func senderOf(req proto.EnvelopeAPI) (id proto.NodeID) {
+	if raw := req.GetNodeID(); raw != nil {
+		id = raw.ToNodeID()
+	}
+	return
+}
+
+// resolvePublicKey returns the public key of node from kms or block producers.
+func resolvePublicKey(id proto.NodeID) (key *asymmetric.PublicKey, err error) {
+	var node *proto.Node
+	if node, err = rpc.GetNodeInfo(id.ToRawNodeID()); err == nil {
+		key = node.PublicKey
+	}
+	return
+}
+
+// verifyLeaderPeers verifies that the peers are signed by its leader.
+func (r *Runtime) verifyLeaderPeers(peers *proto.Peers) (err error) {
+	if err = peers.Verify(); err != nil {
+		return
+	}
+	key, err := r.publicKeyOf(peers.Leader)
+	if err != nil {
+		return errors.Wrapf(err, "get public key of leader %s failed", peers.Leader)
+	}
+	if peers.Signee == nil || !peers.Signee.IsEqual(key) {
+		return errors.Wrapf(kt.ErrUntrustedPeers, "peers not signed by leader %s", peers.Leader)
+	}
+	return
+}
+
+func sameServers(a, b []proto.NodeID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].IsEqual(&b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Runtime) touchLeader() {
+	atomic.StoreInt64(&r.leaderSeen, time.Now().UnixNano())
+}
+
+// seeHeartbeat records the last commit index of leader carried by a heartbeat.
+func (r *Runtime) seeHeartbeat(leaderCommit uint64) {
+	r.syncLock.Lock()
+	defer r.syncLock.Unlock()
+	r.heartbeatSeen = time.Now().UnixNano()
+	r.heartbeatCommit = leaderCommit
+	r.checkSyncedLocked()
+}
+
+// checkSynced updates the synced time if the committed logs catch up with the last heartbeat.
+func (r *Runtime) checkSynced() {
+	r.syncLock.Lock()
+	defer r.syncLock.Unlock()
+	r.checkSyncedLocked()
+}
+
+func (r *Runtime) checkSyncedLocked() {
+	if r.heartbeatSeen > r.syncedAt && atomic.LoadUint64(&r.lastCommit) >= r.heartbeatCommit {
+		r.syncedAt = r.heartbeatSeen
+	}
+}
+
+// SyncedAt returns the last time when the committed logs of current node were known to catch up
+// with the leader, which bounds the staleness of follower reads. It's always the current time for
+// the leader itself, and zero if the follower has not heard from the leader.
+func (r *Runtime) SyncedAt() time.Time {
+	r.peersLock.RLock()
+	role := r.role
+	r.peersLock.RUnlock()
+	if role == proto.Leader {
+		return time.Now()
+	}
+	r.syncLock.Lock()
+	defer r.syncLock.Unlock()
+	if r.syncedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, r.syncedAt)
+}
+
+func (r *Runtime) isLeaderLeaseValid(timeout time.Duration) bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&r.leaderSeen))) < timeout
+}
+
+func (r *Runtime) randomElectionTimeout() time.Duration {
+	// randomize to avoid split votes
+	return r.electionTimeout + time.Duration(rand.Int63n(int64(r.electionTimeout)))
+}
+
+func (r *Runtime) electionCycle() {
+	var (
+		ticker  = time.NewTicker(r.heartbeatInterval)
+		timeout = r.randomElectionTimeout()
+	)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		r.peersLock.RLock()
+		role := r.role
+		r.peersLock.RUnlock()
+
+		if role == proto.Leader {
+			r.sendHeartbeats()
+			continue
+		}
+		if r.isLeaderLeaseValid(timeout) {
+			continue
+		}
+		if err := r.elect(); err != nil {
+			log.WithField("instance", r.instanceID).WithError(err).Debug("leader election failed")
+		}
+		// wait for another timeout before next election
+		r.touchLeader()
+		timeout = r.randomElectionTimeout()
+	}
+}
+
+// sendHeartbeats sends the peers of current term to followers, and renews the leader lease if
+// the majority of peers respond.
+func (r *Runtime) sendHeartbeats() {
+	r.peersLock.RLock()
+	var (
+		peers     = r.peers
+		followers = append([]proto.NodeID(nil), r.followers...)
+		req       = &kt.HeartbeatRequest{
+			Instance:   r.instanceID,
+			Peers:      peers,
+			LastCommit: atomic.LoadUint64(&r.lastCommit),
+		}
+		acked = int32(1) // leader itself
+		wg    sync.WaitGroup
+	)
+	r.peersLock.RUnlock()
+
+	for _, node := range followers {
+		wg.Add(1)
+		go func(node proto.NodeID) {
+			defer wg.Done()
+			if err := r.getCaller(node).Call(r.heartbeatRPCMethod, req, nil); err != nil {
+				log.WithFields(log.Fields{
+					"instance": r.instanceID,
+					"node":     node,
+				}).WithError(err).Debug("send heartbeat failed")
+				return
+			}
+			atomic.AddInt32(&acked, 1)
+		}(node)
+	}
+	wg.Wait()
+
+	if int(acked)*2 > len(peers.Servers) {
+		r.touchLeader()
+	}
+}
+
+// elect starts an election of the next term, and updates the peers with current node as leader
+// if elected.
+func (r *Runtime) elect() (err error) {
+	if r.privKey == nil {
+		return errors.Wrap(kt.ErrInvalidConfig, "no private key to sign peers")
+	}
+
+	peers := r.Peers()
+
+	r.voteLock.Lock()
+	if r.votedTerm < peers.Term {
+		r.votedTerm = peers.Term
+	}
+	r.votedTerm++
+	term := r.votedTerm
+	r.voteLock.Unlock()
+
+	var (
+		lastIndex, lastTerm = r.lastLog()
+
+		req = &kt.VoteRequest{
+			Instance:   r.instanceID,
+			Term:       term,
+			Candidate:  r.nodeID,
+			LastCommit: atomic.LoadUint64(&r.lastCommit),
+			LastIndex:  lastIndex,
+			LastTerm:   lastTerm,
+		}
+		granted = int32(1) // vote for myself
+		wg      sync.WaitGroup
+	)
+	for _, node := range peers.Servers {
+		if node.IsEqual(&r.nodeID) {
+			continue
+		}
+		wg.Add(1)
+		go func(node proto.NodeID) {
+			defer wg.Done()
+			resp := &kt.VoteResponse{}
+			if err := r.getCaller(node).Call(r.voteRPCMethod, req, resp); err != nil {
+				log.WithFields(log.Fields{
+					"instance": r.instanceID,
+					"node":     node,
+				}).WithError(err).Debug("request vote failed")
+				return
+			}
+			if resp.Granted {
+				atomic.AddInt32(&granted, 1)
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	if int(granted)*2 <= len(peers.Servers) {
+		return errors.Wrapf(kt.ErrElectionFailed, "got %d votes of %d peers in term %d",
+			granted, len(peers.Servers), term)
+	}
+
+	newPeers := &proto.Peers{
+		PeersHeader: proto.PeersHeader{
+			Version: peers.Version,
+			Term:    term,
+			Leader:  r.nodeID,
+			Servers: append([]proto.NodeID(nil), peers.Servers...),
+		},
+	}
+	if err = newPeers.Sign(r.privKey); err != nil {
+		return
+	}
+	if err = r.UpdatePeers(newPeers); err != nil {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"instance": r.instanceID,
+		"term":     term,
+	}).Info("elected as leader")
+
+	r.touchLeader()
+	r.sendHeartbeats()
+	return
+}
+
+// Vote handles the vote request from an election candidate.
+func (r *Runtime) Vote(req *kt.VoteRequest) (granted bool, term uint64, err error) {
+	if sender := senderOf(&req.Envelope); !sender.IsEqual(&req.Candidate) {
+		err = errors.Wrapf(kt.ErrInvalidSender, "vote request of %s sent by %s", req.Candidate, sender)
+		return
+	}
+
+	r.peersLock.RLock()
+	var (
+		current   = r.peers.Term
+		leader    = r.peers.Leader
+		_, isPeer = r.peers.Find(req.Candidate)
+	)
+	r.peersLock.RUnlock()
+
+	if !isPeer {
+		err = errors.Wrapf(kt.ErrNotInPeer, "candidate %s not in peers", req.Candidate)
+		return
+	}
+
+	lastIndex, lastTerm := r.lastLog()
+
+	r.voteLock.Lock()
+	defer r.voteLock.Unlock()
+
+	switch {
+	case req.Term <= current || req.Term <= r.votedTerm:
+		// already voted in this term
+	case !leader.IsEqual(&req.Candidate) && r.electionTimeout > 0 &&
+		r.isLeaderLeaseValid(r.electionTimeout):
+		// current leader is still alive
+	case req.LastCommit < atomic.LoadUint64(&r.lastCommit):
+		// candidate log is behind
+	case req.LastTerm < lastTerm || (req.LastTerm == lastTerm && req.LastIndex < lastIndex):
+		// candidate last log is behind
+	default:
+		r.votedTerm = req.Term
+		granted = true
+	}
+
+	term = r.votedTerm
+	if term < current {
+		term = current
+	}
+	return
+}
+
+// Heartbeat handles the heartbeat from leader, the peers of a new term are applied.
+func (r *Runtime) Heartbeat(req *kt.HeartbeatRequest) (err error) {
+	if req.Peers == nil {
+		return errors.Wrap(kt.ErrInvalidConfig, "nil peers")
+	}
+	if sender := senderOf(&req.Envelope); !sender.IsEqual(&req.Peers.Leader) {
+		return errors.Wrapf(kt.ErrInvalidSender, "heartbeat of leader %s sent by %s",
+			req.Peers.Leader, sender)
+	}
+	if err = r.verifyLeaderPeers(req.Peers); err != nil {
+		return errors.Wrap(err, "verify peers of heartbeat failed")
+	}
+
+	current := r.Peers()
+	if req.Peers.Term < current.Term {
+		return errors.Wrapf(kt.ErrStaleTerm, "heartbeat of term %d, current term %d",
+			req.Peers.Term, current.Term)
+	}
+	if !sameServers(req.Peers.Servers, current.Servers) {
+		return errors.Wrap(kt.ErrUntrustedPeers, "servers of heartbeat not match")
+	}
+	if !req.Peers.DataHash.IsEqual(&current.DataHash) {
+		if err = r.updatePeers(req.Peers, req.LastCommit); err != nil {
+			return
+		}
+		if req.Peers.Term > current.Term || !req.Peers.Leader.IsEqual(&current.Leader) {
+			log.WithFields(log.Fields{
+				"instance": r.instanceID,
+				"term":     req.Peers.Term,
+				"leader":   req.Peers.Leader,
+			}).Info("follow new leader")
+		}
+	}
+
+	r.touchLeader()
+	r.seeHeartbeat(req.LastCommit)
+	return
+}
+
+// dropStalePrepares removes the uncommitted prepares of the terms before term above leaderCommit,
+// the caller should hold the peers lock. The new leader never commits these prepares of the
+// deposed leader, and the indexes they take would conflict with the new logs of the new leader.
+func (r *Runtime) dropStalePrepares(term, leaderCommit uint64) {
+	r.pendingPreparesLock.Lock()
+	defer r.pendingPreparesLock.Unlock()
+
+	for i := range r.pendingPrepares {
+		if i <= leaderCommit {
+			continue
+		}
+		if l, err := r.wal.Get(i); err == nil && l.Term >= term {
+			continue
+		}
+		if err := r.wal.Remove(i); err != nil {
+			log.WithFields(log.Fields{
+				"instance": r.instanceID,
+				"index":    i,
+			}).WithError(err).Warning("remove stale prepare failed")
+			continue
+		}
+		delete(r.pendingPrepares, i)
+		log.WithFields(log.Fields{
+			"instance": r.instanceID,
+			"index":    i,
+			"term":     term,
+		}).Debug("stale prepare dropped")
+	}
+}
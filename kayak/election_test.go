@@ -0,0 +1,375 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kayak_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/kayak"
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
+	kl "github.com/CovenantSQL/CovenantSQL/kayak/wal"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/storage"
+)
+
+func TestElection(t *testing.T) {
+	Convey("leader election test", t, func() {
+		nodes := []proto.NodeID{
+			proto.NodeID("000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade"),
+			proto.NodeID("000005f4f22c06f76c43c4f48d5a7ec1309cc94030cbf9ebae814172884ac8b5"),
+			proto.NodeID("000003f49592f83d0473bddb70d543f1096b4ffed5e5f942a3117e256b7052b8"),
+		}
+		peers := &proto.Peers{
+			PeersHeader: proto.PeersHeader{
+				Leader:  nodes[0],
+				Servers: nodes,
+			},
+		}
+		var (
+			privKeys = make([]*asymmetric.PrivateKey, len(nodes))
+			pubKeys  = make(map[proto.NodeID]*asymmetric.PublicKey)
+			err      error
+		)
+		for i, node := range nodes {
+			privKeys[i], pubKeys[node], err = asymmetric.GenSecp256k1KeyPair()
+			So(err, ShouldBeNil)
+		}
+		resolver := func(id proto.NodeID) (*asymmetric.PublicKey, error) {
+			if key, ok := pubKeys[id]; ok {
+				return key, nil
+			}
+			return nil, errors.New("unknown node")
+		}
+		err = peers.Sign(privKeys[0])
+		So(err, ShouldBeNil)
+
+		var (
+			m   = newFakeMux()
+			rts = make([]*kayak.Runtime, len(nodes))
+		)
+		for i, node := range nodes {
+			dsn := fmt.Sprintf("test_election_%d.db", i)
+			db, err := newSQLiteStorage(dsn)
+			So(err, ShouldBeNil)
+			defer func() {
+				db.Close()
+				os.Remove(dsn)
+			}()
+			wal := kl.NewMemWal()
+			defer wal.Close()
+
+			rts[i], err = kayak.NewRuntime(&kt.RuntimeConfig{
+				Handler:             db,
+				PrepareThreshold:    0.5,
+				CommitThreshold:     0.0,
+				PrepareTimeout:      time.Second,
+				CommitTimeout:       time.Second,
+				LogWaitTimeout:      time.Second,
+				Peers:               peers,
+				Wal:                 wal,
+				NodeID:              node,
+				ServiceName:         "Test",
+				ApplyMethodName:     "Apply",
+				FetchMethodName:     "Fetch",
+				VoteMethodName:      "Vote",
+				HeartbeatMethodName: "Heartbeat",
+				HeartbeatInterval:   50 * time.Millisecond,
+				ElectionTimeout:     300 * time.Millisecond,
+				PrivateKey:          privKeys[i],
+				PublicKeyResolver:   resolver,
+			})
+			So(err, ShouldBeNil)
+			m.register(node, newFakeService(rts[i]))
+		}
+		for i := range nodes {
+			for j, node := range nodes {
+				if i != j {
+					rts[i].SetCaller(node, newFakeCaller(m, nodes[i], node))
+				}
+			}
+		}
+
+		Convey("vote and heartbeat should follow terms", func() {
+			var (
+				from = func(node proto.NodeID) proto.Envelope {
+					return proto.Envelope{NodeID: node.ToRawNodeID()}
+				}
+				unknown = proto.NodeID(
+					"00000000000000000000000000000000000000000000000000000000000000ff")
+			)
+			granted, term, err := rts[1].Vote(&kt.VoteRequest{
+				Envelope:  from(unknown),
+				Term:      1,
+				Candidate: unknown,
+			})
+			So(errors.Cause(err), ShouldEqual, kt.ErrNotInPeer)
+			So(granted, ShouldBeFalse)
+
+			// vote request should be sent by the candidate itself
+			granted, term, err = rts[1].Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[0]),
+				Term:      1,
+				Candidate: nodes[2],
+			})
+			So(errors.Cause(err), ShouldEqual, kt.ErrInvalidSender)
+			So(granted, ShouldBeFalse)
+
+			// stale term
+			granted, term, err = rts[1].Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[2]),
+				Term:      0,
+				Candidate: nodes[2],
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeFalse)
+			So(term, ShouldEqual, 0)
+
+			// leader lease is not checked before started
+			granted, term, err = rts[1].Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[2]),
+				Term:      1,
+				Candidate: nodes[2],
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeTrue)
+			So(term, ShouldEqual, 1)
+
+			// only one vote per term
+			granted, _, err = rts[1].Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[0]),
+				Term:      1,
+				Candidate: nodes[0],
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeFalse)
+
+			newPeers := peers.Clone()
+			newPeers.Term = 2
+			newPeers.Leader = nodes[2]
+
+			// peers should be signed by the leader and sent by the leader
+			err = newPeers.Sign(privKeys[0])
+			So(err, ShouldBeNil)
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[2]), Peers: &newPeers})
+			So(errors.Cause(err), ShouldEqual, kt.ErrUntrustedPeers)
+			err = newPeers.Sign(privKeys[2])
+			So(err, ShouldBeNil)
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[0]), Peers: &newPeers})
+			So(errors.Cause(err), ShouldEqual, kt.ErrInvalidSender)
+
+			// servers should not be changed by heartbeat
+			forged := newPeers.Clone()
+			forged.Servers = forged.Servers[1:]
+			err = forged.Sign(privKeys[2])
+			So(err, ShouldBeNil)
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[2]), Peers: &forged})
+			So(errors.Cause(err), ShouldEqual, kt.ErrUntrustedPeers)
+			So(rts[1].Peers().Term, ShouldEqual, 0)
+
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[2]), Peers: &newPeers})
+			So(err, ShouldBeNil)
+			So(rts[1].Peers().Term, ShouldEqual, 2)
+			So(rts[1].Peers().Leader, ShouldEqual, nodes[2])
+
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[0]), Peers: peers})
+			So(errors.Cause(err), ShouldEqual, kt.ErrStaleTerm)
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{})
+			So(err, ShouldNotBeNil)
+
+			// follower is synced only if its committed logs catch up with the heartbeat
+			syncedAt := rts[1].SyncedAt()
+			So(syncedAt.IsZero(), ShouldBeFalse)
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{
+				Envelope: from(nodes[2]), Peers: &newPeers, LastCommit: 10,
+			})
+			So(err, ShouldBeNil)
+			So(rts[1].SyncedAt(), ShouldEqual, syncedAt)
+			err = rts[1].Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[2]), Peers: &newPeers})
+			So(err, ShouldBeNil)
+			So(rts[1].SyncedAt(), ShouldHappenAfter, syncedAt)
+		})
+		Convey("stale prepares of the deposed leader should be dropped on term change", func() {
+			var (
+				dsn     = "test_election_stale.db"
+				walFile = "test_election_stale.ldb"
+				from    = func(node proto.NodeID) proto.Envelope {
+					return proto.Envelope{NodeID: node.ToRawNodeID()}
+				}
+			)
+			db, err := newSQLiteStorage(dsn)
+			So(err, ShouldBeNil)
+			defer func() {
+				db.Close()
+				os.Remove(dsn)
+			}()
+			enc, err := db.EncodePayload(&queryStructure{
+				Queries: []storage.Query{
+					{Pattern: "CREATE TABLE IF NOT EXISTS test (t1 text, t2 text, t3 text)"},
+				},
+			})
+			So(err, ShouldBeNil)
+
+			// an uncommitted prepare of the deposed leader is left in wal
+			wal, err := kl.NewLevelDBWal(walFile)
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(walFile)
+			err = wal.Write(&kt.Log{
+				LogHeader: kt.LogHeader{Index: 1, Type: kt.LogPrepare, Producer: nodes[0]},
+				Data:      enc,
+			})
+			So(err, ShouldBeNil)
+			wal.Close()
+			wal, err = kl.NewLevelDBWal(walFile)
+			So(err, ShouldBeNil)
+			defer wal.Close()
+
+			rt, err := kayak.NewRuntime(&kt.RuntimeConfig{
+				Handler:             db,
+				PrepareThreshold:    0.5,
+				CommitThreshold:     0.0,
+				PrepareTimeout:      time.Second,
+				CommitTimeout:       time.Second,
+				LogWaitTimeout:      time.Second,
+				Peers:               peers,
+				Wal:                 wal,
+				NodeID:              nodes[1],
+				ServiceName:         "Test",
+				ApplyMethodName:     "Apply",
+				FetchMethodName:     "Fetch",
+				VoteMethodName:      "Vote",
+				HeartbeatMethodName: "Heartbeat",
+				PrivateKey:          privKeys[1],
+				PublicKeyResolver:   resolver,
+			})
+			So(err, ShouldBeNil)
+			err = rt.Start()
+			So(err, ShouldBeNil)
+			defer rt.Shutdown()
+
+			// candidate last log is behind
+			granted, _, err := rt.Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[2]),
+				Term:      1,
+				Candidate: nodes[2],
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeFalse)
+			granted, _, err = rt.Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[2]),
+				Term:      1,
+				Candidate: nodes[2],
+				LastIndex: 1,
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeTrue)
+
+			newPeers := peers.Clone()
+			newPeers.Term = 1
+			newPeers.Leader = nodes[2]
+			err = newPeers.Sign(privKeys[2])
+			So(err, ShouldBeNil)
+			err = rt.Heartbeat(&kt.HeartbeatRequest{Envelope: from(nodes[2]), Peers: &newPeers})
+			So(err, ShouldBeNil)
+			_, err = wal.Get(1)
+			So(err, ShouldEqual, kl.ErrNotExists)
+
+			// the new leader reuses the index
+			err = rt.FollowerApply(&kt.Log{
+				LogHeader: kt.LogHeader{Index: 1, Type: kt.LogPrepare, Producer: nodes[2], Term: 1},
+				Data:      enc,
+			})
+			So(err, ShouldBeNil)
+
+			// the term of last log is compared before the index
+			granted, _, err = rt.Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[0]),
+				Term:      2,
+				Candidate: nodes[0],
+				LastIndex: 5,
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeFalse)
+			granted, _, err = rt.Vote(&kt.VoteRequest{
+				Envelope:  from(nodes[0]),
+				Term:      2,
+				Candidate: nodes[0],
+				LastIndex: 1,
+				LastTerm:  1,
+			})
+			So(err, ShouldBeNil)
+			So(granted, ShouldBeTrue)
+		})
+		Convey("followers should elect a new leader after leader failure", func() {
+			for _, rt := range rts {
+				err = rt.Start()
+				So(err, ShouldBeNil)
+			}
+			defer rts[1].Shutdown()
+			defer rts[2].Shutdown()
+
+			q := &queryStructure{
+				Queries: []storage.Query{
+					{Pattern: "CREATE TABLE IF NOT EXISTS test (t1 text, t2 text, t3 text)"},
+				},
+			}
+			_, _, err = rts[0].Apply(context.Background(), q)
+			So(err, ShouldBeNil)
+
+			// leader keeps its lease with heartbeats
+			time.Sleep(time.Second)
+			So(rts[1].Peers().Leader, ShouldEqual, nodes[0])
+			So(rts[2].Peers().Leader, ShouldEqual, nodes[0])
+
+			rts[0].Shutdown()
+
+			var leader proto.NodeID
+			for i := 0; i < 50 && leader.IsEmpty(); i++ {
+				time.Sleep(100 * time.Millisecond)
+				if p := rts[1].Peers(); p.Term > 0 && p.Leader == rts[2].Peers().Leader {
+					leader = p.Leader
+				}
+			}
+			So(leader, ShouldBeIn, nodes[1], nodes[2])
+
+			newLeader := rts[1]
+			if leader == nodes[2] {
+				newLeader = rts[2]
+			}
+			err = newLeader.Peers().Verify()
+			So(err, ShouldBeNil)
+			// the prepare fails if the stopped leader responds before the remaining follower
+			for i := 0; i != 10; i++ {
+				if _, _, err = newLeader.Apply(context.Background(), q); err == nil {
+					break
+				}
+			}
+			So(err, ShouldBeNil)
+
+			// the old leader is no longer the leader
+			_, _, err = rts[0].Apply(context.Background(), q)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -17,6 +17,8 @@
 package kayak
 
 import (
+	"sync/atomic"
+
 	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
 )
@@ -47,9 +49,6 @@ func (r *Runtime) missingLogCycle() {
 
 		// execute
 		func() {
-			r.peersLock.RLock()
-			defer r.peersLock.RUnlock()
-
 			if waitItem == nil {
 				return
 			}
@@ -76,8 +75,25 @@ func (r *Runtime) missingLogCycle() {
 				r.triggerLogAwaits(waitItem.index)
 				return
 			}
+			if waitItem.index < atomic.LoadUint64(&r.logBase) {
+				// covered by installed snapshot
+				r.triggerLogAwaits(waitItem.index)
+				return
+			}
 
-			if err = r.getCaller(r.peers.Leader).Call(r.fetchRPCMethod, req, resp); err != nil {
+			// peers lock is not held during fetching, as the following apply requires the lock
+			// and a pending peers update would block it
+			if err = r.getCaller(r.Peers().Leader).Call(r.fetchRPCMethod, req, resp); isLogTruncated(err) {
+				// too far behind to catch up with logs
+				if err = r.installSnapshot(); err != nil {
+					log.WithFields(log.Fields{
+						"index":    waitItem.index,
+						"instance": r.instanceID,
+					}).WithError(err).Warning("install snapshot failed")
+				}
+				r.triggerLogAwaits(waitItem.index)
+				return
+			} else if err != nil {
 				log.WithFields(log.Fields{
 					"index":    waitItem.index,
 					"instance": r.instanceID,
@@ -87,7 +103,7 @@ func (r *Runtime) missingLogCycle() {
 
 			// call follower apply
 			if resp.Log != nil {
-				if err = r.FollowerApply(resp.Log); err != nil {
+				if err = r.followerApply(resp.Log, false); err != nil {
 					log.WithFields(log.Fields{
 						"index":    waitItem.index,
 						"instance": r.instanceID,
@@ -20,6 +20,7 @@ import (
 	"context"
 	"io"
 	"log"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 
@@ -31,15 +32,20 @@ func (r *Runtime) newLog(ctx context.Context, logType kt.LogType, data []byte) (
 	defer trace.StartRegion(ctx, "newWAL").End()
 
 	// allocate index
+	term := atomic.LoadUint64(&r.term)
 	r.nextIndexLock.Lock()
 	i := r.nextIndex
 	r.nextIndex++
+	if r.lastTerm < term {
+		r.lastTerm = term
+	}
 	r.nextIndexLock.Unlock()
 	l = &kt.Log{
 		LogHeader: kt.LogHeader{
 			Index:    i,
 			Type:     logType,
 			Producer: r.nodeID,
+			Term:     term,
 		},
 		Data: data,
 	}
@@ -76,10 +82,31 @@ func (r *Runtime) readLogs() (err error) {
 		}
 
 		switch l.Type {
-		case kt.LogPrepare:
+		case kt.LogPrepare, kt.LogBatchPrepare:
 			// record in pending prepares
 			r.pendingPrepares[l.Index] = true
+		case kt.LogCheckpoint:
+			// logs before checkpoint are truncated, the last commit is restored from checkpoint
+			var lastCommit uint64
+			if lastCommit, err = r.bytesToUint64(l.Data); err != nil {
+				err = errors.Wrap(err, "checkpoint does not contain valid last commit index")
+				return
+			}
+			r.logBase = l.Index + 1
+			if lastCommit > r.lastCommit {
+				r.lastCommit = lastCommit
+			}
 		case kt.LogCommit:
+			if l.Index <= r.lastCommit {
+				// committed before checkpoint, the prepare log may be truncated
+				var prepareIndex uint64
+				if prepareIndex, err = r.bytesToUint64(l.Data); err != nil {
+					err = errors.Wrap(err, "log does not contain valid prepare index")
+					return
+				}
+				delete(r.pendingPrepares, prepareIndex)
+				break
+			}
 			// record last commit
 			var lastCommit uint64
 			var prepareLog *kt.Log
@@ -100,6 +127,15 @@ func (r *Runtime) readLogs() (err error) {
 			// resolve previous prepared
 			delete(r.pendingPrepares, prepareLog.Index)
 		case kt.LogRollback:
+			var prepareIndex uint64
+			if prepareIndex, err = r.bytesToUint64(l.Data); err != nil {
+				err = errors.Wrap(err, "log does not contain valid prepare index")
+				return
+			}
+			if prepareIndex < r.logBase {
+				// prepare is resolved before checkpoint
+				break
+			}
 			var prepareLog *kt.Log
 			if _, prepareLog, err = r.getPrepareLog(context.Background(), l); err != nil {
 				err = errors.Wrap(err, "previous prepare does not exists, node need full recovery")
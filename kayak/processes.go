@@ -59,22 +59,10 @@ func (r *Runtime) doLeaderPrepare(ctx context.Context, tm *timer.Timer, req inte
 
 	tm.Add("leader_prepare")
 
-	// send prepare to all nodes
-	prepareTracker := r.applyRPC(prepareLog, r.minPreparedFollowers)
-	prepareCtx, prepareCtxCancelFunc := context.WithTimeout(ctx, r.prepareTimeout)
-	defer prepareCtxCancelFunc()
-	prepareErrors, prepareDone, _ := prepareTracker.get(prepareCtx)
-	if !prepareDone {
-		// timeout, rollback
-		err = kt.ErrPrepareTimeout
-		return
-	}
+	err = r.waitFollowerPrepare(ctx, prepareLog)
 
 	tm.Add("follower_prepare")
 
-	// collect errors
-	err = r.errorSummary(prepareErrors)
-
 	return
 }
 
@@ -138,14 +126,16 @@ func (r *Runtime) followerPrepare(ctx context.Context, tm *timer.Timer, l *kt.Lo
 	}()
 
 	// decode
-	var req interface{}
-	if req, err = r.doDecodePayload(ctx, l.Data); err != nil {
+	var reqs []interface{}
+	if reqs, err = r.decodePrepareLog(ctx, l); err != nil {
 		return
 	}
 	tm.Add("decode")
 
-	if err = r.doCheck(ctx, req); err != nil {
-		return
+	for _, req := range reqs {
+		if err = r.doCheck(ctx, req); err != nil {
+			return
+		}
 	}
 	tm.Add("check")
 
@@ -183,7 +173,7 @@ func (r *Runtime) followerRollback(ctx context.Context, tm *timer.Timer, l *kt.L
 	}
 	tm.Add("write_wal")
 
-	r.markPrepareFinished(ctx, l.Index)
+	r.markPrepareFinished(ctx, prepareLog.Index)
 	tm.Add("mark")
 
 	return
@@ -218,7 +208,7 @@ func (r *Runtime) followerCommit(ctx context.Context, tm *timer.Timer, l *kt.Log
 		err = cResult.err
 	}
 
-	r.markPrepareFinished(ctx, l.Index)
+	r.markPrepareFinished(ctx, prepareLog.Index)
 	tm.Add("mark")
 
 	return
@@ -18,13 +18,13 @@ package kayak
 
 import (
 	"context"
-	"math"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
@@ -47,8 +47,13 @@ type Runtime struct {
 	// index for next log.
 	nextIndexLock sync.Mutex
 	nextIndex     uint64
+	// lastTerm, the latest term of logs
+	lastTerm uint64
 	// lastCommit, last commit log index
 	lastCommit uint64
+	// logBase, logs before this index are truncated by checkpoint
+	logBase        uint64
+	checkpointLock sync.Mutex
 	// pendingPrepares, prepares needs to be committed/rollback
 	pendingPrepares     map[uint64]bool
 	pendingPreparesLock sync.RWMutex
@@ -67,6 +72,8 @@ type Runtime struct {
 	/// Peers info
 	// peers defines the server peers.
 	peers *proto.Peers
+	// term of current peers, which is set to the logs produced by current node.
+	term uint64
 	// cached role of current node in peers, calculated from peers info.
 	role proto.ServerRole
 	// cached followers in peers, calculated from peers info.
@@ -87,6 +94,10 @@ type Runtime struct {
 	applyRPCMethod string
 	// rpc method for fetch requests.
 	fetchRPCMethod string
+	// rpc method for vote requests.
+	voteRPCMethod string
+	// rpc method for heartbeat requests.
+	heartbeatRPCMethod string
 
 	//// Parameters
 	// prepare threshold defines the minimum node count requirement for prepare operation.
@@ -105,6 +116,48 @@ type Runtime struct {
 	missingLogCh chan *waitItem
 	waitLogMap   sync.Map // map[uint64]*waitItem
 
+	/// Leader election
+	// interval for leader to send heartbeats.
+	heartbeatInterval time.Duration
+	// max allowed time without leader contact, leader election is disabled if zero.
+	electionTimeout time.Duration
+	// private key to sign the peers of new term.
+	privKey *asymmetric.PrivateKey
+	// public key resolver to verify the peers signed by leader.
+	publicKeyOf func(id proto.NodeID) (*asymmetric.PublicKey, error)
+	// the latest term voted by current node.
+	voteLock  sync.Mutex
+	votedTerm uint64
+	// last time in unix nano of leader contact, for leader it's the last heartbeat acknowledged
+	// by the majority of peers.
+	leaderSeen int64
+	// the last heartbeat received by follower and the last commit index of leader it carries.
+	syncLock        sync.Mutex
+	heartbeatSeen   int64
+	heartbeatCommit uint64
+	// last time in unix nano when the committed logs of follower were known to catch up with
+	// the leader.
+	syncedAt int64
+
+	/// Snapshot
+	// rpc method for snapshot requests.
+	snapshotRPCMethod string
+	// directory for temporary snapshot files.
+	snapshotDir string
+	// commitLock serializes commits with snapshot creation and installation.
+	commitLock sync.Mutex
+	// snapshot currently served by leader.
+	snapshotLock sync.Mutex
+	snapshot     *snapshot
+	// snapshot installation in progress.
+	installing uint32
+
+	/// Batching
+	// maximum count of requests folded into one prepare log.
+	maxBatchSize int
+	// channel for leader requests awaiting to be batched.
+	applyCh chan *applyReq
+
 	/// Sub-routines management.
 	started uint32
 	stopCh  chan struct{}
@@ -120,6 +173,8 @@ type commitReq struct {
 	log        *kt.Log
 	result     *commitFuture
 	tm         *timer.Timer
+	// batch is set instead of data if multiple requests are folded in the prepare log.
+	batch []*applyReq
 }
 
 // commitResult defines the commit operation result.
@@ -182,33 +237,14 @@ func NewRuntime(cfg *kt.RuntimeConfig) (rt *Runtime, err error) {
 		return
 	}
 
-	followers := make([]proto.NodeID, 0, len(peers.Servers))
-	exists := false
-	var role proto.ServerRole
-
-	for _, v := range peers.Servers {
-		if !v.IsEqual(&peers.Leader) {
-			followers = append(followers, v)
-		}
-
-		if v.IsEqual(&cfg.NodeID) {
-			exists = true
-			if v.IsEqual(&peers.Leader) {
-				role = proto.Leader
-			} else {
-				role = proto.Follower
-			}
-		}
-	}
-
-	if !exists {
-		err = errors.Wrapf(kt.ErrNotInPeer, "node %v not in peers %v", cfg.NodeID, peers)
+	followers, role, err := resolvePeers(peers, cfg.NodeID)
+	if err != nil {
 		return
 	}
 
 	// calculate fan-out count according to threshold and peers info
-	minPreparedFollowers := int(math.Max(math.Ceil(cfg.PrepareThreshold*float64(len(peers.Servers))), 1) - 1)
-	minCommitFollowers := int(math.Max(math.Ceil(cfg.CommitThreshold*float64(len(peers.Servers))), 1) - 1)
+	minPreparedFollowers := minFollowers(cfg.PrepareThreshold, peers)
+	minCommitFollowers := minFollowers(cfg.CommitThreshold, peers)
 
 	rt = &Runtime{
 		// indexes
@@ -221,6 +257,7 @@ func NewRuntime(cfg *kt.RuntimeConfig) (rt *Runtime, err error) {
 
 		// peers
 		peers:                cfg.Peers,
+		term:                 cfg.Peers.Term,
 		nodeID:               cfg.NodeID,
 		followers:            followers,
 		role:                 role,
@@ -232,6 +269,19 @@ func NewRuntime(cfg *kt.RuntimeConfig) (rt *Runtime, err error) {
 		applyRPCMethod: cfg.ServiceName + "." + cfg.ApplyMethodName,
 		fetchRPCMethod: cfg.ServiceName + "." + cfg.FetchMethodName,
 
+		// leader election related
+		voteRPCMethod:      cfg.ServiceName + "." + cfg.VoteMethodName,
+		heartbeatRPCMethod: cfg.ServiceName + "." + cfg.HeartbeatMethodName,
+		heartbeatInterval:  cfg.HeartbeatInterval,
+		electionTimeout:    cfg.ElectionTimeout,
+		privKey:            cfg.PrivateKey,
+		publicKeyOf:        cfg.PublicKeyResolver,
+		votedTerm:          peers.Term,
+
+		// snapshot related
+		snapshotRPCMethod: cfg.ServiceName + "." + cfg.SnapshotMethodName,
+		snapshotDir:       cfg.SnapshotDir,
+
 		// commits related
 		prepareThreshold: cfg.PrepareThreshold,
 		prepareTimeout:   cfg.PrepareTimeout,
@@ -241,10 +291,25 @@ func NewRuntime(cfg *kt.RuntimeConfig) (rt *Runtime, err error) {
 		commitCh:         make(chan *commitReq, commitWindow),
 		missingLogCh:     make(chan *waitItem, missingLogWindow),
 
+		// batching related
+		maxBatchSize: cfg.MaxBatchSize,
+
 		// stop coordinator
 		stopCh: make(chan struct{}),
 	}
 
+	if rt.maxBatchSize > 1 {
+		rt.applyCh = make(chan *applyReq, rt.maxBatchSize)
+	}
+
+	if rt.publicKeyOf == nil {
+		rt.publicKeyOf = resolvePublicKey
+	}
+
+	if rt.electionTimeout > 0 && rt.heartbeatInterval <= 0 {
+		rt.heartbeatInterval = rt.electionTimeout / 4
+	}
+
 	// read from pool to rebuild uncommitted log map
 	if err = rt.readLogs(); err != nil {
 		return
@@ -261,10 +326,19 @@ func (r *Runtime) Start() (err error) {
 
 	// start commit cycle
 	r.goFunc(r.commitCycle)
+	// start batch cycle
+	if r.applyCh != nil {
+		r.goFunc(r.batchCycle)
+	}
 	// start missing log worker
 	for i := 0; i != missingLogConcurrency; i++ {
 		r.goFunc(r.missingLogCycle)
 	}
+	// start leader election worker
+	if r.electionTimeout > 0 {
+		r.touchLeader()
+		r.goFunc(r.electionCycle)
+	}
 
 	return
 }
@@ -282,6 +356,8 @@ func (r *Runtime) Shutdown() (err error) {
 	}
 	r.wg.Wait()
 
+	r.removeSnapshot()
+
 	return
 }
 
@@ -314,6 +390,16 @@ func (r *Runtime) Apply(ctx context.Context, req interface{}) (result interface{
 		err = kt.ErrNotLeader
 		return
 	}
+	if r.electionTimeout > 0 && !r.isLeaderLeaseValid(r.electionTimeout) {
+		// isolated from the majority, followers may have elected a new leader
+		err = errors.Wrap(kt.ErrNotLeader, "leader lease expired")
+		return
+	}
+
+	if r.applyCh != nil {
+		// fold into batch, the peers lock is held until the batch is committed
+		return r.doLeaderBatchApply(ctx, tm, req)
+	}
 
 	// prepare
 	prepareLog, err := r.doLeaderPrepare(ctx, tm, req)
@@ -362,12 +448,23 @@ func (r *Runtime) Fetch(ctx context.Context, index uint64) (l *kt.Log, err error
 		return
 	}
 
+	if index < atomic.LoadUint64(&r.logBase) {
+		err = errors.Wrapf(kt.ErrLogTruncated, "log %d before checkpoint", index)
+		return
+	}
+
 	// wal get
 	return r.wal.Get(index)
 }
 
 // FollowerApply defines entry for follower node.
 func (r *Runtime) FollowerApply(l *kt.Log) (err error) {
+	return r.followerApply(l, true)
+}
+
+// followerApply applies the log, the producer of the log is checked if the log is pushed by
+// leader, instead of fetched by current node.
+func (r *Runtime) followerApply(l *kt.Log, pushed bool) (err error) {
 	if l == nil {
 		err = errors.Wrap(kt.ErrInvalidLog, "log is nil")
 		return
@@ -403,10 +500,16 @@ func (r *Runtime) FollowerApply(l *kt.Log) (err error) {
 		err = kt.ErrNotFollower
 		return
 	}
+	if pushed && !l.Producer.IsEqual(&r.peers.Leader) {
+		// log from a deposed leader
+		err = errors.Wrapf(kt.ErrStaleTerm, "log produced by %s, current leader is %s",
+			l.Producer, r.peers.Leader)
+		return
+	}
 
 	// verify log structure
 	switch l.Type {
-	case kt.LogPrepare:
+	case kt.LogPrepare, kt.LogBatchPrepare:
 		err = r.followerPrepare(ctx, tm, l)
 	case kt.LogRollback:
 		err = r.followerRollback(ctx, tm, l)
@@ -417,16 +520,100 @@ func (r *Runtime) FollowerApply(l *kt.Log) (err error) {
 	if err == nil {
 		r.updateNextIndex(ctx, l)
 		r.triggerLogAwaits(l.Index)
+		if pushed {
+			r.touchLeader()
+		}
 	}
 
 	return
 }
 
+// Peers returns the current peers.
+func (r *Runtime) Peers() *proto.Peers {
+	r.peersLock.RLock()
+	defer r.peersLock.RUnlock()
+	return r.peers
+}
+
+// IsLeader returns whether the current node is the leader of current term.
+func (r *Runtime) IsLeader() bool {
+	r.peersLock.RLock()
+	defer r.peersLock.RUnlock()
+	return r.role == proto.Leader
+}
+
 // UpdatePeers defines entry for peers update logic.
+//
+// The role and followers of current node are recalculated from the new peers, a node which is
+// newly added to the peers catches up by fetching missing logs from the leader. Peers of a term
+// older than the current one are treated as a membership change, the elected leader of current
+// term is kept as long as it's still in the new peers.
 func (r *Runtime) UpdatePeers(peers *proto.Peers) (err error) {
+	return r.updatePeers(peers, atomic.LoadUint64(&r.lastCommit))
+}
+
+// updatePeers updates the peers, the uncommitted prepares of former terms above the last commit
+// index of the new leader are dropped on a term change, see dropStalePrepares.
+func (r *Runtime) updatePeers(peers *proto.Peers, leaderCommit uint64) (err error) {
+	if peers == nil {
+		err = errors.Wrap(kt.ErrInvalidConfig, "nil peers")
+		return
+	}
+
+	// verify peers
+	if err = peers.Verify(); err != nil {
+		err = errors.Wrap(err, "verify peers during kayak update failed")
+		return
+	}
+
+	// wait for running leader/follower operations to finish
 	r.peersLock.Lock()
 	defer r.peersLock.Unlock()
 
+	if peers.Term < r.peers.Term {
+		// membership change from block producers, keep the elected leader if it still serves
+		merged := peers.Clone()
+		merged.Term = r.peers.Term
+		if _, found := peers.Find(r.peers.Leader); found {
+			merged.Leader = r.peers.Leader
+		}
+		// Only the leader signs the peers of its term, the followers take the signed ones with
+		// the following heartbeats
+		if merged.Leader.IsEqual(&r.nodeID) && r.privKey != nil {
+			if err = merged.Sign(r.privKey); err != nil {
+				return
+			}
+		}
+		peers = &merged
+	}
+
+	followers, role, err := resolvePeers(peers, r.nodeID)
+	if err != nil {
+		return
+	}
+
+	if peers.Term > r.peers.Term {
+		r.dropStalePrepares(peers.Term, leaderCommit)
+	}
+
+	r.peers = peers
+	atomic.StoreUint64(&r.term, peers.Term)
+	r.followers = followers
+	r.role = role
+	r.minPreparedFollowers = minFollowers(r.prepareThreshold, peers)
+	r.minCommitFollowers = minFollowers(r.commitThreshold, peers)
+
+	// drop cached callers of removed nodes
+	r.callerMap.Range(func(k, v interface{}) bool {
+		if _, found := peers.Find(k.(proto.NodeID)); !found {
+			r.callerMap.Delete(k)
+			if c, ok := v.(interface{ Close() }); ok {
+				c.Close()
+			}
+		}
+		return true
+	})
+
 	return
 }
 
@@ -439,6 +626,21 @@ func (r *Runtime) updateNextIndex(ctx context.Context, l *kt.Log) {
 	if r.nextIndex < l.Index+1 {
 		r.nextIndex = l.Index + 1
 	}
+	if r.lastTerm < l.Term {
+		r.lastTerm = l.Term
+	}
+}
+
+// lastLog returns the index of the last log and the latest term of logs.
+func (r *Runtime) lastLog() (index, term uint64) {
+	r.nextIndexLock.Lock()
+	defer r.nextIndexLock.Unlock()
+
+	if r.nextIndex > 0 {
+		index = r.nextIndex - 1
+	}
+	term = r.lastTerm
+	return
 }
 
 func (r *Runtime) checkIfPrepareFinished(ctx context.Context, index uint64) (finished bool) {
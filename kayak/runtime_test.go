@@ -22,10 +22,12 @@ import (
 	"database/sql"
 	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/rpc"
 	"os"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -62,6 +64,7 @@ func RandStringRunes(n int) string {
 }
 
 type sqliteStorage struct {
+	sync.RWMutex
 	st  *storage.Storage
 	dsn string
 }
@@ -115,6 +118,8 @@ func (s *sqliteStorage) Commit(data interface{}, isLeader bool) (result interfac
 		return
 	}
 
+	s.RLock()
+	defer s.RUnlock()
 	result, err = s.st.Exec(context.Background(), d.Queries)
 
 	return
@@ -122,9 +127,47 @@ func (s *sqliteStorage) Commit(data interface{}, isLeader bool) (result interfac
 
 func (s *sqliteStorage) Query(ctx context.Context, queries []storage.Query) (columns []string, types []string,
 	data [][]interface{}, err error) {
+	s.RLock()
+	defer s.RUnlock()
 	return s.st.Query(ctx, queries)
 }
 
+// Snapshot copies the database file, the storage is reopened to flush the sqlite wal.
+func (s *sqliteStorage) Snapshot(file string) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if err = s.st.Close(); err != nil {
+		return
+	}
+	if err = copyFile(s.dsn, file); err != nil {
+		return
+	}
+	s.st, err = storage.New(s.dsn)
+	return
+}
+
+// Restore replaces the database file with the snapshot file.
+func (s *sqliteStorage) Restore(file string) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if err = s.st.Close(); err != nil {
+		return
+	}
+	if err = copyFile(file, s.dsn); err != nil {
+		return
+	}
+	s.st, err = storage.New(s.dsn)
+	return
+}
+
+func copyFile(src, dst string) (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(src); err != nil {
+		return
+	}
+	return ioutil.WriteFile(dst, data, 0600)
+}
+
 func (s *sqliteStorage) Close() {
 	if s.st != nil {
 		s.st.Close()
@@ -180,18 +223,37 @@ func (s *fakeService) Fetch(req *kt.FetchRequest, resp *kt.FetchResponse) (err e
 	return
 }
 
-func (s *fakeService) serveConn(c net.Conn) {
-	var r proto.NodeID
-	s.s.ServeCodec(crpc.NewNodeAwareServerCodec(context.Background(), utils.GetMsgPackServerCodec(c), r.ToRawNodeID()))
+func (s *fakeService) Vote(req *kt.VoteRequest, resp *kt.VoteResponse) (err error) {
+	resp.Granted, resp.Term, err = s.rt.Vote(req)
+	return
+}
+
+func (s *fakeService) Heartbeat(req *kt.HeartbeatRequest, resp *interface{}) (err error) {
+	return s.rt.Heartbeat(req)
+}
+
+func (s *fakeService) Snapshot(req *kt.SnapshotRequest, resp *kt.SnapshotResponse) (err error) {
+	var r *kt.SnapshotResponse
+	if r, err = s.rt.Snapshot(req); err != nil {
+		return
+	}
+
+	*resp = *r
+	return
+}
+
+func (s *fakeService) serveConn(c net.Conn, from proto.NodeID) {
+	s.s.ServeCodec(crpc.NewNodeAwareServerCodec(context.Background(), utils.GetMsgPackServerCodec(c), from.ToRawNodeID()))
 }
 
 type fakeCaller struct {
 	m      *fakeMux
+	from   proto.NodeID
 	target proto.NodeID
 	s      *smux.Session
 }
 
-func newFakeCaller(m *fakeMux, nodeID proto.NodeID) (c *fakeCaller) {
+func newFakeCaller(m *fakeMux, from, nodeID proto.NodeID) (c *fakeCaller) {
 	fakeConn := mock_conn.NewConn()
 	cipher1 := etls.NewCipher([]byte("123"))
 	cipher2 := etls.NewCipher([]byte("123"))
@@ -206,7 +268,7 @@ func newFakeCaller(m *fakeMux, nodeID proto.NodeID) (c *fakeCaller) {
 				break
 			}
 
-			go c.m.get(c.target).serveConn(s)
+			go c.m.get(c.target).serveConn(s, c.from)
 		}
 	}()
 
@@ -214,6 +276,7 @@ func newFakeCaller(m *fakeMux, nodeID proto.NodeID) (c *fakeCaller) {
 
 	c = &fakeCaller{
 		m:      m,
+		from:   from,
 		target: nodeID,
 		s:      muxClientSess,
 	}
@@ -221,6 +284,12 @@ func newFakeCaller(m *fakeMux, nodeID proto.NodeID) (c *fakeCaller) {
 	return
 }
 
+type offlineCaller struct{}
+
+func (c *offlineCaller) Call(method string, req interface{}, resp interface{}) (err error) {
+	return errors.New("node offline")
+}
+
 func (c *fakeCaller) Call(method string, req interface{}, resp interface{}) (err error) {
 	s, err := c.s.OpenStream()
 	client := rpc.NewClientWithCodec(utils.GetMsgPackClientCodec(s))
@@ -308,8 +377,8 @@ func TestRuntime(t *testing.T) {
 		fs2 := newFakeService(rt2)
 		m.register(node2, fs2)
 
-		rt1.SetCaller(node2, newFakeCaller(m, node2))
-		rt2.SetCaller(node1, newFakeCaller(m, node1))
+		rt1.SetCaller(node2, newFakeCaller(m, node1, node2))
+		rt2.SetCaller(node1, newFakeCaller(m, node2, node1))
 
 		err = rt1.Start()
 		So(err, ShouldBeNil)
@@ -450,6 +519,42 @@ func TestRuntime(t *testing.T) {
 			NodeID: node3,
 		})
 		So(err, ShouldNotBeNil)
+
+		wal := kl.NewMemWal()
+		defer wal.Close()
+		rt, err := kayak.NewRuntime(&kt.RuntimeConfig{
+			Peers:  peers,
+			Wal:    wal,
+			NodeID: node1,
+		})
+		So(err, ShouldBeNil)
+
+		// invalid peers
+		err = rt.UpdatePeers(nil)
+		So(err, ShouldNotBeNil)
+		newPeers := &proto.Peers{
+			PeersHeader: proto.PeersHeader{
+				Leader:  node2,
+				Servers: []proto.NodeID{node2, node3},
+			},
+		}
+		err = rt.UpdatePeers(newPeers)
+		So(err, ShouldNotBeNil)
+		err = newPeers.Sign(privKey)
+		So(err, ShouldBeNil)
+		err = rt.UpdatePeers(newPeers)
+		So(errors.Cause(err), ShouldEqual, kt.ErrNotInPeer)
+
+		// leader is moved to node2
+		newPeers.Servers = []proto.NodeID{node2, node3, node1}
+		err = newPeers.Sign(privKey)
+		So(err, ShouldBeNil)
+		err = rt.UpdatePeers(newPeers)
+		So(err, ShouldBeNil)
+		So(rt.Start(), ShouldBeNil)
+		defer rt.Shutdown()
+		_, _, err = rt.Apply(context.Background(), &queryStructure{})
+		So(errors.Cause(err), ShouldEqual, kt.ErrNotLeader)
 	})
 	Convey("test log loading", t, func() {
 		w, err := kl.NewLevelDBWal("testLoad.db")
@@ -537,6 +642,266 @@ func TestRuntime(t *testing.T) {
 		So(rt.Shutdown(), ShouldBeNil)
 		So(func() { rt.Shutdown() }, ShouldNotPanic)
 	})
+	Convey("test checkpoint", t, func() {
+		db, err := newSQLiteStorage("testCheckpoint.db")
+		So(err, ShouldBeNil)
+		defer func() {
+			db.Close()
+			os.Remove("testCheckpoint.db")
+		}()
+		w, err := kl.NewLevelDBWal("testCheckpoint.ldb")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll("testCheckpoint.ldb")
+
+		node1 := proto.NodeID("000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade")
+		peers := &proto.Peers{
+			PeersHeader: proto.PeersHeader{
+				Leader:  node1,
+				Servers: []proto.NodeID{node1},
+			},
+		}
+		privKey, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		err = peers.Sign(privKey)
+		So(err, ShouldBeNil)
+
+		cfg := &kt.RuntimeConfig{
+			Handler:          db,
+			PrepareThreshold: 1.0,
+			CommitThreshold:  1.0,
+			PrepareTimeout:   time.Second,
+			CommitTimeout:    10 * time.Second,
+			LogWaitTimeout:   10 * time.Second,
+			Peers:            peers,
+			Wal:              w,
+			NodeID:           node1,
+			ServiceName:      "Test",
+			ApplyMethodName:  "Apply",
+		}
+		rt, err := kayak.NewRuntime(cfg)
+		So(err, ShouldBeNil)
+
+		err = rt.Checkpoint(0)
+		So(errors.Cause(err), ShouldEqual, kt.ErrStopped)
+
+		So(rt.Start(), ShouldBeNil)
+
+		q := &queryStructure{
+			Queries: []storage.Query{
+				{Pattern: "CREATE TABLE IF NOT EXISTS test (t1 text)"},
+			},
+		}
+		_, _, err = rt.Apply(context.Background(), q)
+		So(err, ShouldBeNil)
+		q = &queryStructure{
+			Queries: []storage.Query{
+				{Pattern: "INSERT INTO test VALUES(?)", Args: []sql.NamedArg{sql.Named("", "happy")}},
+			},
+		}
+		var midIndex, lastIndex uint64
+		for i := 0; i != 10; i++ {
+			_, lastIndex, err = rt.Apply(context.Background(), q)
+			So(err, ShouldBeNil)
+			if i == 4 {
+				midIndex = lastIndex
+			}
+		}
+
+		// logs after the checkpoint index are kept
+		err = rt.Checkpoint(midIndex)
+		So(err, ShouldBeNil)
+		_, err = rt.Fetch(context.Background(), midIndex-1)
+		So(errors.Cause(err), ShouldEqual, kt.ErrLogTruncated)
+		_, err = rt.Fetch(context.Background(), midIndex)
+		So(err, ShouldBeNil)
+		_, err = rt.Fetch(context.Background(), midIndex+1)
+		So(err, ShouldBeNil)
+
+		err = rt.Checkpoint(lastIndex)
+		So(err, ShouldBeNil)
+		// truncated logs could not be fetched
+		_, err = rt.Fetch(context.Background(), 0)
+		So(errors.Cause(err), ShouldEqual, kt.ErrLogTruncated)
+		_, err = rt.Fetch(context.Background(), midIndex+1)
+		So(errors.Cause(err), ShouldEqual, kt.ErrLogTruncated)
+		_, err = rt.Fetch(context.Background(), lastIndex)
+		So(err, ShouldBeNil)
+		// checkpoint again without new logs
+		err = rt.Checkpoint(lastIndex)
+		So(err, ShouldBeNil)
+
+		_, _, err = rt.Apply(context.Background(), q)
+		So(err, ShouldBeNil)
+		So(rt.Shutdown(), ShouldBeNil)
+		w.Close()
+
+		// restart from checkpoint
+		w, err = kl.NewLevelDBWal("testCheckpoint.ldb")
+		So(err, ShouldBeNil)
+		defer w.Close()
+		cfg.Wal = w
+		rt, err = kayak.NewRuntime(cfg)
+		So(err, ShouldBeNil)
+		So(rt.Start(), ShouldBeNil)
+		defer rt.Shutdown()
+
+		_, err = rt.Fetch(context.Background(), 0)
+		So(errors.Cause(err), ShouldEqual, kt.ErrLogTruncated)
+		var index uint64
+		_, index, err = rt.Apply(context.Background(), q)
+		So(err, ShouldBeNil)
+		So(index, ShouldBeGreaterThan, lastIndex)
+
+		_, _, d, _ := db.Query(context.Background(), []storage.Query{
+			{Pattern: "SELECT COUNT(1) FROM test"},
+		})
+		So(fmt.Sprint(d[0][0]), ShouldEqual, "12")
+	})
+}
+
+func TestSnapshotInstall(t *testing.T) {
+	Convey("test snapshot install", t, func() {
+		db1, err := newSQLiteStorage("testSnapshot1.db")
+		So(err, ShouldBeNil)
+		defer func() {
+			db1.Close()
+			os.Remove("testSnapshot1.db")
+		}()
+		db2, err := newSQLiteStorage("testSnapshot2.db")
+		So(err, ShouldBeNil)
+		defer func() {
+			db2.Close()
+			os.Remove("testSnapshot2.db")
+		}()
+
+		node1 := proto.NodeID("000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade")
+		node2 := proto.NodeID("000005f4f22c06f76c43c4f48d5a7ec1309cc94030cbf9ebae814172884ac8b5")
+		peers := &proto.Peers{
+			PeersHeader: proto.PeersHeader{
+				Leader:  node1,
+				Servers: []proto.NodeID{node1, node2},
+			},
+		}
+		privKey, pubKey, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		err = peers.Sign(privKey)
+		So(err, ShouldBeNil)
+		resolver := func(id proto.NodeID) (*asymmetric.PublicKey, error) {
+			if id.IsEqual(&node1) {
+				return pubKey, nil
+			}
+			return nil, errors.New("unknown node")
+		}
+
+		newConfig := func(db *sqliteStorage, w kt.Wal, nodeID proto.NodeID) *kt.RuntimeConfig {
+			return &kt.RuntimeConfig{
+				Handler: db,
+				// leader could commit without followers
+				PrepareThreshold:   0.5,
+				CommitThreshold:    0.5,
+				PrepareTimeout:     time.Second,
+				CommitTimeout:      10 * time.Second,
+				LogWaitTimeout:     time.Second,
+				Peers:              peers,
+				Wal:                w,
+				NodeID:             nodeID,
+				ServiceName:        "Test",
+				ApplyMethodName:    "Apply",
+				FetchMethodName:    "Fetch",
+				SnapshotMethodName: "Snapshot",
+				PrivateKey:         privKey,
+				PublicKeyResolver:  resolver,
+			}
+		}
+
+		wal1 := kl.NewMemWal()
+		defer wal1.Close()
+		rt1, err := kayak.NewRuntime(newConfig(db1, wal1, node1))
+		So(err, ShouldBeNil)
+		wal2 := kl.NewMemWal()
+		defer wal2.Close()
+		rt2, err := kayak.NewRuntime(newConfig(db2, wal2, node2))
+		So(err, ShouldBeNil)
+
+		m := newFakeMux()
+		m.register(node1, newFakeService(rt1))
+		m.register(node2, newFakeService(rt2))
+		rt2.SetCaller(node1, newFakeCaller(m, node2, node1))
+
+		// follower is offline
+		rt1.SetCaller(node2, &offlineCaller{})
+		So(rt1.Start(), ShouldBeNil)
+		defer rt1.Shutdown()
+
+		q := &queryStructure{
+			Queries: []storage.Query{
+				{Pattern: "CREATE TABLE IF NOT EXISTS test (t1 text)"},
+			},
+		}
+		_, _, err = rt1.Apply(context.Background(), q)
+		So(err, ShouldBeNil)
+		q = &queryStructure{
+			Queries: []storage.Query{
+				{Pattern: "INSERT INTO test VALUES(?)", Args: []sql.NamedArg{sql.Named("", "happy")}},
+			},
+		}
+		var lastIndex uint64
+		for i := 0; i != 10; i++ {
+			_, lastIndex, err = rt1.Apply(context.Background(), q)
+			So(err, ShouldBeNil)
+		}
+		err = rt1.Checkpoint(lastIndex)
+		So(err, ShouldBeNil)
+
+		// snapshot is served to peers only, and signed by leader
+		unknown := proto.NodeID("00000000000000000000000000000000000000000000000000000000000000ff")
+		_, err = rt1.Snapshot(&kt.SnapshotRequest{
+			Envelope: proto.Envelope{NodeID: unknown.ToRawNodeID()},
+		})
+		So(errors.Cause(err), ShouldEqual, kt.ErrNotInPeer)
+		resp, err := rt1.Snapshot(&kt.SnapshotRequest{
+			Envelope: proto.Envelope{NodeID: node2.ToRawNodeID()},
+		})
+		So(err, ShouldBeNil)
+		So(resp.Verify(pubKey), ShouldBeNil)
+
+		// follower comes back, the truncated logs are recovered from leader snapshot
+		rt1.SetCaller(node2, newFakeCaller(m, node1, node2))
+		So(rt2.Start(), ShouldBeNil)
+		defer rt2.Shutdown()
+		_, _, err = rt1.Apply(context.Background(), q)
+		So(err, ShouldBeNil)
+
+		var count string
+		for i := 0; i != 100; i++ {
+			time.Sleep(100 * time.Millisecond)
+			_, _, d, err := db2.Query(context.Background(), []storage.Query{
+				{Pattern: "SELECT COUNT(1) FROM test"},
+			})
+			if err == nil && len(d) == 1 {
+				if count = fmt.Sprint(d[0][0]); count == "11" {
+					break
+				}
+			}
+		}
+		So(count, ShouldEqual, "11")
+
+		// follower keeps applying logs after snapshot install
+		_, _, err = rt1.Apply(context.Background(), q)
+		So(err, ShouldBeNil)
+		for i := 0; i != 100; i++ {
+			time.Sleep(100 * time.Millisecond)
+			_, _, d, err := db2.Query(context.Background(), []storage.Query{
+				{Pattern: "SELECT COUNT(1) FROM test"},
+			})
+			if err == nil && len(d) == 1 {
+				if count = fmt.Sprint(d[0][0]); count == "12" {
+					break
+				}
+			}
+		}
+		So(count, ShouldEqual, "12")
+	})
 }
 
 func BenchmarkRuntime(b *testing.B) {
@@ -620,8 +985,8 @@ func BenchmarkRuntime(b *testing.B) {
 		fs2 := newFakeService(rt2)
 		m.register(node2, fs2)
 
-		rt1.SetCaller(node2, newFakeCaller(m, node2))
-		rt2.SetCaller(node1, newFakeCaller(m, node1))
+		rt1.SetCaller(node2, newFakeCaller(m, node1, node2))
+		rt2.SetCaller(node1, newFakeCaller(m, node2, node1))
 
 		err = rt1.Start()
 		So(err, ShouldBeNil)
@@ -0,0 +1,375 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kayak
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+const (
+	// snapshotChunkSize defines the max data size of a snapshot chunk.
+	snapshotChunkSize = 1 << 20
+)
+
+// snapshot defines a snapshot file created by leader.
+type snapshot struct {
+	file       string
+	lastCommit uint64
+	logBase    uint64
+	size       uint64
+	hash       hash.Hash
+	signee     *asymmetric.PublicKey
+	signature  *asymmetric.Signature
+}
+
+func fileHash(file string) (h hash.Hash, size uint64, err error) {
+	var f *os.File
+	if f, err = os.Open(file); err != nil {
+		return
+	}
+	defer f.Close()
+
+	var (
+		hasher = sha256.New()
+		n      int64
+	)
+	if n, err = io.Copy(hasher, f); err != nil {
+		return
+	}
+	copy(h[:], hasher.Sum(nil))
+	size = uint64(n)
+	return
+}
+
+// Snapshot serves the snapshot chunks to a lagging follower, a new snapshot is created if the
+// committed state changes since the last one when the transfer starts.
+func (r *Runtime) Snapshot(req *kt.SnapshotRequest) (resp *kt.SnapshotResponse, err error) {
+	if atomic.LoadUint32(&r.started) != 1 {
+		err = kt.ErrStopped
+		return
+	}
+
+	sender := senderOf(req)
+	r.peersLock.RLock()
+	role := r.role
+	_, authorized := r.peers.Find(sender)
+	r.peersLock.RUnlock()
+
+	if role != proto.Leader {
+		err = kt.ErrNotLeader
+		return
+	}
+	if !authorized {
+		err = errors.Wrapf(kt.ErrNotInPeer, "snapshot requested by %s", sender)
+		return
+	}
+
+	sh, ok := r.sh.(kt.SnapshotHandler)
+	if !ok {
+		err = kt.ErrSnapshotNotSupported
+		return
+	}
+
+	r.snapshotLock.Lock()
+	defer r.snapshotLock.Unlock()
+
+	if req.Offset == 0 {
+		if r.snapshot == nil || r.snapshot.lastCommit != atomic.LoadUint64(&r.lastCommit) {
+			var s *snapshot
+			if s, err = r.createSnapshot(sh); err != nil {
+				return
+			}
+			r.removeSnapshotLocked()
+			r.snapshot = s
+		}
+	} else if r.snapshot == nil || r.snapshot.lastCommit != req.LastCommit {
+		err = errors.Wrapf(kt.ErrSnapshotExpired, "snapshot of last commit %d", req.LastCommit)
+		return
+	}
+
+	s := r.snapshot
+	resp = &kt.SnapshotResponse{
+		Instance:   r.instanceID,
+		LastCommit: s.lastCommit,
+		LogBase:    s.logBase,
+		Size:       s.size,
+		Hash:       s.hash,
+		Signee:     s.signee,
+		Signature:  s.signature,
+		Offset:     req.Offset,
+	}
+	if req.Offset >= s.size {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.Open(s.file); err != nil {
+		return
+	}
+	defer f.Close()
+
+	size := s.size - req.Offset
+	if size > snapshotChunkSize {
+		size = snapshotChunkSize
+	}
+	resp.Data = make([]byte, size)
+	if _, err = f.ReadAt(resp.Data, int64(req.Offset)); err != nil {
+		err = errors.Wrap(err, "read snapshot file failed")
+		resp = nil
+	}
+
+	return
+}
+
+func (r *Runtime) createSnapshot(sh kt.SnapshotHandler) (s *snapshot, err error) {
+	if r.privKey == nil {
+		err = errors.Wrap(kt.ErrSnapshotNotSupported, "no private key to sign snapshot")
+		return
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile(r.snapshotDir, "kayak-snapshot-"); err != nil {
+		err = errors.Wrap(err, "create snapshot file failed")
+		return
+	}
+	f.Close()
+
+	s = &snapshot{file: f.Name()}
+	defer func() {
+		if err != nil {
+			os.Remove(s.file)
+			s = nil
+		}
+	}()
+
+	// no commits during snapshot, the pending prepares are required to resume log apply
+	r.commitLock.Lock()
+	s.lastCommit = atomic.LoadUint64(&r.lastCommit)
+	s.logBase = s.lastCommit
+	r.pendingPreparesLock.RLock()
+	for i := range r.pendingPrepares {
+		if i < s.logBase {
+			s.logBase = i
+		}
+	}
+	r.pendingPreparesLock.RUnlock()
+	err = sh.Snapshot(s.file)
+	r.commitLock.Unlock()
+
+	if err != nil {
+		err = errors.Wrap(err, "create handler snapshot failed")
+		return
+	}
+	if s.hash, s.size, err = fileHash(s.file); err != nil {
+		err = errors.Wrap(err, "hash snapshot file failed")
+		return
+	}
+	desc := &kt.SnapshotResponse{
+		Instance:   r.instanceID,
+		LastCommit: s.lastCommit,
+		LogBase:    s.logBase,
+		Size:       s.size,
+		Hash:       s.hash,
+	}
+	if err = desc.Sign(r.privKey); err != nil {
+		err = errors.Wrap(err, "sign snapshot failed")
+		return
+	}
+	s.signee, s.signature = desc.Signee, desc.Signature
+
+	log.WithFields(log.Fields{
+		"instance":   r.instanceID,
+		"lastCommit": s.lastCommit,
+		"size":       s.size,
+	}).Info("kayak snapshot created")
+
+	return
+}
+
+func (r *Runtime) removeSnapshot() {
+	r.snapshotLock.Lock()
+	defer r.snapshotLock.Unlock()
+	r.removeSnapshotLocked()
+}
+
+func (r *Runtime) removeSnapshotLocked() {
+	if r.snapshot != nil {
+		os.Remove(r.snapshot.file)
+		r.snapshot = nil
+	}
+}
+
+func isLogTruncated(err error) bool {
+	// errors are transferred as plain text through rpc
+	return err != nil && strings.Contains(err.Error(), kt.ErrLogTruncated.Error())
+}
+
+// installSnapshot downloads the snapshot from leader and installs it, the follower resumes log
+// apply from the log base of the snapshot.
+func (r *Runtime) installSnapshot() (err error) {
+	if !atomic.CompareAndSwapUint32(&r.installing, 0, 1) {
+		// already in progress
+		return
+	}
+	defer atomic.StoreUint32(&r.installing, 0)
+
+	sh, ok := r.sh.(kt.SnapshotHandler)
+	if !ok {
+		err = kt.ErrSnapshotNotSupported
+		return
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile(r.snapshotDir, "kayak-install-"); err != nil {
+		err = errors.Wrap(err, "create snapshot file failed")
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// the snapshot is trusted only if it is signed by the leader of the verified peers
+	var (
+		leader    = r.Peers().Leader
+		leaderKey *asymmetric.PublicKey
+	)
+	if leaderKey, err = r.publicKeyOf(leader); err != nil {
+		err = errors.Wrapf(err, "get public key of leader %s failed", leader)
+		return
+	}
+
+	var (
+		caller = r.getCaller(leader)
+		req    = &kt.SnapshotRequest{
+			Instance: r.instanceID,
+		}
+		first *kt.SnapshotResponse
+		resp  *kt.SnapshotResponse
+	)
+	for {
+		resp = &kt.SnapshotResponse{}
+		if err = caller.Call(r.snapshotRPCMethod, req, resp); err != nil {
+			err = errors.Wrapf(err, "fetch snapshot chunk at %d failed", req.Offset)
+			return
+		}
+		if resp.Offset != req.Offset || (req.Offset > 0 && resp.LastCommit != req.LastCommit) {
+			err = errors.Wrap(kt.ErrSnapshotExpired, "unexpected snapshot chunk")
+			return
+		}
+		if first == nil {
+			if resp.Instance != r.instanceID {
+				err = errors.Wrapf(kt.ErrUntrustedSnapshot, "snapshot of instance %s", resp.Instance)
+				return
+			}
+			if err = resp.Verify(leaderKey); err != nil {
+				return
+			}
+			first = resp
+		} else if resp.LogBase != first.LogBase || resp.Size != first.Size ||
+			!resp.Hash.IsEqual(&first.Hash) {
+			err = errors.Wrap(kt.ErrUntrustedSnapshot, "snapshot description changed during transfer")
+			return
+		}
+		if _, err = f.Write(resp.Data); err != nil {
+			err = errors.Wrap(err, "write snapshot file failed")
+			return
+		}
+		req.LastCommit = resp.LastCommit
+		req.Offset += uint64(len(resp.Data))
+		if req.Offset >= resp.Size {
+			break
+		}
+		if len(resp.Data) == 0 {
+			err = errors.Wrap(kt.ErrInvalidLog, "empty snapshot chunk")
+			return
+		}
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	// verify
+	var h hash.Hash
+	if h, _, err = fileHash(f.Name()); err != nil {
+		return
+	}
+	if !h.IsEqual(&first.Hash) {
+		err = errors.Wrapf(kt.ErrUntrustedSnapshot,
+			"snapshot hash mismatched (expected: %v, actual: %v)", first.Hash, h)
+		return
+	}
+
+	r.commitLock.Lock()
+	defer r.commitLock.Unlock()
+
+	if resp.LastCommit <= atomic.LoadUint64(&r.lastCommit) {
+		// caught up during transfer
+		return
+	}
+	if err = sh.Restore(f.Name()); err != nil {
+		err = errors.Wrap(err, "restore handler snapshot failed")
+		return
+	}
+	if resp.LogBase > 0 {
+		if err = r.wal.Truncate(&kt.Log{
+			LogHeader: kt.LogHeader{
+				Index:    resp.LogBase - 1,
+				Type:     kt.LogCheckpoint,
+				Producer: r.nodeID,
+			},
+			Data: r.uint64ToBytes(resp.LastCommit),
+		}); err != nil {
+			err = errors.Wrap(err, "truncate wal failed")
+			return
+		}
+	}
+
+	atomic.StoreUint64(&r.logBase, resp.LogBase)
+	atomic.StoreUint64(&r.lastCommit, resp.LastCommit)
+	r.pendingPreparesLock.Lock()
+	for i := range r.pendingPrepares {
+		if i < resp.LogBase {
+			delete(r.pendingPrepares, i)
+		}
+	}
+	r.pendingPreparesLock.Unlock()
+	r.updateNextIndex(context.Background(), &kt.Log{LogHeader: kt.LogHeader{Index: resp.LastCommit}})
+	// wake up the waiters of truncated logs
+	r.triggerLogAwaitsBefore(resp.LogBase)
+
+	log.WithFields(log.Fields{
+		"instance":   r.instanceID,
+		"lastCommit": resp.LastCommit,
+		"logBase":    resp.LogBase,
+		"size":       resp.Size,
+	}).Info("kayak snapshot installed")
+
+	return
+}
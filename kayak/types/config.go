@@ -19,6 +19,7 @@ package types
 import (
 	"time"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 )
 
@@ -50,4 +51,25 @@ type RuntimeConfig struct {
 	FetchMethodName string
 	// fetch timeout.
 	LogWaitTimeout time.Duration
+	// vote service method for leader election.
+	VoteMethodName string
+	// heartbeat service method for leader election.
+	HeartbeatMethodName string
+	// interval for leader to send heartbeats.
+	HeartbeatInterval time.Duration
+	// maximum allowed time without leader heartbeat before electing a new leader, zero disables
+	// leader election.
+	ElectionTimeout time.Duration
+	// private key to sign the peers of new term after elected as leader.
+	PrivateKey *asymmetric.PrivateKey
+	// public key resolver of nodes to verify the peers signed by leader, the node info from kms
+	// or block producers is used if not specified.
+	PublicKeyResolver func(id proto.NodeID) (*asymmetric.PublicKey, error)
+	// snapshot service method to install snapshot to lagging followers.
+	SnapshotMethodName string
+	// directory for temporary snapshot files, system temp directory is used if not specified.
+	SnapshotDir string
+	// maximum count of requests folded into one prepare log, batching is disabled if not greater
+	// than 1.
+	MaxBatchSize int
 }
@@ -35,4 +35,21 @@ var (
 	ErrInvalidConfig = errors.New("invalid runtime config")
 	// ErrStopped represents runtime not started.
 	ErrStopped = errors.New("stopped")
+	// ErrStaleTerm represents the request comes from a leader of outdated term.
+	ErrStaleTerm = errors.New("stale term")
+	// ErrElectionFailed represents the candidate does not get enough votes.
+	ErrElectionFailed = errors.New("election failed")
+	// ErrLogTruncated represents the log is already truncated by a checkpoint.
+	ErrLogTruncated = errors.New("log truncated")
+	// ErrSnapshotNotSupported represents the handler does not support snapshot.
+	ErrSnapshotNotSupported = errors.New("snapshot not supported")
+	// ErrSnapshotExpired represents the snapshot in transfer is replaced by a newer one.
+	ErrSnapshotExpired = errors.New("snapshot expired")
+	// ErrUntrustedSnapshot represents the snapshot is not signed by the leader.
+	ErrUntrustedSnapshot = errors.New("untrusted snapshot")
+	// ErrInvalidSender represents the rpc caller is not the node the request claims to be from.
+	ErrInvalidSender = errors.New("invalid sender")
+	// ErrUntrustedPeers represents the peers are not signed by its leader or not served by the
+	// current servers.
+	ErrUntrustedPeers = errors.New("untrusted peers")
 )
@@ -23,3 +23,19 @@ type Handler interface {
 	Check(request interface{}) error
 	Commit(request interface{}, isLeader bool) (result interface{}, err error)
 }
+
+// SnapshotHandler defines the optional snapshot support of the underlying fsm, which is required
+// to install snapshots to the followers that could not catch up with logs.
+type SnapshotHandler interface {
+	// Snapshot writes a consistent copy of the committed state to the file.
+	Snapshot(file string) error
+	// Restore replaces the state with the snapshot file.
+	Restore(file string) error
+}
+
+// CommitIndexHandler defines the optional callback of the underlying fsm, which is notified with
+// the log index once the log is committed, so that the fsm could decide the logs covered by its
+// own persisted data on checkpoint.
+type CommitIndexHandler interface {
+	Committed(index uint64)
+}
@@ -36,6 +36,8 @@ const (
 	LogBarrier
 	// LogNoop defines noop log.
 	LogNoop
+	// LogBatchPrepare defines the prepare phase of a commit with multiple requests folded.
+	LogBatchPrepare
 )
 
 func (t LogType) String() (s string) {
@@ -52,6 +54,8 @@ func (t LogType) String() (s string) {
 		return "LogBarrier"
 	case LogNoop:
 		return "LogNoop"
+	case LogBatchPrepare:
+		return "LogBatchPrepare"
 	default:
 		return "Unknown"
 	}
@@ -64,6 +68,7 @@ type LogHeader struct {
 	Type       LogType      // log type
 	Producer   proto.NodeID // producer node
 	DataLength uint64       // data length
+	Term       uint64       // term of the leader producing the log
 }
 
 // Log defines the log data structure.
@@ -24,7 +24,7 @@ import (
 
 func TestLogType_String(t *testing.T) {
 	Convey("test log string function", t, func() {
-		for i := LogPrepare; i <= LogNoop+1; i++ {
+		for i := LogPrepare; i <= LogBatchPrepare+1; i++ {
 			So(i.String(), ShouldNotBeEmpty)
 		}
 	})
@@ -16,7 +16,15 @@
 
 package types
 
-import "github.com/CovenantSQL/CovenantSQL/proto"
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
 
 // ApplyRequest defines the apply request entity.
 type ApplyRequest struct {
@@ -38,3 +46,87 @@ type FetchResponse struct {
 	Instance string
 	Log      *Log
 }
+
+// VoteRequest defines the leader election vote request entity, which carries the last commit
+// index and the last log of the candidate.
+type VoteRequest struct {
+	proto.Envelope
+	Instance   string
+	Term       uint64
+	Candidate  proto.NodeID
+	LastCommit uint64
+	LastIndex  uint64
+	LastTerm   uint64
+}
+
+// VoteResponse defines the leader election vote response entity.
+type VoteResponse struct {
+	proto.Envelope
+	Instance string
+	Term     uint64
+	Granted  bool
+}
+
+// HeartbeatRequest defines the leader heartbeat request entity, which carries the signed peers
+// of current term and the last commit index of leader.
+type HeartbeatRequest struct {
+	proto.Envelope
+	Instance   string
+	Peers      *proto.Peers
+	LastCommit uint64
+}
+
+// SnapshotRequest defines the snapshot chunk request entity, a zero offset starts a new snapshot
+// transfer, the following chunks are requested with the last commit index of the snapshot.
+type SnapshotRequest struct {
+	proto.Envelope
+	Instance   string
+	LastCommit uint64
+	Offset     uint64
+}
+
+// SnapshotResponse defines the snapshot chunk response entity.
+type SnapshotResponse struct {
+	proto.Envelope
+	Instance   string
+	LastCommit uint64    // last commit log index included in the snapshot
+	LogBase    uint64    // first log index required to resume log apply after the snapshot
+	Size       uint64    // total size of the snapshot file
+	Hash       hash.Hash // hash of the snapshot file
+	Signee     *asymmetric.PublicKey
+	Signature  *asymmetric.Signature // leader signature of the snapshot description
+	Offset     uint64
+	Data       []byte
+}
+
+func (r *SnapshotResponse) digest() hash.Hash {
+	var buf = make([]byte, 0, len(r.Instance)+3*8+hash.HashSize)
+	buf = append(buf, r.Instance...)
+	for _, v := range []uint64{r.LastCommit, r.LogBase, r.Size} {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		buf = append(buf, b[:]...)
+	}
+	buf = append(buf, r.Hash[:]...)
+	return hash.THashH(buf)
+}
+
+// Sign signs the snapshot description, which excludes the transferred chunk.
+func (r *SnapshotResponse) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var h = r.digest()
+	if r.Signature, err = signer.Sign(h[:]); err != nil {
+		return
+	}
+	r.Signee = signer.PubKey()
+	return
+}
+
+// Verify checks that the snapshot description is signed by signee.
+func (r *SnapshotResponse) Verify(signee *asymmetric.PublicKey) (err error) {
+	var h = r.digest()
+	if r.Signee == nil || r.Signature == nil || !r.Signee.IsEqual(signee) ||
+		!r.Signature.Verify(h[:], signee) {
+		err = errors.Wrap(ErrUntrustedSnapshot, "invalid snapshot signature")
+	}
+	return
+}
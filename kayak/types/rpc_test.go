@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+)
+
+func TestSnapshotResponse_Sign(t *testing.T) {
+	Convey("test snapshot signature", t, func() {
+		priv, pub, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		_, otherPub, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+
+		resp := &SnapshotResponse{
+			Instance:   "test",
+			LastCommit: 10,
+			LogBase:    8,
+			Size:       1024,
+			Hash:       hash.THashH([]byte("snapshot")),
+		}
+		So(errors.Cause(resp.Verify(pub)), ShouldEqual, ErrUntrustedSnapshot)
+		So(resp.Sign(priv), ShouldBeNil)
+		So(resp.Verify(pub), ShouldBeNil)
+		So(errors.Cause(resp.Verify(otherPub)), ShouldEqual, ErrUntrustedSnapshot)
+
+		// chunks are not covered by signature
+		resp.Offset, resp.Data = 1, []byte("chunk")
+		So(resp.Verify(pub), ShouldBeNil)
+
+		resp.Hash = hash.THashH([]byte("forged"))
+		So(errors.Cause(resp.Verify(pub)), ShouldEqual, ErrUntrustedSnapshot)
+	})
+}
@@ -24,4 +24,8 @@ type Wal interface {
 	Read() (*Log, error)
 	// random access
 	Get(index uint64) (*Log, error)
+	// remove logs up to the checkpoint log index, and save the checkpoint log in place
+	Truncate(checkpoint *Log) error
+	// remove the log at index if it exists, e.g. an uncommitted prepare of a deposed leader
+	Remove(index uint64) error
 }
@@ -18,6 +18,9 @@ package kayak
 
 import (
 	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
 
 	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
 	"github.com/CovenantSQL/CovenantSQL/proto"
@@ -38,6 +41,39 @@ func (r *Runtime) goFunc(f func()) {
 	}()
 }
 
+// resolvePeers calculates the followers in peers and the role of the node.
+func resolvePeers(peers *proto.Peers, nodeID proto.NodeID) (
+	followers []proto.NodeID, role proto.ServerRole, err error,
+) {
+	followers = make([]proto.NodeID, 0, len(peers.Servers))
+	exists := false
+
+	for _, v := range peers.Servers {
+		if !v.IsEqual(&peers.Leader) {
+			followers = append(followers, v)
+		}
+
+		if v.IsEqual(&nodeID) {
+			exists = true
+			if v.IsEqual(&peers.Leader) {
+				role = proto.Leader
+			} else {
+				role = proto.Follower
+			}
+		}
+	}
+
+	if !exists {
+		err = errors.Wrapf(kt.ErrNotInPeer, "node %v not in peers %v", nodeID, peers)
+	}
+	return
+}
+
+// minFollowers calculates the fan-out count according to threshold and peers info.
+func minFollowers(threshold float64, peers *proto.Peers) int {
+	return int(math.Max(math.Ceil(threshold*float64(len(peers.Servers))), 1) - 1)
+}
+
 /// utils
 func (r *Runtime) uint64ToBytes(i uint64) (res []byte) {
 	res = make([]byte, 8)
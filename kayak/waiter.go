@@ -19,8 +19,11 @@ package kayak
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
+
 	kt "github.com/CovenantSQL/CovenantSQL/kayak/types"
 	"github.com/CovenantSQL/CovenantSQL/utils/trace"
 )
@@ -43,6 +46,11 @@ func (r *Runtime) waitForLog(ctx context.Context, index uint64) (l *kt.Log, err
 	defer trace.StartRegion(ctx, "waitForLog").End()
 
 	for {
+		if index < atomic.LoadUint64(&r.logBase) {
+			err = errors.Wrapf(kt.ErrLogTruncated, "log %d before checkpoint", index)
+			return
+		}
+
 		if l, err = r.wal.Get(index); err == nil {
 			// exists
 			return
@@ -86,3 +94,12 @@ func (r *Runtime) triggerLogAwaits(index uint64) {
 		}
 	})
 }
+
+func (r *Runtime) triggerLogAwaitsBefore(index uint64) {
+	r.waitLogMap.Range(func(key, _ interface{}) bool {
+		if i, ok := key.(uint64); ok && i < index {
+			r.triggerLogAwaits(i)
+		}
+		return true
+	})
+}
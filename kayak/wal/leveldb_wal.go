@@ -116,6 +116,95 @@ func (p *LevelDBWal) Write(l *kt.Log) (err error) {
 	return
 }
 
+// Truncate implements Wal.Truncate, the space of the removed logs is reclaimed by compaction.
+func (p *LevelDBWal) Truncate(checkpoint *kt.Log) (err error) {
+	if atomic.LoadUint32(&p.closed) == 1 {
+		err = ErrWalClosed
+		return
+	}
+
+	if checkpoint == nil || checkpoint.Type != kt.LogCheckpoint {
+		err = ErrInvalidLog
+		return
+	}
+
+	var (
+		batch       = new(leveldb.Batch)
+		headerRange = &util.Range{
+			Start: logHeaderKeyPrefix,
+			Limit: append(append([]byte(nil), logHeaderKeyPrefix...), p.uint64ToBytes(checkpoint.Index+1)...),
+		}
+		dataRange = &util.Range{
+			Start: logDataKeyPrefix,
+			Limit: append(append([]byte(nil), logDataKeyPrefix...), p.uint64ToBytes(checkpoint.Index+1)...),
+		}
+		it = p.db.NewIterator(headerRange, nil)
+	)
+
+	for it.Next() {
+		index := it.Key()[len(logHeaderKeyPrefix):]
+		batch.Delete(append([]byte(nil), it.Key()...))
+		batch.Delete(append(append([]byte(nil), logDataKeyPrefix...), index...))
+	}
+	it.Release()
+	if err = it.Error(); err != nil {
+		err = errors.Wrap(err, "iterate truncated logs failed")
+		return
+	}
+
+	// save checkpoint in the same batch
+	var enc *bytes.Buffer
+	if enc, err = utils.EncodeMsgPack(checkpoint.Data); err != nil {
+		err = errors.Wrap(err, "encode log data failed")
+		return
+	}
+	batch.Put(append(append([]byte(nil), logDataKeyPrefix...), p.uint64ToBytes(checkpoint.Index)...),
+		enc.Bytes())
+	checkpoint.DataLength = uint64(enc.Len())
+	if enc, err = utils.EncodeMsgPack(checkpoint.LogHeader); err != nil {
+		err = errors.Wrap(err, "encode log header failed")
+		return
+	}
+	batch.Put(append(append([]byte(nil), logHeaderKeyPrefix...), p.uint64ToBytes(checkpoint.Index)...),
+		enc.Bytes())
+
+	if err = p.db.Write(batch, nil); err != nil {
+		err = errors.Wrap(err, "write truncate batch failed")
+		return
+	}
+
+	// reclaim space
+	if err = p.db.CompactRange(*headerRange); err != nil {
+		err = errors.Wrap(err, "compact log headers failed")
+		return
+	}
+	if err = p.db.CompactRange(*dataRange); err != nil {
+		err = errors.Wrap(err, "compact log data failed")
+	}
+
+	return
+}
+
+// Remove implements Wal.Remove.
+func (p *LevelDBWal) Remove(i uint64) (err error) {
+	if atomic.LoadUint32(&p.closed) == 1 {
+		err = ErrWalClosed
+		return
+	}
+
+	var (
+		batch = new(leveldb.Batch)
+		index = p.uint64ToBytes(i)
+	)
+	batch.Delete(append(append([]byte(nil), logHeaderKeyPrefix...), index...))
+	batch.Delete(append(append([]byte(nil), logDataKeyPrefix...), index...))
+	if err = p.db.Write(batch, nil); err != nil {
+		err = errors.Wrap(err, "remove log failed")
+	}
+
+	return
+}
+
 // Read implements Wal.Read.
 func (p *LevelDBWal) Read() (l *kt.Log, err error) {
 	if atomic.LoadUint32(&p.closed) == 1 {
@@ -167,6 +256,7 @@ func (p *LevelDBWal) Get(i uint64) (l *kt.Log, err error) {
 	var headerData []byte
 	if headerData, err = p.db.Get(headerKey, nil); err == leveldb.ErrNotFound {
 		err = ErrNotExists
+		return
 	} else if err != nil {
 		err = errors.Wrap(err, "get log header failed")
 		return
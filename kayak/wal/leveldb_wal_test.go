@@ -145,3 +145,120 @@ func TestLevelDBWal_Write(t *testing.T) {
 		So(err, ShouldNotBeNil)
 	})
 }
+
+func TestLevelDBWal_Truncate(t *testing.T) {
+	Convey("wal truncate", t, func() {
+		dbFile := "testTruncate.ldb"
+
+		var p *LevelDBWal
+		var err error
+		p, err = NewLevelDBWal(dbFile)
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dbFile)
+
+		for i := 0; i != 10; i++ {
+			err = p.Write(&kt.Log{
+				LogHeader: kt.LogHeader{
+					Index: uint64(i),
+					Type:  kt.LogPrepare,
+				},
+				Data: []byte("happy"),
+			})
+			So(err, ShouldBeNil)
+		}
+
+		err = p.Truncate(nil)
+		So(err, ShouldEqual, ErrInvalidLog)
+		err = p.Truncate(&kt.Log{LogHeader: kt.LogHeader{Index: 5, Type: kt.LogPrepare}})
+		So(err, ShouldEqual, ErrInvalidLog)
+
+		cp := &kt.Log{
+			LogHeader: kt.LogHeader{
+				Index:    5,
+				Type:     kt.LogCheckpoint,
+				Producer: proto.NodeID("0000000000000000000000000000000000000000000000000000000000000000"),
+			},
+			Data: []byte("checkpoint"),
+		}
+		err = p.Truncate(cp)
+		So(err, ShouldBeNil)
+
+		for i := 0; i != 5; i++ {
+			_, err = p.Get(uint64(i))
+			So(err, ShouldNotBeNil)
+		}
+		var l *kt.Log
+		l, err = p.Get(5)
+		So(err, ShouldBeNil)
+		So(l, ShouldResemble, cp)
+		l, err = p.Get(6)
+		So(err, ShouldBeNil)
+		So(l.Type, ShouldEqual, kt.LogPrepare)
+
+		p.Close()
+		err = p.Truncate(cp)
+		So(err, ShouldEqual, ErrWalClosed)
+
+		// read from checkpoint after reload
+		p, err = NewLevelDBWal(dbFile)
+		So(err, ShouldBeNil)
+		defer p.Close()
+
+		for i := 5; i != 10; i++ {
+			l, err = p.Read()
+			So(err, ShouldBeNil)
+			So(l.Index, ShouldEqual, i)
+		}
+		_, err = p.Read()
+		So(err, ShouldEqual, io.EOF)
+	})
+}
+
+func TestLevelDBWal_Remove(t *testing.T) {
+	Convey("wal remove", t, func() {
+		dbFile := "testRemove.ldb"
+
+		var p *LevelDBWal
+		var err error
+		p, err = NewLevelDBWal(dbFile)
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dbFile)
+
+		for i := 0; i != 5; i++ {
+			err = p.Write(&kt.Log{
+				LogHeader: kt.LogHeader{
+					Index: uint64(i),
+					Type:  kt.LogPrepare,
+				},
+				Data: []byte("happy"),
+			})
+			So(err, ShouldBeNil)
+		}
+
+		err = p.Remove(2)
+		So(err, ShouldBeNil)
+		err = p.Remove(7)
+		So(err, ShouldBeNil)
+		_, err = p.Get(2)
+		So(err, ShouldEqual, ErrNotExists)
+
+		// write the removed index again
+		err = p.Write(&kt.Log{
+			LogHeader: kt.LogHeader{
+				Index: 2,
+				Type:  kt.LogPrepare,
+				Term:  1,
+			},
+			Data: []byte("happy"),
+		})
+		So(err, ShouldBeNil)
+		var l *kt.Log
+		l, err = p.Get(2)
+		So(err, ShouldBeNil)
+		So(l.Term, ShouldEqual, 1)
+
+		p.Close()
+		err = p.Remove(2)
+		So(err, ShouldEqual, ErrWalClosed)
+	})
+}
@@ -105,6 +105,64 @@ func (p *MemWal) Get(index uint64) (l *kt.Log, err error) {
 	return
 }
 
+// Truncate implements Wal.Truncate.
+func (p *MemWal) Truncate(checkpoint *kt.Log) (err error) {
+	if atomic.LoadUint32(&p.closed) == 1 {
+		err = ErrWalClosed
+		return
+	}
+
+	if checkpoint == nil || checkpoint.Type != kt.LogCheckpoint {
+		err = ErrInvalidLog
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	logs := make([]*kt.Log, 0, len(p.logs)+1)
+	logs = append(logs, checkpoint)
+	for _, l := range p.logs {
+		if l.Index > checkpoint.Index {
+			logs = append(logs, l)
+		}
+	}
+
+	p.logs = logs
+	p.revIndex = make(map[uint64]int, len(logs))
+	for i, l := range logs {
+		p.revIndex[l.Index] = i
+	}
+	atomic.StoreUint64(&p.offset, uint64(len(logs)))
+
+	return
+}
+
+// Remove implements Wal.Remove.
+func (p *MemWal) Remove(index uint64) (err error) {
+	if atomic.LoadUint32(&p.closed) == 1 {
+		err = ErrWalClosed
+		return
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	i, exists := p.revIndex[index]
+	if !exists {
+		return
+	}
+
+	p.logs = append(p.logs[:i], p.logs[i+1:]...)
+	delete(p.revIndex, index)
+	for j := i; j < len(p.logs); j++ {
+		p.revIndex[p.logs[j].Index] = j
+	}
+	atomic.AddUint64(&p.offset, ^uint64(0))
+
+	return
+}
+
 // Close implements Wal.Close.
 func (p *MemWal) Close() {
 	if !atomic.CompareAndSwapUint32(&p.closed, 0, 1) {
@@ -188,3 +188,106 @@ func TestMemWal_Write2(t *testing.T) {
 		So(p.offset, ShouldEqual, 5)
 	})
 }
+
+func TestMemWal_Truncate(t *testing.T) {
+	Convey("test mem wal truncate", t, func() {
+		p := NewMemWal()
+
+		var err error
+		for i := 0; i != 10; i++ {
+			err = p.Write(&kt.Log{
+				LogHeader: kt.LogHeader{
+					Index: uint64(i),
+					Type:  kt.LogPrepare,
+				},
+				Data: []byte("happy"),
+			})
+			So(err, ShouldBeNil)
+		}
+
+		err = p.Truncate(nil)
+		So(err, ShouldEqual, ErrInvalidLog)
+
+		cp := &kt.Log{
+			LogHeader: kt.LogHeader{
+				Index: 5,
+				Type:  kt.LogCheckpoint,
+			},
+		}
+		err = p.Truncate(cp)
+		So(err, ShouldBeNil)
+		So(p.logs, ShouldHaveLength, 5)
+		So(p.offset, ShouldEqual, 5)
+
+		_, err = p.Get(4)
+		So(err, ShouldEqual, ErrNotExists)
+		var l *kt.Log
+		l, err = p.Get(5)
+		So(err, ShouldBeNil)
+		So(l, ShouldEqual, cp)
+
+		// write after truncate
+		err = p.Write(&kt.Log{
+			LogHeader: kt.LogHeader{
+				Index: 10,
+				Type:  kt.LogPrepare,
+			},
+		})
+		So(err, ShouldBeNil)
+		l, err = p.Get(10)
+		So(err, ShouldBeNil)
+		So(l.Index, ShouldEqual, 10)
+
+		p.Close()
+		err = p.Truncate(cp)
+		So(err, ShouldEqual, ErrWalClosed)
+	})
+}
+
+func TestMemWal_Remove(t *testing.T) {
+	Convey("test mem wal remove", t, func() {
+		p := NewMemWal()
+
+		var err error
+		for i := 0; i != 5; i++ {
+			err = p.Write(&kt.Log{
+				LogHeader: kt.LogHeader{
+					Index: uint64(i),
+					Type:  kt.LogPrepare,
+				},
+				Data: []byte("happy"),
+			})
+			So(err, ShouldBeNil)
+		}
+
+		err = p.Remove(2)
+		So(err, ShouldBeNil)
+		err = p.Remove(7)
+		So(err, ShouldBeNil)
+		So(p.logs, ShouldHaveLength, 4)
+		So(p.offset, ShouldEqual, 4)
+
+		_, err = p.Get(2)
+		So(err, ShouldEqual, ErrNotExists)
+		var l *kt.Log
+		l, err = p.Get(3)
+		So(err, ShouldBeNil)
+		So(l.Index, ShouldEqual, 3)
+
+		// write the removed index again
+		err = p.Write(&kt.Log{
+			LogHeader: kt.LogHeader{
+				Index: 2,
+				Type:  kt.LogPrepare,
+			},
+		})
+		So(err, ShouldBeNil)
+		l, err = p.Get(2)
+		So(err, ShouldBeNil)
+		So(l.Index, ShouldEqual, 2)
+
+		p.Close()
+		err = p.Remove(2)
+		So(err, ShouldEqual, ErrWalClosed)
+	})
+}
@@ -76,6 +76,101 @@ func (merkle *Merkle) GetRoot() *hash.Hash {
 	return merkle.tree[len(merkle.tree)-1]
 }
 
+// GetPath returns the sibling hashes on the path from the index-th item to the root, which
+// proves the inclusion of the item in the tree.
+func (merkle *Merkle) GetPath(index uint64) (path []hash.Hash, err error) {
+	var (
+		size   = uint64(len(merkle.tree)+1) / 2
+		offset uint64
+	)
+	if index >= size || merkle.tree[index] == nil {
+		err = ErrIndexOutOfRange
+		return
+	}
+	for ; size > 1; size /= 2 {
+		var sibling = merkle.tree[offset+(index^1)]
+		if sibling == nil {
+			// Only left node, which is merged with itself
+			sibling = merkle.tree[offset+index]
+		}
+		path = append(path, *sibling)
+		offset += size
+		index /= 2
+	}
+	return
+}
+
+// setLeaf replaces the index-th item of the tree with leaf, and updates the nodes on the path
+// from the item to the root.
+func (merkle *Merkle) setLeaf(index uint64, leaf *hash.Hash) {
+	var (
+		size   = uint64(len(merkle.tree)+1) / 2
+		offset uint64
+	)
+	merkle.tree[index] = leaf
+	for ; size > 1; size /= 2 {
+		var parent = index / 2
+		merkle.tree[offset+size+parent] = mergeNode(
+			merkle.tree[offset+parent*2], merkle.tree[offset+parent*2+1])
+		offset += size
+		index = parent
+	}
+}
+
+// rootWith computes the root of the tree as if the items were replaced by the given leaves,
+// indexed by the item indexes, the tree itself is not modified.
+func (merkle *Merkle) rootWith(leaves map[uint64]*hash.Hash) *hash.Hash {
+	var (
+		size   = uint64(len(merkle.tree)+1) / 2
+		offset uint64
+		level  = leaves
+		node   = func(index uint64) *hash.Hash {
+			if h, ok := level[index]; ok {
+				return h
+			}
+			return merkle.tree[offset+index]
+		}
+	)
+	for ; size > 1; size /= 2 {
+		var next = make(map[uint64]*hash.Hash, len(level))
+		for i := range level {
+			var parent = i / 2
+			if _, ok := next[parent]; !ok {
+				next[parent] = mergeNode(node(parent*2), node(parent*2+1))
+			}
+		}
+		offset += size
+		level = next
+	}
+	return node(0)
+}
+
+// mergeNode computes the parent node of two sibling nodes, a left node without right sibling is
+// merged with itself.
+func mergeNode(l, r *hash.Hash) *hash.Hash {
+	if l == nil {
+		return nil
+	}
+	if r == nil {
+		return MergeTwoHash(l, l)
+	}
+	return MergeTwoHash(l, r)
+}
+
+// VerifyPath verifies that the leaf is the index-th item of the tree with the given root.
+func VerifyPath(root, leaf *hash.Hash, index uint64, path []hash.Hash) bool {
+	var current = leaf
+	for i := range path {
+		if index%2 == 0 {
+			current = MergeTwoHash(current, &path[i])
+		} else {
+			current = MergeTwoHash(&path[i], current)
+		}
+		index /= 2
+	}
+	return index == 0 && current.IsEqual(root)
+}
+
 // MergeTwoHash computes the hash of the concatenate of two hash.
 func MergeTwoHash(l *hash.Hash, r *hash.Hash) *hash.Hash {
 	result := hash.THashH(append(append([]byte{}, (*l)[:]...), (*r)[:]...))
@@ -44,6 +44,30 @@ func TestNewMerkle(t *testing.T) {
 	})
 }
 
+func TestMerklePath(t *testing.T) {
+	Convey("Each item should be proved by its merkle path", t, func() {
+		for n := 1; n <= 9; n++ {
+			var items = make([]*hash.Hash, n)
+			for i := range items {
+				items[i] = &hash.Hash{}
+				rand.Read(items[i][:])
+			}
+			var (
+				merkle = NewMerkle(items)
+				root   = merkle.GetRoot()
+			)
+			for i := range items {
+				path, err := merkle.GetPath(uint64(i))
+				So(err, ShouldBeNil)
+				So(VerifyPath(root, items[i], uint64(i), path), ShouldBeTrue)
+				So(VerifyPath(root, items[(i+1)%n], uint64(i), path), ShouldEqual, n == 1)
+			}
+			_, err := merkle.GetPath(uint64(n))
+			So(err, ShouldEqual, ErrIndexOutOfRange)
+		}
+	})
+}
+
 func mergeHash(h0 *hash.Hash, h1 *hash.Hash) *hash.Hash {
 	h := hash.THashH(append(h0[:], h1[:]...))
 	return &h
@@ -17,22 +17,46 @@
 package merkle
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"sync"
 
 	"github.com/tchap/go-patricia/patricia"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 )
 
+var (
+	// ErrKeyNotFound indicates that the key is not found in the trie.
+	ErrKeyNotFound = errors.New("no such key")
+	// ErrIndexOutOfRange indicates that the item index is out of the merkle tree range.
+	ErrIndexOutOfRange = errors.New("index out of range")
+)
+
+// Proof is the inclusion proof of a (key, value) pair in the trie.
+type Proof struct {
+	Index uint64
+	Path  []hash.Hash
+}
+
 // Trie is a patricia trie.
 type Trie struct {
 	trie *patricia.Trie
+
+	// lock guards the cached hashed keys and merkle leaves sorted by the hashed keys, and the
+	// merkle tree over them, which are built on demand and kept up to date by Update.
+	lock   sync.Mutex
+	cached bool
+	keys   [][]byte
+	leaves []*hash.Hash
+	tree   *Merkle
 }
 
 // NewPatricia is patricia construction.
 func NewPatricia() *Trie {
 	trie := patricia.NewTrie(patricia.MaxPrefixPerNode(16), patricia.MaxChildrenPerSparseNode(17))
-	return &Trie{trie}
+	return &Trie{trie: trie}
 }
 
 // Insert serializes key into binary and computes its hash,
@@ -40,19 +64,203 @@ func NewPatricia() *Trie {
 func (trie *Trie) Insert(key []byte, value []byte) (inserted bool) {
 	hashedKey := hash.HashB(key)
 
-	inserted = trie.trie.Insert(hashedKey, value)
+	trie.lock.Lock()
+	defer trie.lock.Unlock()
+	if inserted = trie.trie.Insert(hashedKey, value); inserted {
+		trie.cached, trie.keys, trie.leaves, trie.tree = false, nil, nil, nil
+	}
 	return
 }
 
+// Update sets the values of the keys in updates, a nil value deletes the key from the trie.
+// Unlike Insert, it also keeps the cached merkle leaves up to date, only the leaves of the updated
+// keys are rehashed.
+func (trie *Trie) Update(updates map[string][]byte) {
+	trie.lock.Lock()
+	defer trie.lock.Unlock()
+	for k, v := range updates {
+		if hashedKey := hash.HashB([]byte(k)); v != nil {
+			trie.trie.Set(hashedKey, v)
+		} else {
+			trie.trie.Delete(hashedKey)
+		}
+	}
+	if !trie.cached {
+		return
+	}
+	var changed, keys, leaves = trie.merge(updates)
+	if changed == nil {
+		trie.keys, trie.leaves, trie.tree = keys, leaves, nil
+		return
+	}
+	for i, v := range changed {
+		trie.leaves[i] = v
+		if trie.tree != nil {
+			trie.tree.setLeaf(i, v)
+		}
+	}
+}
+
 // Get returns the value according to the key.
 func (trie *Trie) Get(key []byte) ([]byte, error) {
 	hashedKey := hash.HashB(key)
 
 	rawValue := trie.trie.Get(hashedKey)
 	if rawValue == nil {
-		return nil, errors.New("no such key")
+		return nil, ErrKeyNotFound
 	}
 	value := rawValue.([]byte)
 
 	return value, nil
 }
+
+// leafHash computes the merkle leaf of a (hashed key, value) pair.
+func leafHash(hashedKey []byte, value []byte) hash.Hash {
+	return hash.THashH(append(append([]byte{}, hashedKey...), value...))
+}
+
+// sortedLeaves returns the hashed keys and the merkle leaves of all the items in the trie,
+// sorted by the hashed keys.
+func (trie *Trie) sortedLeaves() (keys [][]byte, leaves []*hash.Hash) {
+	trie.trie.Visit(func(prefix patricia.Prefix, item patricia.Item) error {
+		keys = append(keys, append([]byte{}, prefix...))
+		return nil
+	})
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	leaves = make([]*hash.Hash, len(keys))
+	for i, k := range keys {
+		var h = leafHash(k, trie.trie.Get(k).([]byte))
+		leaves[i] = &h
+	}
+	return
+}
+
+// build builds the cached leaves and merkle tree if they are not cached yet, the caller should
+// hold the trie lock.
+func (trie *Trie) build() {
+	if !trie.cached {
+		trie.keys, trie.leaves = trie.sortedLeaves()
+		trie.cached = true
+	}
+	if trie.tree == nil {
+		trie.tree = NewMerkle(trie.leaves)
+	}
+}
+
+// search returns the index of the hashed key in the cached keys, or the index where it would be
+// inserted if it is not found.
+func (trie *Trie) search(hashedKey []byte) (index int, found bool) {
+	index = sort.Search(len(trie.keys), func(i int) bool {
+		return bytes.Compare(trie.keys[i], hashedKey) >= 0
+	})
+	found = index < len(trie.keys) && bytes.Equal(trie.keys[index], hashedKey)
+	return
+}
+
+// merge applies updates to the cached leaves without modifying them. If none of the updates adds
+// or removes an item, only the changed leaves are returned by their indexes. Otherwise changed is
+// nil, and the whole sorted keys and leaves are returned.
+func (trie *Trie) merge(updates map[string][]byte) (
+	changed map[uint64]*hash.Hash, keys [][]byte, leaves []*hash.Hash,
+) {
+	type entry struct {
+		key, value []byte
+	}
+	var (
+		entries    = make([]entry, 0, len(updates))
+		structural bool
+	)
+	for k, v := range updates {
+		entries = append(entries, entry{key: hash.HashB([]byte(k)), value: v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	changed = make(map[uint64]*hash.Hash, len(entries))
+	for _, e := range entries {
+		var index, found = trie.search(e.key)
+		if found && e.value != nil {
+			var h = leafHash(e.key, e.value)
+			changed[uint64(index)] = &h
+		} else if found || e.value != nil {
+			structural = true
+		}
+	}
+	if !structural {
+		return
+	}
+
+	// Merge the sorted updates into the sorted leaves
+	changed = nil
+	keys = make([][]byte, 0, len(trie.keys)+len(entries))
+	leaves = make([]*hash.Hash, 0, len(trie.keys)+len(entries))
+	var i int
+	for _, e := range entries {
+		for ; i < len(trie.keys) && bytes.Compare(trie.keys[i], e.key) < 0; i++ {
+			keys = append(keys, trie.keys[i])
+			leaves = append(leaves, trie.leaves[i])
+		}
+		if i < len(trie.keys) && bytes.Equal(trie.keys[i], e.key) {
+			// Replaced or deleted
+			i++
+		}
+		if e.value != nil {
+			var h = leafHash(e.key, e.value)
+			keys = append(keys, e.key)
+			leaves = append(leaves, &h)
+		}
+	}
+	keys = append(keys, trie.keys[i:]...)
+	leaves = append(leaves, trie.leaves[i:]...)
+	return
+}
+
+// Root computes the merkle root of the trie, the leaves are the (hashed key, value) pairs sorted
+// by the hashed keys.
+func (trie *Trie) Root() *hash.Hash {
+	trie.lock.Lock()
+	defer trie.lock.Unlock()
+	trie.build()
+	return trie.tree.GetRoot()
+}
+
+// RootWith computes the merkle root of the trie as if the updates were applied by Update, the
+// trie itself is not modified.
+func (trie *Trie) RootWith(updates map[string][]byte) *hash.Hash {
+	trie.lock.Lock()
+	defer trie.lock.Unlock()
+	trie.build()
+	var changed, _, leaves = trie.merge(updates)
+	if changed == nil {
+		return NewMerkle(leaves).GetRoot()
+	}
+	return trie.tree.rootWith(changed)
+}
+
+// Prove returns the root and the inclusion proof of the key.
+func (trie *Trie) Prove(key []byte) (root *hash.Hash, proof *Proof, err error) {
+	trie.lock.Lock()
+	defer trie.lock.Unlock()
+	trie.build()
+	var index, found = trie.search(hash.HashB(key))
+	if !found {
+		err = ErrKeyNotFound
+		return
+	}
+	var path []hash.Hash
+	if path, err = trie.tree.GetPath(uint64(index)); err != nil {
+		return
+	}
+	root = trie.tree.GetRoot()
+	proof = &Proof{
+		Index: uint64(index),
+		Path:  path,
+	}
+	return
+}
+
+// Verify verifies that the (key, value) pair is included in the trie with the given root.
+func (p *Proof) Verify(root *hash.Hash, key []byte, value []byte) bool {
+	var leaf = leafHash(hash.HashB(key), value)
+	return VerifyPath(root, &leaf, p.Index, p.Path)
+}
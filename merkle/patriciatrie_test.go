@@ -98,3 +98,123 @@ func TestTrie_Get(t *testing.T) {
 		})
 	})
 }
+
+func TestTrie_Prove(t *testing.T) {
+	Convey("Given a trie with a set of items", t, func() {
+		var (
+			trie  = NewPatricia()
+			empty = NewMerkle(nil).GetRoot()
+		)
+		So(trie.Root(), ShouldResemble, empty)
+		_, _, err := trie.Prove([]byte("a"))
+		So(err, ShouldEqual, ErrKeyNotFound)
+
+		for i := 0; i < 9; i++ {
+			trie.Insert(serialize(i), serialize(int32(i*i)))
+			var root = trie.Root()
+			So(root, ShouldNotResemble, empty)
+			for j := 0; j <= i; j++ {
+				var r, proof, err = trie.Prove(serialize(j))
+				So(err, ShouldBeNil)
+				So(r, ShouldResemble, root)
+				So(proof.Verify(root, serialize(j), serialize(int32(j*j))), ShouldBeTrue)
+				So(proof.Verify(root, serialize(j), serialize(int32(j))), ShouldEqual, j < 2)
+				So(proof.Verify(root, serialize(j+1), serialize(int32(j*j))), ShouldBeFalse)
+			}
+		}
+
+		Convey("The root should not depend on insertion order", func() {
+			var another = NewPatricia()
+			for i := 8; i >= 0; i-- {
+				another.Insert(serialize(i), serialize(int32(i*i)))
+			}
+			So(another.Root(), ShouldResemble, trie.Root())
+		})
+		Convey("The proof should not be valid with a tampered path", func() {
+			var root, proof, err = trie.Prove(serialize(3))
+			So(err, ShouldBeNil)
+			proof.Path[0][0] ^= 0xff
+			So(proof.Verify(root, serialize(3), serialize(int32(9))), ShouldBeFalse)
+			proof.Path[0][0] ^= 0xff
+			proof.Index ^= 1
+			So(proof.Verify(root, serialize(3), serialize(int32(9))), ShouldBeFalse)
+		})
+	})
+}
+
+func TestTrie_Update(t *testing.T) {
+	Convey("Given a trie with a set of items", t, func() {
+		var (
+			trie  = NewPatricia()
+			items = make(map[int]int32)
+			// rebuild builds a fresh trie from the items
+			rebuild = func() *Trie {
+				var fresh = NewPatricia()
+				for k, v := range items {
+					fresh.Insert(serialize(k), serialize(v))
+				}
+				return fresh
+			}
+			// update applies the updates to the trie and the items
+			update = func(updates map[int]int32, deletes ...int) {
+				var raw = make(map[string][]byte)
+				for k, v := range updates {
+					raw[string(serialize(k))] = serialize(v)
+					items[k] = v
+				}
+				for _, k := range deletes {
+					raw[string(serialize(k))] = nil
+					delete(items, k)
+				}
+				var root = trie.RootWith(raw)
+				trie.Update(raw)
+				So(root, ShouldResemble, rebuild().Root())
+				So(trie.Root(), ShouldResemble, root)
+			}
+		)
+		for i := 0; i < 9; i++ {
+			items[i] = int32(i * i)
+			trie.Insert(serialize(i), serialize(items[i]))
+		}
+		So(trie.Root(), ShouldResemble, rebuild().Root())
+
+		Convey("The root should be updated with the changed values", func() {
+			update(map[int]int32{3: 1, 5: 2})
+			update(map[int]int32{0: 7})
+			update(map[int]int32{8: 8})
+			var r, proof, err = trie.Prove(serialize(3))
+			So(err, ShouldBeNil)
+			So(proof.Verify(r, serialize(3), serialize(int32(1))), ShouldBeTrue)
+			So(proof.Verify(r, serialize(3), serialize(int32(9))), ShouldBeFalse)
+			value, err := trie.Get(serialize(3))
+			So(err, ShouldBeNil)
+			So(deserialize(value), ShouldEqual, 1)
+		})
+		Convey("The root should be updated with the added and deleted items", func() {
+			update(map[int]int32{9: 81, 10: 100})
+			update(map[int]int32{4: 0}, 0, 9)
+			update(nil, 10)
+			for i := 1; i < 9; i++ {
+				update(nil, i)
+			}
+			So(trie.Root(), ShouldResemble, NewMerkle(nil).GetRoot())
+			update(map[int]int32{1: 1})
+			var r, proof, err = trie.Prove(serialize(1))
+			So(err, ShouldBeNil)
+			So(proof.Verify(r, serialize(1), serialize(int32(1))), ShouldBeTrue)
+			_, _, err = trie.Prove(serialize(0))
+			So(err, ShouldEqual, ErrKeyNotFound)
+		})
+		Convey("The trie should not be modified by computing the root with updates", func() {
+			var root = trie.Root()
+			So(trie.RootWith(map[string][]byte{
+				string(serialize(3)): serialize(int32(1)),
+			}), ShouldNotResemble, root)
+			So(trie.RootWith(map[string][]byte{
+				string(serialize(3)): nil,
+				string(serialize(9)): serialize(int32(1)),
+			}), ShouldNotResemble, root)
+			So(trie.Root(), ShouldResemble, root)
+		})
+	})
+}
@@ -79,6 +79,16 @@ const (
 	DBSDeploy
 	// DBSObserverFetchBlock is used by observer to fetch block.
 	DBSObserverFetchBlock
+	// DBSQueryPeers is used by client to query the current peers of a database.
+	DBSQueryPeers
+	// DBSBeginTx is used by client to begin an interactive transaction.
+	DBSBeginTx
+	// DBSEndTx is used by client to commit or rollback an interactive transaction.
+	DBSEndTx
+	// DBSFetchRows is used by client to fetch the remaining rows of a query result by cursor.
+	DBSFetchRows
+	// DBSCancelQuery is used by client to cancel an in-flight query request.
+	DBSCancelQuery
 	// DBCCall is used by Miner for data consistency
 	DBCCall
 	// SQLCAdviseNewBlock is used by sqlchain to advise new block between adjacent node
@@ -118,6 +128,17 @@ const (
 	MCCQueryAccountTokenBalance
 	// MCCQueryTxState is used by client to query transaction state.
 	MCCQueryTxState
+	// MCCQueryEscrow is used by block producer to provide escrow object.
+	MCCQueryEscrow
+	// MCCQueryAccountEscrowBalance is used by block producer to provide account escrow balance.
+	MCCQueryAccountEscrowBalance
+	// MCCFetchStateSnapshot is used by nodes to fetch state snapshot chunks from block producer.
+	MCCFetchStateSnapshot
+	// MCCQueryAccountWithProof is used by nodes to query account with state inclusion proof.
+	MCCQueryAccountWithProof
+	// MCCQuerySQLChainProfileWithProof is used by nodes to query SQLChainProfile with state
+	// inclusion proof.
+	MCCQuerySQLChainProfileWithProof
 
 	// DHTRPCName defines the block producer dh-rpc service name
 	DHTRPCName = "DHT"
@@ -152,6 +173,16 @@ func (s RemoteFunc) String() string {
 		return "DBS.Deploy"
 	case DBSObserverFetchBlock:
 		return "DBS.ObserverFetchBlock"
+	case DBSQueryPeers:
+		return "DBS.QueryPeers"
+	case DBSBeginTx:
+		return "DBS.BeginTx"
+	case DBSEndTx:
+		return "DBS.EndTx"
+	case DBSFetchRows:
+		return "DBS.FetchRows"
+	case DBSCancelQuery:
+		return "DBS.CancelQuery"
 	case DBCCall:
 		return "DBC.Call"
 	case SQLCAdviseNewBlock:
@@ -190,6 +221,16 @@ func (s RemoteFunc) String() string {
 		return "MCC.QueryAccountTokenBalance"
 	case MCCQueryTxState:
 		return "MCC.QueryTxState"
+	case MCCQueryEscrow:
+		return "MCC.QueryEscrow"
+	case MCCQueryAccountEscrowBalance:
+		return "MCC.QueryAccountEscrowBalance"
+	case MCCFetchStateSnapshot:
+		return "MCC.FetchStateSnapshot"
+	case MCCQueryAccountWithProof:
+		return "MCC.QueryAccountWithProof"
+	case MCCQuerySQLChainProfileWithProof:
+		return "MCC.QuerySQLChainProfileWithProof"
 	}
 	return "Unknown"
 }
@@ -20,6 +20,8 @@ package rpc
 import (
 	"net"
 	"net/rpc"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	mux "github.com/xtaci/smux"
@@ -30,11 +32,16 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/pow/cpuminer"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
 )
 
 const (
 	// ETLSHeaderSize is the header size with ETLSHeader + NodeID + Nonce
 	ETLSHeaderSize = 2 + hash.HashBSize + 32
+
+	// legacyETLSRetryInterval is the interval to retry the preferred ETLS version with the node
+	// which only accepted etls.Version1 last time, it may be upgraded during rollout.
+	legacyETLSRetryInterval = 10 * time.Minute
 )
 
 // Client is RPC client.
@@ -49,6 +56,15 @@ var (
 	MuxConfig *mux.Config
 	// DefaultDialer holds the default dialer of SessionPool
 	DefaultDialer func(nodeID proto.NodeID) (conn net.Conn, err error)
+	// ETLSVersion is the ETLS protocol version preferred by client, the client falls back to
+	// etls.Version1 if the node does not support it and ETLSMinVersion permits.
+	ETLSVersion = etls.Version2
+	// ETLSMinVersion is the lowest ETLS protocol version accepted by both client and server,
+	// raise it to etls.Version2 to refuse the legacy protocol once all the nodes are upgraded.
+	ETLSMinVersion = etls.Version1
+
+	// legacyETLSNodes records the last time the node address fell back to etls.Version1
+	legacyETLSNodes sync.Map
 )
 
 func init() {
@@ -65,8 +81,7 @@ func dial(network, address string, remoteNodeID *proto.RawNodeID, cipher *etls.C
 		return
 	}
 	writeBuf := make([]byte, ETLSHeaderSize)
-	writeBuf[0] = etls.ETLSMagicBytes[0]
-	writeBuf[1] = etls.ETLSMagicBytes[1]
+	copy(writeBuf, etls.MagicBytes(cipher.Version()))
 	if isAnonymous {
 		copy(writeBuf[2:], kms.AnonymousRawNodeID.AsBytes())
 		copy(writeBuf[2+hash.HashSize:], (&cpuminer.Uint256{}).Bytes())
@@ -99,9 +114,28 @@ func dial(network, address string, remoteNodeID *proto.RawNodeID, cipher *etls.C
 	}
 
 	c = etls.NewConn(conn, cipher, remoteNodeID)
+	c.SetHeader(writeBuf)
+	if err = c.Handshake(); err != nil {
+		_ = conn.Close()
+		c = nil
+	}
 	return
 }
 
+// dialETLSVersion returns the ETLS protocol version to dial the node address with.
+func dialETLSVersion(address string) etls.Version {
+	if ETLSVersion <= etls.Version1 || ETLSMinVersion > etls.Version1 {
+		return ETLSVersion
+	}
+	if last, ok := legacyETLSNodes.Load(address); ok {
+		if time.Since(last.(time.Time)) < legacyETLSRetryInterval {
+			return etls.Version1
+		}
+		legacyETLSNodes.Delete(address)
+	}
+	return ETLSVersion
+}
+
 // DialToNode ties use connection in pool, if fails then connects to the node with nodeID.
 func DialToNode(nodeID proto.NodeID, pool *SessionPool, isAnonymous bool) (conn net.Conn, err error) {
 	if pool == nil || isAnonymous {
@@ -161,8 +195,19 @@ func dialToNodeEx(nodeID proto.NodeID, isAnonymous bool) (conn net.Conn, err err
 		return
 	}
 
-	cipher := etls.NewCipher(symmetricKey)
+	version := dialETLSVersion(nodeAddr)
+	cipher := etls.NewCipherWithVersion(symmetricKey, version)
 	conn, err = dial("tcp", nodeAddr, rawNodeID, cipher, isAnonymous)
+	if errors.Cause(err) == etls.ErrHandshakeRefused && version > etls.Version1 &&
+		ETLSMinVersion <= etls.Version1 {
+		// the node is not upgraded yet and closes the connection of unknown header, any other
+		// handshake failure is not a reason to downgrade
+		log.WithError(err).WithField("node", rawNodeID.String()).Info(
+			"fall back to legacy ETLS protocol")
+		legacyETLSNodes.Store(nodeAddr, time.Now())
+		cipher = etls.NewCipherWithVersion(symmetricKey, etls.Version1)
+		conn, err = dial("tcp", nodeAddr, rawNodeID, cipher, isAnonymous)
+	}
 	if err != nil {
 		err = errors.Wrapf(err, "connect %s %s failed", rawNodeID.String(), nodeAddr)
 		return
@@ -197,8 +197,12 @@ func handleCipher(conn net.Conn) (cryptoConn *etls.CryptoConn, err error) {
 		return
 	}
 
-	if headerBuf[0] != etls.ETLSMagicBytes[0] || headerBuf[1] != etls.ETLSMagicBytes[1] {
-		err = errors.New("bad ETLS header")
+	version, err := etls.ParseMagicBytes(headerBuf)
+	if err != nil {
+		return
+	}
+	if version < ETLSMinVersion {
+		err = errors.Errorf("ETLS version %d is not accepted", version)
 		return
 	}
 
@@ -216,8 +220,9 @@ func handleCipher(conn net.Conn) (cryptoConn *etls.CryptoConn, err error) {
 		err = errors.Wrapf(err, "get shared secret, target: %s", rawNodeID.String())
 		return
 	}
-	cipher := etls.NewCipher(symmetricKey)
+	cipher := etls.NewCipherWithVersion(symmetricKey, version)
 	cryptoConn = etls.NewConn(conn, cipher, rawNodeID)
+	cryptoConn.SetHeader(headerBuf)
 
 	return
 }
@@ -17,14 +17,19 @@
 package rpc
 
 import (
+	"io"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/CovenantSQL/CovenantSQL/consistent"
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/etls"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/kms"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/route"
@@ -232,6 +237,91 @@ func TestETLSBug(t *testing.T) {
 	CheckNum(*repSimple, 10, t)
 }
 
+func TestETLSVersionNegotiation(t *testing.T) {
+	defer utils.RemoveAll(PubKeyStorePath + "*")
+	log.SetLevel(log.FatalLevel)
+	Convey("Given a legacy node only accepting ETLS version 1", t, func() {
+		server, err := NewServerWithService(ServiceMap{"Test": NewTestService()})
+		So(err, ShouldBeNil)
+		route.NewDHTService(PubKeyStorePath, new(consistent.KMSStorage), true)
+		err = server.InitRPCServer("127.0.0.1:0", "../keys/test.key", []byte("abc"))
+		So(err, ShouldBeNil)
+		// the same as handleCipher before etls.Version2 was introduced
+		var tampered uint32
+		server.Listener.(*etls.CryptoListener).CHandler = func(conn net.Conn) (
+			cryptoConn *etls.CryptoConn, err error,
+		) {
+			if atomic.LoadUint32(&tampered) == 1 {
+				// an upgraded node seeing a different header than the one the client sent
+				if cryptoConn, err = handleCipher(conn); err == nil {
+					cryptoConn.SetHeader(make([]byte, ETLSHeaderSize))
+				}
+				return
+			}
+			headerBuf := make([]byte, ETLSHeaderSize)
+			if _, err = io.ReadFull(conn, headerBuf); err != nil {
+				return
+			}
+			if headerBuf[0] != etls.ETLSMagicBytes[0] || headerBuf[1] != etls.ETLSMagicBytes[1] {
+				err = errors.New("bad ETLS header")
+				return
+			}
+			idHash, _ := hash.NewHash(headerBuf[2 : 2+hash.HashBSize])
+			rawNodeID := &proto.RawNodeID{Hash: *idHash}
+			symmetricKey, err := GetSharedSecretWith(rawNodeID, false)
+			if err != nil {
+				return
+			}
+			cryptoConn = etls.NewConn(conn, etls.NewCipher(symmetricKey), rawNodeID)
+			return
+		}
+		go server.Serve()
+		defer server.Stop()
+
+		publicKey, err := kms.GetLocalPublicKey()
+		So(err, ShouldBeNil)
+		nonce := asymmetric.GetPubKeyNonce(publicKey, 10, 100*time.Millisecond, nil)
+		serverNodeID := proto.NodeID(nonce.Hash.String())
+		serverAddr := server.Listener.Addr().String()
+		kms.SetPublicKey(serverNodeID, nonce.Nonce, publicKey)
+		kms.SetLocalNodeIDNonce(nonce.Hash.CloneBytes(), &nonce.Nonce)
+		route.SetNodeAddrCache(&proto.RawNodeID{Hash: nonce.Hash}, serverAddr)
+		defer legacyETLSNodes.Delete(serverAddr)
+
+		var call = func() {
+			conn, err := dialToNodeEx(serverNodeID, false)
+			So(err, ShouldBeNil)
+			So(conn.(*etls.CryptoConn).Version(), ShouldEqual, etls.Version1)
+			client, err := InitClientConn(conn)
+			So(err, ShouldBeNil)
+			defer client.Close()
+			repSimple := new(int)
+			err = client.Call("Test.IncCounterSimpleArgs", 10, repSimple)
+			So(err, ShouldBeNil)
+		}
+
+		Convey("The client should fall back to version 1", func() {
+			So(dialETLSVersion(serverAddr), ShouldEqual, etls.Version2)
+			call()
+			So(dialETLSVersion(serverAddr), ShouldEqual, etls.Version1)
+			call()
+		})
+		Convey("The client should not fall back if the legacy version is refused", func() {
+			ETLSMinVersion = etls.Version2
+			defer func() { ETLSMinVersion = etls.Version1 }()
+			_, err = dialToNodeEx(serverNodeID, false)
+			So(errors.Cause(err), ShouldEqual, etls.ErrHandshakeRefused)
+			So(dialETLSVersion(serverAddr), ShouldEqual, etls.Version2)
+		})
+		Convey("The client should not fall back if the hello is not authenticated", func() {
+			atomic.StoreUint32(&tampered, 1)
+			_, err = dialToNodeEx(serverNodeID, false)
+			So(errors.Cause(err), ShouldEqual, etls.ErrHandshakeFailed)
+			So(dialETLSVersion(serverAddr), ShouldEqual, etls.Version2)
+		})
+	})
+}
+
 func TestEncPingFindNeighbor(t *testing.T) {
 	utils.RemoveAll(PubKeyStorePath + "*")
 	defer utils.RemoveAll(PubKeyStorePath + "*")
@@ -115,6 +115,8 @@ type Chain struct {
 	gasPrice     uint64
 	updatePeriod uint64
 
+	onNewBlock func(*types.Block)
+
 	// Cached fileds, may need to renew some of this fields later.
 	//
 	// pk is the private key of the local miner.
@@ -202,6 +204,7 @@ func NewChainWithContext(ctx context.Context, c *Config) (chain *Chain, err erro
 		gasPrice:     c.GasPrice,
 		updatePeriod: c.UpdatePeriod,
 		databaseID:   c.DatabaseID,
+		onNewBlock:   c.OnNewBlock,
 
 		pk:                pk,
 		addr:              &addr,
@@ -209,6 +212,11 @@ func NewChainWithContext(ctx context.Context, c *Config) (chain *Chain, err erro
 		metaResponseIndex: utils.ConcatAll(metaKeyPrefix[:], metaResponseIndex[:]),
 		metaAckIndex:      utils.ConcatAll(metaKeyPrefix[:], metaAckIndex[:]),
 	}
+	chain.st.SetChunkRows(c.ChunkRows)
+	chain.st.SetCursorLimits(c.CursorQuota, c.MaxCursors, c.MaxClientCursors)
+	chain.st.SetGasLimit(c.GasLimit)
+	chain.st.SetTxLimits(c.TxLifetime, c.MaxWritableTxs)
+	chain.st.SetRowPolicies(c.RowPolicies)
 	le = le.WithField("peer", chain.rt.getPeerInfoString())
 
 	// Read blocks and rebuild memory index
@@ -411,6 +419,10 @@ func (c *Chain) pushBlock(b *types.Block) (err error) {
 			}(), head.Head.String()[:8]),
 		"headHeight": c.rt.getHead().Height,
 	}).Info("pushed new block")
+
+	if c.onNewBlock != nil {
+		c.onNewBlock(b)
+	}
 	return
 }
 
@@ -946,6 +958,62 @@ func (c *Chain) Query(
 	return c.st.QueryWithContext(req.GetContext(), req, isLeader)
 }
 
+// BeginTx opens an interactive transaction owned by node and begun at ts on the chain state.
+func (c *Chain) BeginTx(
+	owner proto.NodeID, ts time.Time, level sql.IsolationLevel, readOnly bool, timeout time.Duration,
+) (uint64, error) {
+	return c.st.BeginTx(owner, ts, level, readOnly, timeout)
+}
+
+// QueryTx executes req in the interactive transaction specified by its header.
+func (c *Chain) QueryTx(req *types.Request) (*types.Response, error) {
+	return c.st.QueryTx(req.GetContext(), req)
+}
+
+// RollbackTx rolls back the interactive transaction id owned by node.
+func (c *Chain) RollbackTx(owner proto.NodeID, id uint64) error {
+	return c.st.RollbackTx(owner, id)
+}
+
+// LockWrite holds off the write request req while a writable interactive transaction is open,
+// the returned unlock function must be called after req is applied.
+func (c *Chain) LockWrite(req *types.Request) (func(), error) {
+	return c.st.LockWrite(req)
+}
+
+// AppliedOffset returns the offset of the chain state advanced by each applied write query.
+func (c *Chain) AppliedOffset() uint64 {
+	return c.st.AppliedOffset()
+}
+
+// WaitApplied waits until the chain state is applied to offset or ctx is done.
+func (c *Chain) WaitApplied(ctx context.Context, offset uint64) error {
+	return c.st.WaitApplied(ctx, offset)
+}
+
+// FetchRows returns the next chunk of rows from the result set cursor id owned by node. The
+// final response and the query tracker are returned with the last chunk.
+func (c *Chain) FetchRows(
+	owner proto.NodeID, id uint64) ([]types.ResponseRow, *x.QueryTracker, *types.Response, error,
+) {
+	return c.st.FetchRows(owner, id)
+}
+
+// CloseCursor releases the result set cursor id owned by node.
+func (c *Chain) CloseCursor(owner proto.NodeID, id uint64) error {
+	return c.st.CloseCursor(owner, id)
+}
+
+// Snapshot writes a consistent copy of the chain state database to file.
+func (c *Chain) Snapshot(file string) error {
+	return c.st.Snapshot(file)
+}
+
+// Restore replaces the chain state database with the copy in file.
+func (c *Chain) Restore(file string) error {
+	return c.st.Restore(file)
+}
+
 // AddResponse addes a response to the ackIndex, awaiting for acknowledgement.
 func (c *Chain) AddResponse(resp *types.SignedResponseHeader) (err error) {
 	return c.ai.addResponse(c.rt.getHeightFromTime(resp.GetRequestTimestamp()), resp)
@@ -995,6 +1063,18 @@ func (c *Chain) stat() {
 	c.st.Stat(c.databaseID)
 }
 
+// responseGas returns the gas charged for the query in tx. The responses produced before gas
+// accounting are charged by row count of reads and affected rows of writes.
+func responseGas(tx *types.QueryAsTx) uint64 {
+	if tx.Response.GasUsed > 0 {
+		return tx.Response.GasUsed
+	}
+	if tx.Request.Header.QueryType == types.ReadQuery {
+		return tx.Response.RowCount
+	}
+	return uint64(tx.Response.AffectedRows)
+}
+
 func (c *Chain) billing(h int32, node *blockNode) (ub *types.UpdateBilling, err error) {
 	le := c.logEntryWithHeadState()
 	le.WithFields(log.Fields{"given_height": h}).Info("begin to billing")
@@ -1028,13 +1108,9 @@ func (c *Chain) billing(h int32, node *blockNode) (ub *types.UpdateBilling, err
 			if _, ok := minersMap[userAddr]; !ok {
 				minersMap[userAddr] = make(map[proto.AccountAddress]uint64)
 			}
-			if tx.Request.Header.QueryType == types.ReadQuery {
-				minersMap[userAddr][minerAddr] += tx.Response.RowCount
-				usersMap[userAddr] += tx.Response.RowCount
-			} else {
-				minersMap[userAddr][minerAddr] += uint64(tx.Response.AffectedRows)
-				usersMap[userAddr] += uint64(tx.Response.AffectedRows)
-			}
+			var gas = responseGas(tx)
+			minersMap[userAddr][minerAddr] += gas
+			usersMap[userAddr] += gas
 		}
 
 		for _, req := range block.FailedReqs {
@@ -1050,8 +1126,9 @@ func (c *Chain) billing(h int32, node *blockNode) (ub *types.UpdateBilling, err
 				minersMap[userAddr] = make(map[proto.AccountAddress]uint64)
 			}
 
-			minersMap[userAddr][minerAddr] += uint64(len(req.Payload.Queries))
-			usersMap[userAddr] += uint64(len(req.Payload.Queries))
+			// Failed requests are charged by the base gas of queries
+			minersMap[userAddr][minerAddr] += uint64(len(req.Payload.Queries)) * x.GasQuery
+			usersMap[userAddr] += uint64(len(req.Payload.Queries)) * x.GasQuery
 		}
 		iter = iter.parent
 	}
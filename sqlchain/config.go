@@ -21,6 +21,7 @@ import (
 
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/types"
+	x "github.com/CovenantSQL/CovenantSQL/xenomint"
 )
 
 // Config represents a sql-chain config.
@@ -47,4 +48,29 @@ type Config struct {
 	UpdatePeriod      uint64
 	LastBillingHeight int32
 	IsolationLevel    int
+
+	// ChunkRows sets the max rows in a query response chunk, larger result sets are streamed by
+	// cursor. Zero disables result set streaming.
+	ChunkRows int
+	// CursorQuota sets the max open result set cursors shared with other databases, nil if
+	// unlimited.
+	CursorQuota *x.CursorQuota
+	// MaxCursors sets the max open result set cursors of the database, zero if unlimited.
+	MaxCursors int
+	// MaxClientCursors sets the max open result set cursors of a single client node, zero if
+	// unlimited.
+	MaxClientCursors int
+	// GasLimit sets the max gas used by a single query request, zero if unlimited.
+	GasLimit uint64
+	// TxLifetime sets the max lifetime of an interactive transaction, zero if unlimited.
+	TxLifetime time.Duration
+	// MaxWritableTxs sets the max writable interactive transactions which are open or waiting
+	// for the writer, zero if unlimited.
+	MaxWritableTxs int
+	// RowPolicies returns the row-level security policies applied to the queries of a user.
+	RowPolicies x.RowPolicyFunc
+
+	// OnNewBlock is called after a new block is pushed to the chain head, e.g. to checkpoint the
+	// consensus logs.
+	OnNewBlock func(*types.Block)
 }
@@ -0,0 +1,113 @@
+/*
+ *  Copyright 2018 The CovenantSQL Authors.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+const (
+	// MaxAccessTokenTTL defines the max lifetime of an access token.
+	MaxAccessTokenTTL = 24 * time.Hour
+	// MaxAccessTokenClockSkew defines the max clock skew between the issuer and the miners, a
+	// token issued later than now plus the skew is rejected.
+	MaxAccessTokenClockSkew = time.Minute
+)
+
+// AccessTokenHeader defines the scope of an access token, which grants the user the role on the
+// database until it expires.
+type AccessTokenHeader struct {
+	DatabaseID proto.DatabaseID
+	User       proto.AccountAddress
+	Role       UserPermissionRole
+	Issued     time.Time
+	Expire     time.Time
+}
+
+// AccessToken defines a short-lived access token delegated by a super user of the database. It's
+// signed off the main chain, and checked by the miners against the current permission of the
+// issuer. A single token can not be revoked before it expires, all the tokens of an issuer are
+// revoked at once when the issuer loses the super permission.
+type AccessToken struct {
+	AccessTokenHeader
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewAccessToken returns new instance.
+func NewAccessToken(header *AccessTokenHeader) *AccessToken {
+	return &AccessToken{
+		AccessTokenHeader: *header,
+	}
+}
+
+// Sign signs the access token by the issuer.
+func (t *AccessToken) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return t.DefaultHashSignVerifierImpl.Sign(&t.AccessTokenHeader, signer)
+}
+
+// Verify checks hash and signature of the access token.
+func (t *AccessToken) Verify() (err error) {
+	if t.Signee == nil || t.Signature == nil {
+		return errors.Wrap(ErrInvalidAccessToken, "token not signed")
+	}
+	return t.DefaultHashSignVerifierImpl.Verify(&t.AccessTokenHeader)
+}
+
+// Issuer returns the account address of the token issuer.
+func (t *AccessToken) Issuer() (proto.AccountAddress, error) {
+	return crypto.PubKeyHash(t.Signee)
+}
+
+// Permission returns the user permission granted by the token.
+func (t *AccessToken) Permission() *UserPermission {
+	return UserPermissionFromRole(t.Role)
+}
+
+// Check verifies the access token, and checks if it grants user the access to the database at
+// the time now. The permission of the issuer is not checked.
+func (t *AccessToken) Check(
+	dbID proto.DatabaseID, user proto.AccountAddress, now time.Time) (err error,
+) {
+	if err = t.Verify(); err != nil {
+		return
+	}
+	switch {
+	case t.DatabaseID != dbID || t.User != user:
+		err = errors.Wrapf(ErrInvalidAccessToken,
+			"token of user %s on database %s", t.User.String(), t.DatabaseID)
+	case t.Role == Void || t.Role&^Admin != 0:
+		err = errors.Wrapf(ErrInvalidAccessToken, "invalid role %d", t.Role)
+	case t.Issued.After(now.Add(MaxAccessTokenClockSkew)):
+		err = errors.Wrapf(ErrInvalidAccessToken,
+			"issued in the future at %s", t.Issued.Format(time.RFC3339))
+	case t.Expire.Sub(t.Issued) > MaxAccessTokenTTL:
+		err = errors.Wrapf(ErrInvalidAccessToken,
+			"lifetime %s exceeds %s", t.Expire.Sub(t.Issued), MaxAccessTokenTTL)
+	case !now.Before(t.Expire):
+		err = errors.Wrapf(ErrAccessTokenExpired, "expired at %s", t.Expire.Format(time.RFC3339))
+	}
+	return
+}
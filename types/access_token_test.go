@@ -0,0 +1,116 @@
+/*
+ *  Copyright 2018 The CovenantSQL Authors.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestAccessToken(t *testing.T) {
+	Convey("test access token", t, func() {
+		privKey, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		issuer, err := crypto.PubKeyHash(privKey.PubKey())
+		So(err, ShouldBeNil)
+
+		var (
+			dbID = proto.DatabaseID("db")
+			user = proto.AccountAddress{0x1}
+			now  = time.Now().UTC()
+		)
+		token := NewAccessToken(&AccessTokenHeader{
+			DatabaseID: dbID,
+			User:       user,
+			Role:       ReadOnly,
+			Issued:     now,
+			Expire:     now.Add(time.Hour),
+		})
+		err = token.Check(dbID, user, now)
+		So(errors.Cause(err), ShouldEqual, ErrInvalidAccessToken)
+		err = token.Sign(privKey)
+		So(err, ShouldBeNil)
+		err = token.Check(dbID, user, now)
+		So(err, ShouldBeNil)
+		addr, err := token.Issuer()
+		So(err, ShouldBeNil)
+		So(addr, ShouldEqual, issuer)
+		So(token.Permission().HasReadPermission(), ShouldBeTrue)
+		So(token.Permission().HasWritePermission(), ShouldBeFalse)
+
+		Convey("token should be scoped to database and user", func() {
+			err = token.Check("other", user, now)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidAccessToken)
+			err = token.Check(dbID, proto.AccountAddress{0x2}, now)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidAccessToken)
+		})
+		Convey("token should expire", func() {
+			err = token.Check(dbID, user, now.Add(time.Hour))
+			So(errors.Cause(err), ShouldEqual, ErrAccessTokenExpired)
+		})
+		Convey("token issued in the future should be rejected", func() {
+			err = token.Check(dbID, user, now.Add(-MaxAccessTokenClockSkew))
+			So(err, ShouldBeNil)
+			err = token.Check(dbID, user, now.Add(-MaxAccessTokenClockSkew-time.Second))
+			So(errors.Cause(err), ShouldEqual, ErrInvalidAccessToken)
+		})
+		Convey("modified token should fail verification", func() {
+			token.Role = Admin
+			err = token.Check(dbID, user, now)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("token with invalid role or lifetime should be rejected", func() {
+			token.Role = Void
+			err = token.Sign(privKey)
+			So(err, ShouldBeNil)
+			err = token.Check(dbID, user, now)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidAccessToken)
+			token.Role = ReadWrite
+			token.Expire = now.Add(MaxAccessTokenTTL + time.Second)
+			err = token.Sign(privKey)
+			So(err, ShouldBeNil)
+			err = token.Check(dbID, user, now)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidAccessToken)
+		})
+		Convey("token should be encoded with request header", func() {
+			var req = &Request{Header: SignedRequestHeader{RequestHeader: RequestHeader{
+				NodeID:      proto.NodeID("0000000000000000000000000000000000000000000000000000000000001111"),
+				DatabaseID:  dbID,
+				AccessToken: token,
+			}}}
+			err = req.Sign(privKey)
+			So(err, ShouldBeNil)
+			enc, err := utils.EncodeMsgPack(req)
+			So(err, ShouldBeNil)
+			var dec = &Request{}
+			err = utils.DecodeMsgPack(enc.Bytes(), dec)
+			So(err, ShouldBeNil)
+			err = dec.Verify()
+			So(err, ShouldBeNil)
+			err = dec.Header.AccessToken.Check(dbID, user, now)
+			So(err, ShouldBeNil)
+		})
+	})
+}
@@ -26,7 +26,7 @@ import (
 )
 
 //go:generate hsp
-//hsp:ignore PermStat
+//hsp:ignore PermStat TableAccess
 
 // SQLChainRole defines roles of account in a SQLChain.
 type SQLChainRole byte
@@ -48,12 +48,23 @@ type UserPermission struct {
 	// User role to access database.
 	Role UserPermissionRole
 	// SQL pattern regulations for user queries
-	// only a fully matched (case-sensitive) sql query is permitted to execute.
+	// only a fully matched (case-sensitive) sql query, or a query matched by the templates
+	// (see QueryTemplatePrefix and QueryRegexpPrefix) is permitted to execute.
 	Patterns []string
+	// Table and column privileges of the user, the queries are not limited by tables if it's
+	// empty, the super user is not limited either.
+	Tables []*TablePrivilege
+	// Main chain height at which the permission expires and drops to Void, zero if it never
+	// expires.
+	ExpireHeight uint32
 
 	// patterns map cache for matching
 	cachedPatternMapOnce sync.Once
 	cachedPatternMap     map[string]bool
+	cachedTemplates      []*queryTemplate
+	// template match results cache
+	cachedMatchLock sync.RWMutex
+	cachedMatches   map[string]bool
 }
 
 const (
@@ -77,6 +88,54 @@ const (
 	Void UserPermissionRole = 0
 )
 
+// TableAction defines the actions permitted on a table, including select/insert/update/delete.
+type TableAction int32
+
+const (
+	// TableSelect defines the privilege to select rows from table.
+	TableSelect TableAction = 1 << iota
+	// TableInsert defines the privilege to insert rows into table.
+	TableInsert
+	// TableUpdate defines the privilege to update rows of table.
+	TableUpdate
+	// TableDelete defines the privilege to delete rows from table.
+	TableDelete
+
+	// TableAll defines all the privileges on table.
+	TableAll = TableSelect | TableInsert | TableUpdate | TableDelete
+)
+
+// TablePrivilege defines the privileges of a user on a single table.
+type TablePrivilege struct {
+	// Table name, case-insensitive.
+	Table string
+	// Actions permitted on the table.
+	Actions TableAction
+	// Column allow-list of the table, all columns are permitted if it's empty.
+	Columns []string
+}
+
+// RowPolicy defines a row-level security policy of a database table, the rows of the table are
+// only visible to and modifiable by the user whose account address equals the owner column.
+type RowPolicy struct {
+	// Table name, case-insensitive.
+	Table string
+	// Column of the table which stores the owner account address of each row.
+	Column string
+}
+
+// TableAccess defines the action and columns of a table accessed by a query.
+type TableAccess struct {
+	// Table name in lower case.
+	Table string
+	// Action performed on the table.
+	Action TableAction
+	// Columns accessed by the action in lower case.
+	Columns []string
+	// AllColumns indicates that the action accesses all columns of the table, e.g. select *.
+	AllColumns bool
+}
+
 // UnmarshalJSON implements the json.Unmarshler interface.
 func (r *UserPermissionRole) UnmarshalJSON(data []byte) (err error) {
 	var s string
@@ -139,6 +198,65 @@ func (r *UserPermissionRole) FromString(perm string) {
 	}
 }
 
+// UnmarshalJSON implements the json.Unmarshler interface.
+func (a *TableAction) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); err != nil {
+		return
+	}
+	a.FromString(s)
+	return
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a TableAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// String implements the fmt.Stringer interface.
+func (a TableAction) String() string {
+	if a == TableAll {
+		return "All"
+	}
+
+	var res []string
+	if a&TableSelect != 0 {
+		res = append(res, "Select")
+	}
+	if a&TableInsert != 0 {
+		res = append(res, "Insert")
+	}
+	if a&TableUpdate != 0 {
+		res = append(res, "Update")
+	}
+	if a&TableDelete != 0 {
+		res = append(res, "Delete")
+	}
+
+	return strings.Join(res, ",")
+}
+
+// FromString converts string to TableAction.
+func (a *TableAction) FromString(actions string) {
+	*a = 0
+
+	for _, p := range strings.Split(actions, ",") {
+		p = strings.TrimSpace(p)
+		switch p {
+		case "All":
+			*a |= TableAll
+		case "Select":
+			*a |= TableSelect
+		case "Insert":
+			*a |= TableInsert
+		case "Update":
+			*a |= TableUpdate
+		case "Delete":
+			*a |= TableDelete
+		}
+	}
+}
+
 // UserPermissionFromRole construct a new user permission instance from primitive user permission role enum.
 func UserPermissionFromRole(role UserPermissionRole) *UserPermission {
 	return &UserPermission{
@@ -170,9 +288,36 @@ func (up *UserPermission) HasSuperPermission() bool {
 	return up.Role&Super != 0
 }
 
+// IsExpired returns true if the permission is expired at the main chain height.
+func (up *UserPermission) IsExpired(height uint32) bool {
+	return up != nil && up.ExpireHeight > 0 && height >= up.ExpireHeight
+}
+
+// Effective returns the permission in effect at the main chain height, which is Void if the
+// permission is expired.
+func (up *UserPermission) Effective(height uint32) *UserPermission {
+	if up.IsExpired(height) {
+		return UserPermissionFromRole(Void)
+	}
+	return up
+}
+
 // IsValid returns whether the permission object is valid or not.
 func (up *UserPermission) IsValid() bool {
-	return up != nil && up.Role != 0
+	if up == nil || up.Role == 0 {
+		return false
+	}
+	for _, p := range up.Patterns {
+		if _, err := compileQueryTemplate(p); err != nil {
+			return false
+		}
+	}
+	for _, t := range up.Tables {
+		if t == nil || t.Table == "" || t.Actions&^TableAll != 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // HasDisallowedQueryPatterns returns whether the queries are permitted.
@@ -190,11 +335,14 @@ func (up *UserPermission) HasDisallowedQueryPatterns(queries []Query) (query str
 		up.cachedPatternMap = make(map[string]bool, len(up.Patterns))
 		for _, p := range up.Patterns {
 			up.cachedPatternMap[p] = true
+			if t, err := compileQueryTemplate(p); err == nil && t != nil {
+				up.cachedTemplates = append(up.cachedTemplates, t)
+			}
 		}
 	})
 
 	for _, q := range queries {
-		if !up.cachedPatternMap[q.Pattern] {
+		if !up.cachedPatternMap[q.Pattern] && !up.matchQueryTemplates(q.Pattern) {
 			// not permitted
 			query = q.Pattern
 			status = true
@@ -205,6 +353,96 @@ func (up *UserPermission) HasDisallowedQueryPatterns(queries []Query) (query str
 	return
 }
 
+// matchQueryTemplates returns whether the query is matched by any of the query templates, the
+// results are cached in the permission object.
+func (up *UserPermission) matchQueryTemplates(query string) (matched bool) {
+	if len(up.cachedTemplates) == 0 {
+		return
+	}
+
+	var ok bool
+	up.cachedMatchLock.RLock()
+	matched, ok = up.cachedMatches[query]
+	up.cachedMatchLock.RUnlock()
+	if ok {
+		return
+	}
+
+	if !hasDoubleQuotedString(query) {
+		if normalized, err := NormalizeQuery(query); err == nil {
+			for _, t := range up.cachedTemplates {
+				if matched = t.match(normalized); matched {
+					break
+				}
+			}
+		}
+	}
+
+	up.cachedMatchLock.Lock()
+	defer up.cachedMatchLock.Unlock()
+	if up.cachedMatches == nil || len(up.cachedMatches) >= maxCachedQueryMatches {
+		up.cachedMatches = make(map[string]bool)
+	}
+	up.cachedMatches[query] = matched
+	return
+}
+
+// HasDisallowedTableAccess returns whether the table accesses are permitted by the table
+// privileges.
+func (up *UserPermission) HasDisallowedTableAccess(access []*TableAccess) (denied *TableAccess, status bool) {
+	if up == nil {
+		status = true
+		return
+	}
+	if len(up.Tables) == 0 || up.HasSuperPermission() {
+		status = false
+		return
+	}
+
+	for _, a := range access {
+		if !up.permitsTableAccess(a) {
+			denied = a
+			status = true
+			break
+		}
+	}
+
+	return
+}
+
+func (up *UserPermission) permitsTableAccess(access *TableAccess) bool {
+	for _, p := range up.Tables {
+		if p == nil || !strings.EqualFold(p.Table, access.Table) {
+			continue
+		}
+		if p.Actions&access.Action != access.Action {
+			return false
+		}
+		if len(p.Columns) == 0 {
+			return true
+		}
+		if access.AllColumns {
+			return false
+		}
+		for _, c := range access.Columns {
+			if !containsFold(p.Columns, c) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // Status defines status of a SQLChain user/miner.
 type Status int32
 
@@ -219,6 +457,8 @@ const (
 	Arrears
 	// Arbitration defines the user/miner is in an arbitration.
 	Arbitration
+	// Slashed defines the miner deposit is slashed for misbehavior.
+	Slashed
 	// NumberOfStatus defines the number of status.
 	NumberOfStatus
 )
@@ -261,6 +501,8 @@ type MinerInfo struct {
 	Deposit        uint64
 	Status         Status
 	EncryptionKey  string
+	// LastActiveHeight is the BP block height of the last billing reported by this miner.
+	LastActiveHeight uint32
 }
 
 // SQLChainProfile defines a SQLChainProfile related to an account.
@@ -278,6 +520,8 @@ type SQLChainProfile struct {
 	Miners []*MinerInfo
 
 	Users []*SQLChainUser
+	// row-level security policies applied to the non-super users
+	RowPolicies []*RowPolicy
 
 	EncodedGenesis []byte
 
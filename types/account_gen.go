@@ -40,8 +40,8 @@ func (z *Account) Msgsize() (s int) {
 func (z *MinerInfo) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 9
-	o = append(o, 0x89)
+	// map header, size 10
+	o = append(o, 0x8a)
 	if oTemp, err := z.Address.MarshalHash(); err != nil {
 		return nil, err
 	} else {
@@ -49,6 +49,7 @@ func (z *MinerInfo) MarshalHash() (o []byte, err error) {
 	}
 	o = hsp.AppendUint64(o, z.Deposit)
 	o = hsp.AppendString(o, z.EncryptionKey)
+	o = hsp.AppendUint32(o, z.LastActiveHeight)
 	o = hsp.AppendString(o, z.Name)
 	if oTemp, err := z.NodeID.MarshalHash(); err != nil {
 		return nil, err
@@ -78,7 +79,7 @@ func (z *MinerInfo) MarshalHash() (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *MinerInfo) Msgsize() (s int) {
-	s = 1 + 8 + z.Address.Msgsize() + 8 + hsp.Uint64Size + 14 + hsp.StringPrefixSize + len(z.EncryptionKey) + 5 + hsp.StringPrefixSize + len(z.Name) + 7 + z.NodeID.Msgsize() + 14 + hsp.Uint64Size + 15 + hsp.Uint64Size + 7 + hsp.Int32Size + 12 + hsp.ArrayHeaderSize
+	s = 1 + 8 + z.Address.Msgsize() + 8 + hsp.Uint64Size + 14 + hsp.StringPrefixSize + len(z.EncryptionKey) + 17 + hsp.Uint32Size + 5 + hsp.StringPrefixSize + len(z.Name) + 7 + z.NodeID.Msgsize() + 14 + hsp.Uint64Size + 15 + hsp.Uint64Size + 7 + hsp.Int32Size + 12 + hsp.ArrayHeaderSize
 	for za0001 := range z.UserArrears {
 		if z.UserArrears[za0001] == nil {
 			s += hsp.NilSize
@@ -136,12 +137,29 @@ func (z *ProviderProfile) Msgsize() (s int) {
 	return
 }
 
+// MarshalHash marshals for hash
+func (z *RowPolicy) MarshalHash() (o []byte, err error) {
+	var b []byte
+	o = hsp.Require(b, z.Msgsize())
+	// map header, size 2
+	o = append(o, 0x82)
+	o = hsp.AppendString(o, z.Column)
+	o = hsp.AppendString(o, z.Table)
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *RowPolicy) Msgsize() (s int) {
+	s = 1 + 7 + hsp.StringPrefixSize + len(z.Column) + 6 + hsp.StringPrefixSize + len(z.Table)
+	return
+}
+
 // MarshalHash marshals for hash
 func (z *SQLChainProfile) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 11
-	o = append(o, 0x8b)
+	// map header, size 12
+	o = append(o, 0x8c)
 	if oTemp, err := z.Address.MarshalHash(); err != nil {
 		return nil, err
 	} else {
@@ -178,6 +196,18 @@ func (z *SQLChainProfile) MarshalHash() (o []byte, err error) {
 		o = hsp.AppendBytes(o, oTemp)
 	}
 	o = hsp.AppendUint64(o, z.Period)
+	o = hsp.AppendArrayHeader(o, uint32(len(z.RowPolicies)))
+	for za0003 := range z.RowPolicies {
+		if z.RowPolicies[za0003] == nil {
+			o = hsp.AppendNil(o)
+		} else {
+			if oTemp, err := z.RowPolicies[za0003].MarshalHash(); err != nil {
+				return nil, err
+			} else {
+				o = hsp.AppendBytes(o, oTemp)
+			}
+		}
+	}
 	if oTemp, err := z.TokenType.MarshalHash(); err != nil {
 		return nil, err
 	} else {
@@ -208,7 +238,15 @@ func (z *SQLChainProfile) Msgsize() (s int) {
 			s += z.Miners[za0001].Msgsize()
 		}
 	}
-	s += 6 + z.Owner.Msgsize() + 7 + hsp.Uint64Size + 10 + z.TokenType.Msgsize() + 6 + hsp.ArrayHeaderSize
+	s += 6 + z.Owner.Msgsize() + 7 + hsp.Uint64Size + 12 + hsp.ArrayHeaderSize
+	for za0003 := range z.RowPolicies {
+		if z.RowPolicies[za0003] == nil {
+			s += hsp.NilSize
+		} else {
+			s += z.RowPolicies[za0003].Msgsize()
+		}
+	}
+	s += 10 + z.TokenType.Msgsize() + 6 + hsp.ArrayHeaderSize
 	for za0002 := range z.Users {
 		if z.Users[za0002] == nil {
 			s += hsp.NilSize
@@ -291,6 +329,45 @@ func (z Status) Msgsize() (s int) {
 	return
 }
 
+// MarshalHash marshals for hash
+func (z TableAction) MarshalHash() (o []byte, err error) {
+	var b []byte
+	o = hsp.Require(b, z.Msgsize())
+	o = hsp.AppendInt32(o, int32(z))
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z TableAction) Msgsize() (s int) {
+	s = hsp.Int32Size
+	return
+}
+
+// MarshalHash marshals for hash
+func (z *TablePrivilege) MarshalHash() (o []byte, err error) {
+	var b []byte
+	o = hsp.Require(b, z.Msgsize())
+	// map header, size 3
+	o = append(o, 0x83)
+	o = hsp.AppendInt32(o, int32(z.Actions))
+	o = hsp.AppendArrayHeader(o, uint32(len(z.Columns)))
+	for za0001 := range z.Columns {
+		o = hsp.AppendString(o, z.Columns[za0001])
+	}
+	o = hsp.AppendString(o, z.Table)
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *TablePrivilege) Msgsize() (s int) {
+	s = 1 + 8 + hsp.Int32Size + 8 + hsp.ArrayHeaderSize
+	for za0001 := range z.Columns {
+		s += hsp.StringPrefixSize + len(z.Columns[za0001])
+	}
+	s += 6 + hsp.StringPrefixSize + len(z.Table)
+	return
+}
+
 // MarshalHash marshals for hash
 func (z *UserArrears) MarshalHash() (o []byte, err error) {
 	var b []byte
@@ -316,23 +393,43 @@ func (z *UserArrears) Msgsize() (s int) {
 func (z *UserPermission) MarshalHash() (o []byte, err error) {
 	var b []byte
 	o = hsp.Require(b, z.Msgsize())
-	// map header, size 2
-	o = append(o, 0x82)
+	// map header, size 4
+	o = append(o, 0x84)
+	o = hsp.AppendUint32(o, z.ExpireHeight)
 	o = hsp.AppendArrayHeader(o, uint32(len(z.Patterns)))
 	for za0001 := range z.Patterns {
 		o = hsp.AppendString(o, z.Patterns[za0001])
 	}
 	o = hsp.AppendInt32(o, int32(z.Role))
+	o = hsp.AppendArrayHeader(o, uint32(len(z.Tables)))
+	for za0002 := range z.Tables {
+		if z.Tables[za0002] == nil {
+			o = hsp.AppendNil(o)
+		} else {
+			if oTemp, err := z.Tables[za0002].MarshalHash(); err != nil {
+				return nil, err
+			} else {
+				o = hsp.AppendBytes(o, oTemp)
+			}
+		}
+	}
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *UserPermission) Msgsize() (s int) {
-	s = 1 + 9 + hsp.ArrayHeaderSize
+	s = 1 + 13 + hsp.Uint32Size + 9 + hsp.ArrayHeaderSize
 	for za0001 := range z.Patterns {
 		s += hsp.StringPrefixSize + len(z.Patterns[za0001])
 	}
-	s += 5 + hsp.Int32Size
+	s += 5 + hsp.Int32Size + 7 + hsp.ArrayHeaderSize
+	for za0002 := range z.Tables {
+		if z.Tables[za0002] == nil {
+			s += hsp.NilSize
+		} else {
+			s += z.Tables[za0002].Msgsize()
+		}
+	}
 	return
 }
 
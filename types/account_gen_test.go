@@ -120,6 +120,43 @@ func BenchmarkAppendMsgProviderProfile(b *testing.B) {
 	}
 }
 
+func TestMarshalHashRowPolicy(t *testing.T) {
+	v := RowPolicy{}
+	binary.Read(rand.Reader, binary.BigEndian, &v)
+	bts1, err := v.MarshalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bts2, err := v.MarshalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts1, bts2) {
+		t.Fatal("hash not stable")
+	}
+}
+
+func BenchmarkMarshalHashRowPolicy(b *testing.B) {
+	v := RowPolicy{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.MarshalHash()
+	}
+}
+
+func BenchmarkAppendMsgRowPolicy(b *testing.B) {
+	v := RowPolicy{}
+	bts := make([]byte, 0, v.Msgsize())
+	bts, _ = v.MarshalHash()
+	b.SetBytes(int64(len(bts)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bts, _ = v.MarshalHash()
+	}
+}
+
 func TestMarshalHashSQLChainProfile(t *testing.T) {
 	v := SQLChainProfile{}
 	binary.Read(rand.Reader, binary.BigEndian, &v)
@@ -194,6 +231,43 @@ func BenchmarkAppendMsgSQLChainUser(b *testing.B) {
 	}
 }
 
+func TestMarshalHashTablePrivilege(t *testing.T) {
+	v := TablePrivilege{}
+	binary.Read(rand.Reader, binary.BigEndian, &v)
+	bts1, err := v.MarshalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bts2, err := v.MarshalHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts1, bts2) {
+		t.Fatal("hash not stable")
+	}
+}
+
+func BenchmarkMarshalHashTablePrivilege(b *testing.B) {
+	v := TablePrivilege{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.MarshalHash()
+	}
+}
+
+func BenchmarkAppendMsgTablePrivilege(b *testing.B) {
+	v := TablePrivilege{}
+	bts := make([]byte, 0, v.Msgsize())
+	bts, _ = v.MarshalHash()
+	b.SetBytes(int64(len(bts)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bts, _ = v.MarshalHash()
+	}
+}
+
 func TestMarshalHashUserArrears(t *testing.T) {
 	v := UserArrears{}
 	binary.Read(rand.Reader, binary.BigEndian, &v)
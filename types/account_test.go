@@ -66,6 +66,8 @@ func TestUserPermission(t *testing.T) {
 		So(p.IsValid(), ShouldBeFalse)
 		_, state := p.HasDisallowedQueryPatterns([]Query{})
 		So(state, ShouldBeTrue)
+		_, state = p.HasDisallowedTableAccess([]*TableAccess{})
+		So(state, ShouldBeTrue)
 	})
 	Convey("has read permission", t, func() {
 		So(UserPermissionFromRole(Void).HasReadPermission(), ShouldBeFalse)
@@ -95,6 +97,16 @@ func TestUserPermission(t *testing.T) {
 		So(UserPermissionFromRole(ReadWrite).IsValid(), ShouldBeTrue)
 		So(UserPermissionFromRole(Admin).IsValid(), ShouldBeTrue)
 	})
+	Convey("expiry", t, func() {
+		p := &UserPermission{Role: Admin, ExpireHeight: 10}
+		So(p.IsExpired(9), ShouldBeFalse)
+		So(p.Effective(9), ShouldEqual, p)
+		So(p.IsExpired(10), ShouldBeTrue)
+		So(p.Effective(10).Role, ShouldEqual, Void)
+		So(p.Effective(10).HasReadPermission(), ShouldBeFalse)
+		So(UserPermissionFromRole(Read).IsExpired(1<<31), ShouldBeFalse)
+		So((*UserPermission)(nil).IsExpired(1), ShouldBeFalse)
+	})
 	Convey("query patterns", t, func() {
 		// empty patterns limitation
 		_, state := UserPermissionFromRole(Read).HasDisallowedQueryPatterns([]Query{
@@ -129,4 +141,69 @@ func TestUserPermission(t *testing.T) {
 		})
 		So(state, ShouldBeFalse)
 	})
+	Convey("table privileges", t, func() {
+		var access = []*TableAccess{
+			{Table: "t", Action: TableSelect, Columns: []string{"a", "b"}},
+			{Table: "u", Action: TableInsert, AllColumns: true},
+		}
+
+		// empty table privileges limitation
+		_, state := UserPermissionFromRole(ReadWrite).HasDisallowedTableAccess(access)
+		So(state, ShouldBeFalse)
+
+		up := UserPermissionFromRole(ReadWrite)
+		up.Tables = []*TablePrivilege{
+			{Table: "T", Actions: TableSelect, Columns: []string{"A", "b"}},
+			{Table: "u", Actions: TableSelect | TableInsert},
+		}
+		_, state = up.HasDisallowedTableAccess(access)
+		So(state, ShouldBeFalse)
+
+		// column not in allow-list
+		denied, state := up.HasDisallowedTableAccess([]*TableAccess{
+			{Table: "t", Action: TableSelect, Columns: []string{"a", "c"}},
+		})
+		So(state, ShouldBeTrue)
+		So(denied.Table, ShouldEqual, "t")
+		_, state = up.HasDisallowedTableAccess([]*TableAccess{
+			{Table: "t", Action: TableSelect, AllColumns: true},
+		})
+		So(state, ShouldBeTrue)
+
+		// action not permitted
+		_, state = up.HasDisallowedTableAccess([]*TableAccess{
+			{Table: "u", Action: TableInsert | TableDelete, AllColumns: true},
+		})
+		So(state, ShouldBeTrue)
+
+		// table not granted
+		_, state = up.HasDisallowedTableAccess([]*TableAccess{
+			{Table: "v", Action: TableSelect},
+		})
+		So(state, ShouldBeTrue)
+
+		// super user is not limited
+		up.Role = Admin
+		_, state = up.HasDisallowedTableAccess([]*TableAccess{
+			{Table: "v", Action: TableSelect},
+		})
+		So(state, ShouldBeFalse)
+	})
+	Convey("table action string/json", t, func() {
+		var a TableAction
+		a.FromString("Select, Update")
+		So(a, ShouldEqual, TableSelect|TableUpdate)
+		So(a.String(), ShouldEqual, "Select,Update")
+		a.FromString(TableAll.String())
+		So(a, ShouldEqual, TableAll)
+
+		var p TablePrivilege
+		err := json.Unmarshal([]byte(`{"table":"t","actions":"Select,Insert","columns":["a"]}`), &p)
+		So(err, ShouldBeNil)
+		So(p, ShouldResemble, TablePrivilege{
+			Table:   "t",
+			Actions: TableSelect | TableInsert,
+			Columns: []string{"a"},
+		})
+	})
 }
@@ -50,6 +50,12 @@ func (b *BaseAccount) GetAccountNonce() pi.AccountNonce {
 	return pi.AccountNonce(0)
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (b *BaseAccount) GetFee() uint64 {
+	// BaseAccount is only allowed in genesis block, no fee is charged.
+	return 0
+}
+
 // Hash implements interfaces/Transaction.Hash.
 func (b *BaseAccount) Hash() (h hash.Hash) {
 	return
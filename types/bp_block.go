@@ -19,6 +19,8 @@ package types
 import (
 	"time"
 
+	"github.com/pkg/errors"
+
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
@@ -35,7 +37,20 @@ type BPHeader struct {
 	Producer   proto.AccountAddress
 	MerkleRoot hash.Hash
 	ParentHash hash.Hash
+	StateRoot  hash.Hash // root of the state trie after applying the block transactions
 	Timestamp  time.Time
+	// HashVersion is the hash version of the header, only a header at a non-zero version
+	// commits to the state root
+	HashVersion int32 `hsp:"v,version"`
+}
+
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// block header.
+func (h *BPHeader) checkVersion() error {
+	if h.HashVersion == 0 && !h.StateRoot.IsEqual(&hash.Hash{}) {
+		return errors.Wrap(ErrUnhashedField, "legacy block header")
+	}
+	return nil
 }
 
 // BPSignedHeader defines the main chain header with the signature.
@@ -45,18 +60,30 @@ type BPSignedHeader struct {
 }
 
 func (s *BPSignedHeader) verifyHash() error {
+	if err := s.BPHeader.checkVersion(); err != nil {
+		return err
+	}
 	return s.DefaultHashSignVerifierImpl.VerifyHash(&s.BPHeader)
 }
 
-func (s *BPSignedHeader) verify() error {
+// Verify verifies the signature of the header, it doesn't check the merkle root of the block
+// transactions.
+func (s *BPSignedHeader) Verify() error {
+	if err := s.BPHeader.checkVersion(); err != nil {
+		return err
+	}
 	return s.DefaultHashSignVerifierImpl.Verify(&s.BPHeader)
 }
 
 func (s *BPSignedHeader) setHash() error {
+	if err := s.BPHeader.checkVersion(); err != nil {
+		return err
+	}
 	return s.DefaultHashSignVerifierImpl.SetHash(&s.BPHeader)
 }
 
 func (s *BPSignedHeader) sign(signer *asymmetric.PrivateKey) error {
+	s.HashVersion = int32(s.HSPDefaultVersion())
 	return s.DefaultHashSignVerifierImpl.Sign(&s.BPHeader, signer)
 }
 
@@ -117,7 +144,7 @@ func (b *BPBlock) Verify() error {
 	if err := b.verifyMerkleRoot(); err != nil {
 		return err
 	}
-	return b.SignedHeader.verify()
+	return b.SignedHeader.Verify()
 }
 
 // Timestamp returns timestamp of block.
@@ -3,6 +3,8 @@ package types
 // Code generated by github.com/CovenantSQL/HashStablePack DO NOT EDIT.
 
 import (
+	herr "errors"
+
 	hsp "github.com/CovenantSQL/HashStablePack/marshalhash"
 )
 
@@ -43,35 +45,50 @@ func (z *BPBlock) Msgsize() (s int) {
 	return
 }
 
+var hspVersionsBPHeader = []string{
+	"oldver",
+	"ca3e03",
+}
+
+// HSPCurrentVersion returns current struct version
+func (z *BPHeader) HSPCurrentVersion() int {
+	return int(z.HashVersion)
+}
+
+// HSPMaxVersion returns max struct version
+func (z *BPHeader) HSPMaxVersion() int {
+	return 1
+}
+
+// HSPDefaultVersion returns default struct version
+func (z *BPHeader) HSPDefaultVersion() int {
+	return 1
+}
+
 // MarshalHash marshals for hash
 func (z *BPHeader) MarshalHash() (o []byte, err error) {
-	var b []byte
-	o = hsp.Require(b, z.Msgsize())
-	// map header, size 5
-	o = append(o, 0x85)
-	if oTemp, err := z.MerkleRoot.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	if oTemp, err := z.ParentHash.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.MarshalHasholdver()
+	case 1:
+		return z.MarshalHashca3e03()
+	default:
+		err = herr.New("invalid struct version")
+		return
 	}
-	if oTemp, err := z.Producer.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	o = hsp.AppendTime(o, z.Timestamp)
-	o = hsp.AppendInt32(o, z.Version)
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *BPHeader) Msgsize() (s int) {
-	s = 1 + 11 + z.MerkleRoot.Msgsize() + 11 + z.ParentHash.Msgsize() + 9 + z.Producer.Msgsize() + 10 + hsp.TimeSize + 8 + hsp.Int32Size
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.Msgsizeoldver()
+	case 1:
+		return z.Msgsizeca3e03()
+	default:
+		return 0
+	}
 	return
 }
 
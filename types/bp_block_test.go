@@ -24,6 +24,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/utils"
 )
@@ -174,3 +175,51 @@ func TestBlock_PackAndSignBlock(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestBlock_LegacyVersion(t *testing.T) {
+	block, err := generateRandomBlock(genesisHash, false)
+	if err != nil {
+		t.Fatalf("failed to generate block: %v", err)
+	}
+	if block.SignedHeader.HashVersion != int32(block.SignedHeader.HSPDefaultVersion()) {
+		t.Fatalf("unexpected hash version: %d", block.SignedHeader.HashVersion)
+	}
+
+	// hash in legacy version, as the blocks before the state root was committed
+	block.SignedHeader.HashVersion = 0
+	err = block.SetHash()
+	if err != nil {
+		t.Fatalf("failed to set hash: %v", err)
+	}
+	enc, err := block.SignedHeader.BPHeader.MarshalHash()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enc[0] != 0x85 {
+		t.Fatalf("unexpected map header: %x", enc[0])
+	}
+	err = block.VerifyHash()
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+
+	// state root is not covered by legacy hash
+	block.SignedHeader.StateRoot = hash.Hash{0x1}
+	err = block.SetHash()
+	if errors.Cause(err) != ErrUnhashedField {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = block.VerifyHash()
+	if errors.Cause(err) != ErrUnhashedField {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	block.SignedHeader.HashVersion = int32(block.SignedHeader.HSPDefaultVersion())
+	err = block.SetHash()
+	if err != nil {
+		t.Fatalf("failed to set hash: %v", err)
+	}
+	err = block.VerifyHash()
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+}
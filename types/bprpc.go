@@ -20,6 +20,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 )
 
@@ -174,3 +175,84 @@ type QueryTxStateResp struct {
 	Hash  hash.Hash
 	State pi.TransactionState
 }
+
+// QueryEscrowReq defines a request of the QueryEscrow RPC method.
+type QueryEscrowReq struct {
+	proto.Envelope
+	ID hash.Hash
+}
+
+// QueryEscrowResp defines a response of the QueryEscrow RPC method.
+type QueryEscrowResp struct {
+	proto.Envelope
+	Escrow EscrowProfile
+}
+
+// QueryAccountEscrowBalanceReq defines a request of the QueryAccountEscrowBalance RPC method.
+type QueryAccountEscrowBalanceReq struct {
+	proto.Envelope
+	Addr      proto.AccountAddress
+	TokenType TokenType
+}
+
+// QueryAccountEscrowBalanceResp defines a response of the QueryAccountEscrowBalance RPC method,
+// Locked is the amount sent by the account in escrows and Incoming is the amount to be received.
+type QueryAccountEscrowBalanceResp struct {
+	proto.Envelope
+	Addr     proto.AccountAddress
+	Locked   uint64
+	Incoming uint64
+}
+
+// FetchStateSnapshotReq defines a request of the FetchStateSnapshot RPC method, a zero Height
+// refers to the latest snapshot.
+type FetchStateSnapshotReq struct {
+	proto.Envelope
+	Height uint32
+	Index  uint32
+}
+
+// FetchStateSnapshotResp defines a response of the FetchStateSnapshot RPC method, Chunk is the
+// Index-th piece of the encoded snapshot, which is split into Total pieces.
+type FetchStateSnapshotResp struct {
+	proto.Envelope
+	Header SignedStateSnapshotHeader
+	Index  uint32
+	Total  uint32
+	Chunk  []byte
+}
+
+// QueryAccountWithProofReq defines a request of the QueryAccountWithProof RPC method.
+type QueryAccountWithProofReq struct {
+	proto.Envelope
+	Addr proto.AccountAddress
+}
+
+// QueryAccountWithProofResp defines a response of the QueryAccountWithProof RPC method, Proof
+// proves the inclusion of Account in the state trie committed by Header.
+type QueryAccountWithProofResp struct {
+	proto.Envelope
+	Header  BPSignedHeader
+	Height  uint32
+	Count   uint32
+	Account *Account
+	Proof   *merkle.Proof
+}
+
+// QuerySQLChainProfileWithProofReq defines a request of the QuerySQLChainProfileWithProof RPC
+// method.
+type QuerySQLChainProfileWithProofReq struct {
+	proto.Envelope
+	DBID proto.DatabaseID
+}
+
+// QuerySQLChainProfileWithProofResp defines a response of the QuerySQLChainProfileWithProof RPC
+// method, Proof proves the inclusion of Profile in the state trie committed by Header.
+type QuerySQLChainProfileWithProofResp struct {
+	proto.Envelope
+	Header  BPSignedHeader
+	Height  uint32
+	Count   uint32
+	Profile *SQLChainProfile
+	Proof   *merkle.Proof
+}
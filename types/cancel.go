@@ -0,0 +1,34 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// CancelQueryReq defines a request of the CancelQuery RPC method, which interrupts the
+// in-flight query request specified by Query.
+type CancelQueryReq struct {
+	proto.Envelope
+	DatabaseID proto.DatabaseID
+	Query      QueryKey
+}
+
+// CancelQueryResp defines a response of the CancelQuery RPC method.
+type CancelQueryResp struct {
+	proto.Envelope
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// CancelTransactionHeader defines the transaction cancellation header.
+type CancelTransactionHeader struct {
+	Nonce pi.AccountNonce
+	Fee   uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *CancelTransactionHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *CancelTransactionHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// CancelTransaction defines the transaction cancellation, which takes the nonce of a pending
+// transaction sent by the same account and does nothing but consume the nonce once applied.
+type CancelTransaction struct {
+	CancelTransactionHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewCancelTransaction returns new instance.
+func NewCancelTransaction(header *CancelTransactionHeader) *CancelTransaction {
+	return &CancelTransaction{
+		CancelTransactionHeader: *header,
+		TransactionTypeMixin:    *pi.NewTransactionTypeMixin(pi.TransactionTypeCancelTransaction),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (ct *CancelTransaction) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(ct.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (ct *CancelTransaction) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return ct.DefaultHashSignVerifierImpl.Sign(&ct.CancelTransactionHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (ct *CancelTransaction) Verify() (err error) {
+	return ct.DefaultHashSignVerifierImpl.Verify(&ct.CancelTransactionHeader)
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeCancelTransaction, (*CancelTransaction)(nil))
+}
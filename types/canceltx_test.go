@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestCancelTransaction(t *testing.T) {
+	Convey("test cancel transaction", t, func() {
+		privKey, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		addr, err := crypto.PubKeyHash(privKey.PubKey())
+		So(err, ShouldBeNil)
+
+		ct := NewCancelTransaction(&CancelTransactionHeader{
+			Nonce: 3,
+			Fee:   10,
+		})
+		err = ct.Sign(privKey)
+		So(err, ShouldBeNil)
+		err = ct.Verify()
+		So(err, ShouldBeNil)
+		So(ct.GetAccountAddress(), ShouldEqual, addr)
+		So(ct.GetAccountNonce(), ShouldEqual, 3)
+		So(ct.GetFee(), ShouldEqual, 10)
+		So(ct.GetTransactionType(), ShouldEqual, pi.TransactionTypeCancelTransaction)
+
+		Convey("modified header should fail verification", func() {
+			ct.Fee = 0
+			err = ct.Verify()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("cancel transaction should be encoded through transaction wrapper", func() {
+			enc, err := utils.EncodeMsgPack(pi.WrapTransaction(ct))
+			So(err, ShouldBeNil)
+			var dec = &pi.TransactionWrapper{}
+			err = utils.DecodeMsgPack(enc.Bytes(), dec)
+			So(err, ShouldBeNil)
+			So(dec.GetTransactionType(), ShouldEqual, pi.TransactionTypeCancelTransaction)
+			err = dec.Verify()
+			So(err, ShouldBeNil)
+			So(dec.Hash(), ShouldEqual, ct.Hash())
+		})
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/pkg/errors"
 )
 
 //go:generate hsp
@@ -34,6 +35,8 @@ type CreateDatabaseHeader struct {
 	AdvancePayment uint64
 	TokenType      TokenType
 	Nonce          pi.AccountNonce
+	Fee            uint64
+	Version        int32 `hsp:"v,version"`
 }
 
 // GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
@@ -41,6 +44,20 @@ func (h *CreateDatabaseHeader) GetAccountNonce() pi.AccountNonce {
 	return h.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *CreateDatabaseHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// database creation header.
+func (h *CreateDatabaseHeader) checkVersion() error {
+	if h.Version == 0 && h.Fee != 0 {
+		return errors.Wrap(ErrUnhashedField, "legacy database creation header")
+	}
+	return nil
+}
+
 // CreateDatabase defines the database creation transaction.
 type CreateDatabase struct {
 	CreateDatabaseHeader
@@ -58,11 +75,15 @@ func NewCreateDatabase(header *CreateDatabaseHeader) *CreateDatabase {
 
 // Sign implements interfaces/Transaction.Sign.
 func (cd *CreateDatabase) Sign(signer *asymmetric.PrivateKey) (err error) {
+	cd.Version = int32(cd.HSPDefaultVersion())
 	return cd.DefaultHashSignVerifierImpl.Sign(&cd.CreateDatabaseHeader, signer)
 }
 
 // Verify implements interfaces/Transaction.Verify.
 func (cd *CreateDatabase) Verify() error {
+	if err := cd.CreateDatabaseHeader.checkVersion(); err != nil {
+		return err
+	}
 	return cd.DefaultHashSignVerifierImpl.Verify(&cd.CreateDatabaseHeader)
 }
 
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// CreateMultiSigAccountHeader defines the multi-signature account creation transaction header.
+type CreateMultiSigAccountHeader struct {
+	Threshold uint32
+	Members   []*asymmetric.PublicKey
+	Nonce     pi.AccountNonce
+	Fee       uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *CreateMultiSigAccountHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *CreateMultiSigAccountHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// CreateMultiSigAccount defines the multi-signature account creation transaction, which can be
+// sent by any member of the new account.
+type CreateMultiSigAccount struct {
+	CreateMultiSigAccountHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewCreateMultiSigAccount returns new instance.
+func NewCreateMultiSigAccount(header *CreateMultiSigAccountHeader) *CreateMultiSigAccount {
+	return &CreateMultiSigAccount{
+		CreateMultiSigAccountHeader: *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(
+			pi.TransactionTypeCreateMultiSigAccount),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (cm *CreateMultiSigAccount) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(cm.Signee)
+	return addr
+}
+
+// MultiSigAccount returns the address of the multi-signature account to be created.
+func (cm *CreateMultiSigAccount) MultiSigAccount() (proto.AccountAddress, error) {
+	return MultiSigAddress(cm.Threshold, cm.Members)
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (cm *CreateMultiSigAccount) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return cm.DefaultHashSignVerifierImpl.Sign(&cm.CreateMultiSigAccountHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (cm *CreateMultiSigAccount) Verify() (err error) {
+	if err = cm.DefaultHashSignVerifierImpl.Verify(&cm.CreateMultiSigAccountHeader); err != nil {
+		return
+	}
+	_, err = cm.MultiSigAccount()
+	return
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeCreateMultiSigAccount, (*CreateMultiSigAccount)(nil))
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// FetchRowsReq defines a request of the FetchRows RPC method, which fetches the next chunk of
+// rows from a cursor returned by a read query. The cursor is released if Close is set.
+type FetchRowsReq struct {
+	proto.Envelope
+	NodeID     proto.NodeID
+	DatabaseID proto.DatabaseID
+	Cursor     uint64
+	Close      bool
+}
+
+// FetchRowsResp defines a response of the FetchRows RPC method. Header is set with the final
+// signed response header along with the last chunk of rows.
+type FetchRowsResp struct {
+	proto.Envelope
+	Rows   []ResponseRow
+	Header *SignedResponseHeader
+}
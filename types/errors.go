@@ -34,4 +34,33 @@ var (
 	ErrHashVerification = errors.New("hash verification failed")
 	// ErrInvalidGenesis indicates a failed genesis block verification.
 	ErrInvalidGenesis = errors.New("invalid genesis block")
+	// ErrInvalidMultiSigMembers indicates that the member list of a multi-signature account is
+	// empty, too long or contains duplicate/nil public keys.
+	ErrInvalidMultiSigMembers = errors.New("invalid multi-signature account members")
+	// ErrInvalidMultiSigThreshold indicates that the threshold of a multi-signature account is out
+	// of range.
+	ErrInvalidMultiSigThreshold = errors.New("invalid multi-signature account threshold")
+	// ErrUnsupportedMultiSigTransaction indicates that the transaction can not be wrapped in a
+	// multi-signature transaction.
+	ErrUnsupportedMultiSigTransaction = errors.New("unsupported multi-signature transaction")
+	// ErrInvalidEscrow indicates that the amount or height range of an escrow is invalid.
+	ErrInvalidEscrow = errors.New("invalid escrow")
+	// ErrStateRootNotMatch indicates that the state root of a snapshot doesn't match its objects.
+	ErrStateRootNotMatch = errors.New("state root doesn't match")
+	// ErrStateProofVerification indicates a failed state object inclusion proof verification.
+	ErrStateProofVerification = errors.New("state proof verification failed")
+	// ErrInvalidEvidence indicates that the misbehavior evidence of a slashing is not valid.
+	ErrInvalidEvidence = errors.New("invalid misbehavior evidence")
+	// ErrInvalidAccessToken indicates that the access token is not signed properly or not scoped to
+	// the request.
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	// ErrAccessTokenExpired indicates that the access token is expired.
+	ErrAccessTokenExpired = errors.New("access token expired")
+	// ErrQueryTimeout indicates that the query execution is interrupted at its deadline.
+	ErrQueryTimeout = errors.New("query execution timeout")
+	// ErrStaleState indicates that the state of a follower is staler than required by a read.
+	ErrStaleState = errors.New("state is staler than required")
+	// ErrUnhashedField indicates that a field which is not covered by the hash version of the
+	// structure is set.
+	ErrUnhashedField = errors.New("field is not covered by hash version")
 )
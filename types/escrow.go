@@ -0,0 +1,230 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/pkg/errors"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// EscrowProfile defines the tokens locked by an escrow, which can be claimed by the receiver in
+// block height range [ReleaseHeight, ExpireHeight), or refunded to the sender since ExpireHeight.
+type EscrowProfile struct {
+	ID            hash.Hash // hash of the escrow creation transaction
+	Sender        proto.AccountAddress
+	Receiver      proto.AccountAddress
+	Amount        uint64
+	TokenType     TokenType
+	ReleaseHeight uint32
+	ExpireHeight  uint32
+}
+
+// IsClaimable returns whether the escrow can be claimed by the receiver at the given height.
+func (p *EscrowProfile) IsClaimable(height uint32) bool {
+	return height >= p.ReleaseHeight && height < p.ExpireHeight
+}
+
+// IsRefundable returns whether the escrow can be refunded to the sender at the given height.
+func (p *EscrowProfile) IsRefundable(height uint32) bool {
+	return height >= p.ExpireHeight
+}
+
+// CreateEscrowHeader defines the escrow creation transaction header.
+type CreateEscrowHeader struct {
+	Receiver      proto.AccountAddress
+	Amount        uint64
+	TokenType     TokenType
+	ReleaseHeight uint32
+	ExpireHeight  uint32
+	Nonce         pi.AccountNonce
+	Fee           uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *CreateEscrowHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *CreateEscrowHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// CreateEscrow defines the escrow creation transaction, which locks tokens of the sender until
+// they are claimed by the receiver or refunded after expiration.
+type CreateEscrow struct {
+	CreateEscrowHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewCreateEscrow returns new instance.
+func NewCreateEscrow(header *CreateEscrowHeader) *CreateEscrow {
+	return &CreateEscrow{
+		CreateEscrowHeader:   *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(pi.TransactionTypeCreateEscrow),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (ce *CreateEscrow) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(ce.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (ce *CreateEscrow) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return ce.DefaultHashSignVerifierImpl.Sign(&ce.CreateEscrowHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (ce *CreateEscrow) Verify() (err error) {
+	if err = ce.DefaultHashSignVerifierImpl.Verify(&ce.CreateEscrowHeader); err != nil {
+		return
+	}
+	if ce.Amount == 0 {
+		return errors.Wrap(ErrInvalidEscrow, "zero amount")
+	}
+	if ce.ExpireHeight <= ce.ReleaseHeight {
+		return errors.Wrapf(ErrInvalidEscrow,
+			"expire height %d not after release height %d", ce.ExpireHeight, ce.ReleaseHeight)
+	}
+	return
+}
+
+// EscrowProfile returns the escrow object created by this transaction.
+func (ce *CreateEscrow) EscrowProfile() *EscrowProfile {
+	return &EscrowProfile{
+		ID:            ce.Hash(),
+		Sender:        ce.GetAccountAddress(),
+		Receiver:      ce.Receiver,
+		Amount:        ce.Amount,
+		TokenType:     ce.TokenType,
+		ReleaseHeight: ce.ReleaseHeight,
+		ExpireHeight:  ce.ExpireHeight,
+	}
+}
+
+// ClaimEscrowHeader defines the escrow claiming transaction header.
+type ClaimEscrowHeader struct {
+	EscrowID hash.Hash
+	Nonce    pi.AccountNonce
+	Fee      uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *ClaimEscrowHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *ClaimEscrowHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// ClaimEscrow defines the escrow claiming transaction sent by the escrow receiver.
+type ClaimEscrow struct {
+	ClaimEscrowHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewClaimEscrow returns new instance.
+func NewClaimEscrow(header *ClaimEscrowHeader) *ClaimEscrow {
+	return &ClaimEscrow{
+		ClaimEscrowHeader:    *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(pi.TransactionTypeClaimEscrow),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (ce *ClaimEscrow) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(ce.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (ce *ClaimEscrow) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return ce.DefaultHashSignVerifierImpl.Sign(&ce.ClaimEscrowHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (ce *ClaimEscrow) Verify() (err error) {
+	return ce.DefaultHashSignVerifierImpl.Verify(&ce.ClaimEscrowHeader)
+}
+
+// RefundEscrowHeader defines the escrow refunding transaction header.
+type RefundEscrowHeader struct {
+	EscrowID hash.Hash
+	Nonce    pi.AccountNonce
+	Fee      uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *RefundEscrowHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *RefundEscrowHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// RefundEscrow defines the escrow refunding transaction sent by the escrow sender.
+type RefundEscrow struct {
+	RefundEscrowHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewRefundEscrow returns new instance.
+func NewRefundEscrow(header *RefundEscrowHeader) *RefundEscrow {
+	return &RefundEscrow{
+		RefundEscrowHeader:   *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(pi.TransactionTypeRefundEscrow),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (re *RefundEscrow) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(re.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (re *RefundEscrow) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return re.DefaultHashSignVerifierImpl.Sign(&re.RefundEscrowHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (re *RefundEscrow) Verify() (err error) {
+	return re.DefaultHashSignVerifierImpl.Verify(&re.RefundEscrowHeader)
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeCreateEscrow, (*CreateEscrow)(nil))
+	pi.RegisterTransaction(pi.TransactionTypeClaimEscrow, (*ClaimEscrow)(nil))
+	pi.RegisterTransaction(pi.TransactionTypeRefundEscrow, (*RefundEscrow)(nil))
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestEscrow(t *testing.T) {
+	Convey("test escrow transactions", t, func() {
+		var (
+			err      error
+			privKey1 *asymmetric.PrivateKey
+			privKey2 *asymmetric.PrivateKey
+			addr1    proto.AccountAddress
+			addr2    proto.AccountAddress
+		)
+
+		privKey1, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		privKey2, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		addr1, err = crypto.PubKeyHash(privKey1.PubKey())
+		So(err, ShouldBeNil)
+		addr2, err = crypto.PubKeyHash(privKey2.PubKey())
+		So(err, ShouldBeNil)
+
+		ce := NewCreateEscrow(&CreateEscrowHeader{
+			Receiver:      addr2,
+			Amount:        100,
+			TokenType:     Particle,
+			ReleaseHeight: 10,
+			ExpireHeight:  20,
+			Nonce:         1,
+		})
+		err = ce.Sign(privKey1)
+		So(err, ShouldBeNil)
+		err = ce.Verify()
+		So(err, ShouldBeNil)
+		So(ce.GetAccountAddress(), ShouldEqual, addr1)
+		So(ce.GetAccountNonce(), ShouldEqual, 1)
+
+		profile := ce.EscrowProfile()
+		So(profile.ID, ShouldResemble, ce.Hash())
+		So(profile.Sender, ShouldEqual, addr1)
+		So(profile.Receiver, ShouldEqual, addr2)
+		So(profile.IsClaimable(9), ShouldBeFalse)
+		So(profile.IsClaimable(10), ShouldBeTrue)
+		So(profile.IsClaimable(19), ShouldBeTrue)
+		So(profile.IsClaimable(20), ShouldBeFalse)
+		So(profile.IsRefundable(19), ShouldBeFalse)
+		So(profile.IsRefundable(20), ShouldBeTrue)
+
+		Convey("invalid escrow parameters should fail verification", func() {
+			ce.Amount = 0
+			err = ce.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = ce.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrInvalidEscrow)
+			ce.Amount = 100
+			ce.ExpireHeight = ce.ReleaseHeight
+			err = ce.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = ce.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrInvalidEscrow)
+		})
+		Convey("modified header should fail verification", func() {
+			ce.Amount = 1000
+			err = ce.Verify()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("claim and refund should be signed by their senders", func() {
+			claim := NewClaimEscrow(&ClaimEscrowHeader{
+				EscrowID: profile.ID,
+				Nonce:    1,
+			})
+			err = claim.Sign(privKey2)
+			So(err, ShouldBeNil)
+			err = claim.Verify()
+			So(err, ShouldBeNil)
+			So(claim.GetAccountAddress(), ShouldEqual, addr2)
+			So(claim.GetAccountNonce(), ShouldEqual, 1)
+
+			refund := NewRefundEscrow(&RefundEscrowHeader{
+				EscrowID: profile.ID,
+				Nonce:    2,
+			})
+			err = refund.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = refund.Verify()
+			So(err, ShouldBeNil)
+			So(refund.GetAccountAddress(), ShouldEqual, addr1)
+			So(refund.GetAccountNonce(), ShouldEqual, 2)
+		})
+		Convey("escrow transactions should be encoded through transaction wrapper", func() {
+			enc, err := utils.EncodeMsgPack(pi.WrapTransaction(ce))
+			So(err, ShouldBeNil)
+			var dec = &pi.TransactionWrapper{}
+			err = utils.DecodeMsgPack(enc.Bytes(), dec)
+			So(err, ShouldBeNil)
+			So(dec.GetTransactionType(), ShouldEqual, pi.TransactionTypeCreateEscrow)
+			err = dec.Verify()
+			So(err, ShouldBeNil)
+			So(dec.Hash(), ShouldEqual, ce.Hash())
+		})
+
+		var nilAddr proto.AccountAddress
+		tx2 := &ClaimEscrow{}
+		err = tx2.Verify()
+		So(err, ShouldNotBeNil)
+		So(tx2.GetAccountAddress(), ShouldEqual, nilAddr)
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/pkg/errors"
 )
 
 //go:generate hsp
@@ -37,6 +38,8 @@ type IssueKeysHeader struct {
 	TargetSQLChain proto.AccountAddress
 	MinerKeys      []MinerKey
 	Nonce          interfaces.AccountNonce
+	Fee            uint64
+	Version        int32 `hsp:"v,version"`
 }
 
 // GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
@@ -44,6 +47,20 @@ func (h *IssueKeysHeader) GetAccountNonce() interfaces.AccountNonce {
 	return h.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *IssueKeysHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// key issuing header.
+func (h *IssueKeysHeader) checkVersion() error {
+	if h.Version == 0 && h.Fee != 0 {
+		return errors.Wrap(ErrUnhashedField, "legacy key issuing header")
+	}
+	return nil
+}
+
 // IssueKeys defines the database creation transaction.
 type IssueKeys struct {
 	IssueKeysHeader
@@ -61,11 +78,15 @@ func NewIssueKeys(header *IssueKeysHeader) *IssueKeys {
 
 // Sign implements interfaces/Transaction.Sign.
 func (ik *IssueKeys) Sign(signer *asymmetric.PrivateKey) (err error) {
+	ik.Version = int32(ik.HSPDefaultVersion())
 	return ik.DefaultHashSignVerifierImpl.Sign(&ik.IssueKeysHeader, signer)
 }
 
 // Verify implements interfaces/Transaction.Verify.
 func (ik *IssueKeys) Verify() error {
+	if err := ik.IssueKeysHeader.checkVersion(); err != nil {
+		return err
+	}
 	return ik.DefaultHashSignVerifierImpl.Verify(&ik.IssueKeysHeader)
 }
 
@@ -3,6 +3,8 @@ package types
 // Code generated by github.com/CovenantSQL/HashStablePack DO NOT EDIT.
 
 import (
+	herr "errors"
+
 	hsp "github.com/CovenantSQL/HashStablePack/marshalhash"
 )
 
@@ -36,43 +38,50 @@ func (z *IssueKeys) Msgsize() (s int) {
 	return
 }
 
+var hspVersionsIssueKeysHeader = []string{
+	"oldver",
+	"2dcde6",
+}
+
+// HSPCurrentVersion returns current struct version
+func (z *IssueKeysHeader) HSPCurrentVersion() int {
+	return int(z.Version)
+}
+
+// HSPMaxVersion returns max struct version
+func (z *IssueKeysHeader) HSPMaxVersion() int {
+	return 1
+}
+
+// HSPDefaultVersion returns default struct version
+func (z *IssueKeysHeader) HSPDefaultVersion() int {
+	return 1
+}
+
 // MarshalHash marshals for hash
 func (z *IssueKeysHeader) MarshalHash() (o []byte, err error) {
-	var b []byte
-	o = hsp.Require(b, z.Msgsize())
-	// map header, size 3
-	o = append(o, 0x83)
-	o = hsp.AppendArrayHeader(o, uint32(len(z.MinerKeys)))
-	for za0001 := range z.MinerKeys {
-		// map header, size 2
-		o = append(o, 0x82)
-		if oTemp, err := z.MinerKeys[za0001].Miner.MarshalHash(); err != nil {
-			return nil, err
-		} else {
-			o = hsp.AppendBytes(o, oTemp)
-		}
-		o = hsp.AppendString(o, z.MinerKeys[za0001].EncryptionKey)
-	}
-	if oTemp, err := z.Nonce.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	if oTemp, err := z.TargetSQLChain.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.MarshalHasholdver()
+	case 1:
+		return z.MarshalHash2dcde6()
+	default:
+		err = herr.New("invalid struct version")
+		return
 	}
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *IssueKeysHeader) Msgsize() (s int) {
-	s = 1 + 10 + hsp.ArrayHeaderSize
-	for za0001 := range z.MinerKeys {
-		s += 1 + 6 + z.MinerKeys[za0001].Miner.Msgsize() + 14 + hsp.StringPrefixSize + len(z.MinerKeys[za0001].EncryptionKey)
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.Msgsizeoldver()
+	case 1:
+		return z.Msgsize2dcde6()
+	default:
+		return 0
 	}
-	s += 6 + z.Nonce.Msgsize() + 15 + z.TargetSQLChain.Msgsize()
 	return
 }
 
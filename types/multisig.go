@@ -0,0 +1,265 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+const (
+	// MaxMultiSigMembers defines the max member count of a multi-signature account.
+	MaxMultiSigMembers = 16
+
+	multiSigAddressPrefix = "multisig"
+)
+
+// MultiSigProfile defines a M-of-N multi-signature account, transactions sent from this account
+// must be signed by at least Threshold of its Members.
+type MultiSigProfile struct {
+	Address   proto.AccountAddress
+	Threshold uint32
+	Members   []*asymmetric.PublicKey // sorted by serialized public key
+}
+
+// DeepCopy implements the mohae/deepcopy.Interface, the member public keys are immutable and
+// shared by the copies.
+func (p *MultiSigProfile) DeepCopy() interface{} {
+	if p == nil {
+		return p
+	}
+	var cpy = &MultiSigProfile{
+		Address:   p.Address,
+		Threshold: p.Threshold,
+		Members:   make([]*asymmetric.PublicKey, len(p.Members)),
+	}
+	copy(cpy.Members, p.Members)
+	return cpy
+}
+
+// IsMember returns whether the public key is one of the account members.
+func (p *MultiSigProfile) IsMember(pub *asymmetric.PublicKey) bool {
+	if pub == nil {
+		return false
+	}
+	for _, m := range p.Members {
+		if m != nil && m.IsEqual(pub) {
+			return true
+		}
+	}
+	return false
+}
+
+// SortMultiSigMembers returns a sorted copy of the member public keys, and reports error if any
+// key is nil or duplicated.
+func SortMultiSigMembers(members []*asymmetric.PublicKey) (sorted []*asymmetric.PublicKey, err error) {
+	if len(members) == 0 || len(members) > MaxMultiSigMembers {
+		err = errors.Wrapf(ErrInvalidMultiSigMembers, "member count %d", len(members))
+		return
+	}
+	sorted = make([]*asymmetric.PublicKey, len(members))
+	copy(sorted, members)
+	for _, m := range sorted {
+		if m == nil {
+			err = errors.Wrap(ErrInvalidMultiSigMembers, "nil member public key")
+			return
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Serialize(), sorted[j].Serialize()) < 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].IsEqual(sorted[i]) {
+			err = errors.Wrap(ErrInvalidMultiSigMembers, "duplicate member public key")
+			return
+		}
+	}
+	return
+}
+
+// MultiSigAddress returns the address of the multi-signature account with the given threshold and
+// members, the order of members doesn't matter.
+func MultiSigAddress(threshold uint32, members []*asymmetric.PublicKey) (addr proto.AccountAddress, err error) {
+	var sorted []*asymmetric.PublicKey
+	if sorted, err = SortMultiSigMembers(members); err != nil {
+		return
+	}
+	if threshold == 0 || int(threshold) > len(sorted) {
+		err = errors.Wrapf(ErrInvalidMultiSigThreshold, "%d of %d", threshold, len(sorted))
+		return
+	}
+	var buf = bytes.NewBufferString(multiSigAddressPrefix)
+	if err = binary.Write(buf, binary.BigEndian, threshold); err != nil {
+		return
+	}
+	for _, m := range sorted {
+		buf.Write(m.Serialize())
+	}
+	addr = proto.AccountAddress(hash.THashH(buf.Bytes()))
+	return
+}
+
+// MultiSigTransactionHeader defines the multi-signature transaction header, which is signed by
+// the members of the multi-signature account.
+type MultiSigTransactionHeader struct {
+	Account proto.AccountAddress
+	TxHash  hash.Hash
+}
+
+// MultiSigTransaction defines a transaction sent from a multi-signature account. The wrapped
+// transaction is signed by one of the members as usual, and the other members add their
+// signatures to the wrapper.
+type MultiSigTransaction struct {
+	MultiSigTransactionHeader
+	pi.TransactionTypeMixin
+	Transaction *pi.TransactionWrapper
+	Signatures  []*verifier.DefaultHashSignVerifierImpl
+}
+
+// NewMultiSigTransaction returns new instance wrapping the signed transaction tx.
+func NewMultiSigTransaction(account proto.AccountAddress, tx pi.Transaction) *MultiSigTransaction {
+	return &MultiSigTransaction{
+		MultiSigTransactionHeader: MultiSigTransactionHeader{
+			Account: account,
+			TxHash:  tx.Hash(),
+		},
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(pi.TransactionTypeMultiSig),
+		Transaction:          pi.WrapTransaction(tx),
+	}
+}
+
+// Unwrap returns the wrapped transaction.
+func (t *MultiSigTransaction) Unwrap() pi.Transaction {
+	if t.Transaction == nil {
+		return nil
+	}
+	return t.Transaction.Unwrap()
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *MultiSigTransaction) GetAccountAddress() proto.AccountAddress {
+	return t.Account
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (t *MultiSigTransaction) GetAccountNonce() pi.AccountNonce {
+	if tx := t.Unwrap(); tx != nil {
+		return tx.GetAccountNonce()
+	}
+	return 0
+}
+
+// GetFee implements interfaces/Transaction.GetFee, the fee of the wrapped transaction is paid by
+// the multi-signature account.
+func (t *MultiSigTransaction) GetFee() uint64 {
+	if tx := t.Unwrap(); tx != nil {
+		return tx.GetFee()
+	}
+	return 0
+}
+
+// Hash implements interfaces/Transaction.Hash.
+func (t *MultiSigTransaction) Hash() (h hash.Hash) {
+	if enc, err := t.MultiSigTransactionHeader.MarshalHash(); err == nil {
+		h = hash.THashH(enc)
+	}
+	return
+}
+
+// Sign implements interfaces/Transaction.Sign, it appends the signature of a member.
+func (t *MultiSigTransaction) Sign(signer *asymmetric.PrivateKey) (err error) {
+	var sig = &verifier.DefaultHashSignVerifierImpl{}
+	if err = sig.Sign(&t.MultiSigTransactionHeader, signer); err != nil {
+		return
+	}
+	t.Signatures = append(t.Signatures, sig)
+	return
+}
+
+// Signers returns the public keys of all the signers, including the signer of the wrapped
+// transaction.
+func (t *MultiSigTransaction) Signers() (signers []*asymmetric.PublicKey) {
+	signers = make([]*asymmetric.PublicKey, 0, len(t.Signatures)+1)
+	if signee := multiSigTransactionSignee(t.Unwrap()); signee != nil {
+		signers = append(signers, signee)
+	}
+	for _, v := range t.Signatures {
+		if v != nil && v.Signee != nil {
+			signers = append(signers, v.Signee)
+		}
+	}
+	return
+}
+
+// Verify implements interfaces/Transaction.Verify. Note that it only checks the signatures, the
+// threshold is checked against the account state by block producers.
+func (t *MultiSigTransaction) Verify() (err error) {
+	var tx = t.Unwrap()
+	if multiSigTransactionSignee(tx) == nil {
+		err = errors.Wrap(ErrUnsupportedMultiSigTransaction, "unsupported or unsigned transaction")
+		return
+	}
+	if h := tx.Hash(); !t.TxHash.IsEqual(&h) {
+		err = errors.Wrap(ErrHashVerification, "wrapped transaction hash not match")
+		return
+	}
+	if err = tx.Verify(); err != nil {
+		return
+	}
+	for _, v := range t.Signatures {
+		if v == nil || v.Signee == nil || v.Signature == nil {
+			err = errors.Wrap(ErrSignVerification, "empty signature")
+			return
+		}
+		if err = v.Verify(&t.MultiSigTransactionHeader); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// multiSigTransactionSignee returns the signee of tx if it can be sent from a multi-signature
+// account, otherwise it returns nil.
+func multiSigTransactionSignee(tx pi.Transaction) *asymmetric.PublicKey {
+	switch t := tx.(type) {
+	case *Transfer:
+		return t.Signee
+	case *UpdatePermission:
+		return t.Signee
+	case *IssueKeys:
+		return t.Signee
+	case *CreateDatabase:
+		return t.Signee
+	default:
+		return nil
+	}
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeMultiSig, (*MultiSigTransaction)(nil))
+}
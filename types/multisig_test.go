@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestMultiSig(t *testing.T) {
+	Convey("Given some key pairs", t, func() {
+		var (
+			err      error
+			privKeys = make([]*asymmetric.PrivateKey, 4)
+			pubKeys  = make([]*asymmetric.PublicKey, 4)
+		)
+		for i := range privKeys {
+			privKeys[i], pubKeys[i], err = asymmetric.GenSecp256k1KeyPair()
+			So(err, ShouldBeNil)
+		}
+		Convey("The multisig address should be independent of member order", func() {
+			addr1, err := MultiSigAddress(2, pubKeys[:3])
+			So(err, ShouldBeNil)
+			addr2, err := MultiSigAddress(2, []*asymmetric.PublicKey{
+				pubKeys[2], pubKeys[0], pubKeys[1],
+			})
+			So(err, ShouldBeNil)
+			So(addr1, ShouldEqual, addr2)
+			addr3, err := MultiSigAddress(3, pubKeys[:3])
+			So(err, ShouldBeNil)
+			So(addr3, ShouldNotEqual, addr1)
+			single, err := crypto.PubKeyHash(pubKeys[0])
+			So(err, ShouldBeNil)
+			addr4, err := MultiSigAddress(1, pubKeys[:1])
+			So(err, ShouldBeNil)
+			So(addr4, ShouldNotEqual, single)
+		})
+		Convey("The multisig address should not accept invalid parameters", func() {
+			_, err = MultiSigAddress(0, pubKeys)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidMultiSigThreshold)
+			_, err = MultiSigAddress(5, pubKeys)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidMultiSigThreshold)
+			_, err = MultiSigAddress(1, nil)
+			So(errors.Cause(err), ShouldEqual, ErrInvalidMultiSigMembers)
+			_, err = MultiSigAddress(1, []*asymmetric.PublicKey{pubKeys[0], nil})
+			So(errors.Cause(err), ShouldEqual, ErrInvalidMultiSigMembers)
+			_, err = MultiSigAddress(1, []*asymmetric.PublicKey{pubKeys[0], pubKeys[0]})
+			So(errors.Cause(err), ShouldEqual, ErrInvalidMultiSigMembers)
+		})
+		Convey("The multisig account creation should be verifiable", func() {
+			cm := NewCreateMultiSigAccount(&CreateMultiSigAccountHeader{
+				Threshold: 2,
+				Members:   pubKeys[:3],
+				Nonce:     1,
+			})
+			err = cm.Sign(privKeys[0])
+			So(err, ShouldBeNil)
+			err = cm.Verify()
+			So(err, ShouldBeNil)
+			So(cm.GetAccountNonce(), ShouldEqual, 1)
+			addr, err := crypto.PubKeyHash(pubKeys[0])
+			So(err, ShouldBeNil)
+			So(cm.GetAccountAddress(), ShouldEqual, addr)
+			cm.Threshold = 4
+			err = cm.Sign(privKeys[0])
+			So(err, ShouldBeNil)
+			err = cm.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrInvalidMultiSigThreshold)
+		})
+		Convey("The multisig transaction should be verifiable", func() {
+			account, err := MultiSigAddress(2, pubKeys[:3])
+			So(err, ShouldBeNil)
+			tx := NewTransfer(&TransferHeader{
+				Sender:   account,
+				Receiver: proto.AccountAddress{},
+				Amount:   10,
+				Nonce:    1,
+			})
+			err = tx.Sign(privKeys[0])
+			So(err, ShouldBeNil)
+			mt := NewMultiSigTransaction(account, tx)
+			err = mt.Sign(privKeys[1])
+			So(err, ShouldBeNil)
+			err = mt.Verify()
+			So(err, ShouldBeNil)
+			So(mt.GetAccountAddress(), ShouldEqual, account)
+			So(mt.GetAccountNonce(), ShouldEqual, 1)
+			So(mt.Unwrap(), ShouldEqual, tx)
+			So(len(mt.Signers()), ShouldEqual, 2)
+			So(mt.Signers()[0].IsEqual(pubKeys[0]), ShouldBeTrue)
+			So(mt.Signers()[1].IsEqual(pubKeys[1]), ShouldBeTrue)
+			So(mt.Hash(), ShouldNotEqual, tx.Hash())
+
+			Convey("The multisig transaction should be serializable", func() {
+				enc, err := utils.EncodeMsgPack(pi.WrapTransaction(mt))
+				So(err, ShouldBeNil)
+				var dec = &pi.TransactionWrapper{}
+				err = utils.DecodeMsgPack(enc.Bytes(), dec)
+				So(err, ShouldBeNil)
+				So(dec.GetTransactionType(), ShouldEqual, pi.TransactionTypeMultiSig)
+				err = dec.Verify()
+				So(err, ShouldBeNil)
+				So(dec.Hash(), ShouldEqual, mt.Hash())
+				So(len(dec.Unwrap().(*MultiSigTransaction).Signers()), ShouldEqual, 2)
+			})
+			Convey("The modified transaction should fail verification", func() {
+				tx.Amount = 100
+				err = mt.Verify()
+				So(err, ShouldNotBeNil)
+				err = tx.Sign(privKeys[0])
+				So(err, ShouldBeNil)
+				err = mt.Verify()
+				So(errors.Cause(err), ShouldEqual, ErrHashVerification)
+			})
+			Convey("The modified header should fail verification", func() {
+				mt.Account = proto.AccountAddress{}
+				err = mt.Verify()
+				So(errors.Cause(err), ShouldEqual, verifier.ErrHashValueNotMatch)
+			})
+			Convey("The unsupported transaction should fail verification", func() {
+				ba := NewBaseAccount(&Account{Address: account})
+				err = ba.Sign(privKeys[0])
+				So(err, ShouldBeNil)
+				mt = NewMultiSigTransaction(account, ba)
+				err = mt.Verify()
+				So(errors.Cause(err), ShouldEqual, ErrUnsupportedMultiSigTransaction)
+			})
+		})
+	})
+}
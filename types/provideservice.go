@@ -22,6 +22,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/pkg/errors"
 )
 
 //go:generate hsp
@@ -38,6 +39,8 @@ type ProvideServiceHeader struct {
 	TokenType     TokenType
 	NodeID        proto.NodeID
 	Nonce         interfaces.AccountNonce
+	Fee           uint64
+	Version       int32 `hsp:"v,version"`
 }
 
 // GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
@@ -45,6 +48,20 @@ func (h *ProvideServiceHeader) GetAccountNonce() interfaces.AccountNonce {
 	return h.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *ProvideServiceHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// service providing header.
+func (h *ProvideServiceHeader) checkVersion() error {
+	if h.Version == 0 && h.Fee != 0 {
+		return errors.Wrap(ErrUnhashedField, "legacy service providing header")
+	}
+	return nil
+}
+
 // ProvideService define the miner providing service transaction.
 type ProvideService struct {
 	ProvideServiceHeader
@@ -62,11 +79,15 @@ func NewProvideService(h *ProvideServiceHeader) *ProvideService {
 
 // Sign implements interfaces/Transaction.Sign.
 func (ps *ProvideService) Sign(signer *asymmetric.PrivateKey) (err error) {
+	ps.Version = int32(ps.HSPDefaultVersion())
 	return ps.DefaultHashSignVerifierImpl.Sign(&ps.ProvideServiceHeader, signer)
 }
 
 // Verify implements interfaces/Transaction.Verify.
 func (ps *ProvideService) Verify() error {
+	if err := ps.ProvideServiceHeader.checkVersion(); err != nil {
+		return err
+	}
 	return ps.DefaultHashSignVerifierImpl.Verify(&ps.ProvideServiceHeader)
 }
 
@@ -3,6 +3,8 @@ package types
 // Code generated by github.com/CovenantSQL/HashStablePack DO NOT EDIT.
 
 import (
+	herr "errors"
+
 	hsp "github.com/CovenantSQL/HashStablePack/marshalhash"
 )
 
@@ -36,48 +38,49 @@ func (z *ProvideService) Msgsize() (s int) {
 	return
 }
 
+var hspVersionsProvideServiceHeader = []string{
+	"oldver",
+	"f38dfb",
+}
+
+// HSPCurrentVersion returns current struct version
+func (z *ProvideServiceHeader) HSPCurrentVersion() int {
+	return int(z.Version)
+}
+
+// HSPMaxVersion returns max struct version
+func (z *ProvideServiceHeader) HSPMaxVersion() int {
+	return 1
+}
+
+// HSPDefaultVersion returns default struct version
+func (z *ProvideServiceHeader) HSPDefaultVersion() int {
+	return 1
+}
+
 // MarshalHash marshals for hash
 func (z *ProvideServiceHeader) MarshalHash() (o []byte, err error) {
-	var b []byte
-	o = hsp.Require(b, z.Msgsize())
-	// map header, size 8
-	o = append(o, 0x88)
-	o = hsp.AppendUint64(o, z.GasPrice)
-	o = hsp.AppendFloat64(o, z.LoadAvgPerCPU)
-	o = hsp.AppendUint64(o, z.Memory)
-	if oTemp, err := z.NodeID.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	if oTemp, err := z.Nonce.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	o = hsp.AppendUint64(o, z.Space)
-	o = hsp.AppendArrayHeader(o, uint32(len(z.TargetUser)))
-	for za0001 := range z.TargetUser {
-		if oTemp, err := z.TargetUser[za0001].MarshalHash(); err != nil {
-			return nil, err
-		} else {
-			o = hsp.AppendBytes(o, oTemp)
-		}
-	}
-	if oTemp, err := z.TokenType.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.MarshalHasholdver()
+	case 1:
+		return z.MarshalHashf38dfb()
+	default:
+		err = herr.New("invalid struct version")
+		return
 	}
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *ProvideServiceHeader) Msgsize() (s int) {
-	s = 1 + 9 + hsp.Uint64Size + 14 + hsp.Float64Size + 7 + hsp.Uint64Size + 7 + z.NodeID.Msgsize() + 6 + z.Nonce.Msgsize() + 6 + hsp.Uint64Size + 11 + hsp.ArrayHeaderSize
-	for za0001 := range z.TargetUser {
-		s += z.TargetUser[za0001].Msgsize()
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.Msgsizeoldver()
+	case 1:
+		return z.Msgsizef38dfb()
+	default:
+		return 0
 	}
-	s += 10 + z.TokenType.Msgsize()
 	return
 }
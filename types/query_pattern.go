@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/CovenantSQL/sqlparser"
+	"github.com/pkg/errors"
+)
+
+// The permission patterns are matched against the query patterns in three forms:
+//
+//   - a plain pattern is matched exactly (case-sensitive) with the query;
+//   - a pattern prefixed with "tpl:" is a query template, which matches any query with the same
+//     normalized form, e.g. "tpl:SELECT * FROM t WHERE id = ?" matches "select * from T where id=1";
+//   - a pattern prefixed with "re:" is a regular expression (RE2 syntax) matching the whole
+//     normalized query, e.g. `re:select \* from t where id in \(\?\)( limit \?)?`.
+//
+// A query is normalized by parsing and formatting it with sqlparser, so the white spaces, comments
+// and the letter case are ignored, and every literal or bound argument is replaced with a "?"
+// placeholder, a list of literals in an IN expression is replaced with a single "(?)" placeholder.
+// The queries with double-quoted strings, which are taken as identifiers by sqlite if possible, are
+// never matched by templates or regular expressions.
+const (
+	// QueryTemplatePrefix defines the prefix of a query template pattern.
+	QueryTemplatePrefix = "tpl:"
+	// QueryRegexpPrefix defines the prefix of a regular expression pattern.
+	QueryRegexpPrefix = "re:"
+
+	// queryPlaceholder defines the placeholder of literals in normalized query.
+	queryPlaceholder = "?"
+	// maxCachedQueryMatches defines the max number of query match results cached per permission.
+	maxCachedQueryMatches = 1024
+)
+
+// queryTemplate defines a compiled query template or regular expression pattern.
+type queryTemplate struct {
+	normalized string
+	re         *regexp.Regexp
+}
+
+func compileQueryTemplate(pattern string) (t *queryTemplate, err error) {
+	switch {
+	case strings.HasPrefix(pattern, QueryTemplatePrefix):
+		var normalized string
+		if normalized, err = NormalizeQuery(strings.TrimPrefix(pattern, QueryTemplatePrefix)); err != nil {
+			return
+		}
+		t = &queryTemplate{normalized: normalized}
+	case strings.HasPrefix(pattern, QueryRegexpPrefix):
+		var re *regexp.Regexp
+		if re, err = regexp.Compile(
+			"^(?:" + strings.TrimPrefix(pattern, QueryRegexpPrefix) + ")$",
+		); err != nil {
+			err = errors.Wrap(err, "compile query regexp failed")
+			return
+		}
+		t = &queryTemplate{re: re}
+	}
+	return
+}
+
+func (t *queryTemplate) match(normalized string) bool {
+	if t.re != nil {
+		return t.re.MatchString(normalized)
+	}
+	return t.normalized == normalized
+}
+
+// NormalizeQuery returns the normalized form of query which is matched by the query templates.
+func NormalizeQuery(query string) (normalized string, err error) {
+	var (
+		tokenizer  = sqlparser.NewStringTokenizer(query)
+		statements []sqlparser.Statement
+		parts      []string
+	)
+	if _, statements, err = sqlparser.ParseMultiple(tokenizer); err != nil {
+		err = errors.Wrap(err, "parse sql failed")
+		return
+	}
+	for _, stmt := range statements {
+		if err = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+			switch n := node.(type) {
+			case *sqlparser.SQLVal:
+				n.Type = sqlparser.ValArg
+				n.Val = []byte(queryPlaceholder)
+			case *sqlparser.ComparisonExpr:
+				if n.Operator != sqlparser.InStr && n.Operator != sqlparser.NotInStr {
+					break
+				}
+				if tuple, ok := n.Right.(sqlparser.ValTuple); ok && isLiteralTuple(tuple) {
+					n.Right = sqlparser.ValTuple{sqlparser.NewValArg([]byte(queryPlaceholder))}
+				}
+			}
+			return true, nil
+		}, stmt); err != nil {
+			return
+		}
+		parts = append(parts, sqlparser.String(stmt))
+	}
+	normalized = strings.ToLower(strings.Join(parts, "; "))
+	return
+}
+
+func isLiteralTuple(tuple sqlparser.ValTuple) bool {
+	for _, e := range tuple {
+		if _, ok := e.(*sqlparser.SQLVal); !ok {
+			return false
+		}
+	}
+	return len(tuple) > 0
+}
+
+// hasDoubleQuotedString returns whether the query contains a double-quoted string outside of the
+// other quoted strings and comments.
+func hasDoubleQuotedString(query string) bool {
+	for i := 0; i < len(query); i++ {
+		var end string
+		switch c := query[i]; {
+		case c == '"':
+			return true
+		case c == '\'' || c == '`':
+			end = string(c)
+		case c == '[':
+			end = "]"
+		case strings.HasPrefix(query[i:], "--"):
+			end = "\n"
+		case strings.HasPrefix(query[i:], "/*"):
+			end = "*/"
+			i++
+		default:
+			continue
+		}
+		// an escaped quote is regarded as two adjacent strings
+		j := strings.Index(query[i+1:], end)
+		if j < 0 {
+			return false
+		}
+		i += j + len(end)
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	Convey("normalize queries", t, func() {
+		for _, c := range []struct {
+			query      string
+			normalized string
+		}{
+			{
+				query:      "SELECT  a FROM T\n WHERE id = 1 -- comment",
+				normalized: "select a from t where id = ?",
+			}, {
+				query:      "select `A` from t where x = :name and y in (1, 'a', ?) limit 10",
+				normalized: "select a from t where x = ? and y in (?) limit ?",
+			}, {
+				query:      "insert into t values (1, 'x'), (?, ?); select 1",
+				normalized: "insert into t values (?, ?), (?, ?); select ?",
+			},
+		} {
+			normalized, err := NormalizeQuery(c.query)
+			So(err, ShouldBeNil)
+			So(normalized, ShouldEqual, c.normalized)
+		}
+		_, err := NormalizeQuery("select [a] from t")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("double-quoted strings", t, func() {
+		So(hasDoubleQuotedString(`select "a" from t`), ShouldBeTrue)
+		So(hasDoubleQuotedString(`select 'a"' from t`), ShouldBeFalse)
+		So(hasDoubleQuotedString(`select 'it''s' || "a" from t`), ShouldBeTrue)
+		So(hasDoubleQuotedString("select a from t -- \"a\"\nwhere 1"), ShouldBeFalse)
+		So(hasDoubleQuotedString(`select a /* "a" */ from t`), ShouldBeFalse)
+		So(hasDoubleQuotedString(`select [a"] from t`), ShouldBeFalse)
+	})
+}
+
+func TestQueryTemplates(t *testing.T) {
+	Convey("query templates", t, func() {
+		up := UserPermissionFromRole(ReadWrite)
+		up.Patterns = []string{
+			"SELECT 1",
+			"tpl:SELECT * FROM t WHERE id = ?",
+			`re:select name from u where id in \(\?\)( limit \?)?`,
+		}
+		So(up.IsValid(), ShouldBeTrue)
+
+		for _, q := range []string{
+			"SELECT 1",
+			"select *  from T where id=100",
+			"SELECT * FROM t WHERE id = :id",
+			"select name from u where id in (1, 2, 3)",
+			"select name from u where id in (1) limit 10",
+		} {
+			_, state := up.HasDisallowedQueryPatterns([]Query{{Pattern: q}})
+			So(state, ShouldBeFalse)
+		}
+		for _, q := range []string{
+			"select 1",
+			"select * from t where id = 1 or 1 = 1",
+			`select * from t where id = "secret"`,
+			"select name, secret from u where id in (1)",
+			"select name from u where id in (select id from v)",
+			"select * from t where id = 1; drop table t",
+		} {
+			disallowed, state := up.HasDisallowedQueryPatterns([]Query{{Pattern: q}})
+			So(state, ShouldBeTrue)
+			So(disallowed, ShouldEqual, q)
+		}
+
+		// match results are cached
+		So(up.cachedMatches, ShouldHaveLength, 10)
+		So(up.cachedMatches["select *  from T where id=100"], ShouldBeTrue)
+		So(up.cachedMatches["select 1"], ShouldBeFalse)
+	})
+	Convey("invalid templates", t, func() {
+		up := UserPermissionFromRole(Read)
+		up.Patterns = []string{"tpl:select [a] from t"}
+		So(up.IsValid(), ShouldBeFalse)
+		up.Patterns = []string{"re:select (a from t"}
+		So(up.IsValid(), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// ReplaceMinerHeader defines the miner replacement transaction header.
+type ReplaceMinerHeader struct {
+	TargetSQLChain proto.AccountAddress
+	OldMiner       proto.AccountAddress
+	// NewMiner is the provider to take over the database, leave it empty to let the block
+	// producer match one automatically.
+	NewMiner proto.AccountAddress
+	Nonce    pi.AccountNonce
+	Fee      uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *ReplaceMinerHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *ReplaceMinerHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// ReplaceMiner defines the transaction to replace a miner of the target SQLChain with another
+// provider. It can be sent by the database owner at any time, or by the other miners of the
+// database once the old miner stops reporting billing.
+type ReplaceMiner struct {
+	ReplaceMinerHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewReplaceMiner returns new instance.
+func NewReplaceMiner(header *ReplaceMinerHeader) *ReplaceMiner {
+	return &ReplaceMiner{
+		ReplaceMinerHeader:   *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(pi.TransactionTypeReplaceMiner),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (rm *ReplaceMiner) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(rm.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (rm *ReplaceMiner) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return rm.DefaultHashSignVerifierImpl.Sign(&rm.ReplaceMinerHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (rm *ReplaceMiner) Verify() (err error) {
+	return rm.DefaultHashSignVerifierImpl.Verify(&rm.ReplaceMinerHeader)
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeReplaceMiner, (*ReplaceMiner)(nil))
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
@@ -67,6 +69,14 @@ type RequestHeader struct {
 	Timestamp    time.Time        `json:"t"`  // time in UTC zone
 	BatchCount   uint64           `json:"bc"` // query count in this request
 	QueriesHash  hash.Hash        `json:"qh"` // hash of query payload
+	TxID         uint64           `json:"tx"` // interactive transaction id, zero if not in one
+	TxTimestamp  time.Time        `json:"tt"` // begin time of the interactive transaction
+	GasLimit     uint64           `json:"gl"` // max gas used by the request, zero if unlimited
+	Deadline     time.Time        `json:"dl"` // execution deadline in UTC zone, zero if none
+	MinOffset    uint64           `json:"mo"` // min applied offset of the state to read from
+	MaxStaleness time.Duration    `json:"ms"` // max staleness of the state to read from, zero if any
+	AccessToken  *AccessToken     `json:"at"` // delegated access token of the request node, if any
+	Version      int32            `json:"v" hsp:"v,version"`
 }
 
 // GetQueryKey returns a unique query key of this request.
@@ -78,6 +88,16 @@ func (h *RequestHeader) GetQueryKey() QueryKey {
 	}
 }
 
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// request header.
+func (h *RequestHeader) checkVersion() error {
+	if h.Version == 0 && (h.TxID != 0 || !h.TxTimestamp.IsZero() || h.GasLimit != 0 ||
+		!h.Deadline.IsZero() || h.MinOffset != 0 || h.MaxStaleness != 0 || h.AccessToken != nil) {
+		return errors.Wrap(ErrUnhashedField, "legacy request header")
+	}
+	return nil
+}
+
 // QueryKey defines an unique query key of a request.
 type QueryKey struct {
 	NodeID       proto.NodeID `json:"id"`
@@ -118,11 +138,15 @@ func (t QueryType) String() string {
 
 // Verify checks hash and signature in request header.
 func (sh *SignedRequestHeader) Verify() (err error) {
+	if err = sh.RequestHeader.checkVersion(); err != nil {
+		return
+	}
 	return sh.DefaultHashSignVerifierImpl.Verify(&sh.RequestHeader)
 }
 
 // Sign the request.
 func (sh *SignedRequestHeader) Sign(signer *asymmetric.PrivateKey) (err error) {
+	sh.Version = int32(sh.HSPDefaultVersion())
 	return sh.DefaultHashSignVerifierImpl.Sign(&sh.RequestHeader, signer)
 }
 
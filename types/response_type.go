@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 )
 
@@ -49,8 +50,12 @@ type ResponseHeader struct {
 	LogOffset       uint64               `json:"o"`  // request log offset
 	LastInsertID    int64                `json:"l"`  // insert insert id
 	AffectedRows    int64                `json:"a"`  // affected rows
+	AppliedOffset   uint64               `json:"ao"` // state offset with the request applied
+	GasUsed         uint64               `json:"g"`  // gas used to execute the request
+	RowPolicies     []*RowPolicy         `json:"rp"` // row policies applied to the queries
 	PayloadHash     hash.Hash            `json:"dh"` // hash of query response payload
 	ResponseAccount proto.AccountAddress `json:"aa"` // response account
+	Version         int32                `json:"v" hsp:"v,version"`
 }
 
 // GetRequestHash returns the request hash.
@@ -63,6 +68,23 @@ func (h *ResponseHeader) GetRequestTimestamp() time.Time {
 	return h.Request.Timestamp
 }
 
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// response header.
+func (h *ResponseHeader) checkVersion() error {
+	if h.Version == 0 && (h.AppliedOffset != 0 || h.GasUsed != 0 || len(h.RowPolicies) != 0) {
+		return errors.Wrap(ErrUnhashedField, "legacy response header")
+	}
+	return nil
+}
+
+func (h *ResponseHeader) setVersion() {
+	if h.Request.Version == 0 {
+		h.Version, h.AppliedOffset, h.GasUsed, h.RowPolicies = 0, 0, 0, nil
+	} else {
+		h.Version = int32(h.HSPDefaultVersion())
+	}
+}
+
 // SignedResponseHeader defines a signed query response header.
 type SignedResponseHeader struct {
 	ResponseHeader
@@ -76,29 +98,103 @@ func (sh *SignedResponseHeader) Hash() hash.Hash {
 
 // VerifyHash verify the hash of the response.
 func (sh *SignedResponseHeader) VerifyHash() (err error) {
+	if err = sh.ResponseHeader.checkVersion(); err != nil {
+		return
+	}
 	return errors.Wrap(verifyHash(&sh.ResponseHeader, &sh.ResponseHash),
 		"verify response header hash failed")
 }
 
-// BuildHash computes the hash of the response header.
+// BuildHash computes the hash of the response header. The response to a legacy request is hashed
+// in the legacy version, so that it's still verifiable by the client, the fields not covered by
+// the legacy hash are dropped.
 func (sh *SignedResponseHeader) BuildHash() (err error) {
+	sh.setVersion()
 	return errors.Wrap(buildHash(&sh.ResponseHeader, &sh.ResponseHash),
 		"compute response header hash failed")
 }
 
+// PayloadHasher computes the running hash of a response payload: the rows are hashed one after
+// another on top of the hash of the columns, so that a large result set can be produced and
+// verified in chunks without holding all the rows.
+type PayloadHasher struct {
+	sum   hash.Hash
+	count uint64
+	buf   []byte
+}
+
+// NewPayloadHasher returns a new PayloadHasher of the payload with columns and declTypes.
+func NewPayloadHasher(columns, declTypes []string) (h *PayloadHasher, err error) {
+	var (
+		p   = &ResponsePayload{Columns: columns, DeclTypes: declTypes}
+		enc []byte
+	)
+	if enc, err = p.MarshalHash(); err != nil {
+		return
+	}
+	h = &PayloadHasher{sum: hash.THashH(enc)}
+	return
+}
+
+// Write appends rows to the running hash.
+func (h *PayloadHasher) Write(rows []ResponseRow) (err error) {
+	for i := range rows {
+		var enc []byte
+		if enc, err = rows[i].MarshalHash(); err != nil {
+			return
+		}
+		h.buf = append(append(h.buf[:0], h.sum[:]...), enc...)
+		h.sum = hash.THashH(h.buf)
+		h.count++
+	}
+	return
+}
+
+// Sum returns the current running hash.
+func (h *PayloadHasher) Sum() hash.Hash {
+	return h.sum
+}
+
+// Count returns the count of rows written.
+func (h *PayloadHasher) Count() uint64 {
+	return h.count
+}
+
+// payloadHash returns the payload hash in the hash version of the response header. The legacy
+// version hashes the whole payload at once, which could not be produced in chunks.
+func payloadHash(p *ResponsePayload, version int32) (h hash.Hash, err error) {
+	if version == 0 {
+		err = buildHash(p, &h)
+		return
+	}
+	var hasher *PayloadHasher
+	if hasher, err = NewPayloadHasher(p.Columns, p.DeclTypes); err != nil {
+		return
+	}
+	if err = hasher.Write(p.Rows); err != nil {
+		return
+	}
+	h = hasher.Sum()
+	return
+}
+
 // Response defines a complete query response.
 type Response struct {
 	Header  SignedResponseHeader `json:"h"`
 	Payload ResponsePayload      `json:"p"`
+	// Cursor is set if the payload only contains the first chunk of the result rows, the
+	// remaining rows and the final signed header are fetched by the cursor.
+	Cursor uint64 `json:"cur"`
 }
 
 // BuildHash computes the hash of the response.
 func (r *Response) BuildHash() (err error) {
 	// set rows count
 	r.Header.RowCount = uint64(len(r.Payload.Rows))
+	r.Header.setVersion()
 
 	// build hash in header
-	if err = buildHash(&r.Payload, &r.Header.PayloadHash); err != nil {
+	if r.Header.PayloadHash, err = payloadHash(&r.Payload, r.Header.Version); err != nil {
 		err = errors.Wrap(err, "compute response payload hash failed")
 		return
 	}
@@ -109,10 +205,15 @@ func (r *Response) BuildHash() (err error) {
 
 // VerifyHash verify the hash of the response.
 func (r *Response) VerifyHash() (err error) {
-	if err = verifyHash(&r.Payload, &r.Header.PayloadHash); err != nil {
+	var h hash.Hash
+	if h, err = payloadHash(&r.Payload, r.Header.Version); err != nil {
 		err = errors.Wrap(err, "verify response payload hash failed")
 		return
 	}
+	if !h.IsEqual(&r.Header.PayloadHash) {
+		err = errors.Wrap(verifier.ErrHashValueNotMatch, "verify response payload hash failed")
+		return
+	}
 
 	return r.Header.VerifyHash()
 }
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"net/rpc"
+
+	"github.com/pkg/errors"
+)
+
+// remoteErrors are the typed errors which are sent as is by the RPC services, so that the
+// callers can recover them from the RPC responses.
+var remoteErrors = []error{ErrQueryTimeout, ErrStaleState}
+
+// ToRemoteError returns the typed cause of err to be sent as the exact error message of an RPC
+// response, or err if it's not a typed one.
+func ToRemoteError(err error) error {
+	var cause = errors.Cause(err)
+	for _, typed := range remoteErrors {
+		if cause == typed {
+			return typed
+		}
+	}
+	return err
+}
+
+// FromRemoteError recovers the typed error from an RPC response error returned by
+// ToRemoteError, or returns err as is.
+func FromRemoteError(err error) error {
+	var se, ok = errors.Cause(err).(rpc.ServerError)
+	if !ok {
+		return err
+	}
+	for _, typed := range remoteErrors {
+		if string(se) == typed.Error() {
+			return errors.Wrap(typed, err.Error())
+		}
+	}
+	return err
+}
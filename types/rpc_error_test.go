@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"net/rpc"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRemoteError(t *testing.T) {
+	Convey("typed errors should be recovered from rpc responses", t, func() {
+		var (
+			wrapped = errors.Wrap(ErrQueryTimeout, "query at #0 failed")
+			other   = errors.New("no such table: " + ErrQueryTimeout.Error())
+			err     = ToRemoteError(wrapped)
+		)
+		So(err, ShouldEqual, ErrQueryTimeout)
+		So(ToRemoteError(other), ShouldEqual, other)
+
+		err = FromRemoteError(errors.Wrap(rpc.ServerError(err.Error()), "call failed"))
+		So(errors.Cause(err), ShouldEqual, ErrQueryTimeout)
+		err = FromRemoteError(rpc.ServerError(ErrStaleState.Error()))
+		So(errors.Cause(err), ShouldEqual, ErrStaleState)
+		err = FromRemoteError(rpc.ServerError(other.Error()))
+		So(errors.Cause(err), ShouldResemble, rpc.ServerError(other.Error()))
+		err = FromRemoteError(wrapped)
+		So(err, ShouldEqual, wrapped)
+		So(FromRemoteError(nil), ShouldBeNil)
+	})
+}
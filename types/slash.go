@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/pkg/errors"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// ConflictingBlocks defines the misbehavior evidence of a SQLChain miner, which signs two
+// different blocks upon the same parent block.
+type ConflictingBlocks struct {
+	BlockA SignedHeader
+	BlockB SignedHeader
+}
+
+// Verify checks that both block headers are correctly signed by the same producer and conflict
+// with each other.
+func (e *ConflictingBlocks) Verify() (err error) {
+	if err = e.BlockA.Verify(); err != nil {
+		return errors.Wrap(err, "verify block A failed")
+	}
+	if err = e.BlockB.Verify(); err != nil {
+		return errors.Wrap(err, "verify block B failed")
+	}
+	var a, b = &e.BlockA, &e.BlockB
+	if a.HSV.DataHash == b.HSV.DataHash {
+		return errors.Wrap(ErrInvalidEvidence, "same block")
+	}
+	if !a.HSV.Signee.IsEqual(b.HSV.Signee) || a.Producer != b.Producer {
+		return errors.Wrap(ErrInvalidEvidence, "blocks signed by different producers")
+	}
+	if a.GenesisHash != b.GenesisHash || a.ParentHash != b.ParentHash {
+		return errors.Wrap(ErrInvalidEvidence, "blocks not built upon the same parent")
+	}
+	return
+}
+
+// Offender returns the account address of the misbehaving miner.
+func (e *ConflictingBlocks) Offender() (addr proto.AccountAddress, err error) {
+	return crypto.PubKeyHash(e.BlockA.HSV.Signee)
+}
+
+// SlashHeader defines the miner deposit slashing transaction header.
+type SlashHeader struct {
+	TargetSQLChain proto.AccountAddress
+	Evidence       ConflictingBlocks
+	Nonce          pi.AccountNonce
+	Fee            uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *SlashHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *SlashHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// Slash defines the miner deposit slashing transaction, which can be sent by any account with
+// the misbehavior evidence of a miner serving the target SQLChain.
+type Slash struct {
+	SlashHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewSlash returns new instance.
+func NewSlash(header *SlashHeader) *Slash {
+	return &Slash{
+		SlashHeader:          *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(pi.TransactionTypeSlash),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (s *Slash) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(s.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (s *Slash) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return s.DefaultHashSignVerifierImpl.Sign(&s.SlashHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (s *Slash) Verify() (err error) {
+	if err = s.DefaultHashSignVerifierImpl.Verify(&s.SlashHeader); err != nil {
+		return
+	}
+	return s.Evidence.Verify()
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeSlash, (*Slash)(nil))
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestSlash(t *testing.T) {
+	Convey("test slash transaction", t, func() {
+		var (
+			err      error
+			privKey1 *asymmetric.PrivateKey
+			privKey2 *asymmetric.PrivateKey
+			now      = time.Now().UTC()
+			newBlock = func(priv *asymmetric.PrivateKey, merkleRoot hash.Hash) (h SignedHeader) {
+				h = SignedHeader{Header: Header{
+					Version:     0x01000000,
+					Producer:    proto.NodeID("0000000000000000000000000000000000000000000000000000000000001111"),
+					GenesisHash: hash.Hash{0x1},
+					ParentHash:  hash.Hash{0x2},
+					MerkleRoot:  merkleRoot,
+					Timestamp:   now,
+				}}
+				err = h.Sign(priv)
+				So(err, ShouldBeNil)
+				return
+			}
+		)
+
+		privKey1, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		privKey2, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		offender, err := crypto.PubKeyHash(privKey1.PubKey())
+		So(err, ShouldBeNil)
+		reporter, err := crypto.PubKeyHash(privKey2.PubKey())
+		So(err, ShouldBeNil)
+
+		sl := NewSlash(&SlashHeader{
+			Evidence: ConflictingBlocks{
+				BlockA: newBlock(privKey1, hash.Hash{0x3}),
+				BlockB: newBlock(privKey1, hash.Hash{0x4}),
+			},
+			Nonce: 1,
+		})
+		err = sl.Sign(privKey2)
+		So(err, ShouldBeNil)
+		err = sl.Verify()
+		So(err, ShouldBeNil)
+		So(sl.GetAccountAddress(), ShouldEqual, reporter)
+		So(sl.GetAccountNonce(), ShouldEqual, 1)
+		addr, err := sl.Evidence.Offender()
+		So(err, ShouldBeNil)
+		So(addr, ShouldEqual, offender)
+
+		Convey("same block should not be taken as evidence", func() {
+			sl.Evidence.BlockB = sl.Evidence.BlockA
+			err = sl.Sign(privKey2)
+			So(err, ShouldBeNil)
+			err = sl.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrInvalidEvidence)
+		})
+		Convey("blocks signed by different producers should not be taken as evidence", func() {
+			sl.Evidence.BlockB = newBlock(privKey2, hash.Hash{0x4})
+			err = sl.Sign(privKey2)
+			So(err, ShouldBeNil)
+			err = sl.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrInvalidEvidence)
+		})
+		Convey("blocks upon different parents should not be taken as evidence", func() {
+			sl.Evidence.BlockB.ParentHash = hash.Hash{0x5}
+			err = sl.Evidence.BlockB.Sign(privKey1)
+			So(err, ShouldBeNil)
+			err = sl.Sign(privKey2)
+			So(err, ShouldBeNil)
+			err = sl.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrInvalidEvidence)
+		})
+		Convey("modified evidence should fail verification", func() {
+			sl.Evidence.BlockA.MerkleRoot = hash.Hash{0x4}
+			err = sl.Verify()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("slash transaction should be encoded through transaction wrapper", func() {
+			enc, err := utils.EncodeMsgPack(pi.WrapTransaction(sl))
+			So(err, ShouldBeNil)
+			var dec = &pi.TransactionWrapper{}
+			err = utils.DecodeMsgPack(enc.Bytes(), dec)
+			So(err, ShouldBeNil)
+			So(dec.GetTransactionType(), ShouldEqual, pi.TransactionTypeSlash)
+			err = dec.Verify()
+			So(err, ShouldBeNil)
+			So(dec.Hash(), ShouldEqual, sl.Hash())
+		})
+	})
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
+)
+
+//go:generate hsp
+
+// StateSnapshotHeader defines the header of a main chain state snapshot.
+type StateSnapshotHeader struct {
+	GenesisHash hash.Hash
+	BlockHash   hash.Hash // hash of the irreversible block which the snapshot is taken at
+	Height      uint32
+	Count       uint32
+	StateRoot   hash.Hash
+	Timestamp   time.Time
+}
+
+// SignedStateSnapshotHeader defines the state snapshot header with the signature of its producer.
+type SignedStateSnapshotHeader struct {
+	StateSnapshotHeader
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// Sign signs the snapshot header.
+func (h *SignedStateSnapshotHeader) Sign(signer *asymmetric.PrivateKey) error {
+	return h.DefaultHashSignVerifierImpl.Sign(&h.StateSnapshotHeader, signer)
+}
+
+// Verify verifies the signature of the snapshot header.
+func (h *SignedStateSnapshotHeader) Verify() error {
+	return h.DefaultHashSignVerifierImpl.Verify(&h.StateSnapshotHeader)
+}
+
+// StateSnapshot defines a full copy of the main chain meta state at an irreversible block.
+type StateSnapshot struct {
+	Header    SignedStateSnapshotHeader
+	Accounts  []*Account
+	Databases []*SQLChainProfile
+	Providers []*ProviderProfile
+	MultiSigs []*MultiSigProfile
+	Escrows   []*EscrowProfile
+}
+
+// ComputeStateRoot computes the state trie root of all the state objects in the snapshot.
+func (s *StateSnapshot) ComputeStateRoot() (root hash.Hash, err error) {
+	var trie = merkle.NewPatricia()
+	for _, v := range s.Accounts {
+		if err = InsertStateObject(trie, v); err != nil {
+			return
+		}
+	}
+	for _, v := range s.Databases {
+		if err = InsertStateObject(trie, v); err != nil {
+			return
+		}
+	}
+	for _, v := range s.Providers {
+		if err = InsertStateObject(trie, v); err != nil {
+			return
+		}
+	}
+	for _, v := range s.MultiSigs {
+		if err = InsertStateObject(trie, v); err != nil {
+			return
+		}
+	}
+	for _, v := range s.Escrows {
+		if err = InsertStateObject(trie, v); err != nil {
+			return
+		}
+	}
+	root = *trie.Root()
+	return
+}
+
+// Sign sets the state root and signs the snapshot header.
+func (s *StateSnapshot) Sign(signer *asymmetric.PrivateKey) (err error) {
+	if s.Header.StateRoot, err = s.ComputeStateRoot(); err != nil {
+		return
+	}
+	return s.Header.Sign(signer)
+}
+
+// Verify verifies the snapshot header signature and the state root.
+func (s *StateSnapshot) Verify() (err error) {
+	var root hash.Hash
+	if err = s.Header.Verify(); err != nil {
+		return
+	}
+	if root, err = s.ComputeStateRoot(); err != nil {
+		return
+	}
+	if !root.IsEqual(&s.Header.StateRoot) {
+		return ErrStateRootNotMatch
+	}
+	return
+}
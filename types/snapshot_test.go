@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/utils"
+)
+
+func TestStateSnapshot(t *testing.T) {
+	Convey("test state snapshot", t, func() {
+		var (
+			err     error
+			privKey *asymmetric.PrivateKey
+			root    hash.Hash
+		)
+
+		privKey, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+
+		snap := &StateSnapshot{
+			Header: SignedStateSnapshotHeader{
+				StateSnapshotHeader: StateSnapshotHeader{
+					GenesisHash: hash.Hash{0x1},
+					BlockHash:   hash.Hash{0x2},
+					Height:      10,
+					Count:       8,
+					Timestamp:   time.Now().UTC(),
+				},
+			},
+			Accounts: []*Account{
+				{Address: proto.AccountAddress{0x1}, NextNonce: 1},
+				{Address: proto.AccountAddress{0x2}, NextNonce: 2},
+			},
+			Databases: []*SQLChainProfile{
+				{ID: proto.DatabaseID("db"), Address: proto.AccountAddress{0x3}},
+			},
+			Providers: []*ProviderProfile{
+				{Provider: proto.AccountAddress{0x4}, NodeID: proto.NodeID(hash.Hash{0x4}.String())},
+			},
+			MultiSigs: []*MultiSigProfile{
+				{Address: proto.AccountAddress{0x5}, Threshold: 1},
+			},
+			Escrows: []*EscrowProfile{
+				{ID: hash.Hash{0x6}, Amount: 1},
+			},
+		}
+		err = snap.Sign(privKey)
+		So(err, ShouldBeNil)
+		err = snap.Verify()
+		So(err, ShouldBeNil)
+
+		Convey("state root should not depend on object order", func() {
+			snap.Accounts[0], snap.Accounts[1] = snap.Accounts[1], snap.Accounts[0]
+			root, err = snap.ComputeStateRoot()
+			So(err, ShouldBeNil)
+			So(root, ShouldEqual, snap.Header.StateRoot)
+			err = snap.Verify()
+			So(err, ShouldBeNil)
+		})
+		Convey("modified state should fail verification", func() {
+			snap.Accounts[0].NextNonce++
+			err = snap.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrStateRootNotMatch)
+		})
+		Convey("missing state object should fail verification", func() {
+			snap.MultiSigs = nil
+			err = snap.Verify()
+			So(errors.Cause(err), ShouldEqual, ErrStateRootNotMatch)
+		})
+		Convey("modified header should fail verification", func() {
+			snap.Header.Height++
+			err = snap.Verify()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("snapshot should be encoded and decoded", func() {
+			enc, err := utils.EncodeMsgPack(snap)
+			So(err, ShouldBeNil)
+			var dec = &StateSnapshot{}
+			err = utils.DecodeMsgPack(enc.Bytes(), dec)
+			So(err, ShouldBeNil)
+			err = dec.Verify()
+			So(err, ShouldBeNil)
+			So(dec.Header.Hash(), ShouldEqual, snap.Header.Hash())
+			So(len(dec.Accounts), ShouldEqual, len(snap.Accounts))
+		})
+	})
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
+)
+
+// Key prefixes of the state objects in the state trie, which avoid key collisions between
+// different object types.
+const (
+	stateKeyAccount byte = iota
+	stateKeyDatabase
+	stateKeyProvider
+	stateKeyMultiSig
+	stateKeyEscrow
+)
+
+// StateObject defines the main chain state object authenticated by the state root.
+type StateObject interface {
+	verifier.MarshalHasher
+	StateKey() []byte
+}
+
+// StateKey implements StateObject.StateKey.
+func (a *Account) StateKey() []byte {
+	return append([]byte{stateKeyAccount}, a.Address[:]...)
+}
+
+// StateKey implements StateObject.StateKey.
+func (p *SQLChainProfile) StateKey() []byte {
+	return append([]byte{stateKeyDatabase}, p.ID...)
+}
+
+// StateKey implements StateObject.StateKey.
+func (p *ProviderProfile) StateKey() []byte {
+	return append([]byte{stateKeyProvider}, p.Provider[:]...)
+}
+
+// StateKey implements StateObject.StateKey.
+func (p *MultiSigProfile) StateKey() []byte {
+	return append([]byte{stateKeyMultiSig}, p.Address[:]...)
+}
+
+// StateKey implements StateObject.StateKey.
+func (p *EscrowProfile) StateKey() []byte {
+	return append([]byte{stateKeyEscrow}, p.ID[:]...)
+}
+
+// InsertStateObject inserts the state object into the state trie.
+func InsertStateObject(trie *merkle.Trie, obj StateObject) (err error) {
+	var enc []byte
+	if enc, err = obj.MarshalHash(); err != nil {
+		return
+	}
+	trie.Insert(obj.StateKey(), enc)
+	return
+}
+
+// VerifyStateProof verifies that the state object is included in the state with the given root.
+func VerifyStateProof(root *hash.Hash, obj StateObject, proof *merkle.Proof) (err error) {
+	var enc []byte
+	if proof == nil {
+		return ErrStateProofVerification
+	}
+	if enc, err = obj.MarshalHash(); err != nil {
+		return
+	}
+	if !proof.Verify(root, obj.StateKey(), enc) {
+		return ErrStateProofVerification
+	}
+	return
+}
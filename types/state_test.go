@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
+	"github.com/CovenantSQL/CovenantSQL/merkle"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestStateProof(t *testing.T) {
+	Convey("test state inclusion proof", t, func() {
+		var (
+			err   error
+			trie  = merkle.NewPatricia()
+			root  *hash.Hash
+			proof *merkle.Proof
+			acc1  = &Account{Address: proto.AccountAddress{0x1}, NextNonce: 1}
+			acc2  = &Account{Address: proto.AccountAddress{0x2}, NextNonce: 2}
+			db    = &SQLChainProfile{ID: proto.DatabaseID("db"), Address: proto.AccountAddress{0x1}}
+			esc   = &EscrowProfile{ID: hash.Hash{0x1}, Amount: 1}
+		)
+		acc1.TokenBalance[Particle] = 100
+
+		for _, v := range []StateObject{acc1, acc2, db, esc} {
+			err = InsertStateObject(trie, v)
+			So(err, ShouldBeNil)
+		}
+		So(acc1.StateKey(), ShouldNotResemble, db.StateKey())
+
+		root, proof, err = trie.Prove(acc1.StateKey())
+		So(err, ShouldBeNil)
+		err = VerifyStateProof(root, acc1, proof)
+		So(err, ShouldBeNil)
+		err = VerifyStateProof(root, acc2, proof)
+		So(err, ShouldEqual, ErrStateProofVerification)
+		err = VerifyStateProof(root, acc1, nil)
+		So(err, ShouldEqual, ErrStateProofVerification)
+		err = VerifyStateProof(&hash.Hash{}, acc1, proof)
+		So(err, ShouldEqual, ErrStateProofVerification)
+
+		Convey("modified object should fail verification", func() {
+			acc1.TokenBalance[Particle] = 1000
+			err = VerifyStateProof(root, acc1, proof)
+			So(err, ShouldEqual, ErrStateProofVerification)
+		})
+		Convey("proof of database profile should be verified", func() {
+			root, proof, err = trie.Prove(db.StateKey())
+			So(err, ShouldBeNil)
+			err = VerifyStateProof(root, db, proof)
+			So(err, ShouldBeNil)
+		})
+		Convey("proof of missing object should not be produced", func() {
+			_, _, err = trie.Prove((&Account{Address: proto.AccountAddress{0x3}}).StateKey())
+			So(err, ShouldEqual, merkle.ErrKeyNotFound)
+		})
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/pkg/errors"
 )
 
 //go:generate hsp
@@ -29,8 +30,10 @@ import (
 type TransferHeader struct {
 	Sender, Receiver proto.AccountAddress
 	Nonce            pi.AccountNonce
+	Fee              uint64
 	Amount           uint64
 	TokenType        TokenType
+	Version          int32 `hsp:"v,version"`
 }
 
 // Transfer defines the transfer transaction.
@@ -58,13 +61,31 @@ func (t *Transfer) GetAccountNonce() pi.AccountNonce {
 	return t.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (t *Transfer) GetFee() uint64 {
+	return t.Fee
+}
+
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// transfer header.
+func (h *TransferHeader) checkVersion() error {
+	if h.Version == 0 && h.Fee != 0 {
+		return errors.Wrap(ErrUnhashedField, "legacy transfer header")
+	}
+	return nil
+}
+
 // Sign implements interfaces/Transaction.Sign.
 func (t *Transfer) Sign(signer *asymmetric.PrivateKey) (err error) {
+	t.Version = int32(t.HSPDefaultVersion())
 	return t.DefaultHashSignVerifierImpl.Sign(&t.TransferHeader, signer)
 }
 
 // Verify implements interfaces/Transaction.Verify.
 func (t *Transfer) Verify() (err error) {
+	if err := t.TransferHeader.checkVersion(); err != nil {
+		return err
+	}
 	return t.DefaultHashSignVerifierImpl.Verify(&t.TransferHeader)
 }
 
@@ -49,6 +49,7 @@ func BenchmarkAppendMsgTransfer(b *testing.B) {
 func TestMarshalHashTransferHeader(t *testing.T) {
 	v := TransferHeader{}
 	binary.Read(rand.Reader, binary.BigEndian, &v)
+	v.Version = int32(v.HSPDefaultVersion())
 	bts1, err := v.MarshalHash()
 	if err != nil {
 		t.Fatal(err)
@@ -19,6 +19,7 @@ package types
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
@@ -45,3 +46,37 @@ func TestTxTransfer(t *testing.T) {
 		So(t.Verify(), ShouldBeNil)
 	})
 }
+
+func TestTxTransferLegacyVersion(t *testing.T) {
+	Convey("test legacy transfer", t, func() {
+		h, err := hash.NewHashFromStr("000005aa62048f85da4ae9698ed59c14ec0d48a88a07c15a32265634e7e64ade")
+		So(err, ShouldBeNil)
+		addr := proto.AccountAddress(*h)
+		priv, _, err := asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+
+		t := NewTransfer(&TransferHeader{
+			Sender: addr,
+			Nonce:  1,
+			Amount: 10,
+		})
+		// sign in legacy hash version, as the transactions packed before the fee is introduced
+		So(t.DefaultHashSignVerifierImpl.Sign(&t.TransferHeader, priv), ShouldBeNil)
+		So(t.Version, ShouldEqual, 0)
+		enc, err := t.TransferHeader.MarshalHash()
+		So(err, ShouldBeNil)
+		So(enc[0], ShouldEqual, 0x85)
+		So(t.Verify(), ShouldBeNil)
+
+		// fee is not covered by legacy hash
+		t.Fee = 1
+		So(t.DefaultHashSignVerifierImpl.Sign(&t.TransferHeader, priv), ShouldBeNil)
+		So(errors.Cause(t.Verify()), ShouldEqual, ErrUnhashedField)
+
+		So(t.Sign(priv), ShouldBeNil)
+		So(t.Version, ShouldEqual, t.HSPDefaultVersion())
+		So(t.Verify(), ShouldBeNil)
+		t.Version = 2
+		So(t.Verify(), ShouldNotBeNil)
+	})
+}
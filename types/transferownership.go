@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/pkg/errors"
+
+	pi "github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// TransferDatabaseOwnershipHeader defines the database ownership transfer transaction header.
+type TransferDatabaseOwnershipHeader struct {
+	TargetSQLChain proto.AccountAddress
+	NewOwner       proto.AccountAddress
+	Nonce          pi.AccountNonce
+	Fee            uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *TransferDatabaseOwnershipHeader) GetAccountNonce() pi.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *TransferDatabaseOwnershipHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// TransferDatabaseOwnership defines the database ownership transfer transaction, which is signed
+// by the current owner and optionally co-signed by the new owner.
+type TransferDatabaseOwnership struct {
+	TransferDatabaseOwnershipHeader
+	pi.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+	// CoSignature is the optional signature of the new owner on the same header.
+	CoSignature *verifier.DefaultHashSignVerifierImpl
+}
+
+// NewTransferDatabaseOwnership returns new instance.
+func NewTransferDatabaseOwnership(header *TransferDatabaseOwnershipHeader) *TransferDatabaseOwnership {
+	return &TransferDatabaseOwnership{
+		TransferDatabaseOwnershipHeader: *header,
+		TransactionTypeMixin: *pi.NewTransactionTypeMixin(
+			pi.TransactionTypeTransferDatabaseOwnership),
+	}
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (t *TransferDatabaseOwnership) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(t.Signee)
+	return addr
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (t *TransferDatabaseOwnership) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return t.DefaultHashSignVerifierImpl.Sign(&t.TransferDatabaseOwnershipHeader, signer)
+}
+
+// CoSign signs the transaction header with the private key of the new owner.
+func (t *TransferDatabaseOwnership) CoSign(signer *asymmetric.PrivateKey) (err error) {
+	var cs = &verifier.DefaultHashSignVerifierImpl{}
+	if err = cs.Sign(&t.TransferDatabaseOwnershipHeader, signer); err != nil {
+		return
+	}
+	t.CoSignature = cs
+	return
+}
+
+// IsCoSigned returns whether the transaction is co-signed by the new owner.
+func (t *TransferDatabaseOwnership) IsCoSigned() bool {
+	return t.CoSignature != nil
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (t *TransferDatabaseOwnership) Verify() (err error) {
+	if err = t.DefaultHashSignVerifierImpl.Verify(&t.TransferDatabaseOwnershipHeader); err != nil {
+		return
+	}
+	if t.CoSignature == nil {
+		return
+	}
+	if err = t.CoSignature.Verify(&t.TransferDatabaseOwnershipHeader); err != nil {
+		return
+	}
+	var coSigner proto.AccountAddress
+	if coSigner, err = crypto.PubKeyHash(t.CoSignature.Signee); err != nil {
+		return
+	}
+	if coSigner != t.NewOwner {
+		err = errors.Wrapf(ErrSignVerification,
+			"co-signer %s is not the new owner %s", coSigner, t.NewOwner)
+	}
+	return
+}
+
+func init() {
+	pi.RegisterTransaction(pi.TransactionTypeTransferDatabaseOwnership, (*TransferDatabaseOwnership)(nil))
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+func TestTransferDatabaseOwnership(t *testing.T) {
+	Convey("test TransferDatabaseOwnership", t, func() {
+		var (
+			err      error
+			privKey1 *asymmetric.PrivateKey
+			privKey2 *asymmetric.PrivateKey
+			privKey3 *asymmetric.PrivateKey
+			addr1    proto.AccountAddress
+			addr2    proto.AccountAddress
+			addr3    proto.AccountAddress
+		)
+
+		// Create key pairs and addresses for test
+		privKey1, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		privKey2, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		privKey3, _, err = asymmetric.GenSecp256k1KeyPair()
+		So(err, ShouldBeNil)
+		addr1, err = crypto.PubKeyHash(privKey1.PubKey())
+		So(err, ShouldBeNil)
+		addr2, err = crypto.PubKeyHash(privKey2.PubKey())
+		So(err, ShouldBeNil)
+		addr3, err = crypto.PubKeyHash(privKey3.PubKey())
+		So(err, ShouldBeNil)
+
+		tx := NewTransferDatabaseOwnership(&TransferDatabaseOwnershipHeader{
+			TargetSQLChain: addr3,
+			NewOwner:       addr2,
+			Nonce:          1,
+		})
+		err = tx.Sign(privKey1)
+		So(err, ShouldBeNil)
+		err = tx.Verify()
+		So(err, ShouldBeNil)
+		So(tx.IsCoSigned(), ShouldBeFalse)
+		So(tx.GetAccountAddress(), ShouldEqual, addr1)
+		So(tx.GetAccountNonce(), ShouldEqual, 1)
+
+		Convey("co-signed by the new owner should pass verification", func() {
+			err = tx.CoSign(privKey2)
+			So(err, ShouldBeNil)
+			So(tx.IsCoSigned(), ShouldBeTrue)
+			err = tx.Verify()
+			So(err, ShouldBeNil)
+			So(tx.GetAccountAddress(), ShouldEqual, addr1)
+		})
+		Convey("co-signed by other account should fail verification", func() {
+			err = tx.CoSign(privKey3)
+			So(err, ShouldBeNil)
+			err = tx.Verify()
+			So(err, ShouldNotBeNil)
+		})
+		Convey("modified header should fail verification", func() {
+			err = tx.CoSign(privKey2)
+			So(err, ShouldBeNil)
+			tx.NewOwner = addr3
+			err = tx.Verify()
+			So(err, ShouldNotBeNil)
+		})
+
+		var nilAddr proto.AccountAddress
+		tx2 := &TransferDatabaseOwnership{}
+		err = tx2.Verify()
+		So(err, ShouldNotBeNil)
+		So(tx2.GetAccountAddress(), ShouldEqual, nilAddr)
+	})
+}
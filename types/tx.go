@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"time"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// TxHeader defines the header of an interactive transaction control request.
+type TxHeader struct {
+	NodeID      proto.NodeID     `json:"id"`   // request node id
+	DatabaseID  proto.DatabaseID `json:"dbid"` // request database id
+	TxID        uint64           `json:"tx"`   // transaction id, zero in begin requests
+	Isolation   int32            `json:"iso"`  // isolation level defined by database/sql
+	ReadOnly    bool             `json:"ro"`
+	Timestamp   time.Time        `json:"t"`  // time in UTC zone
+	AccessToken *AccessToken     `json:"at"` // delegated access token of the request node, if any
+}
+
+// SignedTxHeader defines a signed interactive transaction control request header.
+type SignedTxHeader struct {
+	TxHeader
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// Verify checks hash and signature in transaction header.
+func (sh *SignedTxHeader) Verify() (err error) {
+	return sh.DefaultHashSignVerifierImpl.Verify(&sh.TxHeader)
+}
+
+// Sign the transaction header.
+func (sh *SignedTxHeader) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return sh.DefaultHashSignVerifierImpl.Sign(&sh.TxHeader, signer)
+}
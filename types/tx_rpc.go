@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+// BeginTxReq defines a request of the BeginTx RPC method, which opens an interactive transaction
+// on the database leader.
+type BeginTxReq struct {
+	proto.Envelope
+	Header SignedTxHeader
+}
+
+// BeginTxResp defines a response of the BeginTx RPC method.
+type BeginTxResp struct {
+	proto.Envelope
+	TxID uint64
+}
+
+// EndTxReq defines a request of the EndTx RPC method. The transaction is rolled back if Commit is
+// nil, otherwise the write queries of the transaction carried by Commit are applied.
+type EndTxReq struct {
+	proto.Envelope
+	Header SignedTxHeader
+	Commit *Request
+}
+
+// EndTxResp defines a response of the EndTx RPC method, Response is the response of the commit
+// request if any.
+type EndTxResp struct {
+	proto.Envelope
+	Response *Response
+}
@@ -24,10 +24,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/ugorji/go/codec"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/utils"
 )
@@ -79,6 +81,69 @@ func TestSignedRequestHeader_Sign(t *testing.T) {
 	})
 }
 
+func TestSignedRequestHeader_LegacyVersion(t *testing.T) {
+	privKey, _ := getCommKeys()
+
+	Convey("legacy request header", t, func() {
+		req := &SignedRequestHeader{
+			RequestHeader: RequestHeader{
+				QueryType:    WriteQuery,
+				NodeID:       proto.NodeID("node"),
+				DatabaseID:   proto.DatabaseID("db1"),
+				ConnectionID: uint64(1),
+				SeqNo:        uint64(2),
+				Timestamp:    time.Now().UTC(),
+			},
+		}
+		// sign in legacy hash version, as the requests of the clients before gas accounting
+		So(req.DefaultHashSignVerifierImpl.Sign(&req.RequestHeader, privKey), ShouldBeNil)
+		So(req.Version, ShouldEqual, 0)
+		enc, err := req.RequestHeader.MarshalHash()
+		So(err, ShouldBeNil)
+		So(enc[0], ShouldEqual, 0x88)
+		So(req.Verify(), ShouldBeNil)
+
+		// gas limit is not covered by legacy hash
+		req.GasLimit = 1
+		So(req.DefaultHashSignVerifierImpl.Sign(&req.RequestHeader, privKey), ShouldBeNil)
+		So(errors.Cause(req.Verify()), ShouldEqual, ErrUnhashedField)
+
+		So(req.Sign(privKey), ShouldBeNil)
+		So(req.Version, ShouldEqual, req.HSPDefaultVersion())
+		So(req.Verify(), ShouldBeNil)
+
+		Convey("response follows the request version", func() {
+			res := &SignedResponseHeader{
+				ResponseHeader: ResponseHeader{
+					Request:   req.RequestHeader,
+					NodeID:    proto.NodeID("node"),
+					Timestamp: time.Now().UTC(),
+					GasUsed:   10,
+					RowPolicies: []*RowPolicy{
+						{Table: "t1", Column: "owner"},
+					},
+				},
+			}
+			So(res.BuildHash(), ShouldBeNil)
+			So(res.Version, ShouldEqual, res.HSPDefaultVersion())
+			So(res.GasUsed, ShouldEqual, 10)
+			So(res.VerifyHash(), ShouldBeNil)
+			res.RowPolicies[0].Column = "id"
+			So(res.VerifyHash(), ShouldNotBeNil)
+			res.RowPolicies[0].Column = "owner"
+
+			res.Request.GasLimit, res.Request.Version = 0, 0
+			So(res.BuildHash(), ShouldBeNil)
+			So(res.Version, ShouldEqual, 0)
+			So(res.GasUsed, ShouldEqual, 0)
+			So(res.RowPolicies, ShouldBeNil)
+			So(res.VerifyHash(), ShouldBeNil)
+			res.GasUsed = 10
+			So(errors.Cause(res.VerifyHash()), ShouldEqual, ErrUnhashedField)
+		})
+	})
+}
+
 func TestRequest_Sign(t *testing.T) {
 	privKey, _ := getCommKeys()
 
@@ -209,6 +274,8 @@ func TestResponse_Sign(t *testing.T) {
 			},
 		}
 
+		res.Header.Request.Version = int32(res.Header.Request.HSPDefaultVersion())
+
 		var err error
 
 		// sign
@@ -216,8 +283,19 @@ func TestResponse_Sign(t *testing.T) {
 		So(err, ShouldBeNil)
 
 		// test hash
-		err = verifyHash(&res.Payload, &res.Header.PayloadHash)
+		ph, err := payloadHash(&res.Payload, res.Header.Version)
 		So(err, ShouldBeNil)
+		So(ph, ShouldResemble, res.Header.PayloadHash)
+
+		// test running hash in chunks
+		hasher, err := NewPayloadHasher(res.Payload.Columns, res.Payload.DeclTypes)
+		So(err, ShouldBeNil)
+		for _, row := range res.Payload.Rows {
+			err = hasher.Write([]ResponseRow{row})
+			So(err, ShouldBeNil)
+		}
+		So(hasher.Count(), ShouldEqual, res.Header.RowCount)
+		So(hasher.Sum(), ShouldResemble, res.Header.PayloadHash)
 
 		// verify
 		Convey("verify", func() {
@@ -251,6 +329,20 @@ func TestResponse_Sign(t *testing.T) {
 				err = res.VerifyHash()
 				So(err, ShouldNotBeNil)
 			})
+			Convey("legacy request", func() {
+				// the whole payload is hashed for the legacy clients
+				res.Header.Request.Version = 0
+				err = res.BuildHash()
+				So(err, ShouldBeNil)
+				So(res.Header.Version, ShouldEqual, 0)
+				var ph hash.Hash
+				err = buildHash(&res.Payload, &ph)
+				So(err, ShouldBeNil)
+				So(res.Header.PayloadHash, ShouldResemble, ph)
+				err = res.VerifyHash()
+				So(err, ShouldBeNil)
+				So(res.Header.PayloadHash, ShouldNotResemble, hasher.Sum())
+			})
 		})
 	})
 }
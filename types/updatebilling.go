@@ -31,13 +31,14 @@ type Range struct {
 	From, To uint32
 }
 
-// MinerIncome defines the income of miner.
+// MinerIncome defines the income of miner in gas units.
 type MinerIncome struct {
 	Miner  proto.AccountAddress
 	Income uint64
 }
 
-// UserCost defines the cost of user.
+// UserCost defines the cost of user in gas units, which is charged by the gas price of the
+// database.
 type UserCost struct {
 	User   proto.AccountAddress
 	Cost   uint64
@@ -79,6 +80,13 @@ func (ub *UpdateBilling) GetAccountNonce() pi.AccountNonce {
 	return ub.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (ub *UpdateBilling) GetFee() uint64 {
+	// UpdateBilling is sent by the SQLChain miners to settle the database billing, no fee is
+	// charged.
+	return 0
+}
+
 // Sign implements interfaces/Transaction.Sign.
 func (ub *UpdateBilling) Sign(signer *asymmetric.PrivateKey) (err error) {
 	return ub.DefaultHashSignVerifierImpl.Sign(&ub.UpdateBillingHeader, signer)
@@ -22,6 +22,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
 	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
 	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/pkg/errors"
 )
 
 //go:generate hsp
@@ -32,6 +33,8 @@ type UpdatePermissionHeader struct {
 	TargetUser     proto.AccountAddress
 	Permission     *UserPermission
 	Nonce          interfaces.AccountNonce
+	Fee            uint64
+	Version        int32 `hsp:"v,version"`
 }
 
 // GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
@@ -39,6 +42,21 @@ func (u *UpdatePermissionHeader) GetAccountNonce() interfaces.AccountNonce {
 	return u.Nonce
 }
 
+// GetFee implements interfaces/Transaction.GetFee.
+func (u *UpdatePermissionHeader) GetFee() uint64 {
+	return u.Fee
+}
+
+// checkVersion checks that no field which is not covered by the legacy hash is set in a legacy
+// permission updating header.
+func (u *UpdatePermissionHeader) checkVersion() error {
+	if u.Version == 0 && (u.Fee != 0 || (u.Permission != nil &&
+		(len(u.Permission.Tables) != 0 || u.Permission.ExpireHeight != 0))) {
+		return errors.Wrap(ErrUnhashedField, "legacy permission updating header")
+	}
+	return nil
+}
+
 // UpdatePermission defines the updating sqlchain permission transaction.
 type UpdatePermission struct {
 	UpdatePermissionHeader
@@ -56,11 +74,15 @@ func NewUpdatePermission(header *UpdatePermissionHeader) *UpdatePermission {
 
 // Sign implements interfaces/Transaction.Sign.
 func (up *UpdatePermission) Sign(signer *asymmetric.PrivateKey) (err error) {
+	up.Version = int32(up.HSPDefaultVersion())
 	return up.DefaultHashSignVerifierImpl.Sign(&up.UpdatePermissionHeader, signer)
 }
 
 // Verify implements interfaces/Transaction.Verify.
 func (up *UpdatePermission) Verify() error {
+	if err := up.UpdatePermissionHeader.checkVersion(); err != nil {
+		return err
+	}
 	return up.DefaultHashSignVerifierImpl.Verify(&up.UpdatePermissionHeader)
 }
 
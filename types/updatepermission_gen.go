@@ -3,6 +3,8 @@ package types
 // Code generated by github.com/CovenantSQL/HashStablePack DO NOT EDIT.
 
 import (
+	herr "errors"
+
 	hsp "github.com/CovenantSQL/HashStablePack/marshalhash"
 )
 
@@ -36,47 +38,49 @@ func (z *UpdatePermission) Msgsize() (s int) {
 	return
 }
 
+var hspVersionsUpdatePermissionHeader = []string{
+	"oldver",
+	"2bd295",
+}
+
+// HSPCurrentVersion returns current struct version
+func (z *UpdatePermissionHeader) HSPCurrentVersion() int {
+	return int(z.Version)
+}
+
+// HSPMaxVersion returns max struct version
+func (z *UpdatePermissionHeader) HSPMaxVersion() int {
+	return 1
+}
+
+// HSPDefaultVersion returns default struct version
+func (z *UpdatePermissionHeader) HSPDefaultVersion() int {
+	return 1
+}
+
 // MarshalHash marshals for hash
 func (z *UpdatePermissionHeader) MarshalHash() (o []byte, err error) {
-	var b []byte
-	o = hsp.Require(b, z.Msgsize())
-	// map header, size 4
-	o = append(o, 0x84)
-	if oTemp, err := z.Nonce.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	if z.Permission == nil {
-		o = hsp.AppendNil(o)
-	} else {
-		if oTemp, err := z.Permission.MarshalHash(); err != nil {
-			return nil, err
-		} else {
-			o = hsp.AppendBytes(o, oTemp)
-		}
-	}
-	if oTemp, err := z.TargetSQLChain.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
-	}
-	if oTemp, err := z.TargetUser.MarshalHash(); err != nil {
-		return nil, err
-	} else {
-		o = hsp.AppendBytes(o, oTemp)
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.MarshalHasholdver()
+	case 1:
+		return z.MarshalHash2bd295()
+	default:
+		err = herr.New("invalid struct version")
+		return
 	}
 	return
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *UpdatePermissionHeader) Msgsize() (s int) {
-	s = 1 + 6 + z.Nonce.Msgsize() + 11
-	if z.Permission == nil {
-		s += hsp.NilSize
-	} else {
-		s += z.Permission.Msgsize()
+	switch z.HSPCurrentVersion() {
+	case 0:
+		return z.Msgsizeoldver()
+	case 1:
+		return z.Msgsize2bd295()
+	default:
+		return 0
 	}
-	s += 15 + z.TargetSQLChain.Msgsize() + 11 + z.TargetUser.Msgsize()
 	return
 }
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// UpdateDatabaseResourcesHeader defines the database resources updating transaction header.
+type UpdateDatabaseResourcesHeader struct {
+	TargetSQLChain proto.AccountAddress
+	Node           uint16 // new miner node count
+	Space          uint64 // new reserved storage space in bytes
+	Memory         uint64 // new reserved memory in bytes
+	Nonce          interfaces.AccountNonce
+	Fee            uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *UpdateDatabaseResourcesHeader) GetAccountNonce() interfaces.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *UpdateDatabaseResourcesHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// UpdateDatabaseResources defines the transaction sent by the database owner to scale the
+// database up or down.
+type UpdateDatabaseResources struct {
+	UpdateDatabaseResourcesHeader
+	interfaces.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewUpdateDatabaseResources returns new instance.
+func NewUpdateDatabaseResources(header *UpdateDatabaseResourcesHeader) *UpdateDatabaseResources {
+	return &UpdateDatabaseResources{
+		UpdateDatabaseResourcesHeader: *header,
+		TransactionTypeMixin: *interfaces.NewTransactionTypeMixin(
+			interfaces.TransactionTypeUpdateDatabaseResources),
+	}
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (ur *UpdateDatabaseResources) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return ur.DefaultHashSignVerifierImpl.Sign(&ur.UpdateDatabaseResourcesHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (ur *UpdateDatabaseResources) Verify() error {
+	return ur.DefaultHashSignVerifierImpl.Verify(&ur.UpdateDatabaseResourcesHeader)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (ur *UpdateDatabaseResources) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(ur.Signee)
+	return addr
+}
+
+func init() {
+	interfaces.RegisterTransaction(
+		interfaces.TransactionTypeUpdateDatabaseResources, (*UpdateDatabaseResources)(nil))
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"github.com/CovenantSQL/CovenantSQL/blockproducer/interfaces"
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/crypto/asymmetric"
+	"github.com/CovenantSQL/CovenantSQL/crypto/verifier"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+)
+
+//go:generate hsp
+
+// UpdateRowPoliciesHeader defines the row-level security policies updating transaction header.
+type UpdateRowPoliciesHeader struct {
+	TargetSQLChain proto.AccountAddress
+	Policies       []*RowPolicy // replaces all the policies of the SQLChain
+	Nonce          interfaces.AccountNonce
+	Fee            uint64
+}
+
+// GetAccountNonce implements interfaces/Transaction.GetAccountNonce.
+func (h *UpdateRowPoliciesHeader) GetAccountNonce() interfaces.AccountNonce {
+	return h.Nonce
+}
+
+// GetFee implements interfaces/Transaction.GetFee.
+func (h *UpdateRowPoliciesHeader) GetFee() uint64 {
+	return h.Fee
+}
+
+// UpdateRowPolicies defines the row-level security policies updating transaction.
+type UpdateRowPolicies struct {
+	UpdateRowPoliciesHeader
+	interfaces.TransactionTypeMixin
+	verifier.DefaultHashSignVerifierImpl
+}
+
+// NewUpdateRowPolicies returns new instance.
+func NewUpdateRowPolicies(header *UpdateRowPoliciesHeader) *UpdateRowPolicies {
+	return &UpdateRowPolicies{
+		UpdateRowPoliciesHeader: *header,
+		TransactionTypeMixin: *interfaces.NewTransactionTypeMixin(
+			interfaces.TransactionTypeUpdateRowPolicies),
+	}
+}
+
+// Sign implements interfaces/Transaction.Sign.
+func (ur *UpdateRowPolicies) Sign(signer *asymmetric.PrivateKey) (err error) {
+	return ur.DefaultHashSignVerifierImpl.Sign(&ur.UpdateRowPoliciesHeader, signer)
+}
+
+// Verify implements interfaces/Transaction.Verify.
+func (ur *UpdateRowPolicies) Verify() error {
+	return ur.DefaultHashSignVerifierImpl.Verify(&ur.UpdateRowPoliciesHeader)
+}
+
+// GetAccountAddress implements interfaces/Transaction.GetAccountAddress.
+func (ur *UpdateRowPolicies) GetAccountAddress() proto.AccountAddress {
+	addr, _ := crypto.PubKeyHash(ur.Signee)
+	return addr
+}
+
+func init() {
+	interfaces.RegisterTransaction(
+		interfaces.TransactionTypeUpdateRowPolicies, (*UpdateRowPolicies)(nil))
+}
@@ -197,15 +197,23 @@ func (bs *BusService) RequestSQLProfile(dbID proto.DatabaseID) (p *types.SQLChai
 	return
 }
 
-// RequestPermStat fetches permission state from bus service.
+// RequestPermStat fetches permission state from bus service, the expired permission is returned
+// as Void.
 func (bs *BusService) RequestPermStat(
 	dbID proto.DatabaseID, user proto.AccountAddress) (permStat *types.PermStat, ok bool,
 ) {
 	bs.lock.RLock()
 	defer bs.lock.RUnlock()
 	userState, ok := bs.sqlChainState[dbID]
-	if ok {
-		permStat, ok = userState[user]
+	if !ok {
+		return
+	}
+	if permStat, ok = userState[user]; !ok {
+		return
+	}
+	permStat = &types.PermStat{
+		Permission: permStat.Permission.Effective(atomic.LoadUint32(&bs.blockCount)),
+		Status:     permStat.Status,
 	}
 	return
 }
@@ -18,8 +18,10 @@ package worker
 
 import (
 	"context"
+	"database/sql"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -69,6 +71,48 @@ const (
 	// LogWaitTimeout defines the missing log wait timeout config.
 	LogWaitTimeout = 1 * time.Second
 
+	// HeartbeatInterval defines the interval of leader heartbeats to followers.
+	HeartbeatInterval = 2 * time.Second
+
+	// ElectionTimeout defines the timeout without leader heartbeats to start a leader election.
+	ElectionTimeout = 10 * time.Second
+
+	// MaxBatchSize defines the maximum count of write requests folded into one kayak prepare log.
+	MaxBatchSize = 32
+
+	// TxTimeout defines the maximum lifetime of an interactive transaction.
+	TxTimeout = 5 * time.Second
+
+	// MaxWritableTxs defines the maximum writable interactive transactions of a database which
+	// are open or waiting for the writer, as each of them holds off the other writers.
+	MaxWritableTxs = 2
+
+	// ResultChunkRows defines the max rows in a query response chunk, larger result sets are
+	// streamed to the client by cursor.
+	ResultChunkRows = 1000
+
+	// MaxCursors defines the maximum open result set cursors of all the databases served by
+	// the node, each of them holds a reader transaction until it's drained or idle for a while.
+	MaxCursors = 1024
+
+	// MaxDBCursors defines the maximum open result set cursors of a database.
+	MaxDBCursors = 64
+
+	// MaxClientCursors defines the maximum open result set cursors of a single client node in
+	// a database.
+	MaxClientCursors = 8
+
+	// MaxReadWaitTime defines the max time for a read request to wait for the state to satisfy
+	// its consistency requirement, the request is redirected to leader by client after that.
+	MaxReadWaitTime = time.Second
+
+	// readWaitInterval defines the interval to check the staleness of state.
+	readWaitInterval = 50 * time.Millisecond
+
+	// RequestGasLimit defines the max gas units a single request may use before it is aborted,
+	// clients may ask for a lower limit in the request header.
+	RequestGasLimit = 1 << 32
+
 	// SlowQuerySampleSize defines the maximum slow query log size (default: 1KB).
 	SlowQuerySampleSize = 1 << 10
 )
@@ -82,11 +126,22 @@ type Database struct {
 	kayakConfig    *kt.RuntimeConfig
 	connSeqs       sync.Map
 	connSeqEvictCh chan uint64
+	inflight       sync.Map // cancel functions of in-flight requests, keyed by query key
 	chain          *sqlchain.Chain
 	nodeID         proto.NodeID
 	mux            *DBKayakMuxService
 	privateKey     *asymmetric.PrivateKey
 	accountAddr    proto.AccountAddress
+
+	// applied offsets of the committed kayak logs which are not checkpointed yet
+	committedLock sync.Mutex
+	committed     []logOffset
+}
+
+// logOffset maps a committed kayak log index to the applied offset of the chain state.
+type logOffset struct {
+	index  uint64
+	offset uint64
 }
 
 // NewDatabase create a single database instance using config.
@@ -172,6 +227,15 @@ func NewDatabase(cfg *DBConfig, peers *proto.Peers,
 		LastBillingHeight: cfg.LastBillingHeight,
 		UpdatePeriod:      cfg.UpdateBlockCount,
 		IsolationLevel:    cfg.IsolationLevel,
+		ChunkRows:         ResultChunkRows,
+		CursorQuota:       cfg.CursorQuota,
+		MaxCursors:        MaxDBCursors,
+		MaxClientCursors:  MaxClientCursors,
+		GasLimit:          RequestGasLimit,
+		TxLifetime:        TxTimeout,
+		MaxWritableTxs:    MaxWritableTxs,
+		RowPolicies:       cfg.RowPolicies,
+		OnNewBlock:        db.checkpoint,
 	}
 	if db.chain, err = sqlchain.NewChain(chainCfg); err != nil {
 		return
@@ -200,6 +264,17 @@ func NewDatabase(cfg *DBConfig, peers *proto.Peers,
 		ServiceName:      DBKayakRPCName,
 		ApplyMethodName:  DBKayakApplyMethodName,
 		FetchMethodName:  DBKayakFetchMethodName,
+
+		VoteMethodName:      DBKayakVoteMethodName,
+		HeartbeatMethodName: DBKayakHeartbeatMethodName,
+		HeartbeatInterval:   HeartbeatInterval,
+		ElectionTimeout:     ElectionTimeout,
+		PrivateKey:          privateKey,
+
+		SnapshotMethodName: DBKayakSnapshotMethodName,
+		SnapshotDir:        cfg.DataDir,
+
+		MaxBatchSize: MaxBatchSize,
 	}
 
 	// create kayak runtime
@@ -241,6 +316,8 @@ func (db *Database) Query(request *types.Request) (response *types.Response, err
 		tmStart     = time.Now()
 	)
 
+	defer db.bindContext(request)()
+
 	// log the query if the underlying storage layer take too long to response
 	slowQueryTimer := time.AfterFunc(db.cfg.SlowQueryTime, func() {
 		// mark as slow query
@@ -257,11 +334,20 @@ func (db *Database) Query(request *types.Request) (response *types.Response, err
 
 	switch request.Header.QueryType {
 	case types.ReadQuery:
+		if err = db.waitFresh(request); err != nil {
+			return
+		}
 		if tracker, response, err = db.chain.Query(request, false); err != nil {
 			err = errors.Wrap(err, "failed to query read query")
 			return
 		}
 	case types.WriteQuery:
+		var unlock func()
+		if unlock, err = db.chain.LockWrite(request); err != nil {
+			err = errors.Wrap(err, "failed to lock write")
+			return
+		}
+		defer unlock()
 		if db.cfg.UseEventualConsistency {
 			// reset context
 			request.SetContext(context.Background())
@@ -281,6 +367,10 @@ func (db *Database) Query(request *types.Request) (response *types.Response, err
 	}
 
 	response.Header.ResponseAccount = db.accountAddr
+	if response.Cursor != 0 {
+		// The response header is built and tracked with the last chunk of the result set
+		return
+	}
 
 	// build hash
 	if err = response.BuildHash(); err != nil {
@@ -297,6 +387,139 @@ func (db *Database) Query(request *types.Request) (response *types.Response, err
 	return
 }
 
+// FetchRows returns the next chunk of the streaming result set specified by req, or releases
+// the cursor if req.Close is set. The final response header is returned with the last chunk.
+func (db *Database) FetchRows(req *types.FetchRowsReq) (resp *types.FetchRowsResp, err error) {
+	if req.Close {
+		err = db.chain.CloseCursor(req.NodeID, req.Cursor)
+		return
+	}
+	var (
+		tracker  *x.QueryTracker
+		response *types.Response
+	)
+	resp = &types.FetchRowsResp{}
+	if resp.Rows, tracker, response, err = db.chain.FetchRows(req.NodeID, req.Cursor); err != nil {
+		err = errors.Wrap(err, "failed to fetch rows")
+		return
+	}
+	if response == nil {
+		return
+	}
+	response.Header.ResponseAccount = db.accountAddr
+	if err = response.Header.BuildHash(); err != nil {
+		err = errors.Wrap(err, "failed to build response hash")
+		return
+	}
+	if err = db.chain.AddResponse(&response.Header); err != nil {
+		log.WithError(err).Debug("failed to add response to index")
+		return
+	}
+	tracker.UpdateResp(response)
+	resp.Header = &response.Header
+	return
+}
+
+// bindContext binds the execution deadline of request to its context, which is the earlier one
+// of the deadline in request header and the max execution time of the database. The request
+// can be canceled by CancelQuery until the returned function is called.
+func (db *Database) bindContext(request *types.Request) (done func()) {
+	var (
+		ctx      = request.GetContext()
+		deadline = request.Header.Deadline
+		key      = request.Header.GetQueryKey()
+		cancel   context.CancelFunc
+	)
+	if db.cfg.MaxExecutionTime > 0 {
+		if d := time.Now().Add(db.cfg.MaxExecutionTime); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if deadline.IsZero() {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	}
+	request.SetContext(ctx)
+	db.inflight.Store(key, cancel)
+	return func() {
+		db.inflight.Delete(key)
+		cancel()
+	}
+}
+
+// waitFresh waits for the state to satisfy the min offset and max staleness of a read request
+// for at most MaxReadWaitTime, or returns ErrStaleState.
+func (db *Database) waitFresh(request *types.Request) (err error) {
+	var header = &request.Header
+	if header.MinOffset == 0 && header.MaxStaleness == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(request.GetContext(), MaxReadWaitTime)
+	defer cancel()
+	if header.MinOffset > 0 {
+		if err = db.chain.WaitApplied(ctx, header.MinOffset); err != nil {
+			return
+		}
+	}
+	if header.MaxStaleness == 0 {
+		return
+	}
+	var ticker = time.NewTicker(readWaitInterval)
+	defer ticker.Stop()
+	for {
+		var syncedAt = db.kayakRuntime.SyncedAt()
+		if time.Since(syncedAt) <= header.MaxStaleness {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Wrapf(types.ErrStaleState, "last synced with leader at %s", syncedAt)
+		}
+	}
+}
+
+// CancelQuery cancels the in-flight request specified by key, it's ignored if the request is
+// already done.
+func (db *Database) CancelQuery(key types.QueryKey) {
+	if v, ok := db.inflight.Load(key); ok {
+		v.(context.CancelFunc)()
+	}
+}
+
+// BeginTx opens an interactive transaction described by header, a writable transaction is only
+// served by the leader.
+func (db *Database) BeginTx(header *types.TxHeader) (id uint64, err error) {
+	if !header.ReadOnly && !db.kayakRuntime.IsLeader() {
+		err = errors.Wrap(kt.ErrNotLeader, "writable transaction is served by leader")
+		return
+	}
+	return db.chain.BeginTx(header.NodeID, header.Timestamp,
+		sql.IsolationLevel(header.Isolation), header.ReadOnly, TxTimeout)
+}
+
+// QueryTx executes the queries in request within an interactive transaction. The responses are
+// not tracked for acknowledgement, the write queries are applied by the commit request.
+func (db *Database) QueryTx(request *types.Request) (response *types.Response, err error) {
+	defer db.bindContext(request)()
+	if response, err = db.chain.QueryTx(request); err != nil {
+		err = errors.Wrap(err, "failed to query in transaction")
+		return
+	}
+	response.Header.ResponseAccount = db.accountAddr
+	if err = response.BuildHash(); err != nil {
+		err = errors.Wrap(err, "failed to build response hash")
+		return
+	}
+	return
+}
+
+// RollbackTx rolls back the interactive transaction described by header.
+func (db *Database) RollbackTx(header *types.TxHeader) (err error) {
+	return db.chain.RollbackTx(header.NodeID, header.TxID)
+}
+
 func (db *Database) logSlow(request *types.Request, isFinished bool, tmStart time.Time) {
 	if request == nil {
 		return
@@ -342,6 +565,46 @@ func (db *Database) Ack(ack *types.Ack) (err error) {
 	return db.saveAck(&ack.Header)
 }
 
+// checkpoint truncates the kayak logs whose write queries are all packed into the new sqlchain
+// block, the logs committed after the block are kept until they are packed as well.
+func (db *Database) checkpoint(b *types.Block) {
+	if db.kayakRuntime == nil {
+		return
+	}
+	next, ok := b.CalcNextID()
+	if !ok {
+		// no write queries packed
+		return
+	}
+	index, ok := db.coveredIndex(next)
+	if !ok {
+		return
+	}
+	if err := db.kayakRuntime.Checkpoint(index); err != nil {
+		log.WithFields(log.Fields{
+			"db":    db.dbID,
+			"block": b.BlockHash(),
+			"index": index,
+		}).WithError(err).Debug("kayak checkpoint failed")
+	}
+}
+
+// coveredIndex returns the last committed log index whose write queries are all applied before
+// the offset, and forgets the records up to it.
+func (db *Database) coveredIndex(offset uint64) (index uint64, ok bool) {
+	db.committedLock.Lock()
+	defer db.committedLock.Unlock()
+	var n = sort.Search(len(db.committed), func(i int) bool {
+		return db.committed[i].offset > offset
+	})
+	if n == 0 {
+		return
+	}
+	index, ok = db.committed[n-1].index, true
+	db.committed = append(db.committed[:0], db.committed[n:]...)
+	return
+}
+
 // Shutdown stop database handles and stop service the database.
 func (db *Database) Shutdown() (err error) {
 	if db.kayakRuntime != nil {
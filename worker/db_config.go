@@ -21,6 +21,7 @@ import (
 
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/sqlchain"
+	x "github.com/CovenantSQL/CovenantSQL/xenomint"
 )
 
 // DBConfig defines the database config.
@@ -39,4 +40,7 @@ type DBConfig struct {
 	ConsistencyLevel       float64
 	IsolationLevel         int
 	SlowQueryTime          time.Duration
+	MaxExecutionTime       time.Duration // default execution deadline of a request, zero if none
+	RowPolicies            x.RowPolicyFunc
+	CursorQuota            *x.CursorQuota // open cursors shared by the databases of the node
 }
@@ -134,6 +134,26 @@ func (db *Database) Commit(rawReq interface{}, isLeader bool) (result interface{
 	return
 }
 
+// Committed implements kayak.types.CommitIndexHandler.Committed.
+func (db *Database) Committed(index uint64) {
+	db.committedLock.Lock()
+	defer db.committedLock.Unlock()
+	db.committed = append(db.committed, logOffset{
+		index:  index,
+		offset: db.chain.AppliedOffset(),
+	})
+}
+
+// Snapshot implements kayak.types.SnapshotHandler.Snapshot.
+func (db *Database) Snapshot(file string) error {
+	return db.chain.Snapshot(file)
+}
+
+// Restore implements kayak.types.SnapshotHandler.Restore.
+func (db *Database) Restore(file string) error {
+	return db.chain.Restore(file)
+}
+
 func (db *Database) recordSequence(connID uint64, seqNo uint64) {
 	db.connSeqs.Store(connID, seqNo)
 }
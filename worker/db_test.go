@@ -566,6 +566,32 @@ func TestDatabase_EncodePayload(t *testing.T) {
 	})
 }
 
+func TestDatabase_CoveredIndex(t *testing.T) {
+	Convey("committed logs covered by block", t, func() {
+		db := &Database{
+			committed: []logOffset{
+				{index: 1, offset: 1},
+				{index: 3, offset: 1}, // failed write
+				{index: 5, offset: 3},
+				{index: 7, offset: 4},
+			},
+		}
+		_, ok := db.coveredIndex(0)
+		So(ok, ShouldBeFalse)
+		index, ok := db.coveredIndex(2)
+		So(ok, ShouldBeTrue)
+		So(index, ShouldEqual, 3)
+		// logs committed after the block are kept
+		So(db.committed, ShouldResemble, []logOffset{{index: 5, offset: 3}, {index: 7, offset: 4}})
+		index, ok = db.coveredIndex(4)
+		So(ok, ShouldBeTrue)
+		So(index, ShouldEqual, 7)
+		So(db.committed, ShouldBeEmpty)
+		_, ok = db.coveredIndex(4)
+		So(ok, ShouldBeFalse)
+	})
+}
+
 func buildAck(res *types.Response) (ack *types.Ack, err error) {
 	// get node id
 	var nodeID proto.NodeID
@@ -38,6 +38,7 @@ import (
 	"github.com/CovenantSQL/CovenantSQL/types"
 	"github.com/CovenantSQL/CovenantSQL/utils"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	x "github.com/CovenantSQL/CovenantSQL/xenomint"
 )
 
 const (
@@ -49,6 +50,10 @@ const (
 
 	// DefaultSlowQueryTime defines the default slow query log time
 	DefaultSlowQueryTime = time.Second * 5
+
+	// DefaultMaxExecutionTime defines the default max execution time of a request, an earlier
+	// deadline may be set by client in the request header.
+	DefaultMaxExecutionTime = time.Minute
 )
 
 // DBMS defines a database management instance.
@@ -61,12 +66,14 @@ type DBMS struct {
 	busService *BusService
 	address    proto.AccountAddress
 	privKey    *asymmetric.PrivateKey
+	cursors    *x.CursorQuota
 }
 
 // NewDBMS returns new database management instance.
 func NewDBMS(cfg *DBMSConfig) (dbms *DBMS, err error) {
 	dbms = &DBMS{
-		cfg: cfg,
+		cfg:     cfg,
+		cursors: x.NewCursorQuota(MaxCursors),
 	}
 
 	// init kayak rpc mux
@@ -186,6 +193,16 @@ func (dbms *DBMS) Init() (err error) {
 		err = errors.Wrap(err, "init chain bus failed")
 		return
 	}
+	if err = dbms.busService.Subscribe("/ReplaceMiner/", dbms.replaceMiner); err != nil {
+		err = errors.Wrap(err, "init chain bus failed")
+		return
+	}
+	if err = dbms.busService.Subscribe(
+		"/UpdateDatabaseResources/", dbms.updateDatabaseResources,
+	); err != nil {
+		err = errors.Wrap(err, "init chain bus failed")
+		return
+	}
 	dbms.busService.Start()
 
 	return
@@ -273,6 +290,71 @@ func (dbms *DBMS) createDatabase(tx interfaces.Transaction, count uint32) {
 	}
 }
 
+func (dbms *DBMS) replaceMiner(tx interfaces.Transaction, count uint32) {
+	rm, ok := tx.(*types.ReplaceMiner)
+	if !ok {
+		log.WithError(ErrInvalidTransactionType).Warningf("invalid tx type in replaceMiner: %s",
+			tx.GetTransactionType().String())
+		return
+	}
+	dbms.syncMiners(rm.TargetSQLChain.DatabaseID())
+}
+
+func (dbms *DBMS) updateDatabaseResources(tx interfaces.Transaction, count uint32) {
+	ur, ok := tx.(*types.UpdateDatabaseResources)
+	if !ok {
+		log.WithError(ErrInvalidTransactionType).Warningf(
+			"invalid tx type in updateDatabaseResources: %s", tx.GetTransactionType().String())
+		return
+	}
+	dbms.syncMiners(ur.TargetSQLChain.DatabaseID())
+}
+
+// syncMiners reconciles the local database instance with the miners in the sqlchain profile: a
+// newly added miner deploys the database and syncs its state from the remaining peers, a removed
+// miner drops its local instance, and the other miners update their peers.
+func (dbms *DBMS) syncMiners(dbID proto.DatabaseID) {
+	var (
+		isTargetMiner = false
+		_, exists     = dbms.getMeta(dbID)
+		le            = log.WithField("databaseid", dbID)
+	)
+	p, ok := dbms.busService.RequestSQLProfile(dbID)
+	if !ok {
+		le.Warning("database profile not found")
+		return
+	}
+	for _, mi := range p.Miners {
+		if mi.Address == dbms.address {
+			isTargetMiner = true
+		}
+	}
+	if !isTargetMiner {
+		if exists {
+			if err := dbms.Drop(dbID); err != nil {
+				le.WithError(err).Error("drop removed database error")
+			}
+		}
+		return
+	}
+
+	var si, err = dbms.buildSQLChainServiceInstance(p)
+	if err != nil {
+		le.WithError(err).Warn("failed to build sqlchain service instance from profile")
+		return
+	}
+	if !exists {
+		// Missing logs and blocks are fetched from the remaining peers after started
+		if err = dbms.Create(si, true); err != nil {
+			le.WithError(err).Error("deploy database error")
+		}
+		return
+	}
+	if err = dbms.Update(si); err != nil {
+		le.WithError(err).Error("update database peers error")
+	}
+}
+
 func (dbms *DBMS) buildSQLChainServiceInstance(
 	profile *types.SQLChainProfile) (instance *types.ServiceInstance, err error,
 ) {
@@ -435,6 +517,9 @@ func (dbms *DBMS) Create(instance *types.ServiceInstance, cleanup bool) (err err
 		ConsistencyLevel:       instance.ResourceMeta.ConsistencyLevel,
 		IsolationLevel:         instance.ResourceMeta.IsolationLevel,
 		SlowQueryTime:          DefaultSlowQueryTime,
+		MaxExecutionTime:       DefaultMaxExecutionTime,
+		RowPolicies:            dbms.rowPolicies(instance.DatabaseID),
+		CursorQuota:            dbms.cursors,
 	}
 
 	// set last billing height
@@ -493,7 +578,8 @@ func (dbms *DBMS) Query(req *types.Request) (res *types.Response, err error) {
 	if err != nil {
 		return
 	}
-	err = dbms.checkPermission(addr, req.Header.DatabaseID, req.Header.QueryType, req.Payload.Queries)
+	err = dbms.checkPermission(addr, req.Header.DatabaseID,
+		req.Header.AccessToken, req.Header.QueryType, req.Payload.Queries)
 	if err != nil {
 		return
 	}
@@ -504,9 +590,85 @@ func (dbms *DBMS) Query(req *types.Request) (res *types.Response, err error) {
 		return
 	}
 
+	if req.Header.TxID != 0 {
+		return db.QueryTx(req)
+	}
 	return db.Query(req)
 }
 
+// BeginTx handles interactive transaction begin request in dbms.
+func (dbms *DBMS) BeginTx(req *types.BeginTxReq) (id uint64, err error) {
+	var (
+		db        *Database
+		exists    bool
+		addr      proto.AccountAddress
+		queryType = types.WriteQuery
+	)
+	if err = req.Header.Verify(); err != nil {
+		return
+	}
+	if addr, err = crypto.PubKeyHash(req.Header.Signee); err != nil {
+		return
+	}
+	if req.Header.ReadOnly {
+		queryType = types.ReadQuery
+	}
+	if err = dbms.checkPermission(
+		addr, req.Header.DatabaseID, req.Header.AccessToken, queryType, nil,
+	); err != nil {
+		return
+	}
+	if db, exists = dbms.getMeta(req.Header.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+	return db.BeginTx(&req.Header.TxHeader)
+}
+
+// EndTx handles interactive transaction commit/rollback request in dbms.
+func (dbms *DBMS) EndTx(req *types.EndTxReq) (res *types.Response, err error) {
+	var (
+		db     *Database
+		exists bool
+		addr   proto.AccountAddress
+	)
+	if err = req.Header.Verify(); err != nil {
+		return
+	}
+	if db, exists = dbms.getMeta(req.Header.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+	if req.Commit == nil {
+		err = db.RollbackTx(&req.Header.TxHeader)
+		return
+	}
+	defer func() {
+		if err != nil {
+			// do not leave the transaction holding the database writer after a failed commit
+			_ = db.RollbackTx(&req.Header.TxHeader)
+		}
+	}()
+	// the write queries are checked again as a normal write request
+	if req.Commit.Header.TxID != req.Header.TxID ||
+		req.Commit.Header.NodeID != req.Header.NodeID ||
+		req.Commit.Header.DatabaseID != req.Header.DatabaseID ||
+		req.Commit.Header.QueryType != types.WriteQuery {
+		err = errors.Wrap(ErrInvalidRequest, "commit request mismatch")
+		return
+	}
+	if addr, err = crypto.PubKeyHash(req.Commit.Header.Signee); err != nil {
+		return
+	}
+	if err = dbms.checkPermission(
+		addr, req.Commit.Header.DatabaseID, req.Commit.Header.AccessToken,
+		types.WriteQuery, req.Commit.Payload.Queries,
+	); err != nil {
+		return
+	}
+	return db.Query(req.Commit)
+}
+
 // Ack handles ack of previous response.
 func (dbms *DBMS) Ack(ack *types.Ack) (err error) {
 	var db *Database
@@ -517,7 +679,8 @@ func (dbms *DBMS) Ack(ack *types.Ack) (err error) {
 	if err != nil {
 		return
 	}
-	err = dbms.checkPermission(addr, ack.Header.Response.Request.DatabaseID, types.ReadQuery, nil)
+	err = dbms.checkPermission(addr, ack.Header.Response.Request.DatabaseID,
+		ack.Header.Response.Request.AccessToken, types.ReadQuery, nil)
 	if err != nil {
 		return
 	}
@@ -531,6 +694,17 @@ func (dbms *DBMS) Ack(ack *types.Ack) (err error) {
 	return db.Ack(ack)
 }
 
+// GetPeers returns the current peers of the database, including the leader elected by kayak.
+func (dbms *DBMS) GetPeers(dbID proto.DatabaseID) (peers *proto.Peers, err error) {
+	db, exists := dbms.getMeta(dbID)
+	if !exists {
+		err = ErrNotExists
+		return
+	}
+	peers = db.kayakRuntime.Peers()
+	return
+}
+
 func (dbms *DBMS) getMeta(dbID proto.DatabaseID) (db *Database, exists bool) {
 	var rawDB interface{}
 
@@ -556,8 +730,79 @@ func (dbms *DBMS) removeMeta(dbID proto.DatabaseID) (err error) {
 	return dbms.writeMeta()
 }
 
-func (dbms *DBMS) checkPermission(addr proto.AccountAddress,
-	dbID proto.DatabaseID, queryType types.QueryType, queries []types.Query) (err error) {
+// FetchRows handles streaming result set fetch request in dbms.
+func (dbms *DBMS) FetchRows(req *types.FetchRowsReq) (res *types.FetchRowsResp, err error) {
+	var db *Database
+	var exists bool
+	if db, exists = dbms.getMeta(req.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+	return db.FetchRows(req)
+}
+
+// CancelQuery handles the cancellation of an in-flight query request in dbms.
+func (dbms *DBMS) CancelQuery(req *types.CancelQueryReq) (err error) {
+	var db *Database
+	var exists bool
+	if db, exists = dbms.getMeta(req.DatabaseID); !exists {
+		err = ErrNotExists
+		return
+	}
+	db.CancelQuery(req.Query)
+	return
+}
+
+// rowPolicies returns the row-level security policies of the database, which apply to all the
+// users without super permission.
+func (dbms *DBMS) rowPolicies(dbID proto.DatabaseID) x.RowPolicyFunc {
+	return func(user proto.AccountAddress) []*types.RowPolicy {
+		var profile, ok = dbms.busService.RequestSQLProfile(dbID)
+		if !ok || len(profile.RowPolicies) == 0 {
+			return nil
+		}
+		if permStat, ok := dbms.busService.RequestPermStat(dbID, user); ok &&
+			permStat.Permission.HasSuperPermission() {
+			return nil
+		}
+		return profile.RowPolicies
+	}
+}
+
+// tokenPermStat returns the permission delegated to the user by the access token, the token is
+// only valid while its issuer keeps the super permission of the database. The status of the
+// issuer applies to the user without any permission granted on chain.
+func (dbms *DBMS) tokenPermStat(addr proto.AccountAddress, dbID proto.DatabaseID,
+	token *types.AccessToken, permStat *types.PermStat) (_ *types.PermStat, err error) {
+	var (
+		issuer     proto.AccountAddress
+		issuerStat *types.PermStat
+		ok         bool
+	)
+	if err = token.Check(dbID, addr, time.Now()); err != nil {
+		err = errors.Wrapf(ErrPermissionDeny, "invalid access token: %v", err)
+		return
+	}
+	if issuer, err = token.Issuer(); err != nil {
+		err = errors.Wrapf(ErrPermissionDeny, "invalid access token issuer: %v", err)
+		return
+	}
+	if issuerStat, ok = dbms.busService.RequestPermStat(dbID, issuer); !ok ||
+		!issuerStat.Permission.HasSuperPermission() {
+		err = errors.Wrapf(ErrPermissionDeny, "access token issuer %s is not a super user", issuer)
+		return
+	}
+	if permStat == nil {
+		permStat = issuerStat
+	}
+	return &types.PermStat{
+		Permission: token.Permission(),
+		Status:     permStat.Status,
+	}, nil
+}
+
+func (dbms *DBMS) checkPermission(addr proto.AccountAddress, dbID proto.DatabaseID,
+	token *types.AccessToken, queryType types.QueryType, queries []types.Query) (err error) {
 	log.Debugf("in checkPermission, database id: %s, user addr: %s", dbID, addr.String())
 
 	var (
@@ -568,8 +813,17 @@ func (dbms *DBMS) checkPermission(addr proto.AccountAddress,
 	// get database perm stat
 	permStat, ok = dbms.busService.RequestPermStat(dbID, addr)
 
-	// perm stat not exists
-	if !ok {
+	// the access token overrides the permission granted on chain, or grants the permission to
+	// the user not found on chain
+	if token != nil {
+		if !ok {
+			permStat = nil
+		}
+		if permStat, err = dbms.tokenPermStat(addr, dbID, token, permStat); err != nil {
+			return
+		}
+	} else if !ok {
+		// perm stat not exists
 		err = errors.Wrap(ErrPermissionDeny, "database not exists")
 		return
 	}
@@ -613,6 +867,28 @@ func (dbms *DBMS) checkPermission(addr proto.AccountAddress,
 		return
 	}
 
+	// check for table and column privileges
+	if len(permStat.Permission.Tables) == 0 || permStat.Permission.HasSuperPermission() {
+		return
+	}
+
+	for _, q := range queries {
+		var access []*types.TableAccess
+		if access, err = x.QueryAccess(q.Pattern); err != nil {
+			err = errors.Wrapf(ErrPermissionDeny, "disallowed query %s: %v", q.Pattern, err)
+			return
+		}
+		if denied, hasDisallowedAccess := permStat.Permission.HasDisallowedTableAccess(access); hasDisallowedAccess {
+			err = errors.Wrapf(ErrPermissionDeny, "disallowed %s access on table %s, query %s",
+				denied.Action, denied.Table, q.Pattern)
+			log.WithError(err).WithFields(log.Fields{
+				"permission": permStat.Permission,
+				"query":      q.Pattern,
+			}).Debug("can not query")
+			return
+		}
+	}
+
 	return
 }
 
@@ -32,6 +32,12 @@ const (
 	DBKayakApplyMethodName = "Apply"
 	// DBKayakFetchMethodName defines the database kayak fetch rpc method name.
 	DBKayakFetchMethodName = "Fetch"
+	// DBKayakVoteMethodName defines the database kayak leader election vote rpc method name.
+	DBKayakVoteMethodName = "Vote"
+	// DBKayakHeartbeatMethodName defines the database kayak leader heartbeat rpc method name.
+	DBKayakHeartbeatMethodName = "Heartbeat"
+	// DBKayakSnapshotMethodName defines the database kayak snapshot transfer rpc method name.
+	DBKayakSnapshotMethodName = "Snapshot"
 )
 
 // DBKayakMuxService defines a mux service for sqlchain kayak.
@@ -78,10 +84,53 @@ func (s *DBKayakMuxService) Fetch(req *kt.FetchRequest, resp *kt.FetchResponse)
 	if v, ok := s.serviceMap.Load(id); ok {
 		var l *kt.Log
 		if l, err = v.(*kayak.Runtime).Fetch(req.GetContext(), req.Index); err != nil {
-			resp.Log = l
-			resp.Instance = req.Instance
-			return err
+			return
 		}
+		resp.Log = l
+		resp.Instance = req.Instance
+		return
+	}
+
+	return errors.Wrapf(ErrUnknownMuxRequest, "instance %v", req.Instance)
+}
+
+// Vote handles kayak leader election vote call.
+func (s *DBKayakMuxService) Vote(req *kt.VoteRequest, resp *kt.VoteResponse) (err error) {
+	id := proto.DatabaseID(req.Instance)
+
+	if v, ok := s.serviceMap.Load(id); ok {
+		if resp.Granted, resp.Term, err = v.(*kayak.Runtime).Vote(req); err != nil {
+			return
+		}
+		resp.Instance = req.Instance
+		return
+	}
+
+	return errors.Wrapf(ErrUnknownMuxRequest, "instance %v", req.Instance)
+}
+
+// Heartbeat handles kayak leader heartbeat call.
+func (s *DBKayakMuxService) Heartbeat(req *kt.HeartbeatRequest, _ *interface{}) (err error) {
+	id := proto.DatabaseID(req.Instance)
+
+	if v, ok := s.serviceMap.Load(id); ok {
+		return v.(*kayak.Runtime).Heartbeat(req)
+	}
+
+	return errors.Wrapf(ErrUnknownMuxRequest, "instance %v", req.Instance)
+}
+
+// Snapshot handles kayak snapshot chunk transfer call.
+func (s *DBKayakMuxService) Snapshot(req *kt.SnapshotRequest, resp *kt.SnapshotResponse) (err error) {
+	id := proto.DatabaseID(req.Instance)
+
+	if v, ok := s.serviceMap.Load(id); ok {
+		var r *kt.SnapshotResponse
+		if r, err = v.(*kayak.Runtime).Snapshot(req); err != nil {
+			return
+		}
+		*resp = *r
+		return
 	}
 
 	return errors.Wrapf(ErrUnknownMuxRequest, "instance %v", req.Instance)
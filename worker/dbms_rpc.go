@@ -82,6 +82,8 @@ func (rpc *DBMSRPCService) Query(req *types.Request, res *types.Response) (err e
 	var r *types.Response
 	if r, err = rpc.dbms.Query(req); err != nil {
 		dbQueryFailCounter.Mark(1)
+		// typed errors are sent as is to be recovered by the client
+		err = types.ToRemoteError(err)
 		return
 	}
 
@@ -91,6 +93,51 @@ func (rpc *DBMSRPCService) Query(req *types.Request, res *types.Response) (err e
 	return
 }
 
+// BeginTx rpc, called by client to begin an interactive transaction.
+func (rpc *DBMSRPCService) BeginTx(req *types.BeginTxReq, res *types.BeginTxResp) (err error) {
+	if req.Envelope.NodeID.String() != string(req.Header.NodeID) {
+		err = errors.Wrap(ErrInvalidRequest, "request node id mismatch in begin tx")
+		return
+	}
+	res.TxID, err = rpc.dbms.BeginTx(req)
+	return
+}
+
+// EndTx rpc, called by client to commit or rollback an interactive transaction.
+func (rpc *DBMSRPCService) EndTx(req *types.EndTxReq, res *types.EndTxResp) (err error) {
+	if req.Envelope.NodeID.String() != string(req.Header.NodeID) {
+		err = errors.Wrap(ErrInvalidRequest, "request node id mismatch in end tx")
+		return
+	}
+	res.Response, err = rpc.dbms.EndTx(req)
+	return
+}
+
+// FetchRows rpc, called by client to fetch the next chunk of a streaming result set.
+func (rpc *DBMSRPCService) FetchRows(req *types.FetchRowsReq, res *types.FetchRowsResp) (err error) {
+	// the cursor is only accessible by its owner node
+	if req.Envelope.NodeID.String() != string(req.NodeID) {
+		err = errors.Wrap(ErrInvalidRequest, "request node id mismatch in fetch rows")
+		return
+	}
+	var r *types.FetchRowsResp
+	if r, err = rpc.dbms.FetchRows(req); err != nil || r == nil {
+		return
+	}
+	res.Rows, res.Header = r.Rows, r.Header
+	return
+}
+
+// CancelQuery rpc, called by client to cancel an in-flight query request.
+func (rpc *DBMSRPCService) CancelQuery(req *types.CancelQueryReq, _ *types.CancelQueryResp) (err error) {
+	// the query is only cancellable by its sender node
+	if req.Envelope.NodeID.String() != string(req.Query.NodeID) {
+		err = errors.Wrap(ErrInvalidRequest, "request node id mismatch in cancel query")
+		return
+	}
+	return rpc.dbms.CancelQuery(req)
+}
+
 // Ack rpc, called by client to confirm read request.
 func (rpc *DBMSRPCService) Ack(ack *types.Ack, _ *types.AckResponse) (err error) {
 	// Just need to verify signature in db.saveAck
@@ -134,3 +181,9 @@ func (rpc *DBMSRPCService) Deploy(req *types.UpdateService, _ *types.UpdateServi
 
 	return
 }
+
+// QueryPeers rpc, called by client to query the current peers and leader of a database.
+func (rpc *DBMSRPCService) QueryPeers(req *types.QueryPeersReq, resp *types.QueryPeersResp) (err error) {
+	resp.Peers, err = rpc.dbms.GetPeers(req.DatabaseID)
+	return
+}
@@ -503,6 +503,185 @@ func TestDBMS(t *testing.T) {
 				So(err, ShouldNotBeNil)
 			})
 
+			Convey("table privileges restrictions", func() {
+				var writeQuery *types.Request
+				var queryRes *types.Response
+
+				// set back to admin and prepare tables
+				err = dbms.UpdatePermission(dbAddr.DatabaseID(), userAddr,
+					&types.PermStat{Permission: types.UserPermissionFromRole(types.Admin), Status: types.Normal})
+				So(err, ShouldBeNil)
+				writeQuery, err = buildQueryWithDatabaseID(types.WriteQuery,
+					1, atomic.AddUint64(&seqNo, 1),
+					dbID, []string{
+						"create table test (test int, secret int)",
+						"create table other (test int)",
+						"insert into test values(1, 2)",
+					})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+
+				err = dbms.UpdatePermission(dbAddr.DatabaseID(), userAddr,
+					&types.PermStat{Permission: &types.UserPermission{
+						Role: types.ReadWrite,
+						Tables: []*types.TablePrivilege{
+							{Table: "test", Actions: types.TableSelect | types.TableInsert, Columns: []string{"test"}},
+						},
+					}, Status: types.Normal})
+				So(err, ShouldBeNil)
+
+				// sending allowed queries
+				writeQuery, err = buildQueryWithDatabaseID(types.WriteQuery,
+					1, atomic.AddUint64(&seqNo, 1),
+					dbID, []string{
+						"insert into test (test) values(3)",
+					})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+				So(err, ShouldBeNil)
+
+				var readQuery *types.Request
+				readQuery, err = buildQueryWithDatabaseID(types.ReadQuery,
+					1, atomic.AddUint64(&seqNo, 1),
+					dbID, []string{
+						"select test from test where test > 0",
+					})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, readQuery, &queryRes)
+				So(err, ShouldBeNil)
+				So(queryRes.Header.RowCount, ShouldEqual, uint64(2))
+
+				// sending disallowed queries
+				for _, q := range []string{
+					"select * from test",
+					"select secret from test",
+					`select "secret" from test`,
+					"select test from other",
+					"select count(*) from test, other",
+				} {
+					readQuery, err = buildQueryWithDatabaseID(types.ReadQuery,
+						1, atomic.AddUint64(&seqNo, 1),
+						dbID, []string{q})
+					So(err, ShouldBeNil)
+					err = testRequest(route.DBSQuery, readQuery, &queryRes)
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, ErrPermissionDeny.Error())
+				}
+				for _, q := range []string{
+					"insert into test values(4, 5)",
+					"update test set test = 1",
+					"delete from test",
+					"drop table test",
+				} {
+					writeQuery, err = buildQueryWithDatabaseID(types.WriteQuery,
+						1, atomic.AddUint64(&seqNo, 1),
+						dbID, []string{q})
+					So(err, ShouldBeNil)
+					err = testRequest(route.DBSQuery, writeQuery, &queryRes)
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, ErrPermissionDeny.Error())
+				}
+			})
+
+			Convey("permission expiry and access token", func() {
+				var (
+					readQuery  *types.Request
+					writeQuery *types.Request
+					queryRes   *types.Response
+					count      = atomic.LoadUint32(&dbms.busService.blockCount)
+				)
+
+				// expire read permission
+				err = dbms.UpdatePermission(dbAddr.DatabaseID(), userAddr,
+					&types.PermStat{Permission: &types.UserPermission{
+						Role:         types.Read,
+						ExpireHeight: count + 1,
+					}, Status: types.Normal})
+				So(err, ShouldBeNil)
+				readQuery, err = buildQueryWithDatabaseID(types.ReadQuery,
+					1, atomic.AddUint64(&seqNo, 1),
+					dbID, []string{
+						"SELECT 1",
+					})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, readQuery, &queryRes)
+				So(err, ShouldBeNil)
+				atomic.StoreUint32(&dbms.busService.blockCount, count+1)
+				userState, ok = dbms.busService.RequestPermStat(dbAddr.DatabaseID(), userAddr)
+				So(ok, ShouldBeTrue)
+				So(userState.Permission.Role, ShouldEqual, types.Void)
+				readQuery, err = buildQueryWithDatabaseID(types.ReadQuery,
+					1, atomic.AddUint64(&seqNo, 1),
+					dbID, []string{
+						"SELECT 1",
+					})
+				So(err, ShouldBeNil)
+				err = testRequest(route.DBSQuery, readQuery, &queryRes)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, ErrPermissionDeny.Error())
+
+				// delegate write permission by access token
+				issuerKey, _, err := asymmetric.GenSecp256k1KeyPair()
+				So(err, ShouldBeNil)
+				issuerAddr, err := crypto.PubKeyHash(issuerKey.PubKey())
+				So(err, ShouldBeNil)
+				err = dbms.UpdatePermission(dbAddr.DatabaseID(), issuerAddr,
+					&types.PermStat{Permission: types.UserPermissionFromRole(types.Admin), Status: types.Normal})
+				So(err, ShouldBeNil)
+				var (
+					now   = time.Now().UTC()
+					token = types.NewAccessToken(&types.AccessTokenHeader{
+						DatabaseID: dbID,
+						User:       userAddr,
+						Role:       types.ReadWrite,
+						Issued:     now,
+						Expire:     now.Add(time.Hour),
+					})
+					writeWithToken = func() error {
+						writeQuery, err = buildQueryWithDatabaseID(types.WriteQuery,
+							1, atomic.AddUint64(&seqNo, 1),
+							dbID, []string{
+								"create table if not exists test (test int)",
+							})
+						So(err, ShouldBeNil)
+						writeQuery.Header.AccessToken = token
+						err = writeQuery.Sign(privateKey)
+						So(err, ShouldBeNil)
+						return testRequest(route.DBSQuery, writeQuery, &queryRes)
+					}
+				)
+				err = token.Sign(privateKey)
+				So(err, ShouldBeNil)
+				err = writeWithToken()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, ErrPermissionDeny.Error())
+				err = token.Sign(issuerKey)
+				So(err, ShouldBeNil)
+				err = writeWithToken()
+				So(err, ShouldBeNil)
+
+				// revoke the issuer
+				err = dbms.UpdatePermission(dbAddr.DatabaseID(), issuerAddr,
+					&types.PermStat{Permission: types.UserPermissionFromRole(types.Read), Status: types.Normal})
+				So(err, ShouldBeNil)
+				err = writeWithToken()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, ErrPermissionDeny.Error())
+
+				// grant to the user not found on chain
+				err = dbms.UpdatePermission(dbAddr.DatabaseID(), issuerAddr,
+					&types.PermStat{Permission: types.UserPermissionFromRole(types.Admin), Status: types.Normal})
+				So(err, ShouldBeNil)
+				dbms.busService.lock.Lock()
+				delete(dbms.busService.sqlChainState[dbAddr.DatabaseID()], userAddr)
+				dbms.busService.lock.Unlock()
+				_, ok = dbms.busService.RequestPermStat(dbAddr.DatabaseID(), userAddr)
+				So(ok, ShouldBeFalse)
+				err = writeWithToken()
+				So(err, ShouldBeNil)
+			})
+
 			// set back permission object
 			err = dbms.UpdatePermission(dbAddr.DatabaseID(), userAddr,
 				&types.PermStat{Permission: types.UserPermissionFromRole(types.Admin), Status: types.Normal})
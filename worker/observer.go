@@ -78,7 +78,7 @@ func (dbms *DBMS) observerFetchBlock(dbID proto.DatabaseID, nodeID proto.NodeID,
 	}()
 
 	// check permission
-	err = dbms.checkPermission(addr, dbID, types.ReadQuery, nil)
+	err = dbms.checkPermission(addr, dbID, nil, types.ReadQuery, nil)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"databaseID": dbID,
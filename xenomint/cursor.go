@@ -0,0 +1,312 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+const (
+	// CursorIdleTimeout defines the idle timeout of a cursor, it's released if the next chunk is
+	// not fetched in time.
+	CursorIdleTimeout = time.Minute
+)
+
+// cursor defines an open result set of a read request. The rows are sent in chunks and hashed
+// as they are read, the response header is built with the running hash after the last chunk.
+type cursor struct {
+	sync.Mutex
+	id      uint64
+	owner   proto.NodeID
+	ref     *QueryTracker
+	header  types.ResponseHeader
+	tx      *sql.Tx
+	rows    *sql.Rows
+	ncols   int
+	next    []interface{} // the row read ahead of the next chunk
+	hasher  *types.PayloadHasher
+	meter   *gasMeter
+	cancel  context.CancelFunc // cancels the context of rows
+	release func()             // releases the cursor from the limits of state
+	timer   *time.Timer
+	done    bool
+}
+
+// openCursor reads the first chunk of the result set of q. A cursor holding the reader tx is
+// returned if there are more rows than a single chunk. The rows outlive the request, so only
+// the first chunk is interrupted by the request context.
+func (s *State) openCursor(
+	ctx context.Context, tx *sql.Tx, req *types.Request, q *types.Query, meter *gasMeter,
+) (
+	cur *cursor, names []string, declTypes []string, data [][]interface{}, err error,
+) {
+	var (
+		rows          *sql.Rows
+		rctx, cancel  = context.WithCancel(context.Background())
+		firstFetched  = make(chan struct{})
+		watcherClosed = make(chan struct{})
+	)
+	if err = meter.query(); err != nil {
+		cancel()
+		return
+	}
+	go func() {
+		defer close(watcherClosed)
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-firstFetched:
+		}
+	}()
+	defer func() {
+		close(firstFetched)
+		<-watcherClosed
+		if err == nil {
+			err = interruptError(ctx, nil)
+		}
+		if cur == nil || err != nil {
+			if rows != nil {
+				_ = rows.Close()
+			}
+			cancel()
+			if cur != nil {
+				cur.release()
+			}
+			cur = nil
+		}
+	}()
+	if rows, names, declTypes, err = openRows(meter.withSteps(rctx), tx, q); err != nil {
+		err = meter.stepped(interruptError(ctx, err))
+		return
+	}
+	data, err = scanRows(rows, len(names), s.chunkRows+1, meter)
+	if err = meter.stepped(err); err != nil || len(data) <= s.chunkRows {
+		return
+	}
+	var owner = req.Header.NodeID
+	if err = s.acquireCursor(owner); err != nil {
+		return
+	}
+	cur = &cursor{
+		owner:   owner,
+		tx:      tx,
+		rows:    rows,
+		ncols:   len(names),
+		next:    data[s.chunkRows],
+		meter:   meter,
+		cancel:  cancel,
+		release: func() { s.releaseCursor(owner) },
+	}
+	data = data[:s.chunkRows]
+	if cur.hasher, err = types.NewPayloadHasher(names, declTypes); err == nil {
+		err = cur.hasher.Write(buildRowsFromNativeData(data))
+	}
+	return
+}
+
+// CursorQuota defines the max open cursors shared by states, e.g. of all the databases served
+// by a node.
+type CursorQuota struct {
+	sync.Mutex
+	max  int
+	used int
+}
+
+// NewCursorQuota returns a new CursorQuota of max open cursors, zero means unlimited.
+func NewCursorQuota(max int) *CursorQuota {
+	return &CursorQuota{max: max}
+}
+
+func (q *CursorQuota) acquire() (err error) {
+	q.Lock()
+	defer q.Unlock()
+	if q.max > 0 && q.used >= q.max {
+		return errors.Wrapf(ErrCursorLimitExceeded, "%d open cursors of node", q.used)
+	}
+	q.used++
+	return
+}
+
+func (q *CursorQuota) release() {
+	q.Lock()
+	defer q.Unlock()
+	q.used--
+}
+
+// acquireCursor reserves an open cursor for owner within the cursor limits.
+func (s *State) acquireCursor(owner proto.NodeID) (err error) {
+	s.cursorLock.Lock()
+	defer s.cursorLock.Unlock()
+	if s.maxCursors > 0 && s.cursorCount >= s.maxCursors {
+		return errors.Wrapf(ErrCursorLimitExceeded, "%d open cursors of database", s.cursorCount)
+	}
+	if n := s.ownerCursors[owner]; s.maxOwnerCursors > 0 && n >= s.maxOwnerCursors {
+		return errors.Wrapf(ErrCursorLimitExceeded, "%d open cursors of %s", n, owner)
+	}
+	if s.cursorQuota != nil {
+		if err = s.cursorQuota.acquire(); err != nil {
+			return
+		}
+	}
+	s.cursorCount++
+	s.ownerCursors[owner]++
+	return
+}
+
+func (s *State) releaseCursor(owner proto.NodeID) {
+	s.cursorLock.Lock()
+	defer s.cursorLock.Unlock()
+	if s.cursorQuota != nil {
+		s.cursorQuota.release()
+	}
+	s.cursorCount--
+	if s.ownerCursors[owner]--; s.ownerCursors[owner] <= 0 {
+		delete(s.ownerCursors, owner)
+	}
+}
+
+func (s *State) registerCursor(cur *cursor, ref *QueryTracker, resp *types.Response) {
+	cur.id = atomic.AddUint64(&s.cursorSeq, 1)
+	cur.ref = ref
+	cur.header = resp.Header.ResponseHeader
+	s.cursors.Store(cur.id, cur)
+	cur.Lock()
+	cur.timer = time.AfterFunc(CursorIdleTimeout, func() { s.closeCursor(cur) })
+	cur.Unlock()
+	resp.Cursor = cur.id
+}
+
+func (s *State) getCursor(id uint64, owner proto.NodeID) (cur *cursor, err error) {
+	if v, ok := s.cursors.Load(id); ok {
+		if cur = v.(*cursor); cur.owner == owner {
+			return
+		}
+	}
+	return nil, errors.Wrapf(ErrCursorNotFound, "cursor %d of %s", id, owner)
+}
+
+// FetchRows returns the next chunk of rows from the cursor id owned by node. The cursor is
+// released after the last chunk is fetched, which is returned with the final response and
+// the query tracker of the read request. The final response only contains the header, whose
+// payload hash commits to the whole result set.
+func (s *State) FetchRows(
+	owner proto.NodeID, id uint64,
+) (
+	rows []types.ResponseRow, ref *QueryTracker, resp *types.Response, err error,
+) {
+	var (
+		cur  *cursor
+		data [][]interface{}
+	)
+	if cur, err = s.getCursor(id, owner); err != nil {
+		return
+	}
+	if err = func() (err error) {
+		cur.Lock()
+		defer cur.Unlock()
+		if cur.done {
+			return errors.Wrapf(ErrCursorNotFound, "cursor %d already closed", id)
+		}
+		defer func() {
+			if err != nil || cur.next == nil {
+				cur.closeLocked()
+			}
+		}()
+		cur.timer.Reset(CursorIdleTimeout)
+		data, err = scanRows(cur.rows, cur.ncols, s.chunkRows, cur.meter)
+		if err = cur.meter.stepped(err); err != nil {
+			return
+		}
+		data = append([][]interface{}{cur.next}, data...)
+		cur.next = nil
+		if len(data) > s.chunkRows {
+			cur.next = data[s.chunkRows]
+			data = data[:s.chunkRows]
+		}
+		rows = buildRowsFromNativeData(data)
+		if err = cur.hasher.Write(rows); err != nil {
+			return
+		}
+		if cur.next == nil {
+			cur.header.RowCount = cur.hasher.Count()
+			cur.header.PayloadHash = cur.hasher.Sum()
+			cur.header.GasUsed = cur.meter.used
+			resp = &types.Response{
+				Header: types.SignedResponseHeader{ResponseHeader: cur.header},
+			}
+			ref = cur.ref
+		}
+		return
+	}(); err != nil {
+		s.cursors.Delete(id)
+		return
+	}
+	if ref != nil {
+		s.cursors.Delete(id)
+		s.Lock()
+		s.pool.enqueueRead(ref)
+		s.Unlock()
+	}
+	return
+}
+
+// CloseCursor releases the cursor id owned by node before all rows are fetched.
+func (s *State) CloseCursor(owner proto.NodeID, id uint64) (err error) {
+	var cur *cursor
+	if cur, err = s.getCursor(id, owner); err != nil {
+		return
+	}
+	s.closeCursor(cur)
+	return
+}
+
+func (s *State) closeCursor(cur *cursor) {
+	cur.Lock()
+	cur.closeLocked()
+	cur.Unlock()
+	s.cursors.Delete(cur.id)
+}
+
+func (s *State) closeCursors() {
+	s.cursors.Range(func(_, v interface{}) bool {
+		s.closeCursor(v.(*cursor))
+		return true
+	})
+}
+
+func (cur *cursor) closeLocked() {
+	if cur.done {
+		return
+	}
+	cur.done = true
+	if cur.timer != nil {
+		cur.timer.Stop()
+	}
+	_ = cur.rows.Close()
+	cur.cancel()
+	_ = cur.tx.Rollback()
+	cur.release()
+}
@@ -33,4 +33,33 @@ var (
 	ErrStatefulQueryParts = errors.New("query contains stateful query parts")
 	// ErrInvalidTableName indicates query contains invalid table name in ddl statement.
 	ErrInvalidTableName = errors.New("invalid table name in ddl")
+	// ErrUnknownTableAccess indicates the tables accessed by the query statement can not be
+	// determined.
+	ErrUnknownTableAccess = errors.New("unknown table access of statement")
+	// ErrRowPolicyViolation indicates the query of a restricted user can not be rewritten by the
+	// row-level security policies, or it writes rows not owned by the user.
+	ErrRowPolicyViolation = errors.New("row policy violation")
+	// ErrStateClosed indicates the state is already closed.
+	ErrStateClosed = errors.New("state closed")
+	// ErrBackupNotSupported indicates the underlying storage does not support backup.
+	ErrBackupNotSupported = errors.New("storage backup not supported")
+	// ErrTxNotFound indicates the interactive transaction is not found or already finished.
+	ErrTxNotFound = errors.New("transaction not found")
+	// ErrTxReadOnly indicates a write query is sent to a read-only interactive transaction.
+	ErrTxReadOnly = errors.New("transaction is read-only")
+	// ErrTxIsolationLevel indicates the isolation level of the interactive transaction is not
+	// supported.
+	ErrTxIsolationLevel = errors.New("unsupported transaction isolation level")
+	// ErrTxLimitExceeded indicates too many writable interactive transactions are open or waiting
+	// for the writer.
+	ErrTxLimitExceeded = errors.New("too many writable transactions")
+	// ErrTxCommitMismatch indicates the commit request does not match the write queries executed
+	// in the interactive transaction.
+	ErrTxCommitMismatch = errors.New("commit request mismatch")
+	// ErrCursorNotFound indicates the result set cursor is not found or already closed.
+	ErrCursorNotFound = errors.New("cursor not found")
+	// ErrCursorLimitExceeded indicates too many result set cursors are open.
+	ErrCursorLimitExceeded = errors.New("too many open cursors")
+	// ErrGasLimitExceeded indicates the request is aborted as it runs out of gas.
+	ErrGasLimitExceeded = errors.New("gas limit exceeded")
 )
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"context"
+	"time"
+
+	sqlite3 "github.com/CovenantSQL/go-sqlite3-encrypt"
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+// Gas schedule of the work done to execute a request, the gas used is recorded in the response
+// header and charged with the database gas price.
+const (
+	// GasQuery is the base gas of each query.
+	GasQuery uint64 = 10
+	// GasRowRead is the gas of each row read from the result set of a query.
+	GasRowRead uint64 = 1
+	// GasRowWritten is the gas of each row affected by a write query.
+	GasRowWritten uint64 = 5
+	// GasReturnedBytes is the count of returned bytes charged as a single gas.
+	GasReturnedBytes uint64 = 64
+	// GasVMSteps is the count of sqlite virtual machine instructions charged as a single gas.
+	GasVMSteps uint64 = 1000
+)
+
+// gasMeter measures the gas used by a request, and aborts the execution once the gas limit is
+// exceeded.
+type gasMeter struct {
+	limit uint64 // zero if unlimited
+	used  uint64
+	bytes uint64 // returned bytes not charged yet
+	err   error  // gas limit error raised by the vm step meter
+}
+
+// newGasMeter returns a gas meter of req, the gas limit is the lower one of the request and the
+// state.
+func (s *State) newGasMeter(req *types.Request) *gasMeter {
+	var limit = req.Header.GasLimit
+	if limit == 0 || (s.gasLimit > 0 && s.gasLimit < limit) {
+		limit = s.gasLimit
+	}
+	return &gasMeter{limit: limit}
+}
+
+func (m *gasMeter) consume(gas uint64) (err error) {
+	if m.used += gas; m.limit > 0 && m.used > m.limit {
+		err = errors.Wrapf(ErrGasLimitExceeded, "gas used %d exceeds limit %d", m.used, m.limit)
+	}
+	return
+}
+
+// withSteps returns a copy of ctx which meters the virtual machine instructions of the
+// statements executed with it. A statement exceeding the gas limit is interrupted, and the
+// error is reported by stepped.
+//
+// NOTE: an interrupted write statement rolls back the whole enclosing transaction of sqlite
+// rather than the savepoint of the request, which must be handled by the caller.
+func (m *gasMeter) withSteps(ctx context.Context) context.Context {
+	return sqlite3.WithProgressHandler(ctx, int(GasVMSteps), func() int {
+		if err := m.consume(1); err != nil && m.err == nil {
+			m.err = err
+		}
+		if m.err != nil {
+			return 1
+		}
+		return 0
+	})
+}
+
+// stepped returns the gas limit error raised by the vm step meter if any, or err otherwise.
+func (m *gasMeter) stepped(err error) error {
+	if m.err != nil {
+		return m.err
+	}
+	return err
+}
+
+func (m *gasMeter) query() error {
+	return m.consume(GasQuery)
+}
+
+func (m *gasMeter) written(rows int64) error {
+	if rows < 0 {
+		rows = 0
+	}
+	return m.consume(uint64(rows) * GasRowWritten)
+}
+
+func (m *gasMeter) read(row []interface{}) error {
+	for _, v := range row {
+		m.bytes += valueSize(v)
+	}
+	var gas = GasRowRead + m.bytes/GasReturnedBytes
+	m.bytes %= GasReturnedBytes
+	return m.consume(gas)
+}
+
+// valueSize returns the size in bytes of a value scanned from the storage.
+func valueSize(v interface{}) uint64 {
+	switch v := v.(type) {
+	case []byte:
+		return uint64(len(v))
+	case string:
+		return uint64(len(v))
+	case int64, float64, time.Time:
+		return 8
+	case bool:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -28,3 +28,11 @@ type Storage interface {
 	Writer() *sql.DB
 	Close() error
 }
+
+// BackupStorage is the interface implemented by a Storage which can copy its content to a
+// standalone file and replace its content with such a file.
+type BackupStorage interface {
+	Storage
+	Backup(file string) error
+	Restore(file string) error
+}
@@ -0,0 +1,426 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"strings"
+
+	"github.com/CovenantSQL/sqlparser"
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+// QueryAccess returns the tables and columns accessed by the query pattern, which is used to
+// enforce the table privileges of users.
+//
+// The column names are not resolved against the database schema, so an unqualified column is
+// regarded as accessed in every table of its scope and the enclosing scopes, and a double-quoted
+// string, which is taken as an identifier by sqlite if such column exists, is regarded as a column
+// accessed in every table of the statement. Reading columns in where clauses or expressions of
+// insert/update/delete statements also requires the select privilege on these columns.
+func QueryAccess(pattern string) (access []*types.TableAccess, err error) {
+	if isTransactionControl(pattern) {
+		return
+	}
+	var (
+		tokenizer  = sqlparser.NewStringTokenizer(pattern)
+		queryParts []string
+		statements []sqlparser.Statement
+	)
+	if queryParts, statements, err = sqlparser.ParseMultiple(tokenizer); err != nil {
+		err = errors.Wrap(err, "parse sql failed")
+		return
+	}
+	var c = &accessCollector{}
+	for i := range statements {
+		var begin = len(c.access)
+		if err = c.statement(statements[i]); err != nil {
+			return
+		}
+		for _, name := range quotedNames(queryParts[i]) {
+			for _, a := range c.access[begin:] {
+				c.add(a.Table, types.TableSelect, name)
+			}
+		}
+	}
+	access = c.access
+	return
+}
+
+// accessScope defines the tables visible to the column references of a select statement.
+type accessScope struct {
+	parent *accessScope
+	// tables maps the aliases or names of the tables to the underlying table names, the derived
+	// tables of sub-queries are mapped to empty names.
+	tables  map[string]string
+	names   []string
+	aliases map[string]bool
+}
+
+func newAccessScope(parent *accessScope) *accessScope {
+	return &accessScope{
+		parent:  parent,
+		tables:  make(map[string]string),
+		aliases: make(map[string]bool),
+	}
+}
+
+func (s *accessScope) register(name, table string) {
+	if _, ok := s.tables[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.tables[name] = table
+}
+
+// baseTables returns the underlying tables visible in scope.
+func (s *accessScope) baseTables() (tables []string) {
+	for _, name := range s.names {
+		if table := s.tables[name]; table != "" {
+			tables = append(tables, table)
+		}
+	}
+	return
+}
+
+type accessCollector struct {
+	access []*types.TableAccess
+}
+
+func (c *accessCollector) find(table string, action types.TableAction) *types.TableAccess {
+	for _, a := range c.access {
+		if a.Table == table && a.Action == action {
+			return a
+		}
+	}
+	a := &types.TableAccess{
+		Table:  table,
+		Action: action,
+	}
+	c.access = append(c.access, a)
+	return a
+}
+
+// add records the access of the column in table, an empty column records the table access only,
+// and a "*" column records the access of all columns.
+func (c *accessCollector) add(table string, action types.TableAction, column string) {
+	var a = c.find(table, action)
+	switch column {
+	case "":
+	case "*":
+		a.AllColumns = true
+	default:
+		for _, v := range a.Columns {
+			if v == column {
+				return
+			}
+		}
+		a.Columns = append(a.Columns, column)
+	}
+}
+
+func (c *accessCollector) statement(stmt sqlparser.Statement) (err error) {
+	switch stmt := stmt.(type) {
+	case sqlparser.SelectStatement:
+		return c.selectStatement(stmt, nil)
+	case *sqlparser.Insert:
+		var (
+			table  = lowered(stmt.Table.Name)
+			scope  = newAccessScope(nil)
+			action = types.TableInsert
+		)
+		if stmt.Action == sqlparser.ReplaceStr {
+			// the conflicting rows are deleted before insert
+			action |= types.TableDelete
+		}
+		if len(stmt.Columns) == 0 {
+			c.add(table, action, "*")
+		}
+		for _, col := range stmt.Columns {
+			c.add(table, action, col.Lowered())
+		}
+		switch rows := stmt.Rows.(type) {
+		case sqlparser.SelectStatement:
+			if err = c.selectStatement(rows, nil); err != nil {
+				return
+			}
+		default:
+			if err = c.expr(scope, rows); err != nil {
+				return
+			}
+		}
+		if len(stmt.OnDup) > 0 {
+			scope.register(table, table)
+			if err = c.updateExprs(scope, sqlparser.UpdateExprs(stmt.OnDup)); err != nil {
+				return
+			}
+		}
+	case *sqlparser.Update:
+		var scope = newAccessScope(nil)
+		if err = c.tableExprs(scope, stmt.TableExprs, types.TableUpdate); err != nil {
+			return
+		}
+		if err = c.updateExprs(scope, stmt.Exprs); err != nil {
+			return
+		}
+		return c.expr(scope, stmt.Where, stmt.OrderBy, stmt.Limit)
+	case *sqlparser.Delete:
+		var (
+			scope  = newAccessScope(nil)
+			action = types.TableDelete
+		)
+		if len(stmt.Targets) > 0 {
+			// multiple-table syntax, the tables in from clause are only read
+			action = types.TableSelect
+			for _, t := range stmt.Targets {
+				c.add(lowered(t.Name), types.TableDelete, "")
+			}
+		}
+		if err = c.tableExprs(scope, stmt.TableExprs, action); err != nil {
+			return
+		}
+		return c.expr(scope, stmt.Where, stmt.OrderBy, stmt.Limit)
+	case *sqlparser.Show:
+		switch stmt.Type {
+		case "tables":
+		case "table", "index":
+			c.add(lowered(stmt.OnTable.Name), types.TableSelect, "")
+		default:
+			return errors.Wrapf(ErrUnknownTableAccess, "show %s", stmt.Type)
+		}
+	default:
+		return errors.Wrapf(ErrUnknownTableAccess, "%T", stmt)
+	}
+	return
+}
+
+func (c *accessCollector) selectStatement(stmt sqlparser.SelectStatement, parent *accessScope) (err error) {
+	switch stmt := stmt.(type) {
+	case *sqlparser.Select:
+		var scope = newAccessScope(parent)
+		if err = c.tableExprs(scope, stmt.From, types.TableSelect); err != nil {
+			return
+		}
+		for _, e := range stmt.SelectExprs {
+			if ae, ok := e.(*sqlparser.AliasedExpr); ok && !ae.As.IsEmpty() {
+				scope.aliases[ae.As.Lowered()] = true
+			}
+		}
+		if err = c.expr(scope, stmt.SelectExprs, stmt.Where, stmt.GroupBy, stmt.Having,
+			stmt.Limit); err != nil {
+			return
+		}
+		for _, o := range stmt.OrderBy {
+			// result column aliases take precedence in order by clause
+			if col, ok := o.Expr.(*sqlparser.ColName); ok && col.Qualifier.IsEmpty() &&
+				scope.aliases[col.Name.Lowered()] {
+				continue
+			}
+			if err = c.expr(scope, o); err != nil {
+				return
+			}
+		}
+	case *sqlparser.Union:
+		if err = c.selectStatement(stmt.Left, parent); err != nil {
+			return
+		}
+		return c.selectStatement(stmt.Right, parent)
+	case *sqlparser.ParenSelect:
+		return c.selectStatement(stmt.Select, parent)
+	default:
+		return errors.Wrapf(ErrUnknownTableAccess, "%T", stmt)
+	}
+	return
+}
+
+// tableExprs registers the tables of the from clause in scope with the table access action, and
+// records the columns accessed by the join conditions.
+func (c *accessCollector) tableExprs(scope *accessScope, exprs sqlparser.TableExprs, action types.TableAction) (err error) {
+	var conds []sqlparser.SQLNode
+	if err = c.registerTables(scope, exprs, action, &conds); err != nil {
+		return
+	}
+	return c.expr(scope, conds...)
+}
+
+func (c *accessCollector) registerTables(
+	scope *accessScope, exprs sqlparser.TableExprs, action types.TableAction, conds *[]sqlparser.SQLNode,
+) (err error) {
+	for _, expr := range exprs {
+		switch expr := expr.(type) {
+		case *sqlparser.AliasedTableExpr:
+			switch t := expr.Expr.(type) {
+			case sqlparser.TableName:
+				var table = lowered(t.Name)
+				c.add(table, action, "")
+				if expr.As.IsEmpty() {
+					scope.register(table, table)
+				} else {
+					scope.register(lowered(expr.As), table)
+				}
+			case *sqlparser.Subquery:
+				// derived table can not refer to the tables of the enclosing scopes
+				if err = c.selectStatement(t.Select, nil); err != nil {
+					return
+				}
+				scope.register(lowered(expr.As), "")
+			default:
+				return errors.Wrapf(ErrUnknownTableAccess, "%T", t)
+			}
+		case *sqlparser.ParenTableExpr:
+			if err = c.registerTables(scope, expr.Exprs, action, conds); err != nil {
+				return
+			}
+		case *sqlparser.JoinTableExpr:
+			if err = c.registerTables(scope, sqlparser.TableExprs{expr.LeftExpr, expr.RightExpr},
+				action, conds); err != nil {
+				return
+			}
+			switch expr.Join {
+			case sqlparser.NaturalJoinStr, sqlparser.NaturalLeftJoinStr:
+				// natural join compares all the common columns
+				for _, table := range scope.baseTables() {
+					c.add(table, types.TableSelect, "*")
+				}
+			}
+			*conds = append(*conds, expr.Condition.On)
+			for _, col := range expr.Condition.Using {
+				*conds = append(*conds, &sqlparser.ColName{Name: col})
+			}
+		default:
+			return errors.Wrapf(ErrUnknownTableAccess, "%T", expr)
+		}
+	}
+	return
+}
+
+func (c *accessCollector) updateExprs(scope *accessScope, exprs sqlparser.UpdateExprs) (err error) {
+	for _, e := range exprs {
+		c.column(scope, e.Name, types.TableUpdate)
+		if err = c.expr(scope, e.Expr); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// expr records the columns read by the expressions.
+func (c *accessCollector) expr(scope *accessScope, nodes ...sqlparser.SQLNode) error {
+	return sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+		switch n := node.(type) {
+		case *sqlparser.ColName:
+			c.column(scope, n, types.TableSelect)
+			return false, nil
+		case *sqlparser.StarExpr:
+			if n.TableName.IsEmpty() {
+				for _, table := range scope.baseTables() {
+					c.add(table, types.TableSelect, "*")
+				}
+			} else if table, ok := scope.resolve(lowered(n.TableName.Name)); !ok {
+				c.add(lowered(n.TableName.Name), types.TableSelect, "*")
+			} else if table != "" {
+				c.add(table, types.TableSelect, "*")
+			}
+			return false, nil
+		case *sqlparser.FuncExpr:
+			// skip the star argument of aggregate functions like count(*)
+			for _, e := range n.Exprs {
+				if star, ok := e.(*sqlparser.StarExpr); ok && star.TableName.IsEmpty() {
+					continue
+				}
+				if err = c.expr(scope, e); err != nil {
+					return
+				}
+			}
+			return false, nil
+		case *sqlparser.Subquery:
+			return false, c.selectStatement(n.Select, scope)
+		}
+		return true, nil
+	}, nodes...)
+}
+
+// column records the column access, see QueryAccess for the resolution of unqualified columns.
+func (c *accessCollector) column(scope *accessScope, col *sqlparser.ColName, action types.TableAction) {
+	var name = col.Name.Lowered()
+	if !col.Qualifier.IsEmpty() {
+		var qualifier = lowered(col.Qualifier.Name)
+		if table, ok := scope.resolve(qualifier); !ok {
+			c.add(qualifier, action, name)
+		} else if table != "" {
+			c.add(table, action, name)
+		}
+		return
+	}
+	for s := scope; s != nil; s = s.parent {
+		for _, table := range s.baseTables() {
+			c.add(table, action, name)
+		}
+	}
+}
+
+// resolve returns the table name of the alias or name visible in scope.
+func (s *accessScope) resolve(name string) (table string, ok bool) {
+	for ; s != nil; s = s.parent {
+		if table, ok = s.tables[name]; ok {
+			return
+		}
+	}
+	return
+}
+
+// isTransactionControl reports whether pattern is a single transaction control statement.
+func isTransactionControl(pattern string) bool {
+	switch strings.ToLower(strings.TrimRight(strings.TrimSpace(pattern), "; \t\r\n")) {
+	case "begin", "begin transaction", "commit", "commit transaction", "end", "end transaction",
+		"rollback", "rollback transaction":
+		return true
+	}
+	return false
+}
+
+func lowered(ident sqlparser.TableIdent) string {
+	return strings.ToLower(ident.String())
+}
+
+// quotedNames returns the double-quoted strings in query, which may be identifiers in sqlite.
+func quotedNames(query string) (names []string) {
+	for i := 0; i < len(query); {
+		var c, j = query[i], i + 1
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			j = scanQuoted(query, i)
+			if c == '"' && j-i >= 2 {
+				names = append(names, strings.ToLower(strings.Replace(query[i+1:j-1], `""`, `"`, -1)))
+			}
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if j = strings.IndexByte(query[i:], '\n'); j < 0 {
+				j = len(query)
+			} else {
+				j += i + 1
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if j = strings.Index(query[i+2:], "*/"); j < 0 {
+				j = len(query)
+			} else {
+				j += i + 4
+			}
+		}
+		i = j
+	}
+	return
+}
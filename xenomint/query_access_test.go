@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+func TestQueryAccess(t *testing.T) {
+	Convey("Given a set of queries", t, func() {
+		var cases = []struct {
+			query  string
+			access []*types.TableAccess
+		}{
+			{
+				query: `SELECT a, t.b FROM T WHERE c = 1 ORDER BY a`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect, Columns: []string{"a", "b", "c"}},
+				},
+			}, {
+				query: `SELECT count(*) AS n FROM t ORDER BY n`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect},
+				},
+			}, {
+				query: `SELECT x.*, y.id FROM t x JOIN u y ON x.uid = y.id`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect, Columns: []string{"uid"}, AllColumns: true},
+					{Table: "u", Action: types.TableSelect, Columns: []string{"id"}},
+				},
+			}, {
+				query: `SELECT (SELECT name FROM u LIMIT 1) FROM t`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect, Columns: []string{"name"}},
+					{Table: "u", Action: types.TableSelect, Columns: []string{"name"}},
+				},
+			}, {
+				query: `SELECT s.x FROM (SELECT a AS x FROM t) s`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect, Columns: []string{"a"}},
+				},
+			}, {
+				query: `SELECT a FROM t WHERE b = "c"`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect, Columns: []string{"a", "b", "c"}},
+				},
+			}, {
+				query: `INSERT INTO t (a, b) SELECT c, d FROM u`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableInsert, Columns: []string{"a", "b"}},
+					{Table: "u", Action: types.TableSelect, Columns: []string{"c", "d"}},
+				},
+			}, {
+				query: `REPLACE INTO t VALUES (1)`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableInsert | types.TableDelete, AllColumns: true},
+				},
+			}, {
+				query: `UPDATE t SET a = b + 1 WHERE id = 1`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableUpdate, Columns: []string{"a"}},
+					{Table: "t", Action: types.TableSelect, Columns: []string{"b", "id"}},
+				},
+			}, {
+				query: `DELETE FROM t WHERE id IN (SELECT tid FROM u)`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableDelete},
+					{Table: "t", Action: types.TableSelect, Columns: []string{"id", "tid"}},
+					{Table: "u", Action: types.TableSelect, Columns: []string{"tid"}},
+				},
+			}, {
+				query: `SHOW CREATE TABLE t; SHOW TABLES`,
+				access: []*types.TableAccess{
+					{Table: "t", Action: types.TableSelect},
+				},
+			}, {
+				query: `BEGIN`,
+			},
+		}
+		for _, c := range cases {
+			access, err := QueryAccess(c.query)
+			So(err, ShouldBeNil)
+			So(access, ShouldResemble, c.access)
+		}
+	})
+	Convey("Given a set of queries with unknown table access", t, func() {
+		for _, q := range []string{
+			`CREATE TABLE t (a INT)`,
+			`DROP TABLE t`,
+			`SELECT [a] FROM t`,
+			`WITH x AS (SELECT a FROM t) SELECT * FROM x`,
+		} {
+			_, err := QueryAccess(q)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -17,7 +17,6 @@
 package xenomint
 
 import (
-	"bytes"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -27,9 +26,16 @@ import (
 
 	"github.com/CovenantSQL/CovenantSQL/types"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
+	xs "github.com/CovenantSQL/CovenantSQL/xenomint/sqlite"
 )
 
 var (
+	// sanitizeFunctionMap defines the functions rejected by the sanitizer, or the arguments
+	// rejected if the value is not nil.
+	//
+	// NOTE: random() and randomblob() are overridden by the storage with a random source seeded
+	// by request, and the 'now' time value is substituted by the request timestamp, see
+	// substituteTimeValues.
 	sanitizeFunctionMap = map[string]map[string]bool{
 		"load_extension": nil,
 		"unlikely":       nil,
@@ -37,27 +43,20 @@ var (
 		"likely":         nil,
 		"affinity":       nil,
 		"typeof":         nil,
-		"random":         nil,
-		"randomblob":     nil,
 		"unknown":        nil,
 		"date": {
-			"now":       true,
 			"localtime": true,
 		},
 		"time": {
-			"now":       true,
 			"localtime": true,
 		},
 		"datetime": {
-			"now":       true,
 			"localtime": true,
 		},
 		"julianday": {
-			"now":       true,
 			"localtime": true,
 		},
 		"strftime": {
-			"now":       true,
 			"localtime": true,
 		},
 
@@ -72,6 +71,23 @@ var (
 		//"sqlite_rename_parent":      nil,
 		//"sqlite_record":             nil,
 	}
+
+	// timeFunctions defines the date and time functions accepting the 'now' time value.
+	timeFunctions = map[string]bool{
+		"date":      true,
+		"time":      true,
+		"datetime":  true,
+		"julianday": true,
+		"strftime":  true,
+	}
+
+	// timeKeywords defines the substitutes of the current time keywords, which are also used as
+	// column default values.
+	timeKeywords = map[string]string{
+		"current_timestamp": "(datetime(" + xs.NowFunc + "()))",
+		"current_date":      "(date(" + xs.NowFunc + "()))",
+		"current_time":      "(time(" + xs.NowFunc + "()))",
+	}
 )
 
 func convertQueryAndBuildArgs(pattern string, args []types.NamedArg) (containsDDL bool, p string, ifs []interface{}, err error) {
@@ -152,20 +168,9 @@ WHERE type = "index" AND tbl_name = "%s"
 			}
 		}
 
-		// scan query and test if there is any stateful query logic like time expression or random function
+		// scan query and test if there is any stateful query logic like local time modifier
 		err = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
 			switch n := node.(type) {
-			case *sqlparser.SQLVal:
-				if n.Type == sqlparser.ValArg && bytes.EqualFold([]byte("CURRENT_TIMESTAMP"), n.Val) {
-					// current_timestamp literal in default expression
-					err = errors.Wrap(ErrStatefulQueryParts, "DEFAULT CURRENT_TIMESTAMP not supported")
-					return
-				}
-			case *sqlparser.TimeExpr:
-				tb := sqlparser.NewTrackedBuffer(nil)
-				err = errors.Wrapf(ErrStatefulQueryParts, "time expression %s not supported",
-					tb.WriteNode(n).String())
-				return
 			case *sqlparser.FuncExpr:
 				if strings.HasPrefix(n.Name.Lowered(), "sqlite") {
 					tb := sqlparser.NewTrackedBuffer(nil)
@@ -196,7 +201,7 @@ WHERE type = "index" AND tbl_name = "%s"
 							}
 						}
 						return true, nil
-					})
+					}, n.Exprs)
 
 					return
 				}
@@ -207,6 +212,8 @@ WHERE type = "index" AND tbl_name = "%s"
 			err = errors.Wrap(err, "parse sql failed")
 			return
 		}
+
+		queryParts[i] = substituteTimeValues(queryParts[i])
 	}
 
 	p = strings.Join(queryParts, "; ")
@@ -220,3 +227,98 @@ WHERE type = "index" AND tbl_name = "%s"
 	}
 	return
 }
+
+// substituteTimeValues replaces the current time keywords and the 'now' time value arguments of
+// the date and time functions in query with the request timestamp bound to the connection. The
+// query is scanned lexically, so that the original text is kept for the other parts.
+func substituteTimeValues(query string) string {
+	var (
+		buf      strings.Builder
+		frames   []bool // whether each open parenthesis starts the arguments of a time function
+		lastWord string
+		i        int
+	)
+	buf.Grow(len(query))
+	for i < len(query) {
+		var c, j = query[i], i + 1
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			j = scanQuoted(query, i)
+			if tok := query[i:j]; (c == '\'' || c == '"') && len(frames) > 0 &&
+				frames[len(frames)-1] && strings.EqualFold(tok, string(c)+"now"+string(c)) {
+				buf.WriteString(xs.NowFunc + "()")
+			} else {
+				buf.WriteString(tok)
+			}
+			lastWord = ""
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if j = strings.IndexByte(query[i:], '\n'); j < 0 {
+				j = len(query)
+			} else {
+				j += i + 1
+			}
+			buf.WriteString(query[i:j])
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if j = strings.Index(query[i+2:], "*/"); j < 0 {
+				j = len(query)
+			} else {
+				j += i + 4
+			}
+			buf.WriteString(query[i:j])
+		case isWordChar(c):
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			var word = strings.ToLower(query[i:j])
+			if sub, ok := timeKeywords[word]; ok && (i == 0 || query[i-1] != '.') {
+				buf.WriteString(sub)
+			} else {
+				buf.WriteString(query[i:j])
+			}
+			lastWord = word
+		case c == '(':
+			frames = append(frames, timeFunctions[lastWord])
+			buf.WriteByte(c)
+			lastWord = ""
+		case c == ')':
+			if len(frames) > 0 {
+				frames = frames[:len(frames)-1]
+			}
+			buf.WriteByte(c)
+			lastWord = ""
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			// keep last word for the function call like "datetime ('now')"
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+			lastWord = ""
+		}
+		i = j
+	}
+	return buf.String()
+}
+
+// scanQuoted returns the end offset of the quoted string or identifier starting at offset i.
+func scanQuoted(query string, i int) int {
+	var quote = query[i]
+	if quote == '[' {
+		quote = ']'
+	}
+	for j := i + 1; j < len(query); j++ {
+		if query[j] != quote {
+			continue
+		}
+		if quote != ']' && j+1 < len(query) && query[j+1] == quote {
+			// escaped quote
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(query)
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,528 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/CovenantSQL/sqlparser"
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/types"
+)
+
+const (
+	// CallerArgName is the name of the argument bound to the account address of the caller in
+	// the queries rewritten by the row-level security policies.
+	CallerArgName = "cql_caller"
+
+	// reservedArgPrefix is the name prefix of the arguments bound by the rewritten queries, the
+	// parameters of the original query are renamed to reservedArgPrefix + "p" + index.
+	reservedArgPrefix = "cql_"
+)
+
+// RowPolicyFunc returns the row-level security policies applied to the queries of user, or nil
+// if the user is not restricted.
+type RowPolicyFunc func(user proto.AccountAddress) []*types.RowPolicy
+
+// SetRowPolicies sets the row-level security policies of the state, the queries of a restricted
+// user are rewritten to only access the rows owned by the user.
+func (s *State) SetRowPolicies(f RowPolicyFunc) {
+	s.rowPolicies = f
+}
+
+// rowPolicyQueries returns the queries of req rewritten by the row-level security policies of
+// its signee and the policies applied, or the queries as is if the signee is not restricted.
+//
+// NOTE: the policies are looked up locally, the followers lagging behind the block producers may
+// apply a stale set of policies to the replicated requests. The replays of the responses apply
+// the policies pinned in the response headers instead, see replayQueries.
+func (s *State) rowPolicyQueries(req *types.Request) (
+	queries []types.Query, policies []*types.RowPolicy, err error,
+) {
+	queries = req.Payload.Queries
+	if s.rowPolicies == nil {
+		return
+	}
+	var caller proto.AccountAddress
+	if caller, err = requestCaller(req); err != nil {
+		return
+	}
+	if policies = s.rowPolicies(caller); len(policies) == 0 {
+		return
+	}
+	queries, err = applyRequestPolicies(req, caller, policies)
+	return
+}
+
+// replayQueries returns the queries of req rewritten by the row-level security policies applied
+// by the node which built resp. The policies of a legacy response are not pinned and looked up
+// locally.
+func (s *State) replayQueries(req *types.Request, resp *types.ResponseHeader) (
+	queries []types.Query, err error,
+) {
+	if resp.Version == 0 {
+		queries, _, err = s.rowPolicyQueries(req)
+		return
+	}
+	queries = req.Payload.Queries
+	if len(resp.RowPolicies) == 0 {
+		return
+	}
+	var caller proto.AccountAddress
+	if caller, err = requestCaller(req); err != nil {
+		return
+	}
+	queries, err = applyRequestPolicies(req, caller, resp.RowPolicies)
+	return
+}
+
+func requestCaller(req *types.Request) (caller proto.AccountAddress, err error) {
+	if req.Header.Signee == nil {
+		err = errors.Wrap(ErrRowPolicyViolation, "unknown caller of request")
+		return
+	}
+	return crypto.PubKeyHash(req.Header.Signee)
+}
+
+// applyRequestPolicies rewrites the queries of req of the caller restricted by policies. The
+// request itself is never modified, as its signature is verified again by the peers.
+func applyRequestPolicies(
+	req *types.Request, caller proto.AccountAddress, policies []*types.RowPolicy,
+) (
+	queries []types.Query, err error,
+) {
+	queries = make([]types.Query, len(req.Payload.Queries))
+	for i := range req.Payload.Queries {
+		if queries[i], err = applyRowPolicies(
+			&req.Payload.Queries[i], caller, policies,
+		); err != nil {
+			err = errors.Wrapf(err, "query at #%d failed", i)
+			return
+		}
+	}
+	return
+}
+
+// applyRowPolicies rewrites q of the caller restricted by policies. Every policy table read by
+// the query is replaced by a subquery selecting the rows owned by the caller, and the rows of a
+// policy table updated or deleted are filtered by the same predicate. Rows inserted into a policy
+// table, or updated to a new owner, must be owned by the caller.
+//
+// The query is rewritten on its syntax tree and formatted again, with the parameters renamed and
+// the caller address bound as CallerArgName, so that sqlite executes exactly the statement
+// checked here. Thus a query of a restricted user must hold a single statement parsed by the
+// sanitizer, and must not use double-quoted strings, which may be taken as identifiers by sqlite.
+// The policies only apply to the tables named, views and triggers reading a policy table are not
+// rewritten and need their own policies or privileges.
+func applyRowPolicies(
+	q *types.Query, caller proto.AccountAddress, policies []*types.RowPolicy,
+) (
+	rq types.Query, err error,
+) {
+	rq = *q
+	if isTransactionControl(q.Pattern) {
+		return
+	}
+	var (
+		tokenizer  = sqlparser.NewStringTokenizer(q.Pattern)
+		statements []sqlparser.Statement
+	)
+	tokenizer.SeparatePositionalArgs = true
+	if _, statements, err = sqlparser.ParseMultiple(tokenizer); err != nil {
+		err = errors.Wrapf(ErrRowPolicyViolation, "parse sql failed: %v", err)
+		return
+	}
+	if len(statements) != 1 {
+		err = errors.Wrapf(ErrRowPolicyViolation, "%d statements in query", len(statements))
+		return
+	}
+	if names := quotedNames(q.Pattern); len(names) > 0 {
+		err = errors.Wrapf(ErrRowPolicyViolation, "double-quoted string \"%s\" is ambiguous", names[0])
+		return
+	}
+	var (
+		stmt = statements[0]
+		r    = &rowPolicyRewriter{
+			caller:   caller.String(),
+			columns:  make(map[string]string, len(policies)),
+			args:     q.Args,
+			paramIdx: make(map[string]int),
+		}
+		rewritten bool
+	)
+	for _, p := range policies {
+		r.columns[strings.ToLower(p.Table)] = p.Column
+	}
+	if err = r.indexParams(q.Pattern); err != nil {
+		return
+	}
+	if err = r.check(stmt); err != nil {
+		return
+	}
+	if rq.Args, err = r.bindArgs(stmt); err != nil {
+		return
+	}
+	if rewritten, err = r.rewrite(stmt); err != nil {
+		return
+	}
+	if rewritten {
+		// the caller argument goes first, the driver binds at most as many arguments as the
+		// parameters of the statement
+		rq.Args = append([]types.NamedArg{{Name: CallerArgName, Value: r.caller}}, rq.Args...)
+	}
+	rq.Pattern = sqlparser.NewTrackedBuffer(formatRowPolicyNode).WriteNode(stmt).String()
+	return
+}
+
+// formatRowPolicyNode formats the string literals in sqlite syntax, in which the backslash is not
+// an escape character. The comments are dropped, as they may be taken as part of the statement by
+// sqlite, e.g. the mysql-style comments starting with #.
+func formatRowPolicyNode(buf *sqlparser.TrackedBuffer, node sqlparser.SQLNode) {
+	switch n := node.(type) {
+	case sqlparser.Comments:
+		return
+	case *sqlparser.SQLVal:
+		if n.Type == sqlparser.StrVal {
+			buf.WriteString("'" + strings.Replace(string(n.Val), "'", "''", -1) + "'")
+			return
+		}
+	}
+	node.Format(buf)
+}
+
+type rowPolicyRewriter struct {
+	caller  string
+	columns map[string]string // owner columns keyed by the lowered table names
+	args    []types.NamedArg
+
+	paramIdx  map[string]int // indexes of the named parameters
+	positions []int          // indexes of the positional parameters in order
+	order     []int          // indexes of the parameters in order of appearance
+	maxIdx    int
+}
+
+// indexParams indexes the parameters of query as sqlite does, the positional parameters are
+// scanned as :v1, :v2, ... by the tokenizer.
+func (r *rowPolicyRewriter) indexParams(query string) (err error) {
+	var tokenizer = sqlparser.NewStringTokenizer(query)
+	tokenizer.SeparatePositionalArgs = true
+	for {
+		var typ, val = tokenizer.Scan()
+		var idx int
+		switch typ {
+		case 0:
+			return
+		case sqlparser.POS_ARG:
+			idx = r.maxIdx + 1
+			r.positions = append(r.positions, idx)
+		case sqlparser.VALUE_ARG:
+			var ok bool
+			if idx, ok = r.paramIdx[string(val)]; !ok {
+				idx = r.maxIdx + 1
+				r.paramIdx[string(val)] = idx
+			}
+		default:
+			continue
+		}
+		if idx > r.maxIdx {
+			r.maxIdx = idx
+			r.order = append(r.order, idx)
+		}
+	}
+}
+
+// paramIndex returns the index of the parameter v.
+func (r *rowPolicyRewriter) paramIndex(v *sqlparser.SQLVal) (idx int, ok bool) {
+	if v.Type == sqlparser.PosArg {
+		var n, err = strconv.Atoi(strings.TrimPrefix(string(v.Val), ":v"))
+		if err != nil || n <= 0 || n > len(r.positions) {
+			return
+		}
+		return r.positions[n-1], true
+	}
+	idx, ok = r.paramIdx[string(v.Val)]
+	return
+}
+
+// check checks the statement type and the owner values written by stmt.
+func (r *rowPolicyRewriter) check(stmt sqlparser.Statement) (err error) {
+	switch stmt := stmt.(type) {
+	case sqlparser.SelectStatement, *sqlparser.Delete, *sqlparser.Show:
+	case *sqlparser.DDL:
+		return errors.Wrap(ErrRowPolicyViolation, "schema change not allowed")
+	case *sqlparser.Insert:
+		var col, ok = r.columns[lowered(stmt.Table.Name)]
+		if !ok {
+			return
+		}
+		if stmt.Action == sqlparser.ReplaceStr || len(stmt.OnDup) > 0 {
+			return errors.Wrapf(ErrRowPolicyViolation,
+				"replacing rows of table %s not allowed", stmt.Table.Name.String())
+		}
+		var rows, isValues = stmt.Rows.(sqlparser.Values)
+		if !isValues {
+			return errors.Wrapf(ErrRowPolicyViolation,
+				"inserting rows of table %s must use values", stmt.Table.Name.String())
+		}
+		var pos = -1
+		for i, c := range stmt.Columns {
+			if strings.EqualFold(c.String(), col) {
+				pos = i
+			}
+		}
+		if pos < 0 {
+			return errors.Wrapf(ErrRowPolicyViolation,
+				"column %s of table %s not specified", col, stmt.Table.Name.String())
+		}
+		for _, row := range rows {
+			if pos >= len(row) || !r.isCaller(row[pos]) {
+				return errors.Wrapf(ErrRowPolicyViolation,
+					"inserting row of table %s not owned by caller", stmt.Table.Name.String())
+			}
+		}
+	case *sqlparser.Update:
+		for _, te := range stmt.TableExprs {
+			var ate, ok = te.(*sqlparser.AliasedTableExpr)
+			if !ok {
+				continue
+			}
+			var tn, isName = ate.Expr.(sqlparser.TableName)
+			if !isName {
+				continue
+			}
+			var col, hasPolicy = r.columns[lowered(tn.Name)]
+			if !hasPolicy {
+				continue
+			}
+			for _, ue := range stmt.Exprs {
+				if strings.EqualFold(ue.Name.Name.String(), col) && !r.isCaller(ue.Expr) {
+					return errors.Wrapf(ErrRowPolicyViolation,
+						"updating row of table %s to owner other than caller", tn.Name.String())
+				}
+			}
+		}
+	default:
+		return errors.Wrapf(ErrRowPolicyViolation, "%T statement not allowed", stmt)
+	}
+	return
+}
+
+// isCaller reports whether expr is a literal or an argument of the caller address.
+func (r *rowPolicyRewriter) isCaller(expr sqlparser.Expr) bool {
+	for {
+		var pe, ok = expr.(*sqlparser.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = pe.Expr
+	}
+	var v, ok = expr.(*sqlparser.SQLVal)
+	if !ok {
+		return false
+	}
+	switch v.Type {
+	case sqlparser.StrVal:
+		return string(v.Val) == r.caller
+	case sqlparser.ValArg, sqlparser.PosArg:
+		var idx, ok = r.paramIndex(v)
+		if !ok {
+			return false
+		}
+		switch value := r.argValues()[idx].(type) {
+		case string:
+			return value == r.caller
+		case []byte:
+			return string(value) == r.caller
+		}
+	}
+	return false
+}
+
+// argValues returns the argument values keyed by parameter indexes, following the binding
+// order of the driver: the arguments are bound by position and then by name.
+func (r *rowPolicyRewriter) argValues() (values map[int]interface{}) {
+	values = make(map[int]interface{})
+	for i, v := range r.args {
+		if v.Name == "" {
+			values[i+1] = v.Value
+		} else if idx, ok := r.paramIdx[":"+v.Name]; ok {
+			values[idx] = v.Value
+		}
+	}
+	return
+}
+
+// bindArgs renames the parameters of stmt to reservedArgPrefix + "p" + index, and returns the
+// arguments bound by the new names.
+func (r *rowPolicyRewriter) bindArgs(stmt sqlparser.Statement) (args []types.NamedArg, err error) {
+	for _, v := range r.args {
+		if strings.HasPrefix(strings.ToLower(v.Name), reservedArgPrefix) {
+			err = errors.Wrapf(ErrRowPolicyViolation, "reserved argument name %s", v.Name)
+			return
+		}
+	}
+	if err = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+		var v, ok = node.(*sqlparser.SQLVal)
+		if !ok || (v.Type != sqlparser.ValArg && v.Type != sqlparser.PosArg) {
+			return true, nil
+		}
+		var idx, indexed = r.paramIndex(v)
+		if !indexed {
+			return false, errors.Wrapf(ErrRowPolicyViolation, "unknown parameter %s", v.Val)
+		}
+		v.Type, v.Val = sqlparser.ValArg, []byte(":"+r.argName(idx))
+		return true, nil
+	}, stmt); err != nil {
+		return
+	}
+	var values = r.argValues()
+	for _, idx := range r.order {
+		if v, ok := values[idx]; ok {
+			args = append(args, types.NamedArg{Name: r.argName(idx), Value: v})
+		}
+	}
+	return
+}
+
+func (r *rowPolicyRewriter) argName(idx int) string {
+	return reservedArgPrefix + "p" + strconv.Itoa(idx)
+}
+
+// rewrite adds the row policy predicates to stmt, and reports whether any policy table is
+// accessed.
+func (r *rowPolicyRewriter) rewrite(stmt sqlparser.Statement) (rewritten bool, err error) {
+	// collect the select statements first, as the subqueries replacing the policy tables are
+	// also select statements
+	var selects []*sqlparser.Select
+	if err = sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
+		if sel, ok := node.(*sqlparser.Select); ok {
+			selects = append(selects, sel)
+		}
+		return true, nil
+	}, stmt); err != nil {
+		return
+	}
+	for _, sel := range selects {
+		if r.replaceTables(sel.From) {
+			rewritten = true
+		}
+	}
+	switch stmt := stmt.(type) {
+	case *sqlparser.Update:
+		if r.filterTargets(stmt.TableExprs, &stmt.Where) {
+			rewritten = true
+		}
+	case *sqlparser.Delete:
+		if len(stmt.Targets) > 0 {
+			err = errors.Wrap(ErrRowPolicyViolation, "multiple-table delete not allowed")
+			return
+		}
+		if r.filterTargets(stmt.TableExprs, &stmt.Where) {
+			rewritten = true
+		}
+	}
+	return
+}
+
+// replaceTables replaces the policy tables in exprs by the subqueries selecting the rows owned by
+// the caller, the aliases of the tables are kept.
+func (r *rowPolicyRewriter) replaceTables(exprs sqlparser.TableExprs) (rewritten bool) {
+	for _, expr := range exprs {
+		switch expr := expr.(type) {
+		case *sqlparser.AliasedTableExpr:
+			var tn, ok = expr.Expr.(sqlparser.TableName)
+			if !ok {
+				continue
+			}
+			var col, hasPolicy = r.columns[lowered(tn.Name)]
+			if !hasPolicy {
+				continue
+			}
+			if expr.As.IsEmpty() {
+				expr.As = tn.Name
+			}
+			expr.Expr = &sqlparser.Subquery{Select: &sqlparser.Select{
+				SelectExprs: sqlparser.SelectExprs{&sqlparser.StarExpr{}},
+				From: sqlparser.TableExprs{
+					&sqlparser.AliasedTableExpr{Expr: tn, Hints: expr.Hints},
+				},
+				Where: sqlparser.NewWhere(sqlparser.WhereStr, r.predicate(sqlparser.TableName{}, col)),
+			}}
+			expr.Hints = nil
+			rewritten = true
+		case *sqlparser.ParenTableExpr:
+			if r.replaceTables(expr.Exprs) {
+				rewritten = true
+			}
+		case *sqlparser.JoinTableExpr:
+			if r.replaceTables(sqlparser.TableExprs{expr.LeftExpr, expr.RightExpr}) {
+				rewritten = true
+			}
+		}
+	}
+	return
+}
+
+// filterTargets adds the row policy predicates of the policy tables updated or deleted to where.
+func (r *rowPolicyRewriter) filterTargets(exprs sqlparser.TableExprs, where **sqlparser.Where) (filtered bool) {
+	for _, expr := range exprs {
+		var ate, ok = expr.(*sqlparser.AliasedTableExpr)
+		if !ok {
+			// the tables joined are only read
+			if r.replaceTables(sqlparser.TableExprs{expr}) {
+				filtered = true
+			}
+			continue
+		}
+		var tn, isName = ate.Expr.(sqlparser.TableName)
+		if !isName {
+			continue
+		}
+		var col, hasPolicy = r.columns[lowered(tn.Name)]
+		if !hasPolicy {
+			continue
+		}
+		var qualifier = tn
+		if !ate.As.IsEmpty() {
+			qualifier = sqlparser.TableName{Name: ate.As}
+		}
+		var pred = r.predicate(qualifier, col)
+		if *where == nil || (*where).Expr == nil {
+			*where = sqlparser.NewWhere(sqlparser.WhereStr, pred)
+		} else {
+			(*where).Expr = &sqlparser.AndExpr{
+				Left:  pred,
+				Right: &sqlparser.ParenExpr{Expr: (*where).Expr},
+			}
+		}
+		filtered = true
+	}
+	return
+}
+
+// predicate returns the predicate of the rows of table owned by the caller.
+func (r *rowPolicyRewriter) predicate(table sqlparser.TableName, col string) sqlparser.Expr {
+	return &sqlparser.ComparisonExpr{
+		Operator: sqlparser.EqualStr,
+		Left:     &sqlparser.ColName{Name: sqlparser.NewColIdent(col), Qualifier: table},
+		Right:    sqlparser.NewValArg([]byte(":" + CallerArgName)),
+	}
+}
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/CovenantSQL/CovenantSQL/crypto"
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/types"
+	xs "github.com/CovenantSQL/CovenantSQL/xenomint/sqlite"
+)
+
+func TestApplyRowPolicies(t *testing.T) {
+	Convey("Given the row policies of a restricted user", t, func() {
+		var (
+			caller    = proto.AccountAddress{0x01}
+			owner     = caller.String()
+			policies  = []*types.RowPolicy{{Table: "orders", Column: "owner"}}
+			callerArg = types.NamedArg{Name: CallerArgName, Value: owner}
+		)
+		Convey("The policy tables read by the query should be filtered", func() {
+			for _, c := range []struct {
+				query    types.Query
+				expected string
+				args     []types.NamedArg
+			}{
+				{
+					query:    buildQuery(`SELECT * FROM orders`),
+					expected: "select * from (select * from orders where owner = :cql_caller) as orders",
+					args:     []types.NamedArg{callerArg},
+				}, {
+					query: buildQuery(
+						`SELECT o.id FROM Orders o JOIN items AS i ON o.id = i.oid WHERE o.v > ?`, 1),
+					expected: "select o.id from (select * from Orders where owner = :cql_caller) as o " +
+						"join items as i on o.id = i.oid where o.v > :cql_p1",
+					args: []types.NamedArg{callerArg, {Name: "cql_p1", Value: 1}},
+				}, {
+					query: buildQuery(
+						`SELECT * FROM items WHERE oid IN (SELECT id FROM main.orders) -- orders`),
+					expected: "select * from items where oid in (select id from " +
+						"(select * from main.orders where owner = :cql_caller) as orders)",
+					args: []types.NamedArg{callerArg},
+				}, {
+					query: buildQuery("SELECT * FROM items, `orders` WHERE items.oid = `orders`.id"),
+					expected: "select * from items, (select * from orders where owner = :cql_caller) " +
+						"as orders where items.oid = orders.id",
+					args: []types.NamedArg{callerArg},
+				}, {
+					query: buildQuery(`UPDATE orders SET v = ? WHERE id = ? OR id = 3`, 1, 2),
+					expected: "update orders set v = :cql_p1 " +
+						"where orders.owner = :cql_caller and (id = :cql_p2 or id = 3)",
+					args: []types.NamedArg{
+						callerArg, {Name: "cql_p1", Value: 1}, {Name: "cql_p2", Value: 2},
+					},
+				}, {
+					query: buildQuery(`UPDATE orders AS o SET v = (SELECT count(*) FROM orders)`),
+					expected: "update orders as o set v = (select count(*) from " +
+						"(select * from orders where owner = :cql_caller) as orders) " +
+						"where o.owner = :cql_caller",
+					args: []types.NamedArg{callerArg},
+				}, {
+					query:    buildQuery(`DELETE FROM orders;`),
+					expected: "delete from orders where orders.owner = :cql_caller",
+					args:     []types.NamedArg{callerArg},
+				}, {
+					query: types.Query{
+						Pattern: `DELETE FROM orders WHERE id = :id LIMIT 1`,
+						Args:    []types.NamedArg{{Name: "id", Value: 1}},
+					},
+					expected: "delete from orders where orders.owner = :cql_caller and " +
+						"(id = :cql_p1) limit 1",
+					args: []types.NamedArg{callerArg, {Name: "cql_p1", Value: 1}},
+				}, {
+					query: buildQuery(`INSERT INTO items SELECT * FROM orders`),
+					expected: "insert into items select * from " +
+						"(select * from orders where owner = :cql_caller) as orders",
+					args: []types.NamedArg{callerArg},
+				}, {
+					// not a comment in sqlite
+					query:    buildQuery("SELECT #a, (SELECT owner FROM orders LIMIT 1)\n- 1"),
+					expected: "select -1",
+				},
+			} {
+				var rq, err = applyRowPolicies(&c.query, caller, policies)
+				So(err, ShouldBeNil)
+				So(rq.Pattern, ShouldEqual, c.expected)
+				So(rq.Args, ShouldResemble, c.args)
+			}
+		})
+		Convey("The query not accessing any policy table should only be reformatted", func() {
+			for _, c := range []struct {
+				query    types.Query
+				expected string
+				args     []types.NamedArg
+			}{
+				{
+					query:    buildQuery(`SELECT * FROM items WHERE id IN (?, :a, ?)`, 1, 2, 3),
+					expected: "select * from items where id in (:cql_p1, :cql_p2, :cql_p3)",
+					args: []types.NamedArg{
+						{Name: "cql_p1", Value: 1}, {Name: "cql_p2", Value: 2}, {Name: "cql_p3", Value: 3},
+					},
+				}, {
+					query:    buildQuery(`SELECT orders, 'a''b\' FROM items`),
+					expected: "select orders, 'a''b\\' from items",
+				}, {
+					query:    buildQuery(`INSERT INTO orders (id, owner) VALUES (1, ?)`, owner),
+					expected: "insert into orders(id, owner) values (1, :cql_p1)",
+					args:     []types.NamedArg{{Name: "cql_p1", Value: owner}},
+				}, {
+					query:    buildQuery(fmt.Sprintf(`INSERT INTO orders (owner, id) VALUES ('%s', 1)`, owner)),
+					expected: fmt.Sprintf("insert into orders(owner, id) values ('%s', 1)", owner),
+				}, {
+					query:    buildQuery(`BEGIN`),
+					expected: "BEGIN",
+					args:     []types.NamedArg{},
+				},
+			} {
+				var rq, err = applyRowPolicies(&c.query, caller, policies)
+				So(err, ShouldBeNil)
+				So(rq.Pattern, ShouldEqual, c.expected)
+				So(rq.Args, ShouldResemble, c.args)
+			}
+		})
+		Convey("The query violating the policies should be rejected", func() {
+			for _, q := range []types.Query{
+				buildQuery(`INSERT INTO orders (id, owner) VALUES (1, ?)`, "other"),
+				buildQuery(`INSERT INTO orders (id, owner) VALUES (1, ?), (2, 'other')`, owner),
+				buildQuery(`INSERT INTO orders (id) VALUES (1)`),
+				buildQuery(`REPLACE INTO orders (id, owner) VALUES (1, ?)`, owner),
+				buildQuery(`INSERT INTO orders SELECT * FROM items`),
+				buildQuery(`UPDATE orders SET owner = v`),
+				buildQuery(`CREATE TABLE t1 (k INT)`),
+				buildQuery(`SELECT 1; SELECT * FROM orders`),
+				buildQuery(`SELECT * FROM [orders]`),
+				buildQuery(`SELECT "owner" FROM items`),
+				buildQuery(fmt.Sprintf(`UPDATE orders SET owner = "%s"`, owner)),
+				{
+					Pattern: `SELECT * FROM orders WHERE id = :cql_caller`,
+					Args:    []types.NamedArg{{Name: CallerArgName, Value: owner}},
+				},
+			} {
+				var _, err = applyRowPolicies(&q, caller, policies)
+				So(errors.Cause(err), ShouldEqual, ErrRowPolicyViolation)
+			}
+		})
+	})
+}
+
+func TestRowPolicyState(t *testing.T) {
+	Convey("Given a state with row policies", t, func() {
+		var (
+			fl     = path.Join(testingDataDir, t.Name())
+			st     *State
+			caller proto.AccountAddress
+			resp   *types.Response
+			err    error
+		)
+		strg, err := xs.NewSqlite(fmt.Sprint("file:", fl))
+		So(err, ShouldBeNil)
+		st = NewState(sql.LevelReadUncommitted, nodeID, strg)
+		Reset(func() {
+			err = st.Close(true)
+			So(err, ShouldBeNil)
+			for _, suffix := range []string{"", "-shm", "-wal"} {
+				err = os.Remove(fl + suffix)
+				So(err == nil || os.IsNotExist(err), ShouldBeTrue)
+			}
+		})
+		caller, err = crypto.PubKeyHash(testingPublicKey)
+		So(err, ShouldBeNil)
+		var initReq = buildRequest(types.WriteQuery, []types.Query{
+			buildQuery(`CREATE TABLE orders (id INT, owner TEXT, v INT)`),
+			buildQuery(`INSERT INTO orders VALUES (1, ?, 10), (2, 'other', 20), (3, ?, 30)`,
+				caller.String(), caller.String()),
+		})
+		_, initResp, err := st.Query(initReq, true)
+		So(err, ShouldBeNil)
+		st.SetRowPolicies(func(user proto.AccountAddress) []*types.RowPolicy {
+			if user == caller {
+				return []*types.RowPolicy{{Table: "orders", Column: "owner"}}
+			}
+			return nil
+		})
+		var query = func(qt types.QueryType, qs ...types.Query) (*types.Response, error) {
+			var req = buildRequest(qt, qs)
+			So(req.Sign(testingPrivateKey), ShouldBeNil)
+			_, resp, err := st.Query(req, true)
+			return resp, err
+		}
+
+		Convey("The unsigned request should be rejected", func() {
+			_, _, err = st.Query(buildRequest(types.ReadQuery, []types.Query{
+				buildQuery(`SELECT * FROM orders`),
+			}), true)
+			So(errors.Cause(err), ShouldEqual, ErrRowPolicyViolation)
+		})
+		Convey("The restricted user should only read and write the owned rows", func() {
+			resp, err = query(types.ReadQuery, buildQuery(`SELECT id FROM orders WHERE v > ?`, 0))
+			So(err, ShouldBeNil)
+			So(resp.Payload.Rows, ShouldHaveLength, 2)
+			So(resp.Payload.Rows[0].Values, ShouldResemble, []interface{}{int64(1)})
+			So(resp.Payload.Rows[1].Values, ShouldResemble, []interface{}{int64(3)})
+
+			resp, err = query(types.WriteQuery, buildQuery(`UPDATE orders SET v = ?`, 0))
+			So(err, ShouldBeNil)
+			So(resp.Header.AffectedRows, ShouldEqual, 2)
+			resp, err = query(types.WriteQuery, buildQuery(`DELETE FROM orders WHERE id = ?`, 2))
+			So(err, ShouldBeNil)
+			So(resp.Header.AffectedRows, ShouldEqual, 0)
+			resp, err = query(types.WriteQuery,
+				buildQuery(`INSERT INTO orders (id, owner, v) VALUES (?, ?, ?)`, 4, caller.String(), 40))
+			So(err, ShouldBeNil)
+			_, err = query(types.WriteQuery,
+				buildQuery(`INSERT INTO orders (id, owner, v) VALUES (5, 'other', 50)`))
+			So(errors.Cause(err), ShouldEqual, ErrRowPolicyViolation)
+
+			st.SetRowPolicies(nil)
+			resp, err = query(types.ReadQuery, buildQuery(`SELECT id, v FROM orders`))
+			So(err, ShouldBeNil)
+			So(resp.Payload.Rows, ShouldHaveLength, 4)
+			So(resp.Payload.Rows[1].Values, ShouldResemble, []interface{}{int64(2), int64(20)})
+		})
+		Convey("The replay should apply the policies pinned in the response", func() {
+			var fl2 = fl + "-replica"
+			strg2, err := xs.NewSqlite(fmt.Sprint("file:", fl2))
+			So(err, ShouldBeNil)
+			var st2 = NewState(sql.LevelReadUncommitted, nodeID, strg2)
+			defer func() {
+				So(st2.Close(true), ShouldBeNil)
+				for _, suffix := range []string{"", "-shm", "-wal"} {
+					err = os.Remove(fl2 + suffix)
+					So(err == nil || os.IsNotExist(err), ShouldBeTrue)
+				}
+			}()
+			So(st2.Replay(initReq, initResp), ShouldBeNil)
+
+			var req = buildRequest(types.WriteQuery, []types.Query{
+				buildQuery(`UPDATE orders SET v = ?`, 0),
+			})
+			So(req.Sign(testingPrivateKey), ShouldBeNil)
+			_, resp, err = st.Query(req, true)
+			So(err, ShouldBeNil)
+			So(resp.Header.BuildHash(), ShouldBeNil)
+			So(resp.Header.RowPolicies, ShouldResemble,
+				[]*types.RowPolicy{{Table: "orders", Column: "owner"}})
+			// the replica has no policies set
+			So(st2.Replay(req, resp), ShouldBeNil)
+			_, resp, err = st2.Query(buildRequest(types.ReadQuery, []types.Query{
+				buildQuery(`SELECT v FROM orders ORDER BY id`),
+			}), true)
+			So(err, ShouldBeNil)
+			So(resp.Payload.Rows, ShouldHaveLength, 3)
+			So(resp.Payload.Rows[1].Values, ShouldResemble, []interface{}{int64(20)})
+			So(resp.Payload.Rows[2].Values, ShouldResemble, []interface{}{int64(0)})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sqlite
+
+import (
+	"math/rand"
+	"time"
+
+	sqlite3 "github.com/CovenantSQL/go-sqlite3-encrypt"
+)
+
+const (
+	// BindFunc is the name of the sql function which binds a request-scoped timestamp in unix
+	// nanoseconds and a random seed to the connection, e.g. SELECT cql_bind(?, ?). A zero
+	// timestamp unbinds the connection, which then falls back to the local clock.
+	BindFunc = "cql_bind"
+	// NowFunc is the name of the sql function which returns the bound timestamp as a time
+	// string, it substitutes the 'now' time value of the sqlite date and time functions.
+	NowFunc = "cql_now"
+
+	nowLayout = "2006-01-02 15:04:05.000"
+)
+
+// boundContext holds the timestamp and the random source bound to a single connection, so that
+// the queries replayed on another node produce the same result.
+type boundContext struct {
+	now time.Time
+	rng *rand.Rand
+}
+
+func newBoundContext() *boundContext {
+	return &boundContext{
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (b *boundContext) bind(ts, seed int64) int64 {
+	if ts == 0 {
+		b.now = time.Time{}
+		b.rng.Seed(time.Now().UnixNano())
+		return 0
+	}
+	b.now = time.Unix(0, ts).UTC()
+	b.rng.Seed(seed)
+	return ts
+}
+
+func (b *boundContext) nowString() string {
+	if b.now.IsZero() {
+		return time.Now().UTC().Format(nowLayout)
+	}
+	return b.now.Format(nowLayout)
+}
+
+func (b *boundContext) random() int64 {
+	return int64(b.rng.Uint64())
+}
+
+func (b *boundContext) randomBlob(n int64) []byte {
+	if n < 1 {
+		n = 1
+	}
+	var buf = make([]byte, n)
+	_, _ = b.rng.Read(buf)
+	return buf
+}
+
+// registerDeterministicFuncs overrides the builtin random functions and registers the bind and
+// now functions on connection c. The connection is executed by one goroutine at a time, thus
+// the context needs no locking.
+func registerDeterministicFuncs(c *sqlite3.SQLiteConn) (err error) {
+	var b = newBoundContext()
+	if err = c.RegisterFunc(BindFunc, b.bind, false); err != nil {
+		return
+	}
+	if err = c.RegisterFunc(NowFunc, b.nowString, false); err != nil {
+		return
+	}
+	if err = c.RegisterFunc("random", b.random, false); err != nil {
+		return
+	}
+	if err = c.RegisterFunc("randomblob", b.randomBlob, false); err != nil {
+		return
+	}
+	return
+}
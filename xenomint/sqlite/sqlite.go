@@ -17,10 +17,13 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"time"
 
 	sqlite3 "github.com/CovenantSQL/go-sqlite3-encrypt"
+	"github.com/pkg/errors"
 
 	"github.com/CovenantSQL/CovenantSQL/crypto/symmetric"
 	"github.com/CovenantSQL/CovenantSQL/storage"
@@ -30,6 +33,8 @@ import (
 const (
 	serializableDriver = "sqlite3-custom"
 	dirtyReadDriver    = "sqlite3-dirty-reader"
+
+	backupRetryInterval = 10 * time.Millisecond
 )
 
 func init() {
@@ -60,6 +65,9 @@ func init() {
 		if err = c.RegisterFunc("decrypt", decryptFunc, true); err != nil {
 			return
 		}
+		if err = registerDeterministicFuncs(c); err != nil {
+			return
+		}
 		return
 	}
 
@@ -163,3 +171,88 @@ func (s *SQLite3) Close() (err error) {
 	}
 	return
 }
+
+// Backup implements Backup method of the xenomint/interfaces.BackupStorage interface. It writes
+// a consistent copy of the committed database content to file using the sqlite online backup api.
+func (s *SQLite3) Backup(file string) (err error) {
+	var dst *sqlite3.SQLiteConn
+	if dst, err = s.openFile(file); err != nil {
+		return
+	}
+	defer dst.Close()
+	return withRawConn(s.reader, func(src *sqlite3.SQLiteConn) error {
+		return backup(dst, src)
+	})
+}
+
+// Restore implements Restore method of the xenomint/interfaces.BackupStorage interface. It
+// replaces the database content with the copy in file, which is usually produced by Backup.
+// The caller should make sure that no transaction is in progress on the writer.
+func (s *SQLite3) Restore(file string) (err error) {
+	var src *sqlite3.SQLiteConn
+	if src, err = s.openFile(file); err != nil {
+		return
+	}
+	defer src.Close()
+	return withRawConn(s.writer, func(dst *sqlite3.SQLiteConn) error {
+		return backup(dst, src)
+	})
+}
+
+// openFile opens a raw connection to file, sharing the same parameters (e.g. crypto key) with
+// the storage dsn.
+func (s *SQLite3) openFile(file string) (c *sqlite3.SQLiteConn, err error) {
+	var dsn *storage.DSN
+	if dsn, err = storage.NewDSN(s.filename); err != nil {
+		return
+	}
+	dsn.SetFileName(file)
+	var (
+		conn driver.Conn
+		ok   bool
+	)
+	if conn, err = (&sqlite3.SQLiteDriver{}).Open(dsn.Format()); err != nil {
+		return
+	}
+	if c, ok = conn.(*sqlite3.SQLiteConn); !ok {
+		err = errors.Errorf("unexpected driver connection type %T", conn)
+	}
+	return
+}
+
+func withRawConn(db *sql.DB, fn func(*sqlite3.SQLiteConn) error) (err error) {
+	var conn *sql.Conn
+	if conn, err = db.Conn(context.Background()); err != nil {
+		return
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return errors.Errorf("unexpected driver connection type %T", driverConn)
+		}
+		return fn(c)
+	})
+}
+
+func backup(dst, src *sqlite3.SQLiteConn) (err error) {
+	var (
+		bk   *sqlite3.SQLiteBackup
+		done bool
+	)
+	if bk, err = dst.Backup("main", src, "main"); err != nil {
+		return
+	}
+	defer func() {
+		if ferr := bk.Finish(); err == nil {
+			err = ferr
+		}
+	}()
+	for {
+		if done, err = bk.Step(-1); err != nil || done {
+			return
+		}
+		// source or destination is busy, retry later
+		time.Sleep(backupRetryInterval)
+	}
+}
@@ -17,6 +17,7 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"math/rand"
@@ -87,6 +88,41 @@ func TestStorage(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(destStr, ShouldEqual, largeText)
 			})
+			Convey("Test deterministic time and random funcs bound by request", func() {
+				var (
+					ts     = time.Date(2019, 1, 2, 3, 4, 5, 6e6, time.UTC)
+					r1, r2 int64
+					b1, b2 []byte
+					now    string
+					conn   *sql.Conn
+				)
+				conn, err = st.Writer().Conn(context.Background())
+				So(err, ShouldBeNil)
+				defer conn.Close()
+				for _, v := range []struct {
+					r *int64
+					b *[]byte
+				}{{&r1, &b1}, {&r2, &b2}} {
+					_, err = conn.ExecContext(context.Background(),
+						fmt.Sprintf("SELECT %s(?, ?)", BindFunc), ts.UnixNano(), 42)
+					So(err, ShouldBeNil)
+					err = conn.QueryRowContext(context.Background(), fmt.Sprintf(
+						"SELECT random(), randomblob(8), %s()", NowFunc)).Scan(v.r, v.b, &now)
+					So(err, ShouldBeNil)
+					So(now, ShouldEqual, "2019-01-02 03:04:05.006")
+				}
+				So(r1, ShouldEqual, r2)
+				So(b1, ShouldResemble, b2)
+				So(b1, ShouldHaveLength, 8)
+
+				// unbound connection falls back to the local clock
+				_, err = conn.ExecContext(context.Background(), fmt.Sprintf("SELECT %s(0, 0)", BindFunc))
+				So(err, ShouldBeNil)
+				err = conn.QueryRowContext(context.Background(), fmt.Sprintf(
+					"SELECT %s()", NowFunc)).Scan(&now)
+				So(err, ShouldBeNil)
+				So(now, ShouldNotEqual, "2019-01-02 03:04:05.006")
+			})
 			Convey("When storage is closed", func() {
 				err = st.Close()
 				So(err, ShouldBeNil)
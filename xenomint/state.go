@@ -19,16 +19,19 @@ package xenomint
 import (
 	"context"
 	"database/sql"
+	"encoding/binary"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/CovenantSQL/CovenantSQL/crypto/hash"
 	"github.com/CovenantSQL/CovenantSQL/proto"
 	"github.com/CovenantSQL/CovenantSQL/types"
 	"github.com/CovenantSQL/CovenantSQL/utils/log"
 	xi "github.com/CovenantSQL/CovenantSQL/xenomint/interfaces"
+	xs "github.com/CovenantSQL/CovenantSQL/xenomint/sqlite"
 )
 
 type sqlQuerier interface {
@@ -63,9 +66,35 @@ type State struct {
 
 	handler         sqlHandler
 	maxTx           uint64
+	chunkRows       int    // max rows in a response chunk, zero to disable result set streaming
+	gasLimit        uint64 // max gas used by a single request, zero if unlimited
+	rowPolicies     RowPolicyFunc
 	lastCommitPoint uint64
 	current         uint64 // current is the current lastSeq of the current transaction
 	hasSchemaChange uint32 // indicates schema change happens in this uncommitted transaction
+
+	// readers waiting for the state to be applied to an offset
+	appliedLock sync.Mutex
+	appliedCh   chan struct{} // closed once the offset advances, nil if no waiter
+
+	// interactive transactions
+	txSeq          uint64
+	txs            sync.Map
+	txGate         sync.RWMutex
+	wtx            *interactiveTx // the writable transaction which holds the writer
+	txLifetime     time.Duration  // max lifetime of an interactive transaction, zero if unlimited
+	maxWritableTxs int32          // max writable transactions open or waiting, zero if unlimited
+	writableTxs    int32
+
+	// cursors of streaming result sets
+	cursorSeq       uint64
+	cursors         sync.Map
+	cursorLock      sync.Mutex
+	cursorCount     int
+	ownerCursors    map[proto.NodeID]int
+	cursorQuota     *CursorQuota // shared quota of open cursors, nil if unlimited
+	maxCursors      int          // max open cursors of the state, zero if unlimited
+	maxOwnerCursors int          // max open cursors of a single client node, zero if unlimited
 }
 
 // NewState returns a new State bound to strg.
@@ -76,6 +105,8 @@ func NewState(level sql.IsolationLevel, nodeID proto.NodeID, strg xi.Storage) (s
 		strg:   strg,
 		pool:   newPool(),
 		maxTx:  100,
+
+		ownerCursors: make(map[proto.NodeID]int),
 	}
 	s.openHandler()
 	return
@@ -101,11 +132,54 @@ func (s *State) reader() *sql.DB {
 
 func (s *State) incSeq() {
 	atomic.AddUint64(&s.current, 1)
+	s.notifyApplied()
 }
 
 // SetSeq sets the initial id of the current transaction.
 func (s *State) SetSeq(id uint64) {
 	atomic.StoreUint64(&s.current, id)
+	s.notifyApplied()
+}
+
+func (s *State) notifyApplied() {
+	s.appliedLock.Lock()
+	defer s.appliedLock.Unlock()
+	if s.appliedCh != nil {
+		close(s.appliedCh)
+		s.appliedCh = nil
+	}
+}
+
+func (s *State) appliedChan() chan struct{} {
+	s.appliedLock.Lock()
+	defer s.appliedLock.Unlock()
+	if s.appliedCh == nil {
+		s.appliedCh = make(chan struct{})
+	}
+	return s.appliedCh
+}
+
+// AppliedOffset returns the offset of the state, which is advanced by each applied write query.
+// The offset is replicated along with the queries, so it's comparable among the peers.
+func (s *State) AppliedOffset() uint64 {
+	return s.getSeq()
+}
+
+// WaitApplied waits until the state is applied to offset, or returns ErrStaleState if ctx is
+// done before that.
+func (s *State) WaitApplied(ctx context.Context, offset uint64) (err error) {
+	for {
+		var ch = s.appliedChan()
+		if s.getSeq() >= offset {
+			return
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return errors.Wrapf(types.ErrStaleState,
+				"applied offset %d behind required %d", s.getSeq(), offset)
+		}
+	}
 }
 
 func (s *State) getSeq() uint64 {
@@ -116,13 +190,43 @@ func (s *State) getLastCommitPoint() uint64 {
 	return atomic.LoadUint64(&s.lastCommitPoint)
 }
 
+// SetChunkRows sets the max rows in a response chunk, a larger result set of read query is
+// streamed by cursor. Zero disables result set streaming.
+func (s *State) SetChunkRows(n int) {
+	s.chunkRows = n
+}
+
+// SetCursorLimits sets the max open cursors of the state and of a single client node, and the
+// quota shared with other states, as each cursor holds a reader transaction until it's drained
+// or idle for CursorIdleTimeout. Zero or nil means unlimited.
+func (s *State) SetCursorLimits(quota *CursorQuota, total, perClient int) {
+	s.cursorQuota = quota
+	s.maxCursors = total
+	s.maxOwnerCursors = perClient
+}
+
+// SetGasLimit sets the max gas used by a single request, a request with a lower gas limit in
+// its header is limited by its own. Zero means unlimited.
+func (s *State) SetGasLimit(limit uint64) {
+	s.gasLimit = limit
+}
+
+// SetTxLimits sets the max lifetime of an interactive transaction and the max count of the
+// writable ones which are open or waiting for the writer. Zero means unlimited.
+func (s *State) SetTxLimits(lifetime time.Duration, writable int) {
+	s.txLifetime = lifetime
+	s.maxWritableTxs = int32(writable)
+}
+
 // Close commits any ongoing transaction if needed and closes the underlying storage.
 func (s *State) Close(commit bool) (err error) {
+	s.closeCursors()
 	s.Lock()
 	defer s.Unlock()
 	if s.closed {
 		return
 	}
+	s.abortTxsLocked()
 	if s.handler != nil {
 		if commit {
 			s.commitHandler()
@@ -137,6 +241,44 @@ func (s *State) Close(commit bool) (err error) {
 	return
 }
 
+// Snapshot commits the ongoing transaction and writes a consistent copy of the underlying
+// storage to file.
+func (s *State) Snapshot(file string) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return ErrStateClosed
+	}
+	strg, ok := s.strg.(xi.BackupStorage)
+	if !ok {
+		return ErrBackupNotSupported
+	}
+	s.flushHandler()
+	return strg.Backup(file)
+}
+
+// Restore replaces the content of the underlying storage with the copy in file, which is
+// produced by Snapshot. All pooled queries are dropped.
+func (s *State) Restore(file string) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return ErrStateClosed
+	}
+	strg, ok := s.strg.(xi.BackupStorage)
+	if !ok {
+		return ErrBackupNotSupported
+	}
+	s.abortTxsLocked()
+	s.commitHandler()
+	defer s.openHandler()
+	if err = strg.Restore(file); err != nil {
+		return
+	}
+	s.pool = newPool()
+	return
+}
+
 func buildTypeNamesFromSQLColumnTypes(types []*sql.ColumnType) (names []string) {
 	names = make([]string, len(types))
 	for i, v := range types {
@@ -146,12 +288,46 @@ func buildTypeNamesFromSQLColumnTypes(types []*sql.ColumnType) (names []string)
 }
 
 func readSingle(
-	ctx context.Context, qer sqlQuerier, q *types.Query,
+	ctx context.Context, qer sqlQuerier, q *types.Query, meter *gasMeter,
 ) (
 	names []string, types []string, data [][]interface{}, err error,
+) {
+	var rows *sql.Rows
+	if err = meter.query(); err != nil {
+		return
+	}
+	if rows, names, types, err = openRows(meter.withSteps(ctx), qer, q); err != nil {
+		err = meter.stepped(interruptError(ctx, err))
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	data, err = scanRows(rows, len(names), -1, meter)
+	err = meter.stepped(interruptError(ctx, err))
+	return
+}
+
+// interruptError returns a typed error if the execution is interrupted by the deadline or
+// cancellation of ctx, or err otherwise. An interrupted result set may end early without error,
+// so the context is checked even if err is nil.
+func interruptError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return errors.Wrap(types.ErrQueryTimeout, "execution interrupted")
+	case context.Canceled:
+		return errors.Wrap(context.Canceled, "execution interrupted")
+	default:
+		return err
+	}
+}
+
+func openRows(
+	ctx context.Context, qer sqlQuerier, q *types.Query,
+) (
+	rows *sql.Rows, names []string, types []string, err error,
 ) {
 	var (
-		rows    *sql.Rows
 		cols    []*sql.ColumnType
 		pattern string
 		args    []interface{}
@@ -164,7 +340,10 @@ func readSingle(
 		return
 	}
 	defer func() {
-		_ = rows.Close()
+		if err != nil {
+			_ = rows.Close()
+			rows = nil
+		}
 	}()
 	// Fetch column names and types
 	if names, err = rows.Columns(); err != nil {
@@ -174,12 +353,20 @@ func readSingle(
 		return
 	}
 	types = buildTypeNamesFromSQLColumnTypes(cols)
+	return
+}
+
+// scanRows scans at most limit rows, or all the remaining rows if limit is negative. The gas of
+// each row is consumed from meter.
+func scanRows(
+	rows *sql.Rows, ncols int, limit int, meter *gasMeter) (data [][]interface{}, err error,
+) {
 	// Scan data row by row
 	data = make([][]interface{}, 0)
-	for rows.Next() {
+	for (limit < 0 || len(data) < limit) && rows.Next() {
 		var (
-			row  = make([]interface{}, len(cols))
-			dest = make([]interface{}, len(cols))
+			row  = make([]interface{}, ncols)
+			dest = make([]interface{}, ncols)
 		)
 		for i := range row {
 			dest[i] = &row[i]
@@ -187,6 +374,9 @@ func readSingle(
 		if err = rows.Scan(dest...); err != nil {
 			return
 		}
+		if err = meter.read(row); err != nil {
+			return
+		}
 		data = append(data, row)
 	}
 	return
@@ -211,10 +401,19 @@ func (s *State) readWithContext(
 		ierr           error
 		cnames, ctypes []string
 		data           [][]interface{}
+		meter          = s.newGasMeter(req)
+	)
+	var (
+		queries  []types.Query
+		policies []*types.RowPolicy
 	)
+	if queries, policies, err = s.rowPolicyQueries(req); err != nil {
+		s.pool.setFailed(req)
+		return
+	}
 	// TODO(leventeliu): no need to run every read query here.
-	for i, v := range req.Payload.Queries {
-		if cnames, ctypes, data, ierr = readSingle(ctx, s.reader(), &v); ierr != nil {
+	for i, v := range queries {
+		if cnames, ctypes, data, ierr = readSingle(ctx, s.reader(), &v, meter); ierr != nil {
 			err = errors.Wrapf(ierr, "query at #%d failed", i)
 			// Add to failed pool list
 			s.pool.setFailed(req)
@@ -229,12 +428,15 @@ func (s *State) readWithContext(
 	resp = &types.Response{
 		Header: types.SignedResponseHeader{
 			ResponseHeader: types.ResponseHeader{
-				Request:     req.Header.RequestHeader,
-				RequestHash: req.Header.Hash(),
-				NodeID:      s.nodeID,
-				Timestamp:   s.getLocalTime(),
-				RowCount:    uint64(len(data)),
-				LogOffset:   s.getSeq(),
+				Request:       req.Header.RequestHeader,
+				RequestHash:   req.Header.Hash(),
+				NodeID:        s.nodeID,
+				Timestamp:     s.getLocalTime(),
+				RowCount:      uint64(len(data)),
+				LogOffset:     s.getSeq(),
+				AppliedOffset: s.getSeq(),
+				GasUsed:       meter.used,
+				RowPolicies:   policies,
 			},
 		},
 		Payload: types.ResponsePayload{
@@ -255,6 +457,9 @@ func (s *State) readTx(
 		cnames, ctypes []string
 		data           [][]interface{}
 		querier        sqlQuerier
+		tx             *sql.Tx
+		cur            *cursor
+		meter          = s.newGasMeter(req)
 	)
 	if s.level == sql.LevelReadUncommitted && atomic.LoadUint32(&s.hasSchemaChange) == 1 {
 		// lock transaction
@@ -262,14 +467,15 @@ func (s *State) readTx(
 		defer s.Unlock()
 		querier = s.handler
 	} else {
-		var tx *sql.Tx
 		if tx, ierr = s.reader().Begin(); ierr != nil {
 			err = errors.Wrap(ierr, "open tx failed")
 			return
 		}
 		querier = tx
 		defer func() {
-			_ = tx.Rollback()
+			if cur == nil {
+				_ = tx.Rollback()
+			}
 		}()
 	}
 
@@ -283,8 +489,26 @@ func (s *State) readTx(
 		}
 	}()
 
-	for i, v := range req.Payload.Queries {
-		if cnames, ctypes, data, ierr = readSingle(ctx, querier, &v); ierr != nil {
+	var (
+		queries  []types.Query
+		policies []*types.RowPolicy
+	)
+	if queries, policies, err = s.rowPolicyQueries(req); err != nil {
+		s.pool.setFailed(req)
+		return
+	}
+	for i, v := range queries {
+		// The result set of the last query may be streamed by cursor, except for the legacy
+		// clients which verify the whole payload at once
+		if tx != nil && s.chunkRows > 0 && i == len(queries)-1 && req.Header.Version != 0 {
+			if cur, cnames, ctypes, data, ierr = s.openCursor(ctx, tx, req, &v, meter); ierr != nil {
+				err = errors.Wrapf(ierr, "query at #%d failed", i)
+				s.pool.setFailed(req)
+				return
+			}
+			break
+		}
+		if cnames, ctypes, data, ierr = readSingle(ctx, querier, &v, meter); ierr != nil {
 			err = errors.Wrapf(ierr, "query at #%d failed", i)
 			// Add to failed pool list
 			s.pool.setFailed(req)
@@ -293,18 +517,18 @@ func (s *State) readTx(
 	}
 	// Build query response
 	ref = &QueryTracker{Req: req}
-	s.Lock()
-	s.pool.enqueueRead(ref)
-	s.Unlock()
 	resp = &types.Response{
 		Header: types.SignedResponseHeader{
 			ResponseHeader: types.ResponseHeader{
-				Request:     req.Header.RequestHeader,
-				RequestHash: req.Header.Hash(),
-				NodeID:      s.nodeID,
-				Timestamp:   s.getLocalTime(),
-				RowCount:    uint64(len(data)),
-				LogOffset:   id,
+				Request:       req.Header.RequestHeader,
+				RequestHash:   req.Header.Hash(),
+				NodeID:        s.nodeID,
+				Timestamp:     s.getLocalTime(),
+				RowCount:      uint64(len(data)),
+				LogOffset:     id,
+				AppliedOffset: id,
+				GasUsed:       meter.used,
+				RowPolicies:   policies,
 			},
 		},
 		Payload: types.ResponsePayload{
@@ -313,11 +537,81 @@ func (s *State) readTx(
 			Rows:      buildRowsFromNativeData(data),
 		},
 	}
+	if cur != nil {
+		// The request is pooled when the last chunk is fetched
+		s.registerCursor(cur, ref, resp)
+		ref = nil
+		return
+	}
+	s.Lock()
+	s.pool.enqueueRead(ref)
+	s.Unlock()
+	return
+}
+
+// bindRequest binds the timestamp and a random seed derived from the hash of req to the
+// connection of e. The time values and random functions in the queries of req are evaluated
+// with them, thus replaying the logged request produces the same result on every node.
+func bindRequest(e sqlExecuter, req *types.Request) (unbind func(), err error) {
+	var (
+		h  = req.Header.Hash()
+		ts int64
+	)
+	if !req.Header.Timestamp.IsZero() {
+		ts = req.Header.Timestamp.UnixNano()
+	}
+	if err = bind(e, ts, int64(binary.BigEndian.Uint64(h[:8]))); err != nil {
+		return
+	}
+	unbind = func() {
+		_ = bind(e, 0, 0)
+	}
+	return
+}
+
+// bindTxQuery binds the begin time of the interactive transaction id owned by owner and a seed
+// derived from it and the ordinal i of a write query in the transaction to the connection of e.
+// Every write query is bound again before it's executed, both in the transaction and by the
+// commit request, thus the commit replays the writes with the same values as seen in the
+// transaction, no matter how many statements are executed in between.
+func bindTxQuery(e sqlExecuter, owner proto.NodeID, id uint64, ts time.Time, i int) (err error) {
+	var (
+		buf  = make([]byte, len(owner)+24)
+		n    = copy(buf, owner)
+		nsec int64
+	)
+	if !ts.IsZero() {
+		nsec = ts.UnixNano()
+	}
+	binary.BigEndian.PutUint64(buf[n:], id)
+	binary.BigEndian.PutUint64(buf[n+8:], uint64(nsec))
+	binary.BigEndian.PutUint64(buf[n+16:], uint64(i))
+	var h = hash.THashH(buf)
+	return bind(e, nsec, int64(binary.BigEndian.Uint64(h[:8])))
+}
+
+// bindTxCommit binds the i-th query of req if it's the commit request of an interactive
+// transaction, see bindTxQuery.
+func bindTxCommit(e sqlExecuter, req *types.Request, i int) (err error) {
+	if req.Header.TxID == 0 {
+		return
+	}
+	return bindTxQuery(e, req.Header.NodeID, req.Header.TxID, req.Header.TxTimestamp, i)
+}
+
+// bind binds the timestamp ts in unix nanoseconds and the random seed to the connection of e, a
+// zero timestamp unbinds the connection.
+func bind(e sqlExecuter, ts, seed int64) (err error) {
+	if _, err = e.Exec("SELECT "+xs.BindFunc+"(?, ?)", ts, seed); err != nil {
+		err = errors.Wrap(err, "failed to bind request")
+	}
 	return
 }
 
+// writeSingle executes the write query q, the vm steps are metered by meter if it's not nil and
+// the statement is interrupted once the gas limit is exceeded.
 func (s *State) writeSingle(
-	ctx context.Context, q *types.Query) (res sql.Result, err error,
+	ctx context.Context, q *types.Query, meter *gasMeter) (res sql.Result, err error,
 ) {
 	var (
 		containsDDL bool
@@ -343,12 +637,20 @@ func (s *State) writeSingle(
 		return
 	}
 	//parsed = time.Since(start)
-	if res, err = s.handler.Exec(pattern, args...); err == nil {
-		if containsDDL {
-			atomic.StoreUint32(&s.hasSchemaChange, 1)
+	var ectx = context.Background()
+	if meter != nil {
+		ectx = meter.withSteps(ectx)
+	}
+	if res, err = s.handler.ExecContext(ectx, pattern, args...); err != nil {
+		if meter != nil {
+			err = meter.stepped(err)
 		}
-		s.incSeq()
+		return
+	}
+	if containsDDL {
+		atomic.StoreUint32(&s.hasSchemaChange, 1)
 	}
+	s.incSeq()
 	//executed = time.Since(start)
 	return
 }
@@ -358,10 +660,13 @@ func (s *State) write(
 ) {
 	var (
 		lastSeq           uint64
+		applied           uint64
 		query             = &QueryTracker{Req: req}
 		totalAffectedRows int64
 		curAffectedRows   int64
 		lastInsertID      int64
+		policies          []*types.RowPolicy
+		meter             = s.newGasMeter(req)
 		start             = time.Now()
 
 		lockAcquired, writeDone, enqueued, lockReleased, respBuilt time.Duration
@@ -400,15 +705,42 @@ func (s *State) write(
 			s.Unlock()
 			lockReleased = time.Since(start)
 		}()
+		if tx := s.wtx; tx != nil {
+			if tx.id != req.Header.TxID {
+				// NOTE(leventeliu): write requests should be held off by LockWrite, this may
+				// only happen if the leadership is changed during the transaction.
+				log.WithField("tx", tx.id).Warning("interactive transaction aborted by write")
+				s.endTxLocked(tx)
+			} else {
+				// Discard the transaction handle, the write queries are applied below
+				s.detachTxLocked(tx)
+			}
+		}
 		lastSeq = s.getSeq()
-		if qcnt > 1 && s.level == sql.LevelReadUncommitted {
+		// The request may also be aborted by gas limit during or after a query is executed
+		var useSavepoint = (qcnt > 1 || meter.limit > 0) && s.level == sql.LevelReadUncommitted
+		if useSavepoint {
+			// An interrupted write statement rolls back the whole transaction of sqlite instead
+			// of the savepoint, thus the writes of the former requests are committed first
+			if meter.limit > 0 && s.getSeq() > s.getLastCommitPoint() {
+				s.flushHandler()
+			}
 			// Set savepoint
 			if _, ierr = s.handler.Exec(`SAVEPOINT "?"`, lastSeq); ierr != nil {
 				err = errors.Wrapf(ierr, "failed to create savepoint %d", lastSeq)
 				return
 			}
+			// NOTE: ROLLBACK TO keeps the savepoint on the stack, and any later request which
+			// rolls back to the same name would also drop the writes applied since then.
 			defer func() {
-				_, _ = s.handler.Exec(`ROLLBACK TO "?"`, lastSeq)
+				if err != nil {
+					if _, ierr := s.handler.Exec(`ROLLBACK TO "?"`, lastSeq); ierr != nil {
+						// The transaction is already rolled back by an interrupted statement
+						s.resetHandler()
+						return
+					}
+					_, _ = s.handler.Exec(`RELEASE SAVEPOINT "?"`, lastSeq)
+				}
 			}()
 		}
 		if s.level != sql.LevelReadUncommitted {
@@ -418,9 +750,32 @@ func (s *State) write(
 				_, _ = s.handler.Exec(`ROLLBACK`)
 			}()
 		}
-		for i, v := range req.Payload.Queries {
+		var queries []types.Query
+		if queries, policies, err = s.rowPolicyQueries(req); err != nil {
+			s.pool.setFailed(req)
+			return
+		}
+		var unbind func()
+		if unbind, err = bindRequest(s.handler, req); err != nil {
+			s.pool.setFailed(req)
+			return
+		}
+		defer unbind()
+		for i, v := range queries {
 			var res sql.Result
-			if res, ierr = s.writeSingle(ctx, &v); ierr != nil {
+			// NOTE: an interrupted write statement rolls back the whole enclosing transaction
+			// of sqlite, so the deadline is only checked between queries.
+			if ierr = interruptError(ctx, nil); ierr != nil {
+				err = errors.Wrapf(ierr, "execute at #%d failed", i)
+				s.pool.setFailed(req)
+				return
+			}
+			if ierr = bindTxCommit(s.handler, req, i); ierr != nil {
+				err = errors.Wrapf(ierr, "execute at #%d failed", i)
+				s.pool.setFailed(req)
+				return
+			}
+			if res, ierr = s.writeSingle(ctx, &v, meter); ierr != nil {
 				err = errors.Wrapf(ierr, "execute at #%d failed", i)
 				// TODO(leventeliu): request may actually be partial successed without
 				// rolling back.
@@ -431,16 +786,23 @@ func (s *State) write(
 			curAffectedRows, _ = res.RowsAffected()
 			lastInsertID, _ = res.LastInsertId()
 			totalAffectedRows += curAffectedRows
+			if ierr = meter.query(); ierr == nil {
+				ierr = meter.written(curAffectedRows)
+			}
+			if ierr != nil {
+				err = errors.Wrapf(ierr, "execute at #%d failed", i)
+				s.pool.setFailed(req)
+				return
+			}
 		}
-		if s.level == sql.LevelReadUncommitted {
-			if qcnt > 1 {
-				// Release savepoint
-				if _, ierr = s.handler.Exec(`RELEASE SAVEPOINT "?"`, lastSeq); ierr != nil {
-					err = errors.Wrapf(ierr, "failed to release savepoint %d", lastSeq)
-					return
-				}
+		if useSavepoint {
+			// Release savepoint
+			if _, ierr = s.handler.Exec(`RELEASE SAVEPOINT "?"`, lastSeq); ierr != nil {
+				err = errors.Wrapf(ierr, "failed to release savepoint %d", lastSeq)
+				return
 			}
 		}
+		applied = s.getSeq()
 		// Try to commit if the ongoing tx is too large or schema is changed
 		if s.getSeq()-s.getLastCommitPoint() > s.maxTx ||
 			atomic.LoadUint32(&s.hasSchemaChange) != 0 {
@@ -460,14 +822,17 @@ func (s *State) write(
 	resp = &types.Response{
 		Header: types.SignedResponseHeader{
 			ResponseHeader: types.ResponseHeader{
-				Request:      req.Header.RequestHeader,
-				RequestHash:  req.Header.Hash(),
-				NodeID:       s.nodeID,
-				Timestamp:    s.getLocalTime(),
-				RowCount:     0,
-				LogOffset:    lastSeq,
-				AffectedRows: totalAffectedRows,
-				LastInsertID: lastInsertID,
+				Request:       req.Header.RequestHeader,
+				RequestHash:   req.Header.Hash(),
+				NodeID:        s.nodeID,
+				Timestamp:     s.getLocalTime(),
+				RowCount:      0,
+				LogOffset:     lastSeq,
+				AppliedOffset: applied,
+				AffectedRows:  totalAffectedRows,
+				LastInsertID:  lastInsertID,
+				GasUsed:       meter.used,
+				RowPolicies:   policies,
 			},
 		},
 	}
@@ -483,6 +848,9 @@ func (s *State) replay(ctx context.Context, req *types.Request, resp *types.Resp
 	)
 	s.Lock()
 	defer s.Unlock()
+	if s.wtx != nil {
+		s.endTxLocked(s.wtx)
+	}
 	lastSeq = s.getSeq()
 	if resp.Header.ResponseHeader.LogOffset != lastSeq {
 		err = errors.Wrapf(
@@ -491,8 +859,21 @@ func (s *State) replay(ctx context.Context, req *types.Request, resp *types.Resp
 		)
 		return
 	}
-	for i, v := range req.Payload.Queries {
-		if _, ierr = s.writeSingle(ctx, &v); ierr != nil {
+	var queries []types.Query
+	if queries, err = s.replayQueries(req, &resp.Header.ResponseHeader); err != nil {
+		return
+	}
+	var unbind func()
+	if unbind, err = bindRequest(s.handler, req); err != nil {
+		return
+	}
+	defer unbind()
+	for i, v := range queries {
+		if ierr = bindTxCommit(s.handler, req, i); ierr != nil {
+			err = errors.Wrapf(ierr, "execute at #%d failed", i)
+			return
+		}
+		if _, ierr = s.writeSingle(ctx, &v, nil); ierr != nil {
 			err = errors.Wrapf(ierr, "execute at #%d failed", i)
 			return
 		}
@@ -521,6 +902,9 @@ func (s *State) ReplayBlockWithContext(ctx context.Context, block *types.Block)
 	)
 	s.Lock()
 	defer s.Unlock()
+	if s.wtx != nil {
+		s.endTxLocked(s.wtx)
+	}
 	for i, q := range block.QueryTxs {
 		if q.Request.Header.QueryType == types.ReadQuery {
 			continue
@@ -537,15 +921,30 @@ func (s *State) ReplayBlockWithContext(ctx context.Context, block *types.Block)
 			continue
 		}
 		// Replay query
-		for j, v := range q.Request.Payload.Queries {
-			if q.Request.Header.QueryType != types.WriteQuery {
-				err = errors.Wrapf(ErrInvalidRequest, "replay block at %d:%d", i, j)
-				return
+		if err = func() (err error) {
+			var queries []types.Query
+			if queries, err = s.replayQueries(q.Request, &q.Response.ResponseHeader); err != nil {
+				return errors.Wrapf(err, "replay block at %d", i)
 			}
-			if _, ierr = s.writeSingle(ctx, &v); ierr != nil {
-				err = errors.Wrapf(ierr, "execute at %d:%d failed", i, j)
+			var unbind func()
+			if unbind, err = bindRequest(s.handler, q.Request); err != nil {
 				return
 			}
+			defer unbind()
+			for j, v := range queries {
+				if q.Request.Header.QueryType != types.WriteQuery {
+					return errors.Wrapf(ErrInvalidRequest, "replay block at %d:%d", i, j)
+				}
+				if ierr = bindTxCommit(s.handler, q.Request, j); ierr != nil {
+					return errors.Wrapf(ierr, "execute at %d:%d failed", i, j)
+				}
+				if _, ierr = s.writeSingle(ctx, &v, nil); ierr != nil {
+					return errors.Wrapf(ierr, "execute at %d:%d failed", i, j)
+				}
+			}
+			return
+		}(); err != nil {
+			return
 		}
 		s.pool.enqueue(lastsp, query)
 	}
@@ -648,6 +1047,11 @@ func (s *State) CommitExWithContext(
 }
 
 func (s *State) flushHandler() {
+	if s.wtx != nil {
+		// The handler is committed when the writable interactive transaction begins, and
+		// nothing is written by the handler until it ends
+		return
+	}
 	s.commitHandler()
 	s.openHandler()
 }
@@ -663,6 +1067,16 @@ func (s *State) commitHandler() {
 	atomic.StoreUint64(&s.lastCommitPoint, s.getSeq())
 }
 
+// resetHandler discards the transaction of the handler, which is already rolled back by sqlite,
+// and opens a new one.
+func (s *State) resetHandler() {
+	if tx, ok := s.handler.(sqlTransaction); ok {
+		_ = tx.Rollback()
+	}
+	atomic.StoreUint32(&s.hasSchemaChange, 0)
+	s.openHandler()
+}
+
 func (s *State) rollbackHandler() {
 	if tx, ok := s.handler.(sqlTransaction); ok {
 		if err := tx.Rollback(); err != nil {
@@ -23,8 +23,10 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	. "github.com/smartystreets/goconvey/convey"
@@ -367,6 +369,540 @@ INSERT INTO t1 (k, v) VALUES (?, ?)`, concat(values[2:4])...),
 					So(resp1.Payload, ShouldResemble, resp2.Payload)
 				}
 			})
+			Convey("The state should be restored from a snapshot of another instance", func() {
+				var snapshot = path.Join(testingDataDir, fmt.Sprint(t.Name(), "snapshot"))
+				defer os.Remove(snapshot)
+				for i := range values {
+					_, resp, err = st1.Query(buildRequest(types.WriteQuery, []types.Query{
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[i]...),
+					}), true)
+					So(err, ShouldBeNil)
+					So(resp, ShouldNotBeNil)
+				}
+				// uncommitted writes should be flushed into the snapshot
+				err = st1.Snapshot(snapshot)
+				So(err, ShouldBeNil)
+				err = st2.Restore(snapshot)
+				So(err, ShouldBeNil)
+				for i := range values {
+					var resp1, resp2 *types.Response
+					req = buildRequest(types.ReadQuery, []types.Query{
+						buildQuery(`SELECT v FROM t1 WHERE k=?`, values[i][0]),
+					})
+					_, resp1, err = st1.Query(req, true)
+					So(err, ShouldBeNil)
+					_, resp2, err = st2.Query(req, true)
+					So(err, ShouldBeNil)
+					So(resp2.Header.RowCount, ShouldEqual, 1)
+					So(resp1.Payload, ShouldResemble, resp2.Payload)
+				}
+				// restored state should accept new writes
+				_, resp, err = st2.Query(buildRequest(types.WriteQuery, []types.Query{
+					buildQuery(`DELETE FROM t1 WHERE k=?`, values[0][0]),
+				}), true)
+				So(err, ShouldBeNil)
+				So(resp.Header.AffectedRows, ShouldEqual, 1)
+			})
+			Convey("The state should execute interactive transactions", func() {
+				var (
+					owner   = buildRequest(types.ReadQuery, nil).Header.NodeID
+					begin   = time.Now().UTC()
+					txID    uint64
+					unlock  func()
+					ins     = buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[0]...)
+					count   = buildQuery(`SELECT COUNT(1) FROM t1`)
+					txQuery = func(qt types.QueryType, qs ...types.Query) (*types.Response, error) {
+						var req = buildRequest(qt, qs)
+						req.Header.TxID = txID
+						return st1.QueryTx(context.Background(), req)
+					}
+				)
+				_, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelLinearizable, false, time.Second)
+				So(errors.Cause(err), ShouldEqual, ErrTxIsolationLevel)
+
+				txID, err = st1.BeginTx(owner, begin, sql.LevelSerializable, false, time.Second)
+				So(err, ShouldBeNil)
+				resp, err = txQuery(types.WriteQuery, ins)
+				So(err, ShouldBeNil)
+				So(resp.Header.AffectedRows, ShouldEqual, 1)
+				resp, err = txQuery(types.ReadQuery, count)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows[0].Values[0], ShouldEqual, 1)
+				// commit request should match the executed write queries
+				req = buildRequest(types.WriteQuery, []types.Query{ins, ins})
+				req.Header.TxID = txID
+				req.Header.TxTimestamp = begin
+				_, err = st1.LockWrite(req)
+				So(errors.Cause(err), ShouldEqual, ErrTxCommitMismatch)
+				req = buildRequest(types.WriteQuery, []types.Query{ins})
+				req.Header.TxID = txID
+				_, err = st1.LockWrite(req)
+				So(errors.Cause(err), ShouldEqual, ErrTxCommitMismatch)
+				req.Header.TxTimestamp = begin
+				unlock, err = st1.LockWrite(req)
+				So(err, ShouldBeNil)
+				_, resp, err = st1.Query(req, true)
+				So(err, ShouldBeNil)
+				So(resp.Header.AffectedRows, ShouldEqual, 1)
+				unlock()
+				_, err = txQuery(types.ReadQuery, count)
+				So(errors.Cause(err), ShouldEqual, ErrTxNotFound)
+				_, resp, err = st1.Query(buildRequest(types.ReadQuery, []types.Query{count}), true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows[0].Values[0], ShouldEqual, 1)
+
+				// rolled back transaction should leave no change
+				txID, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, false, time.Second)
+				So(err, ShouldBeNil)
+				_, err = txQuery(types.WriteQuery, buildQuery(`DELETE FROM t1`))
+				So(err, ShouldBeNil)
+				err = st1.RollbackTx(owner, txID)
+				So(err, ShouldBeNil)
+				err = st1.RollbackTx(owner, txID)
+				So(errors.Cause(err), ShouldEqual, ErrTxNotFound)
+				_, resp, err = st1.Query(buildRequest(types.ReadQuery, []types.Query{count}), true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows[0].Values[0], ShouldEqual, 1)
+
+				// read-only transaction should reject write queries
+				txID, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, true, time.Second)
+				So(err, ShouldBeNil)
+				_, err = txQuery(types.WriteQuery, buildQuery(`DELETE FROM t1`))
+				So(errors.Cause(err), ShouldEqual, ErrTxReadOnly)
+				resp, err = txQuery(types.ReadQuery, count)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows[0].Values[0], ShouldEqual, 1)
+				err = st1.RollbackTx(owner, txID)
+				So(err, ShouldBeNil)
+
+				// expired transaction should release the writer
+				txID, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, false, 100*time.Millisecond)
+				So(err, ShouldBeNil)
+				time.Sleep(300 * time.Millisecond)
+				_, err = txQuery(types.ReadQuery, count)
+				So(errors.Cause(err), ShouldEqual, ErrTxNotFound)
+				req = buildRequest(types.WriteQuery, []types.Query{buildQuery(`DELETE FROM t1`)})
+				unlock, err = st1.LockWrite(req)
+				So(err, ShouldBeNil)
+				_, resp, err = st1.Query(req, true)
+				unlock()
+				So(err, ShouldBeNil)
+				So(resp.Header.AffectedRows, ShouldEqual, 1)
+
+				// lifetime and writable transactions should be limited
+				st1.SetTxLimits(100*time.Millisecond, 1)
+				txID, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, false, time.Minute)
+				So(err, ShouldBeNil)
+				_, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, false, time.Minute)
+				So(errors.Cause(err), ShouldEqual, ErrTxLimitExceeded)
+				roID, err := st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, true, time.Minute)
+				So(err, ShouldBeNil)
+				time.Sleep(300 * time.Millisecond)
+				_, err = txQuery(types.ReadQuery, count)
+				So(errors.Cause(err), ShouldEqual, ErrTxNotFound)
+				err = st1.RollbackTx(owner, roID)
+				So(errors.Cause(err), ShouldEqual, ErrTxNotFound)
+				txID, err = st1.BeginTx(owner, time.Now().UTC(), sql.LevelDefault, false, time.Minute)
+				So(err, ShouldBeNil)
+				err = st1.RollbackTx(owner, txID)
+				So(err, ShouldBeNil)
+			})
+			Convey("The state should commit interactive transactions with the values seen in them", func() {
+				var (
+					owner   = buildRequest(types.ReadQuery, nil).Header.NodeID
+					begin   = time.Now().UTC().Add(-time.Hour)
+					txID    uint64
+					unlock  func()
+					ins1    = buildQuery(`INSERT INTO t1 (k, v) VALUES (?, random() || datetime('now'))`, 1)
+					ins2    = buildQuery(`INSERT INTO t1 (k, v) VALUES (?, hex(randomblob(8)))`, 2)
+					sel     = buildQuery(`SELECT * FROM t1 ORDER BY k`)
+					txQuery = func(qt types.QueryType, qs ...types.Query) (*types.Response, error) {
+						var req = buildRequest(qt, qs)
+						req.Header.TxID = txID
+						return st1.QueryTx(context.Background(), req)
+					}
+					seen, committed, replayed *types.Response
+				)
+				txID, err = st1.BeginTx(owner, begin, sql.LevelDefault, false, time.Second)
+				So(err, ShouldBeNil)
+				_, err = txQuery(types.WriteQuery, ins1)
+				So(err, ShouldBeNil)
+				// statements in between should not change the values of the following writes
+				_, err = txQuery(types.ReadQuery, buildQuery(`SELECT random(), randomblob(16)`))
+				So(err, ShouldBeNil)
+				_, err = txQuery(types.WriteQuery, ins2)
+				So(err, ShouldBeNil)
+				seen, err = txQuery(types.ReadQuery, sel)
+				So(err, ShouldBeNil)
+				So(seen.Payload.Rows, ShouldHaveLength, 2)
+				So(string(seen.Payload.Rows[0].Values[1].([]byte)), ShouldEndWith,
+					begin.Format("2006-01-02 15:04:05"))
+
+				req = buildRequest(types.WriteQuery, []types.Query{ins1, ins2})
+				req.Header.TxID = txID
+				req.Header.TxTimestamp = begin
+				unlock, err = st1.LockWrite(req)
+				So(err, ShouldBeNil)
+				_, resp, err = st1.Query(req, true)
+				unlock()
+				So(err, ShouldBeNil)
+				err = st2.Replay(req, resp)
+				So(err, ShouldBeNil)
+				_, committed, err = st1.Query(buildRequest(types.ReadQuery, []types.Query{sel}), true)
+				So(err, ShouldBeNil)
+				So(committed.Payload.Rows, ShouldResemble, seen.Payload.Rows)
+				_, replayed, err = st2.Query(buildRequest(types.ReadQuery, []types.Query{sel}), true)
+				So(err, ShouldBeNil)
+				So(replayed.Payload.Rows, ShouldResemble, seen.Payload.Rows)
+			})
+			Convey("The state should stream large result sets by cursor", func() {
+				var (
+					owner  = buildRequest(types.ReadQuery, nil).Header.NodeID
+					sel    = buildRequest(types.ReadQuery, []types.Query{buildQuery(`SELECT * FROM t1`)})
+					full   *types.Response
+					rows   []types.ResponseRow
+					qt     *QueryTracker
+					fetchd []types.ResponseRow
+				)
+				for _, v := range values {
+					_, _, err = st1.Query(buildRequest(types.WriteQuery, []types.Query{
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, v...),
+					}), true)
+					So(err, ShouldBeNil)
+				}
+				_, full, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(full.Cursor, ShouldEqual, 0)
+				err = full.BuildHash()
+				So(err, ShouldBeNil)
+
+				st1.SetChunkRows(2)
+				qt, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(qt, ShouldBeNil)
+				So(resp.Cursor, ShouldNotEqual, 0)
+				So(len(resp.Payload.Rows), ShouldEqual, 2)
+				fetchd = append(fetchd, resp.Payload.Rows...)
+				_, _, _, err = st1.FetchRows(nodeID, resp.Cursor)
+				So(errors.Cause(err), ShouldEqual, ErrCursorNotFound)
+				rows, qt, resp, err = st1.FetchRows(owner, resp.Cursor)
+				So(err, ShouldBeNil)
+				So(qt, ShouldNotBeNil)
+				So(resp, ShouldNotBeNil)
+				fetchd = append(fetchd, rows...)
+				So(fetchd, ShouldResemble, full.Payload.Rows)
+				So(resp.Header.RowCount, ShouldEqual, len(values))
+				So(resp.Header.PayloadHash, ShouldResemble, full.Header.PayloadHash)
+
+				// closed cursor should be released
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Cursor, ShouldNotEqual, 0)
+				err = st1.CloseCursor(owner, resp.Cursor)
+				So(err, ShouldBeNil)
+				_, _, _, err = st1.FetchRows(owner, resp.Cursor)
+				So(errors.Cause(err), ShouldEqual, ErrCursorNotFound)
+
+				// result set within a single chunk should be returned directly
+				_, resp, err = st1.Query(buildRequest(types.ReadQuery, []types.Query{
+					buildQuery(`SELECT * FROM t1 LIMIT 2`),
+				}), true)
+				So(err, ShouldBeNil)
+				So(resp.Cursor, ShouldEqual, 0)
+				So(len(resp.Payload.Rows), ShouldEqual, 2)
+
+				// legacy clients should get the whole result set
+				legacy := buildRequest(types.ReadQuery, []types.Query{buildQuery(`SELECT * FROM t1`)})
+				legacy.Header.Version = 0
+				_, resp, err = st1.Query(legacy, true)
+				So(err, ShouldBeNil)
+				So(resp.Cursor, ShouldEqual, 0)
+				So(resp.Payload.Rows, ShouldResemble, full.Payload.Rows)
+
+				// open cursors should be limited
+				var (
+					other   = proto.NodeID("0000000000000000000000000000000000000000000000000000000000000001")
+					cursors []uint64
+				)
+				st1.SetCursorLimits(NewCursorQuota(2), 2, 1)
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Cursor, ShouldNotEqual, 0)
+				cursors = append(cursors, resp.Cursor)
+				_, _, err = st1.Query(sel, true)
+				So(errors.Cause(err), ShouldEqual, ErrCursorLimitExceeded)
+				sel.Header.NodeID = other
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Cursor, ShouldNotEqual, 0)
+				st1.SetCursorLimits(st1.cursorQuota, 2, 0)
+				_, _, err = st1.Query(sel, true)
+				So(errors.Cause(err), ShouldEqual, ErrCursorLimitExceeded)
+				st1.SetCursorLimits(st1.cursorQuota, 0, 0)
+				_, _, err = st1.Query(sel, true)
+				So(errors.Cause(err), ShouldEqual, ErrCursorLimitExceeded)
+				err = st1.CloseCursor(owner, cursors[0])
+				So(err, ShouldBeNil)
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Cursor, ShouldNotEqual, 0)
+			})
+			Convey("The state should replay time values and random functions identically", func() {
+				var (
+					reqs = []*types.Request{
+						buildRequest(types.WriteQuery, []types.Query{
+							buildQuery(`CREATE TABLE t2 (k INT, r INT, b BLOB, d TEXT,
+								ts TEXT DEFAULT CURRENT_TIMESTAMP)`),
+						}),
+						buildRequest(types.WriteQuery, []types.Query{
+							buildQuery(`INSERT INTO t2 (k, r, b, d) VALUES (?, random(), randomblob(8),
+								datetime('now'))`, 1),
+							buildQuery(`INSERT INTO t2 (k, r, b, d) VALUES (?, random(), randomblob(8),
+								strftime('%Y-%m-%d %H:%M:%f', 'now'))`, 2),
+						}),
+					}
+					sel          = buildRequest(types.ReadQuery, []types.Query{buildQuery(`SELECT * FROM t2`)})
+					resp1, resp2 *types.Response
+				)
+				for _, v := range reqs {
+					_, resp, err = st1.Query(v, true)
+					So(err, ShouldBeNil)
+					err = st2.Replay(v, resp)
+					So(err, ShouldBeNil)
+				}
+				_, resp1, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				_, resp2, err = st2.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp1.Payload, ShouldResemble, resp2.Payload)
+				So(resp1.Payload.Rows, ShouldHaveLength, 2)
+
+				var ts = reqs[1].Header.Timestamp.UTC()
+				So(resp1.Payload.Rows[0].Values[3], ShouldResemble,
+					[]byte(ts.Format("2006-01-02 15:04:05")))
+				So(resp1.Payload.Rows[0].Values[4], ShouldResemble, resp1.Payload.Rows[0].Values[3])
+				So(resp1.Payload.Rows[1].Values[3], ShouldResemble,
+					[]byte(ts.Format("2006-01-02 15:04:05.000")))
+				So(resp1.Payload.Rows[0].Values[1], ShouldNotEqual, resp1.Payload.Rows[1].Values[1])
+			})
+			Convey("The state should keep applied writes after a failed write request", func() {
+				st1.SetGasLimit(1 << 32)
+				_, _, err = st1.Query(buildRequest(types.WriteQuery, []types.Query{
+					buildQuery(`INSERT INTO t0 (k, v) VALUES (?, ?)`, values[0]...),
+				}), true)
+				So(err, ShouldNotBeNil)
+				for _, v := range values[:2] {
+					_, _, err = st1.Query(buildRequest(types.WriteQuery, []types.Query{
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, v...),
+					}), true)
+					So(err, ShouldBeNil)
+				}
+				_, resp, err = st1.Query(buildRequest(types.ReadQuery, []types.Query{
+					buildQuery(`SELECT * FROM t1`),
+				}), true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows, ShouldHaveLength, 2)
+			})
+			Convey("The state should charge gas and abort requests exceeding the gas limit", func() {
+				var (
+					ins = buildRequest(types.WriteQuery, []types.Query{
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[0]...),
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[1]...),
+					})
+					sel = buildRequest(types.ReadQuery, []types.Query{buildQuery(`SELECT * FROM t1`)})
+				)
+				_, resp, err = st1.Query(ins, true)
+				So(err, ShouldBeNil)
+				So(resp.Header.GasUsed, ShouldEqual, 2*GasQuery+2*GasRowWritten)
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Header.GasUsed, ShouldBeGreaterThanOrEqualTo, GasQuery+2*GasRowRead)
+
+				sel.Header.GasLimit = GasQuery + GasRowRead
+				_, _, err = st1.Query(sel, true)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+
+				// the exceeded write request should be rolled back as a whole
+				ins = buildRequest(types.WriteQuery, []types.Query{
+					buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[2]...),
+					buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[3]...),
+				})
+				ins.Header.GasLimit = GasQuery + GasRowWritten
+				_, _, err = st1.Query(ins, true)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+				st1.SetGasLimit(GasQuery + GasRowWritten)
+				ins.Header.GasLimit = 0
+				_, _, err = st1.Query(ins, true)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+				st1.SetGasLimit(0)
+				sel.Header.GasLimit = 0
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows, ShouldHaveLength, 2)
+
+				// the vm steps should be charged even if few rows are returned
+				var (
+					fill  = []types.Query{buildQuery(`CREATE TABLE t3 (x INT)`)}
+					heavy = buildRequest(types.ReadQuery, []types.Query{
+						buildQuery(`SELECT count(*) FROM t3 a, t3 b, t3 c`),
+					})
+				)
+				for i := 0; i < 50; i++ {
+					fill = append(fill, buildQuery(`INSERT INTO t3 (x) VALUES (?)`, i))
+				}
+				_, _, err = st1.Query(buildRequest(types.WriteQuery, fill), true)
+				So(err, ShouldBeNil)
+				_, resp, err = st1.Query(heavy, true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows, ShouldHaveLength, 1)
+				So(resp.Header.GasUsed, ShouldBeGreaterThan, GasQuery+GasRowRead+100)
+				heavy.Header.GasLimit = GasQuery + GasRowRead + 100
+				_, _, err = st1.Query(heavy, true)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+				ins = buildRequest(types.WriteQuery, []types.Query{
+					buildQuery(`INSERT INTO t1 (k, v) SELECT 0, count(*) FROM t3 a, t3 b, t3 c`),
+				})
+				ins.Header.GasLimit = GasQuery + GasRowWritten + 100
+				_, _, err = st1.Query(ins, true)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows, ShouldHaveLength, 2)
+
+				// the write statement should be interrupted at the limit, and the writes of the
+				// former requests should be kept
+				var (
+					start   = time.Now()
+					endless = buildQuery(`INSERT INTO t1 (k, v)
+						SELECT 0, count(*) FROM t3 a, t3 b, t3 c, t3 d, t3 e, t3 f`)
+				)
+				ins = buildRequest(types.WriteQuery, []types.Query{
+					buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[2]...),
+				})
+				_, _, err = st1.Query(ins, true)
+				So(err, ShouldBeNil)
+				ins = buildRequest(types.WriteQuery, []types.Query{endless})
+				ins.Header.GasLimit = GasQuery + GasRowWritten + 100
+				_, _, err = st1.Query(ins, true)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+				So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows, ShouldHaveLength, 3)
+
+				// the interactive transaction should be restored to the state before the
+				// interrupted request
+				var (
+					owner   = buildRequest(types.ReadQuery, nil).Header.NodeID
+					begin   = time.Now().UTC()
+					txID    uint64
+					unlock  func()
+					txWrite = buildQuery(`INSERT INTO t1 (k, v) VALUES (?, random())`, values[3][0])
+					txQuery = func(qt types.QueryType, limit uint64, qs ...types.Query) (
+						*types.Response, error,
+					) {
+						var req = buildRequest(qt, qs)
+						req.Header.TxID = txID
+						req.Header.GasLimit = limit
+						return st1.QueryTx(context.Background(), req)
+					}
+					seen *types.Response
+				)
+				txID, err = st1.BeginTx(owner, begin, sql.LevelDefault, false, time.Second)
+				So(err, ShouldBeNil)
+				_, err = txQuery(types.WriteQuery, 0, txWrite)
+				So(err, ShouldBeNil)
+				_, err = txQuery(types.WriteQuery, GasQuery+GasRowWritten+100, endless)
+				So(errors.Cause(err), ShouldEqual, ErrGasLimitExceeded)
+				seen, err = txQuery(types.ReadQuery, 0, buildQuery(`SELECT * FROM t1`))
+				So(err, ShouldBeNil)
+				So(seen.Payload.Rows, ShouldHaveLength, 4)
+				req = buildRequest(types.WriteQuery, []types.Query{txWrite})
+				req.Header.TxID = txID
+				req.Header.TxTimestamp = begin
+				unlock, err = st1.LockWrite(req)
+				So(err, ShouldBeNil)
+				_, _, err = st1.Query(req, true)
+				unlock()
+				So(err, ShouldBeNil)
+				_, resp, err = st1.Query(sel, true)
+				So(err, ShouldBeNil)
+				So(resp.Payload.Rows, ShouldResemble, seen.Payload.Rows)
+			})
+			Convey("The state should wait for reads requiring a later applied offset", func() {
+				var (
+					ins = buildRequest(types.WriteQuery, []types.Query{
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[0]...),
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[1]...),
+					})
+					offset = st1.AppliedOffset()
+				)
+				_, resp, err = st1.Query(ins, true)
+				So(err, ShouldBeNil)
+				So(resp.Header.LogOffset, ShouldEqual, offset)
+				So(resp.Header.AppliedOffset, ShouldEqual, offset+2)
+				So(st1.AppliedOffset(), ShouldEqual, offset+2)
+				_, resp, err = st1.Query(buildRequest(types.ReadQuery, []types.Query{
+					buildQuery(`SELECT * FROM t1`),
+				}), true)
+				So(err, ShouldBeNil)
+				So(resp.Header.AppliedOffset, ShouldEqual, offset+2)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				err = st1.WaitApplied(ctx, offset+2)
+				So(err, ShouldBeNil)
+				err = st1.WaitApplied(ctx, offset+3)
+				So(errors.Cause(err), ShouldEqual, types.ErrStaleState)
+
+				ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				time.AfterFunc(100*time.Millisecond, func() {
+					_, _, _ = st1.Query(buildRequest(types.WriteQuery, []types.Query{
+						buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[2]...),
+					}), true)
+				})
+				err = st1.WaitApplied(ctx, offset+3)
+				So(err, ShouldBeNil)
+			})
+			Convey("The state should interrupt queries at the context deadline", func() {
+				var (
+					args  = make([]types.NamedArg, 64)
+					marks = make([]string, 64)
+					ctx   context.Context
+					start time.Time
+				)
+				for i := range args {
+					args[i] = types.NamedArg{Value: int64(i)}
+					marks[i] = "(?, 'v')"
+				}
+				_, _, err = st1.Query(buildRequest(types.WriteQuery, []types.Query{{
+					Pattern: `INSERT INTO t1 (k, v) VALUES ` + strings.Join(marks, ", "),
+					Args:    args,
+				}}), true)
+				So(err, ShouldBeNil)
+
+				// a cross join of 64^6 rows should never finish in time
+				var sel = buildRequest(types.ReadQuery, []types.Query{buildQuery(
+					`SELECT count(*) FROM t1 a, t1 b, t1 c, t1 d, t1 e, t1 f`)})
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+				start = time.Now()
+				_, _, err = st1.QueryWithContext(ctx, sel, true)
+				So(errors.Cause(err), ShouldEqual, types.ErrQueryTimeout)
+				So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+
+				ctx, cancel = context.WithCancel(context.Background())
+				time.AfterFunc(100*time.Millisecond, cancel)
+				_, _, err = st1.QueryWithContext(ctx, sel, true)
+				So(errors.Cause(err), ShouldEqual, context.Canceled)
+
+				// write queries are not started after the deadline
+				_, _, err = st1.QueryWithContext(ctx, buildRequest(types.WriteQuery, []types.Query{
+					buildQuery(`INSERT INTO t1 (k, v) VALUES (?, ?)`, values[0]...),
+				}), true)
+				So(errors.Cause(err), ShouldEqual, context.Canceled)
+			})
 			Convey("When queries are committed to blocks on state instance #1", func() {
 				var (
 					qt   *QueryTracker
@@ -583,34 +1119,41 @@ func TestConvertQueryAndBuildArgs(t *testing.T) {
 			"CREATE 1", []types.NamedArg{})
 		So(err, ShouldNotBeNil)
 
-		// contains stateful query parts, create table with default current_timestamp
+		// time keywords are substituted by the bound request timestamp, including default value
 		ddlQuery = "CREATE TABLE test (test datetime default current_timestamp)"
 		containsDDL, sanitizedQuery, sanitizedArgs, err = convertQueryAndBuildArgs(
 			ddlQuery, []types.NamedArg{})
-		So(err, ShouldNotBeNil)
-		So(errors.Cause(err), ShouldEqual, ErrStatefulQueryParts)
+		So(err, ShouldBeNil)
+		So(containsDDL, ShouldBeTrue)
+		So(sanitizedQuery, ShouldEqual,
+			"CREATE TABLE test (test datetime default (datetime(cql_now())))")
 
-		// contains stateful query parts, using time expression
 		containsDDL, sanitizedQuery, sanitizedArgs, err = convertQueryAndBuildArgs(
-			"SELECT current_timestamp", []types.NamedArg{})
-		So(err, ShouldNotBeNil)
-		So(errors.Cause(err), ShouldEqual, ErrStatefulQueryParts)
+			"SELECT current_timestamp, CURRENT_DATE, current_time, t.current_date FROM t",
+			[]types.NamedArg{})
+		So(err, ShouldBeNil)
+		So(sanitizedQuery, ShouldEqual, "SELECT (datetime(cql_now())), (date(cql_now())), "+
+			"(time(cql_now())), t.current_date FROM t")
 
+		// 'now' time value is substituted only as argument of date and time functions
 		containsDDL, sanitizedQuery, sanitizedArgs, err = convertQueryAndBuildArgs(
-			"SELECT current_date", []types.NamedArg{})
-		So(err, ShouldNotBeNil)
-		So(errors.Cause(err), ShouldEqual, ErrStatefulQueryParts)
+			"SELECT datetime('now', '+1 day'), strftime('%s', \"NOW\"), 'now', "+
+				"julianday (upper('now')) -- 'now'", []types.NamedArg{})
+		So(err, ShouldBeNil)
+		So(sanitizedQuery, ShouldEqual, "SELECT datetime(cql_now(), '+1 day'), "+
+			"strftime('%s', cql_now()), 'now', julianday (upper('now')) -- 'now'")
 
+		// local time modifier depends on the node time zone
 		containsDDL, sanitizedQuery, sanitizedArgs, err = convertQueryAndBuildArgs(
-			"SELECT current_time", []types.NamedArg{})
+			"SELECT datetime('now', 'localtime')", []types.NamedArg{})
 		So(err, ShouldNotBeNil)
 		So(errors.Cause(err), ShouldEqual, ErrStatefulQueryParts)
 
-		// contains stateful query parts, using random function
+		// random functions are kept, which are seeded by the bound request
 		containsDDL, sanitizedQuery, sanitizedArgs, err = convertQueryAndBuildArgs(
-			"SELECT random()", []types.NamedArg{})
-		So(err, ShouldNotBeNil)
-		So(errors.Cause(err), ShouldEqual, ErrStatefulQueryParts)
+			"SELECT random(), randomblob(4)", []types.NamedArg{})
+		So(err, ShouldBeNil)
+		So(sanitizedQuery, ShouldEqual, "SELECT random(), randomblob(4)")
 
 		// counterpart to prove successful parsing of normal query
 		containsDDL, sanitizedQuery, sanitizedArgs, err = convertQueryAndBuildArgs(
@@ -0,0 +1,451 @@
+/*
+ * Copyright 2018 The CovenantSQL Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xenomint
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/CovenantSQL/CovenantSQL/proto"
+	"github.com/CovenantSQL/CovenantSQL/types"
+	"github.com/CovenantSQL/CovenantSQL/utils/log"
+)
+
+// interactiveTx defines an interactive transaction held by the state. A writable transaction owns
+// the writer of the state exclusively, its statements are executed in a separated sql.Tx and
+// rolled back when it ends: the write queries are applied again by the commit request, which
+// is replicated to the followers like any other write request. The statements are all bound
+// with the begin time of the transaction, see bindTxQuery.
+type interactiveTx struct {
+	sync.Mutex
+	id       uint64
+	owner    proto.NodeID
+	ts       time.Time
+	readOnly bool
+	db       *sql.DB // writer of the state, nil if read-only
+	handle   *sql.Tx
+	writes   []types.Query
+	applied  []types.Query // write queries executed, rewritten from writes by row policies
+	timer    *time.Timer
+
+	committing bool
+	done       bool
+	release    sync.Once
+}
+
+// exec executes queries, which may be rewritten from the queries of req, in the transaction.
+// The original queries of req are recorded to verify the commit request. A statement exceeding
+// the gas limit is interrupted, and the transaction is restored to the state before req.
+func (tx *interactiveTx) exec(
+	ctx context.Context, req *types.Request, queries []types.Query, meter *gasMeter,
+) (
+	affectedRows, lastInsertID int64, err error,
+) {
+	if len(queries) > 1 || meter.limit > 0 {
+		if _, err = tx.handle.Exec(`SAVEPOINT "tx"`); err != nil {
+			err = errors.Wrap(err, "failed to create savepoint")
+			return
+		}
+		defer func() {
+			if err != nil {
+				if _, ierr := tx.handle.Exec(`ROLLBACK TO "tx"`); ierr != nil {
+					// The transaction is already rolled back by an interrupted statement
+					if ierr = tx.restore(); ierr != nil {
+						err = errors.Wrap(ierr, "failed to restore interrupted transaction")
+					}
+					return
+				}
+			}
+			_, _ = tx.handle.Exec(`RELEASE SAVEPOINT "tx"`)
+		}()
+	}
+	for i, v := range queries {
+		var (
+			pattern string
+			args    []interface{}
+			res     sql.Result
+			cur     int64
+		)
+		if _, pattern, args, err = convertQueryAndBuildArgs(v.Pattern, v.Args); err != nil {
+			err = errors.Wrapf(err, "execute at #%d failed", i)
+			return
+		}
+		// The deadline is only checked between statements, see restore
+		if err = interruptError(ctx, nil); err != nil {
+			err = errors.Wrapf(err, "execute at #%d failed", i)
+			return
+		}
+		if err = bindTxQuery(tx.handle, tx.owner, tx.id, tx.ts, len(tx.writes)+i); err != nil {
+			err = errors.Wrapf(err, "execute at #%d failed", i)
+			return
+		}
+		if res, err = tx.handle.ExecContext(
+			meter.withSteps(context.Background()), pattern, args...,
+		); err != nil {
+			err = errors.Wrapf(meter.stepped(err), "execute at #%d failed", i)
+			return
+		}
+		cur, _ = res.RowsAffected()
+		lastInsertID, _ = res.LastInsertId()
+		affectedRows += cur
+		if err = meter.query(); err == nil {
+			err = meter.written(cur)
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "execute at #%d failed", i)
+			return
+		}
+	}
+	tx.writes = append(tx.writes, req.Payload.Queries...)
+	tx.applied = append(tx.applied, queries...)
+	return
+}
+
+// restore opens the transaction again and executes the applied write queries, as sqlite rolls
+// back the whole transaction if a write statement in it is interrupted.
+func (tx *interactiveTx) restore() (err error) {
+	var handle *sql.Tx
+	_ = tx.handle.Rollback()
+	if handle, err = tx.db.Begin(); err != nil {
+		return errors.Wrap(err, "open tx failed")
+	}
+	tx.handle = handle
+	for i, v := range tx.applied {
+		var (
+			pattern string
+			args    []interface{}
+		)
+		if _, pattern, args, err = convertQueryAndBuildArgs(v.Pattern, v.Args); err != nil {
+			return errors.Wrapf(err, "execute at #%d failed", i)
+		}
+		if err = bindTxQuery(tx.handle, tx.owner, tx.id, tx.ts, i); err != nil {
+			return errors.Wrapf(err, "execute at #%d failed", i)
+		}
+		if _, err = tx.handle.Exec(pattern, args...); err != nil {
+			return errors.Wrapf(err, "execute at #%d failed", i)
+		}
+	}
+	return
+}
+
+func (tx *interactiveTx) verify(header *types.RequestHeader, queries []types.Query) (err error) {
+	if !header.TxTimestamp.Equal(tx.ts) {
+		return errors.Wrapf(ErrTxCommitMismatch,
+			"begin time %s vs %s", header.TxTimestamp, tx.ts)
+	}
+	if len(queries) != len(tx.writes) {
+		return errors.Wrapf(ErrTxCommitMismatch,
+			"query count %d vs executed %d", len(queries), len(tx.writes))
+	}
+	for i := range queries {
+		var enc, exp []byte
+		if enc, err = queries[i].MarshalHash(); err != nil {
+			return
+		}
+		if exp, err = tx.writes[i].MarshalHash(); err != nil {
+			return
+		}
+		if !bytes.Equal(enc, exp) {
+			return errors.Wrapf(ErrTxCommitMismatch, "query at #%d", i)
+		}
+	}
+	return
+}
+
+// rollback discards the transaction handle, it's safe to be called more than once.
+func (tx *interactiveTx) rollback() {
+	tx.Lock()
+	defer tx.Unlock()
+	if tx.done {
+		return
+	}
+	tx.done = true
+	if tx.timer != nil {
+		tx.timer.Stop()
+	}
+	if err := tx.handle.Rollback(); err != nil {
+		log.WithError(err).WithField("tx", tx.id).Warning("failed to rollback transaction")
+	}
+}
+
+// BeginTx opens an interactive transaction owned by node and returns its id. A writable
+// transaction waits for the in-flight write requests and holds off the following ones until
+// it ends. The transaction is rolled back if it's not finished within timeout, which is capped
+// by the lifetime limit of the state. The time values of the statements are evaluated with ts,
+// the begin time signed by the owner, which must be carried by the commit request.
+func (s *State) BeginTx(
+	owner proto.NodeID, ts time.Time, level sql.IsolationLevel, readOnly bool, timeout time.Duration,
+) (id uint64, err error) {
+	if level < sql.LevelDefault || level > sql.LevelSerializable {
+		err = errors.Wrapf(ErrTxIsolationLevel, "level %s", level)
+		return
+	}
+	if s.txLifetime > 0 && (timeout <= 0 || timeout > s.txLifetime) {
+		timeout = s.txLifetime
+	}
+	var tx = &interactiveTx{
+		id:       atomic.AddUint64(&s.txSeq, 1),
+		owner:    owner,
+		ts:       ts,
+		readOnly: readOnly,
+	}
+	if readOnly {
+		// A read-only transaction reads from the same reader as the read requests, so that
+		// the writes applied by the leader are visible before they are committed to block
+		if tx.handle, err = s.reader().Begin(); err != nil {
+			err = errors.Wrap(err, "open tx failed")
+			return
+		}
+	} else {
+		// Bound the writers queued on the gate, each of them holds off the write requests
+		// for its whole lifetime
+		if n := atomic.AddInt32(&s.writableTxs, 1); s.maxWritableTxs > 0 && n > s.maxWritableTxs {
+			atomic.AddInt32(&s.writableTxs, -1)
+			err = errors.Wrapf(ErrTxLimitExceeded, "%d writable transactions", n-1)
+			return
+		}
+		s.txGate.Lock()
+		if err = func() (err error) {
+			s.Lock()
+			defer s.Unlock()
+			if s.closed {
+				return ErrStateClosed
+			}
+			// Commit the ongoing transaction, the writer is taken over by the interactive one
+			// until it ends
+			s.commitHandler()
+			s.handler = nil
+			tx.db = s.strg.Writer()
+			if tx.handle, err = tx.db.Begin(); err != nil {
+				s.openHandler()
+				return errors.Wrap(err, "open tx failed")
+			}
+			s.wtx = tx
+			return
+		}(); err != nil {
+			s.releaseWriter()
+			return
+		}
+	}
+	s.txs.Store(tx.id, tx)
+	tx.Lock()
+	tx.timer = time.AfterFunc(timeout, func() { s.expireTx(tx) })
+	tx.Unlock()
+	id = tx.id
+	return
+}
+
+func (s *State) getTx(id uint64, owner proto.NodeID) (tx *interactiveTx, err error) {
+	if v, ok := s.txs.Load(id); ok {
+		if tx = v.(*interactiveTx); tx.owner == owner {
+			return
+		}
+	}
+	return nil, errors.Wrapf(ErrTxNotFound, "tx %d of %s", id, owner)
+}
+
+// QueryTx executes the queries of req in the interactive transaction specified by the request
+// header. The write queries are recorded to verify the commit request of the transaction.
+func (s *State) QueryTx(ctx context.Context, req *types.Request) (resp *types.Response, err error) {
+	var (
+		tx                         *interactiveTx
+		cnames, ctypes             []string
+		data                       [][]interface{}
+		affectedRows, lastInsertID int64
+		meter                      = s.newGasMeter(req)
+	)
+	if tx, err = s.getTx(req.Header.TxID, req.Header.NodeID); err != nil {
+		return
+	}
+	tx.Lock()
+	defer tx.Unlock()
+	if tx.done || tx.committing {
+		err = errors.Wrapf(ErrTxNotFound, "tx %d already finished", tx.id)
+		return
+	}
+	var (
+		queries  []types.Query
+		policies []*types.RowPolicy
+	)
+	if queries, policies, err = s.rowPolicyQueries(req); err != nil {
+		return
+	}
+	// Reads are bound as the next write query, which is bound again when it's executed
+	if err = bindTxQuery(tx.handle, tx.owner, tx.id, tx.ts, len(tx.writes)); err != nil {
+		return
+	}
+	defer func() {
+		_ = bind(tx.handle, 0, 0)
+	}()
+	switch req.Header.QueryType {
+	case types.ReadQuery:
+		for i, v := range queries {
+			if cnames, ctypes, data, err = readSingle(ctx, tx.handle, &v, meter); err != nil {
+				err = errors.Wrapf(err, "query at #%d failed", i)
+				return
+			}
+		}
+	case types.WriteQuery:
+		if tx.readOnly {
+			err = ErrTxReadOnly
+			return
+		}
+		if affectedRows, lastInsertID, err = tx.exec(ctx, req, queries, meter); err != nil {
+			return
+		}
+	default:
+		err = ErrInvalidRequest
+		return
+	}
+	resp = &types.Response{
+		Header: types.SignedResponseHeader{
+			ResponseHeader: types.ResponseHeader{
+				Request:       req.Header.RequestHeader,
+				RequestHash:   req.Header.Hash(),
+				NodeID:        s.nodeID,
+				Timestamp:     s.getLocalTime(),
+				RowCount:      uint64(len(data)),
+				LogOffset:     s.getSeq(),
+				AppliedOffset: s.getSeq(),
+				AffectedRows:  affectedRows,
+				LastInsertID:  lastInsertID,
+				GasUsed:       meter.used,
+				RowPolicies:   policies,
+			},
+		},
+		Payload: types.ResponsePayload{
+			Columns:   cnames,
+			DeclTypes: ctypes,
+			Rows:      buildRowsFromNativeData(data),
+		},
+	}
+	return
+}
+
+// RollbackTx rolls back the interactive transaction id owned by node.
+func (s *State) RollbackTx(owner proto.NodeID, id uint64) (err error) {
+	var tx *interactiveTx
+	if tx, err = s.getTx(id, owner); err != nil {
+		return
+	}
+	tx.Lock()
+	var committing = tx.committing
+	tx.Unlock()
+	if committing {
+		return errors.Wrapf(ErrTxNotFound, "tx %d is committing", id)
+	}
+	s.endTx(tx)
+	return
+}
+
+// LockWrite must be called by the leader before a write request is applied, and the returned
+// unlock function must be called after it's done. Write requests are held off while a writable
+// interactive transaction is open, except for the commit request of the transaction itself,
+// which is verified against the executed write queries of the transaction.
+func (s *State) LockWrite(req *types.Request) (unlock func(), err error) {
+	if req.Header.TxID == 0 {
+		s.txGate.RLock()
+		return s.txGate.RUnlock, nil
+	}
+	var tx *interactiveTx
+	if tx, err = s.getTx(req.Header.TxID, req.Header.NodeID); err != nil {
+		return
+	}
+	if tx.readOnly {
+		err = ErrTxReadOnly
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	tx.Lock()
+	defer tx.Unlock()
+	if tx.done || tx.committing {
+		err = errors.Wrapf(ErrTxNotFound, "tx %d already finished", tx.id)
+		return
+	}
+	if err = tx.verify(&req.Header.RequestHeader, req.Payload.Queries); err != nil {
+		return
+	}
+	tx.committing = true
+	tx.timer.Stop()
+	unlock = func() { s.endTx(tx) }
+	return
+}
+
+func (s *State) expireTx(tx *interactiveTx) {
+	if !tx.readOnly {
+		s.Lock()
+		defer s.Unlock()
+	}
+	tx.Lock()
+	var committing = tx.committing
+	tx.Unlock()
+	if committing {
+		return
+	}
+	log.WithFields(log.Fields{
+		"tx":    tx.id,
+		"owner": tx.owner,
+	}).Warning("interactive transaction timeout")
+	s.endTxLocked(tx)
+}
+
+// endTx rolls back tx and releases the writer if it's held by tx.
+func (s *State) endTx(tx *interactiveTx) {
+	if !tx.readOnly {
+		s.Lock()
+		defer s.Unlock()
+	}
+	s.endTxLocked(tx)
+}
+
+func (s *State) endTxLocked(tx *interactiveTx) {
+	s.detachTxLocked(tx)
+	if !tx.readOnly {
+		tx.release.Do(s.releaseWriter)
+	}
+}
+
+func (s *State) releaseWriter() {
+	s.txGate.Unlock()
+	atomic.AddInt32(&s.writableTxs, -1)
+}
+
+// detachTxLocked rolls back tx and reopens the handler if the writer is held by tx, but leaves
+// the write requests held off.
+func (s *State) detachTxLocked(tx *interactiveTx) {
+	tx.rollback()
+	s.txs.Delete(tx.id)
+	if !tx.readOnly && s.wtx == tx {
+		s.wtx = nil
+		s.openHandler()
+	}
+}
+
+// abortTxsLocked ends all the ongoing interactive transactions.
+func (s *State) abortTxsLocked() {
+	s.txs.Range(func(_, v interface{}) bool {
+		s.endTxLocked(v.(*interactiveTx))
+		return true
+	})
+}
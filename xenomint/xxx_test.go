@@ -87,7 +87,7 @@ func buildRequest(qt types.QueryType, qs []types.Query) *types.Request {
 	if id, err = kms.GetLocalNodeID(); err != nil {
 		id = proto.NodeID("00000000000000000000000000000000")
 	}
-	return &types.Request{
+	var req = &types.Request{
 		Header: types.SignedRequestHeader{
 			RequestHeader: types.RequestHeader{
 				NodeID:    id,
@@ -97,6 +97,8 @@ func buildRequest(qt types.QueryType, qs []types.Query) *types.Request {
 		},
 		Payload: types.RequestPayload{Queries: qs},
 	}
+	req.Header.Version = int32(req.Header.HSPDefaultVersion())
+	return req
 }
 
 func concat(args [][]interface{}) (ret []interface{}) {